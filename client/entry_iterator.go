@@ -0,0 +1,140 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+// DefaultIteratorBatchSize is the default maximum number of entries an
+// EntryIterator requests from the log at once.
+const DefaultIteratorBatchSize = 256
+
+// EntryParseError reports a log entry which couldn't be parsed.
+type EntryParseError struct {
+	Index int64
+	Err   error
+}
+
+// Error formats the EntryParseError.
+func (e *EntryParseError) Error() string {
+	return fmt.Sprintf("failed to parse entry %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying parsing error.
+func (e *EntryParseError) Unwrap() error {
+	return e.Err
+}
+
+// EntryIterator yields the entries of a range of the log one at a time,
+// fetching them from the log in batches as needed.
+type EntryIterator struct {
+	// BatchSize is the maximum number of entries requested from the log at
+	// once. It defaults to DefaultIteratorBatchSize.
+	BatchSize int64
+	// AbortOnParseError makes the iteration stop at the first entry which
+	// fails to parse. Otherwise Next reports the failure for that entry only,
+	// and the following call moves on to the next entry.
+	AbortOnParseError bool
+
+	ctx  context.Context
+	c    *LogClient
+	next int64 // index of the next entry to return
+	end  int64 // index of the last entry to return
+
+	buf      []ct.LeafEntry // fetched entries, buf[0] has index next
+	finalErr error          // once set, returned by all calls to Next
+}
+
+// GetEntriesIter returns an iterator over the entries in the sequence
+// [start, end] of the log.
+func (c *LogClient) GetEntriesIter(ctx context.Context, start, end int64) *EntryIterator {
+	it := &EntryIterator{
+		BatchSize: DefaultIteratorBatchSize,
+		ctx:       ctx,
+		c:         c,
+		next:      start,
+		end:       end,
+	}
+	if end < 0 {
+		it.finalErr = errors.New("end should be >= 0")
+	} else if end < start {
+		it.finalErr = errors.New("start should be <= end")
+	}
+	return it
+}
+
+// Next returns the next entry of the range. It returns io.EOF once all the
+// entries have been returned. An entry which fails to parse is reported with
+// an *EntryParseError.
+func (it *EntryIterator) Next() (*ct.LogEntry, error) {
+	if it.finalErr != nil {
+		return nil, it.finalErr
+	}
+	if it.next > it.end {
+		return nil, io.EOF
+	}
+	if len(it.buf) == 0 {
+		if err := it.fetch(); err != nil {
+			it.finalErr = err
+			return nil, err
+		}
+	}
+
+	index := it.next
+	leaf := it.buf[0]
+	it.buf = it.buf[1:]
+	it.next++
+	entry, err := ct.LogEntryFromLeaf(index, &leaf)
+	if x509.IsFatal(err) {
+		perr := &EntryParseError{Index: index, Err: err}
+		if it.AbortOnParseError {
+			it.finalErr = perr
+		}
+		return nil, perr
+	}
+	return entry, nil
+}
+
+// fetch retrieves the next batch of entries from the log.
+func (it *EntryIterator) fetch() error {
+	batchSize := it.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultIteratorBatchSize
+	}
+	end := it.next + batchSize - 1
+	if end > it.end {
+		end = it.end
+	}
+	resp, err := it.c.GetRawEntries(it.ctx, it.next, end)
+	if err != nil {
+		return err
+	}
+	if len(resp.Entries) == 0 {
+		return fmt.Errorf("log returned no entries for range [%d, %d]", it.next, end)
+	}
+	if remaining := end - it.next + 1; int64(len(resp.Entries)) > remaining {
+		// Ignore any entries beyond the range requested.
+		resp.Entries = resp.Entries[:remaining]
+	}
+	it.buf = resp.Entries
+	return nil
+}
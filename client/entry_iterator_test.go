@@ -0,0 +1,137 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/certificate-transparency-go/client"
+	"github.com/google/certificate-transparency-go/jsonclient"
+)
+
+// serveEntries returns a test HTTP server serving get-entries requests with
+// at most maxEntries entries, all valid except the one at index corrupt.
+func serveEntries(t *testing.T, maxEntries, corrupt int64) *httptest.Server {
+	t.Helper()
+	return serveHandlerAt(t, "/ct/v1/get-entries", func(w http.ResponseWriter, r *http.Request) {
+		start, err := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		if err != nil {
+			t.Fatalf("Invalid start parameter: %v", err)
+		}
+		end, err := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+		if err != nil {
+			t.Fatalf("Invalid end parameter: %v", err)
+		}
+		if end-start+1 > maxEntries {
+			end = start + maxEntries - 1
+		}
+		var entries []string
+		for i := start; i <= end; i++ {
+			if i == corrupt {
+				entries = append(entries, `{"leaf_input": "Z29vZA==", "extra_data": "Z29vZA=="}`)
+				continue
+			}
+			entries = append(entries, fmt.Sprintf(`{"leaf_input": "%s","extra_data": "%s"}`, CertEntryB64, CertEntryExtraDataB64))
+		}
+		fmt.Fprintf(w, `{"entries":[%s]}`, strings.Join(entries, ","))
+	})
+}
+
+func TestGetEntriesIter(t *testing.T) {
+	tests := []struct {
+		desc        string
+		maxEntries  int64
+		batchSize   int64
+		start, end  int64
+		corrupt     int64
+		abort       bool
+		wantIndices []int64
+		wantErrAt   int64 // index reported in a parse error, -1 for none
+		wantErr     string
+	}{
+		{desc: "single batch", maxEntries: 100, start: 0, end: 3, corrupt: -1, wantIndices: []int64{0, 1, 2, 3}, wantErrAt: -1},
+		{desc: "capped by log", maxEntries: 2, start: 5, end: 9, corrupt: -1, wantIndices: []int64{5, 6, 7, 8, 9}, wantErrAt: -1},
+		{desc: "capped by batch size", maxEntries: 100, batchSize: 3, start: 0, end: 6, corrupt: -1, wantIndices: []int64{0, 1, 2, 3, 4, 5, 6}, wantErrAt: -1},
+		{desc: "parse error skipped", maxEntries: 3, start: 0, end: 5, corrupt: 2, wantIndices: []int64{0, 1, 3, 4, 5}, wantErrAt: 2},
+		{desc: "parse error aborts", maxEntries: 3, start: 0, end: 5, corrupt: 2, abort: true, wantIndices: []int64{0, 1}, wantErrAt: 2},
+		{desc: "no progress", maxEntries: 0, start: 0, end: 5, corrupt: -1, wantErrAt: -1, wantErr: "no entries"},
+		{desc: "invalid range", maxEntries: 3, start: 3, end: 2, corrupt: -1, wantErrAt: -1, wantErr: "start should be <= end"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			ts := serveEntries(t, test.maxEntries, test.corrupt)
+			defer ts.Close()
+			lc, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			it := lc.GetEntriesIter(context.Background(), test.start, test.end)
+			if test.batchSize > 0 {
+				it.BatchSize = test.batchSize
+			}
+			it.AbortOnParseError = test.abort
+
+			var gotIndices []int64
+			gotErrAt := int64(-1)
+			var gotErr error
+			// Bound the loop in case the iterator never terminates.
+			for i := 0; i < 100; i++ {
+				entry, err := it.Next()
+				if err == io.EOF {
+					break
+				}
+				var perr *client.EntryParseError
+				if errors.As(err, &perr) {
+					if gotErrAt >= 0 && perr.Index == gotErrAt {
+						// Aborted iteration keeps reporting the same error.
+						break
+					}
+					gotErrAt = perr.Index
+					continue
+				}
+				if err != nil {
+					gotErr = err
+					break
+				}
+				gotIndices = append(gotIndices, entry.Index)
+			}
+
+			if test.wantErr != "" {
+				if gotErr == nil || !strings.Contains(gotErr.Error(), test.wantErr) {
+					t.Errorf("Next()=_, %v; want error %q", gotErr, test.wantErr)
+				}
+				return
+			}
+			if gotErr != nil {
+				t.Fatalf("Next()=_, %v; want no error", gotErr)
+			}
+			if fmt.Sprint(gotIndices) != fmt.Sprint(test.wantIndices) {
+				t.Errorf("Next() returned entries %v; want %v", gotIndices, test.wantIndices)
+			}
+			if gotErrAt != test.wantErrAt {
+				t.Errorf("Next() reported parse error at %d; want %d", gotErrAt, test.wantErrAt)
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ package client
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
 
 	ct "github.com/google/certificate-transparency-go"
@@ -24,6 +25,9 @@ import (
 )
 
 // GetRawEntries exposes the /ct/v1/get-entries result with only the JSON parsing done.
+// The leaf_input and extra_data of each entry are returned exactly as served,
+// base64-decoded but not otherwise parsed, e.g. for storing them verbatim; a
+// malformed leaf or certificate does not cause an error.
 func (c *LogClient) GetRawEntries(ctx context.Context, start, end int64) (*ct.GetEntriesResponse, error) {
 	if end < 0 {
 		return nil, errors.New("end should be >= 0")
@@ -51,18 +55,90 @@ func (c *LogClient) GetRawEntries(ctx context.Context, start, end int64) (*ct.Ge
 // retrieval operation; for more robust retrieval of parsed certificates, use GetRawEntries() and invoke
 // ct.LogEntryFromLeaf() on each individual entry.
 func (c *LogClient) GetEntries(ctx context.Context, start, end int64) ([]ct.LogEntry, error) {
+	entries, _, err := c.GetEntriesWithOptions(ctx, start, end, GetEntriesOptions{})
+	return entries, err
+}
+
+// GetEntriesOptions holds optional settings for GetEntriesWithOptions.
+type GetEntriesOptions struct {
+	// Validate enables checking that the extra_data of each entry is
+	// consistent with its leaf_input, as done by ct.RawLogEntry.CheckExtraData.
+	Validate bool
+}
+
+// GetEntriesWithOptions retrieves the entries in the sequence [start, end]
+// like GetEntries. If opts.Validate is set, it also returns the validation
+// error of each entry, at the same index as the entry, or nil if the entry is
+// valid. Invalid entries don't fail the retrieval.
+func (c *LogClient) GetEntriesWithOptions(ctx context.Context, start, end int64, opts GetEntriesOptions) ([]ct.LogEntry, []error, error) {
 	resp, err := c.GetRawEntries(ctx, start, end)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	entries := make([]ct.LogEntry, len(resp.Entries))
+	var errs []error
+	if opts.Validate {
+		errs = make([]error, len(resp.Entries))
+	}
 	for i, entry := range resp.Entries {
 		index := start + int64(i)
-		logEntry, err := ct.LogEntryFromLeaf(index, &entry)
+		rawEntry, err := ct.RawLogEntryFromLeaf(index, &entry)
+		if err != nil {
+			return nil, nil, err
+		}
+		logEntry, err := rawEntry.ToLogEntry()
 		if x509.IsFatal(err) {
-			return nil, err
+			return nil, nil, err
 		}
 		entries[i] = *logEntry
+		if opts.Validate {
+			errs[i] = rawEntry.CheckExtraData()
+		}
+	}
+	return entries, errs, nil
+}
+
+// GetEntriesFull retrieves all the entries in the sequence [start, end] from
+// the CT log server, like GetEntries. Logs commonly cap the number of entries
+// served per request, so the range is fetched with as many get-entries
+// requests as needed, each one continuing after the last entry received.
+// Returns an error if the log returns no entries for a request, to avoid
+// looping forever.
+func (c *LogClient) GetEntriesFull(ctx context.Context, start, end int64) ([]ct.LogEntry, error) {
+	if start < 0 {
+		return nil, errors.New("start should be >= 0")
+	}
+	if end < start {
+		return nil, errors.New("start should be <= end")
+	}
+
+	// The range may be much larger than what the Log holds, so entries are
+	// only allocated as they are received.
+	var entries []ct.LogEntry
+	for next := start; ; {
+		resp, err := c.GetRawEntries(ctx, next, end)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Entries) == 0 {
+			return nil, fmt.Errorf("log returned no entries for range [%d, %d]", next, end)
+		}
+		last := int64(len(resp.Entries))-1 >= end-next
+		if last {
+			// Ignore any entries beyond the range requested.
+			resp.Entries = resp.Entries[:end-next+1]
+		}
+		for i := range resp.Entries {
+			logEntry, err := ct.LogEntryFromLeaf(next+int64(i), &resp.Entries[i])
+			if x509.IsFatal(err) {
+				return nil, err
+			}
+			entries = append(entries, *logEntry)
+		}
+		if last {
+			break
+		}
+		next += int64(len(resp.Entries))
 	}
 	return entries, nil
 }
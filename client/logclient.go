@@ -19,21 +19,44 @@ package client
 
 import (
 	"context"
+	"crypto"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"sync"
 
 	ct "github.com/google/certificate-transparency-go"
 	"github.com/google/certificate-transparency-go/jsonclient"
 	"github.com/google/certificate-transparency-go/tls"
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/transparency-dev/merkle/proof"
+	"github.com/transparency-dev/merkle/rfc6962"
 )
 
 // LogClient represents a client for a given CT Log instance
 type LogClient struct {
 	jsonclient.JSONClient
+
+	sthMu   sync.Mutex
+	lastSTH *ct.SignedTreeHead // last STH accepted by GetSTHValidated
+	condSTH *sthCache          // nil unless conditional get-sth is enabled
+}
+
+// sthCache holds the last STH served by a Log along with its HTTP validators,
+// used to make conditional get-sth requests.
+type sthCache struct {
+	sth          *ct.SignedTreeHead
+	etag         string
+	lastModified string
 }
 
+// ErrSTHRegressed is returned (wrapped) by GetSTHValidated when the Log serves
+// an STH which is older or smaller than one it served before.
+var ErrSTHRegressed = errors.New("STH regressed")
+
 // CheckLogClient is an interface that allows (just) checking of various log contents.
 type CheckLogClient interface {
 	BaseURI() string
@@ -47,18 +70,28 @@ type CheckLogClient interface {
 // https://ct.googleapis.com/pilot
 // |hc| is the underlying client to be used for HTTP requests to the CT log.
 // |opts| can be used to provide a custom logger interface and a public key
-// for signature verification.
+// for signature verification. The public key pins the Log's key: the SCTs
+// returned by AddChain and AddPreChain and the STHs returned by GetSTH must
+// be signed by it, or an error is returned instead.
 func New(uri string, hc *http.Client, opts jsonclient.Options) (*LogClient, error) {
 	logClient, err := jsonclient.New(uri, hc, opts)
 	if err != nil {
 		return nil, err
 	}
-	return &LogClient{*logClient}, err
+	return &LogClient{JSONClient: *logClient}, err
 }
 
 // RspError represents a server error including HTTP information.
 type RspError = jsonclient.RspError
 
+// ClientError is the underlying error of an RspError for an HTTP 4xx
+// response, e.g. when the Log rejects a chain.
+type ClientError = jsonclient.ClientError
+
+// ServerError is the underlying error of an RspError for an HTTP 5xx
+// response, e.g. when the Log is unavailable.
+type ServerError = jsonclient.ServerError
+
 // Attempts to add |chain| to the log, using the api end-point specified by
 // |path|. If provided context expires before submission is complete an
 // error will be returned.
@@ -114,18 +147,73 @@ func (c *LogClient) AddChain(ctx context.Context, chain []ct.ASN1Cert) (*ct.Sign
 	return c.addChainWithRetry(ctx, ct.X509LogEntryType, ct.AddChainPath, chain)
 }
 
+// AddChainAndVerify adds the (DER represented) X509 |chain| to the log, then
+// checks that the returned SCT is signed over |chain| by |logPubKey|,
+// regardless of any public key the client was created with. It returns an
+// error if verification fails, even though the log accepted the chain.
+func (c *LogClient) AddChainAndVerify(ctx context.Context, chain []ct.ASN1Cert, logPubKey crypto.PublicKey) (*ct.SignedCertificateTimestamp, error) {
+	sv, err := ct.NewSignatureVerifier(logPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signature verifier: %v", err)
+	}
+	sct, err := c.AddChain(ctx, chain)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySCTSignature(sv, *sct, ct.X509LogEntryType, chain); err != nil {
+		return nil, fmt.Errorf("SCT returned by log failed verification: %v", err)
+	}
+	return sct, nil
+}
+
 // AddPreChain adds the (DER represented) Precertificate |chain| to the log.
 func (c *LogClient) AddPreChain(ctx context.Context, chain []ct.ASN1Cert) (*ct.SignedCertificateTimestamp, error) {
 	return c.addChainWithRetry(ctx, ct.PrecertLogEntryType, ct.AddPreChainPath, chain)
 }
 
+// EnableConditionalGetSTH makes GetSTH send conditional requests based on the
+// ETag or Last-Modified headers of the Log's previous get-sth response. When
+// the Log replies that the STH is unchanged, GetSTH returns a copy of the
+// previously retrieved STH.
+//
+// This is a method rather than a setting of New because the jsonclient.Options
+// taken by New configure the JSON transport shared by all the CT clients, not
+// LogClient behaviour. It is safe to call at any time, also concurrently with
+// GetSTH, and only affects the requests made after it.
+func (c *LogClient) EnableConditionalGetSTH() {
+	c.sthMu.Lock()
+	defer c.sthMu.Unlock()
+	if c.condSTH == nil {
+		c.condSTH = &sthCache{}
+	}
+}
+
 // GetSTH retrieves the current STH from the log.
 // Returns a populated SignedTreeHead, or a non-nil error (which may be of type
 // RspError if a raw http.Response is available).
 func (c *LogClient) GetSTH(ctx context.Context) (*ct.SignedTreeHead, error) {
+	var hdr http.Header
+	var cached *ct.SignedTreeHead
+	c.sthMu.Lock()
+	conditional := c.condSTH != nil
+	if conditional && c.condSTH.sth != nil {
+		cached = c.condSTH.sth
+		hdr = make(http.Header)
+		if c.condSTH.etag != "" {
+			hdr.Set("If-None-Match", c.condSTH.etag)
+		} else if c.condSTH.lastModified != "" {
+			hdr.Set("If-Modified-Since", c.condSTH.lastModified)
+		}
+	}
+	c.sthMu.Unlock()
+
 	var resp ct.GetSTHResponse
-	httpRsp, body, err := c.GetAndParse(ctx, ct.GetSTHPath, nil, &resp)
+	httpRsp, body, err := c.GetAndParseWithHeaders(ctx, ct.GetSTHPath, nil, hdr, &resp)
 	if err != nil {
+		var rspErr RspError
+		if cached != nil && errors.As(err, &rspErr) && rspErr.StatusCode == http.StatusNotModified {
+			return copySTH(cached), nil
+		}
 		return nil, err
 	}
 
@@ -137,6 +225,46 @@ func (c *LogClient) GetSTH(ctx context.Context) (*ct.SignedTreeHead, error) {
 	if err := c.VerifySTHSignature(*sth); err != nil {
 		return nil, RspError{Err: err, StatusCode: httpRsp.StatusCode, Body: body}
 	}
+
+	if conditional {
+		c.sthMu.Lock()
+		c.condSTH.sth = copySTH(sth)
+		c.condSTH.etag = httpRsp.Header.Get("ETag")
+		c.condSTH.lastModified = httpRsp.Header.Get("Last-Modified")
+		c.sthMu.Unlock()
+	}
+	return sth, nil
+}
+
+// copySTH returns a copy of sth which shares no memory with it.
+func copySTH(sth *ct.SignedTreeHead) *ct.SignedTreeHead {
+	c := *sth
+	c.TreeHeadSignature.Signature = append([]byte(nil), sth.TreeHeadSignature.Signature...)
+	return &c
+}
+
+// GetSTHValidated retrieves the current STH from the log like GetSTH, and
+// additionally checks it against the last STH returned by this method. If the
+// tree size shrank or the timestamp went backwards, it returns an error
+// wrapping ErrSTHRegressed, and the previous STH remains the reference for
+// subsequent calls.
+func (c *LogClient) GetSTHValidated(ctx context.Context) (*ct.SignedTreeHead, error) {
+	sth, err := c.GetSTH(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.sthMu.Lock()
+	defer c.sthMu.Unlock()
+	if last := c.lastSTH; last != nil {
+		if sth.TreeSize < last.TreeSize {
+			return nil, fmt.Errorf("%w: tree size %d is smaller than previously seen %d", ErrSTHRegressed, sth.TreeSize, last.TreeSize)
+		}
+		if sth.Timestamp < last.Timestamp {
+			return nil, fmt.Errorf("%w: timestamp %d is older than previously seen %d", ErrSTHRegressed, sth.Timestamp, last.Timestamp)
+		}
+	}
+	c.lastSTH = sth
 	return sth, nil
 }
 
@@ -156,12 +284,16 @@ func (c *LogClient) VerifySCTSignature(sct ct.SignedCertificateTimestamp, ctype
 		// Can't verify signatures without a verifier
 		return nil
 	}
+	return verifySCTSignature(c.Verifier, sct, ctype, certData)
+}
+
+func verifySCTSignature(sv *ct.SignatureVerifier, sct ct.SignedCertificateTimestamp, ctype ct.LogEntryType, certData []ct.ASN1Cert) error {
 	leaf, err := ct.MerkleTreeLeafFromRawChain(certData, ctype, sct.Timestamp)
 	if err != nil {
 		return fmt.Errorf("failed to build MerkleTreeLeaf: %v", err)
 	}
 	entry := ct.LogEntry{Leaf: *leaf}
-	return c.Verifier.VerifySCTSignature(sct, entry)
+	return sv.VerifySCTSignature(sct, entry)
 }
 
 // GetSTHConsistency retrieves the consistency proof between two snapshots.
@@ -193,13 +325,87 @@ func (c *LogClient) GetProofByHash(ctx context.Context, hash []byte, treeSize ui
 	return &resp, nil
 }
 
+// GetAndVerifyInclusionProof retrieves the inclusion proof of the leaf with
+// the given Merkle leaf hash in the tree described by sth, and verifies it
+// against the STH's root hash. Returns the proof, or an error if it couldn't
+// be retrieved or doesn't verify.
+func (c *LogClient) GetAndVerifyInclusionProof(ctx context.Context, leafHash []byte, sth *ct.SignedTreeHead) (*ct.GetProofByHashResponse, error) {
+	if sth == nil {
+		return nil, errors.New("nil STH")
+	}
+	rsp, err := c.GetProofByHash(ctx, leafHash, sth.TreeSize)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.LeafIndex < 0 {
+		return nil, fmt.Errorf("invalid leaf index %d", rsp.LeafIndex)
+	}
+	if err := proof.VerifyInclusion(rfc6962.DefaultHasher, uint64(rsp.LeafIndex), sth.TreeSize, leafHash, rsp.AuditPath, sth.SHA256RootHash[:]); err != nil {
+		return nil, fmt.Errorf("failed to verify inclusion proof at size %d: %v", sth.TreeSize, err)
+	}
+	return rsp, nil
+}
+
+// VerifySTHConsistency checks that the given consistency proof, as returned by
+// GetSTHConsistency, shows that the tree of the second STH is an extension of
+// the tree of the first one. This does not check the STH signatures.
+func VerifySTHConsistency(first, second *ct.SignedTreeHead, pf [][]byte) error {
+	if first == nil || second == nil {
+		return errors.New("nil STH")
+	}
+	if err := proof.VerifyConsistency(rfc6962.DefaultHasher, first.TreeSize, second.TreeSize, pf, first.SHA256RootHash[:], second.SHA256RootHash[:]); err != nil {
+		return fmt.Errorf("failed to verify consistency proof from size %d to %d: %v", first.TreeSize, second.TreeSize, err)
+	}
+	return nil
+}
+
+// GetConsistencyBetween fetches the consistency proof between the tree sizes
+// of the old and new STHs, and verifies it against their root hashes. Returns
+// nil if the tree of new is an extension of the tree of old. No proof is
+// requested if the trees have the same size, as their root hashes must then
+// simply match, or if old is the empty tree. This does not check the STH
+// signatures.
+func (c *LogClient) GetConsistencyBetween(ctx context.Context, old, new *ct.SignedTreeHead) error {
+	if old == nil || new == nil {
+		return errors.New("nil STH")
+	}
+	switch {
+	case old.TreeSize > new.TreeSize:
+		return fmt.Errorf("tree size %d of old STH is larger than %d of new STH", old.TreeSize, new.TreeSize)
+	case old.TreeSize == new.TreeSize:
+		if old.SHA256RootHash != new.SHA256RootHash {
+			return fmt.Errorf("different root hashes at tree size %d", old.TreeSize)
+		}
+		return nil
+	case old.TreeSize == 0:
+		return nil // Every tree extends the empty tree.
+	}
+	pf, err := c.GetSTHConsistency(ctx, old.TreeSize, new.TreeSize)
+	if err != nil {
+		return err
+	}
+	return VerifySTHConsistency(old, new, pf)
+}
+
 // GetAcceptedRoots retrieves the set of acceptable root certificates for a log.
 func (c *LogClient) GetAcceptedRoots(ctx context.Context) ([]ct.ASN1Cert, error) {
+	return c.GetAcceptedRootsWithLimit(ctx, 0)
+}
+
+// GetAcceptedRootsWithLimit is like GetAcceptedRoots, but fails if the Log
+// returns more than maxRoots roots. Zero means no limit.
+//
+// RFC 6962 has no pagination for get-roots, so all roots are expected in a
+// single response; following pages is left until a Log specifies how.
+func (c *LogClient) GetAcceptedRootsWithLimit(ctx context.Context, maxRoots int) ([]ct.ASN1Cert, error) {
 	var resp ct.GetRootsResponse
 	httpRsp, body, err := c.GetAndParse(ctx, ct.GetRootsPath, nil, &resp)
 	if err != nil {
 		return nil, err
 	}
+	if maxRoots > 0 && len(resp.Certificates) > maxRoots {
+		return nil, RspError{Err: fmt.Errorf("log returned %d roots, more than %d", len(resp.Certificates), maxRoots), StatusCode: httpRsp.StatusCode, Body: body}
+	}
 	var roots []ct.ASN1Cert
 	for _, cert64 := range resp.Certificates {
 		cert, err := base64.StdEncoding.DecodeString(cert64)
@@ -211,6 +417,28 @@ func (c *LogClient) GetAcceptedRoots(ctx context.Context) ([]ct.ASN1Cert, error)
 	return roots, nil
 }
 
+// GetAcceptedRootsParsed is like GetAcceptedRoots, but also parses the roots.
+// It returns the roots which parse, along with an error identifying each root
+// which doesn't by its index in the Log's list. If the roots cannot be
+// retrieved, the only error returned is the retrieval one.
+func (c *LogClient) GetAcceptedRootsParsed(ctx context.Context) ([]*x509.Certificate, []error) {
+	roots, err := c.GetAcceptedRoots(ctx)
+	if err != nil {
+		return nil, []error{err}
+	}
+	var certs []*x509.Certificate
+	var errs []error
+	for i, root := range roots {
+		cert, err := x509.ParseCertificate(root.Data)
+		if x509.IsFatal(err) {
+			errs = append(errs, fmt.Errorf("failed to parse root %d: %v", i, err))
+			continue
+		}
+		certs = append(certs, cert)
+	}
+	return certs, errs
+}
+
 // GetEntryAndProof returns a log entry and audit path for the index of a leaf.
 func (c *LogClient) GetEntryAndProof(ctx context.Context, index, treeSize uint64) (*ct.GetEntryAndProofResponse, error) {
 	base10 := 10
@@ -224,3 +452,22 @@ func (c *LogClient) GetEntryAndProof(ctx context.Context, index, treeSize uint64
 	}
 	return &resp, nil
 }
+
+// GetLogEntryAndProof is like GetEntryAndProof, but returns the entry parsed
+// into a ct.LogEntry, along with its audit path in the tree of the given size.
+// As for GetEntries, non-fatal certificate parsing errors are ignored.
+func (c *LogClient) GetLogEntryAndProof(ctx context.Context, index, treeSize uint64) (*ct.LogEntry, [][]byte, error) {
+	if index > math.MaxInt64 {
+		return nil, nil, fmt.Errorf("index %d too large", index)
+	}
+	resp, err := c.GetEntryAndProof(ctx, index, treeSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	leaf := ct.LeafEntry{LeafInput: resp.LeafInput, ExtraData: resp.ExtraData}
+	entry, err := ct.LogEntryFromLeaf(int64(index), &leaf)
+	if x509.IsFatal(err) {
+		return nil, nil, err
+	}
+	return entry, resp.AuditPath, nil
+}
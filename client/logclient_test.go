@@ -17,9 +17,14 @@ package client_test
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
@@ -28,6 +33,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -38,6 +45,8 @@ import (
 	"github.com/google/certificate-transparency-go/tls"
 	"github.com/google/certificate-transparency-go/x509"
 	"github.com/google/certificate-transparency-go/x509util"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/merkle/testonly"
 )
 
 func dh(s string) []byte {
@@ -183,6 +192,81 @@ func TestGetEntries(t *testing.T) {
 	}
 }
 
+func TestGetEntriesWithOptions(t *testing.T) {
+	mustB64 := func(s string) []byte {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatalf("failed to decode %q: %v", s, err)
+		}
+		return b
+	}
+	mustTLS := func(v interface{}) string {
+		b, err := tls.Marshal(v)
+		if err != nil {
+			t.Fatalf("tls.Marshal(): %v", err)
+		}
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	unrelated, err := x509util.CertificateFromPEM([]byte(testdata.CACertPEM))
+	if x509.IsFatal(err) {
+		t.Fatalf("failed to parse CA cert: %v", err)
+	}
+	unrelatedChain := []ct.ASN1Cert{{Data: unrelated.Raw}}
+
+	// Crafted extra_data which doesn't match the leaf_input: the precert
+	// chain has a different issuer, and the cert chain doesn't start with the
+	// issuer of the cert.
+	var precertChain ct.PrecertChainEntry
+	if _, err := tls.Unmarshal(mustB64(PrecertEntryExtraDataB64), &precertChain); err != nil {
+		t.Fatalf("failed to parse precert extra_data: %v", err)
+	}
+	precertChain.CertificateChain = unrelatedChain
+	badPrecertExtraData := mustTLS(precertChain)
+	badCertExtraData := mustTLS(ct.CertificateChain{Entries: unrelatedChain})
+
+	ts := serveRspAt(t, "/ct/v1/get-entries", fmt.Sprintf(`{"entries":[`+
+		`{"leaf_input":"%s","extra_data":"%s"},{"leaf_input":"%s","extra_data":"%s"},`+
+		`{"leaf_input":"%s","extra_data":"%s"},{"leaf_input":"%s","extra_data":"%s"}]}`,
+		PrecertEntryB64, PrecertEntryExtraDataB64,
+		CertEntryB64, CertEntryExtraDataB64,
+		PrecertEntryB64, badPrecertExtraData,
+		CertEntryB64, badCertExtraData))
+	defer ts.Close()
+	lc, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	entries, errs, err := lc.GetEntriesWithOptions(ctx, 10, 13, client.GetEntriesOptions{})
+	if err != nil {
+		t.Fatalf("GetEntriesWithOptions(Validate=false)=_,_,%v; want nil", err)
+	}
+	if len(entries) != 4 || errs != nil {
+		t.Errorf("GetEntriesWithOptions(Validate=false)=%d entries, %v; want 4 entries, nil", len(entries), errs)
+	}
+
+	entries, errs, err = lc.GetEntriesWithOptions(ctx, 10, 13, client.GetEntriesOptions{Validate: true})
+	if err != nil {
+		t.Fatalf("GetEntriesWithOptions(Validate=true)=_,_,%v; want nil", err)
+	}
+	if len(entries) != 4 || len(errs) != 4 {
+		t.Fatalf("GetEntriesWithOptions(Validate=true)=%d entries, %d errors; want 4, 4", len(entries), len(errs))
+	}
+	for i, want := range []string{"", "", "issuer key hash mismatch", "not signed by chain[0]"} {
+		if got := entries[i].Index; got != int64(10+i) {
+			t.Errorf("entries[%d].Index=%d; want %d", i, got, 10+i)
+		}
+		if want == "" {
+			if errs[i] != nil {
+				t.Errorf("errs[%d]=%v; want nil", i, errs[i])
+			}
+		} else if errs[i] == nil || !strings.Contains(errs[i].Error(), want) {
+			t.Errorf("errs[%d]=%v; want error containing %q", i, errs[i], want)
+		}
+	}
+}
+
 func TestGetEntriesErrors(t *testing.T) {
 	ctx := context.Background()
 	var tests = []struct {
@@ -221,6 +305,127 @@ func TestGetEntriesErrors(t *testing.T) {
 	}
 }
 
+func TestGetEntriesFull(t *testing.T) {
+	entry := fmt.Sprintf(`{"leaf_input": "%s","extra_data": "%s"}`, CertEntryB64, CertEntryExtraDataB64)
+	tests := []struct {
+		desc       string
+		maxEntries int64
+		treeSize   int64 // entries from treeSize onwards are not served, if set
+		start, end int64
+		wantReqs   int
+		wantErr    string
+	}{
+		{desc: "single request", maxEntries: 10, start: 0, end: 4, wantReqs: 1},
+		{desc: "exact batches", maxEntries: 2, start: 0, end: 5, wantReqs: 3},
+		{desc: "partial last batch", maxEntries: 3, start: 5, end: 12, wantReqs: 3},
+		{desc: "single entry", maxEntries: 3, start: 7, end: 7, wantReqs: 1},
+		{desc: "no progress", maxEntries: 0, start: 0, end: 4, wantReqs: 1, wantErr: "no entries"},
+		{desc: "invalid range", maxEntries: 3, start: 3, end: 2, wantErr: "start should be <= end"},
+		{desc: "negative start", maxEntries: 3, start: -1, end: 2, wantErr: "start should be >= 0"},
+		{desc: "huge range", maxEntries: 2, treeSize: 4, start: 0, end: math.MaxInt64, wantReqs: 3, wantErr: "no entries"},
+		{desc: "huge range end", maxEntries: 2, start: math.MaxInt64 - 2, end: math.MaxInt64, wantReqs: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			reqs := 0
+			ts := serveHandlerAt(t, "/ct/v1/get-entries", func(w http.ResponseWriter, r *http.Request) {
+				reqs++
+				start, err := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+				if err != nil {
+					t.Fatalf("Invalid start parameter: %v", err)
+				}
+				end, err := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+				if err != nil {
+					t.Fatalf("Invalid end parameter: %v", err)
+				}
+				if test.treeSize > 0 && end >= test.treeSize {
+					end = test.treeSize - 1
+				}
+				count := end - start + 1
+				if count > test.maxEntries {
+					count = test.maxEntries
+				}
+				if count < 0 {
+					count = 0
+				}
+				entries := make([]string, count)
+				for i := range entries {
+					entries[i] = entry
+				}
+				fmt.Fprintf(w, `{"entries":[%s]}`, strings.Join(entries, ","))
+			})
+			defer ts.Close()
+			lc, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			got, err := lc.GetEntriesFull(context.Background(), test.start, test.end)
+			if reqs != test.wantReqs {
+				t.Errorf("GetEntriesFull(%d, %d) made %d requests; want %d", test.start, test.end, reqs, test.wantReqs)
+			}
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("GetEntriesFull(%d, %d)=_, %v; want nil, %q", test.start, test.end, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetEntriesFull(%d, %d)=nil, %v; want entries, nil", test.start, test.end, err)
+			}
+			if want := test.end - test.start + 1; int64(len(got)) != want {
+				t.Fatalf("GetEntriesFull(%d, %d)=%d entries; want %d", test.start, test.end, len(got), want)
+			}
+			for i, e := range got {
+				if want := test.start + int64(i); e.Index != want {
+					t.Errorf("GetEntriesFull(%d, %d)[%d].Index=%d; want %d", test.start, test.end, i, e.Index, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetRawEntries(t *testing.T) {
+	// The second entry is not a valid MerkleTreeLeaf, and must be returned
+	// as is.
+	const garbageB64 = "bm90IGEgbGVhZg==" // "not a leaf"
+	rsp := fmt.Sprintf(`{"entries":[{"leaf_input": "%s","extra_data": "%s"},{"leaf_input": "%s","extra_data": ""}]}`,
+		PrecertEntryB64,
+		PrecertEntryExtraDataB64,
+		garbageB64)
+	ts := serveRspAt(t, "/ct/v1/get-entries", rsp)
+	defer ts.Close()
+	lc, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	got, err := lc.GetRawEntries(context.Background(), 0, 1)
+	if err != nil {
+		t.Fatalf("GetRawEntries(0,1)=nil,%v; want 2 entries,nil", err)
+	}
+	want := []ct.LeafEntry{
+		{LeafInput: b64(PrecertEntryB64), ExtraData: b64(PrecertEntryExtraDataB64)},
+		{LeafInput: b64(garbageB64), ExtraData: []byte{}},
+	}
+	if len(got.Entries) != len(want) {
+		t.Fatalf("GetRawEntries(0,1)=%d entries; want %d", len(got.Entries), len(want))
+	}
+	for i, entry := range got.Entries {
+		if !bytes.Equal(entry.LeafInput, want[i].LeafInput) {
+			t.Errorf("GetRawEntries(0,1).Entries[%d].LeafInput=%x; want %x", i, entry.LeafInput, want[i].LeafInput)
+		}
+		if !bytes.Equal(entry.ExtraData, want[i].ExtraData) {
+			t.Errorf("GetRawEntries(0,1).Entries[%d].ExtraData=%x; want %x", i, entry.ExtraData, want[i].ExtraData)
+		}
+	}
+
+	// Parsing the same entries fails.
+	if _, err := lc.GetEntries(context.Background(), 0, 1); err == nil {
+		t.Error("GetEntries(0,1)=_,nil; want error for the garbage leaf")
+	}
+}
+
 func TestGetRawEntriesErrors(t *testing.T) {
 	ctx := context.Background()
 	var tests = []struct {
@@ -355,6 +560,141 @@ func TestGetSTHErrors(t *testing.T) {
 	}
 }
 
+func TestGetSTHConditional(t *testing.T) {
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	tests := []struct {
+		desc        string
+		conditional bool
+		etag        string
+		wantHeader  string
+		wantValue   string
+	}{
+		{desc: "etag", conditional: true, etag: `"sth-1"`, wantHeader: "If-None-Match", wantValue: `"sth-1"`},
+		{desc: "last-modified", conditional: true, wantHeader: "If-Modified-Since", wantValue: lastModified},
+		{desc: "disabled", etag: `"sth-1"`},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			call := 0
+			ts := serveHandlerAt(t, "/ct/v1/get-sth", func(w http.ResponseWriter, r *http.Request) {
+				call++
+				if call > 1 && test.wantHeader != "" {
+					if got := r.Header.Get(test.wantHeader); got != test.wantValue {
+						t.Errorf("Request #%d header %s=%q; want %q", call, test.wantHeader, got, test.wantValue)
+					}
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+					t.Errorf("Request #%d unexpectedly conditional", call)
+				}
+				if test.etag != "" {
+					w.Header().Set("ETag", test.etag)
+				}
+				w.Header().Set("Last-Modified", lastModified)
+				fmt.Fprintf(w, `{"tree_size": %d, "timestamp": %d, "sha256_root_hash": "%s", "tree_head_signature": "%s"}`,
+					ValidSTHResponseTreeSize, int64(ValidSTHResponseTimestamp), ValidSTHResponseSHA256RootHash, ValidSTHResponseTreeHeadSignature)
+			})
+			defer ts.Close()
+			lc, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			if test.conditional {
+				lc.EnableConditionalGetSTH()
+			}
+
+			ctx := context.Background()
+			first, err := lc.GetSTH(ctx)
+			if err != nil {
+				t.Fatalf("GetSTH()#1=nil, %v; want _, nil", err)
+			}
+			second, err := lc.GetSTH(ctx)
+			if err != nil {
+				t.Fatalf("GetSTH()#2=nil, %v; want _, nil", err)
+			}
+			if !reflect.DeepEqual(second, first) {
+				t.Errorf("GetSTH()#2=%+v; want %+v", second, first)
+			}
+			if !test.conditional {
+				return
+			}
+			// The cached STH is served as a copy, unaffected by callers
+			// modifying the STHs they got.
+			want := *second
+			want.TreeHeadSignature.Signature = append([]byte(nil), second.TreeHeadSignature.Signature...)
+			first.TreeSize++
+			second.TreeSize++
+			second.TreeHeadSignature.Signature[0] ^= 0xff
+			third, err := lc.GetSTH(ctx)
+			if err != nil {
+				t.Fatalf("GetSTH()#3=nil, %v; want _, nil", err)
+			}
+			if third == first || third == second {
+				t.Errorf("GetSTH()#3=%p; want a copy of the cached STH", third)
+			}
+			if !reflect.DeepEqual(*third, want) {
+				t.Errorf("GetSTH()#3=%+v; want %+v", third, want)
+			}
+		})
+	}
+}
+
+func TestGetSTHValidated(t *testing.T) {
+	type sthRsp struct {
+		treeSize  uint64
+		timestamp uint64
+	}
+	tests := []struct {
+		desc    string
+		rsps    []sthRsp
+		wantErr bool
+	}{
+		{desc: "growing", rsps: []sthRsp{{10, 1000}, {10, 1000}, {20, 2000}}},
+		{desc: "new timestamp", rsps: []sthRsp{{10, 1000}, {10, 2000}}},
+		{desc: "size regressed", rsps: []sthRsp{{10, 1000}, {9, 2000}}, wantErr: true},
+		{desc: "timestamp regressed", rsps: []sthRsp{{10, 2000}, {20, 1999}}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			call := 0
+			ts := serveHandlerAt(t, "/ct/v1/get-sth", func(w http.ResponseWriter, r *http.Request) {
+				rsp := test.rsps[call]
+				call++
+				fmt.Fprintf(w, `{"tree_size": %d, "timestamp": %d, "sha256_root_hash": "%s", "tree_head_signature": "%s"}`,
+					rsp.treeSize, rsp.timestamp, ValidSTHResponseSHA256RootHash, ValidSTHResponseTreeHeadSignature)
+			})
+			defer ts.Close()
+			lc, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			ctx := context.Background()
+			last := len(test.rsps) - 1
+			for i := 0; i < last; i++ {
+				if _, err := lc.GetSTHValidated(ctx); err != nil {
+					t.Fatalf("GetSTHValidated()#%d=nil, %v; want _, nil", i, err)
+				}
+			}
+			sth, err := lc.GetSTHValidated(ctx)
+			if test.wantErr {
+				if !errors.Is(err, client.ErrSTHRegressed) {
+					t.Errorf("GetSTHValidated()#%d=%+v, %v; want nil, ErrSTHRegressed", last, sth, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetSTHValidated()#%d=nil, %v; want _, nil", last, err)
+			}
+			want := test.rsps[last]
+			if sth.TreeSize != want.treeSize || sth.Timestamp != want.timestamp {
+				t.Errorf("GetSTHValidated()#%d={TreeSize: %d, Timestamp: %d}; want {%d, %d}", last, sth.TreeSize, sth.Timestamp, want.treeSize, want.timestamp)
+			}
+		})
+	}
+}
+
 func TestAddChainRetries(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping retry test in short mode")
@@ -506,6 +846,110 @@ func TestAddChain(t *testing.T) {
 	}
 }
 
+func TestStatusErrors(t *testing.T) {
+	for _, test := range []struct {
+		status     int
+		wantClient bool
+		wantServer bool
+	}{
+		{status: http.StatusBadRequest, wantClient: true},
+		{status: http.StatusNotFound, wantClient: true},
+		{status: http.StatusInternalServerError, wantServer: true},
+		{status: http.StatusBadGateway, wantServer: true},
+		{status: http.StatusMovedPermanently},
+	} {
+		t.Run(strconv.Itoa(test.status), func(t *testing.T) {
+			const body = "go away"
+			hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				fmt.Fprint(w, body)
+			}))
+			defer hs.Close()
+			lc, err := client.New(hs.URL, &http.Client{}, jsonclient.Options{})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			_, addErr := lc.AddChain(ctx, []ct.ASN1Cert{{Data: []byte{0}}})
+			_, sthErr := lc.GetSTH(ctx)
+			for name, err := range map[string]error{"AddChain": addErr, "GetSTH": sthErr} {
+				var rspErr client.RspError
+				if !errors.As(err, &rspErr) || rspErr.StatusCode != test.status {
+					t.Errorf("%s()=_,%v; want RspError with status %d", name, err, test.status)
+				}
+				var clientErr *client.ClientError
+				if got := errors.As(err, &clientErr); got != test.wantClient {
+					t.Errorf("%s() returned ClientError: %t; want %t", name, got, test.wantClient)
+				} else if got && (clientErr.StatusCode != test.status || string(clientErr.Body) != body) {
+					t.Errorf("%s()=%+v; want status %d and body %q", name, clientErr, test.status, body)
+				}
+				var serverErr *client.ServerError
+				if got := errors.As(err, &serverErr); got != test.wantServer {
+					t.Errorf("%s() returned ServerError: %t; want %t", name, got, test.wantServer)
+				} else if got && (serverErr.StatusCode != test.status || string(serverErr.Body) != body) {
+					t.Errorf("%s()=%+v; want status %d and body %q", name, serverErr, test.status, body)
+				}
+			}
+		})
+	}
+}
+
+func TestAddChainAndVerify(t *testing.T) {
+	hs := serveSCTAt(t, "/ct/v1/add-chain", testdata.TestCertProof)
+	defer hs.Close()
+	// The client has no public key, so AddChain alone does not verify.
+	lc, err := client.New(hs.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	cert, err := x509util.CertificateFromPEM([]byte(testdata.TestCertPEM))
+	if x509.IsFatal(err) {
+		t.Fatalf("Failed to parse certificate from PEM: %v", err)
+	}
+	chain := []ct.ASN1Cert{{Data: cert.Raw}}
+
+	logPubKey, _, _, err := ct.PublicKeyFromPEM([]byte(testdata.LogPublicKeyPEM))
+	if err != nil {
+		t.Fatalf("Failed to parse log public key: %v", err)
+	}
+	wrongKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	for _, test := range []struct {
+		desc    string
+		key     interface{}
+		wantErr string
+	}{
+		{desc: "correct-key", key: logPubKey},
+		{desc: "wrong-key", key: wrongKey.Public(), wantErr: "failed verification"},
+		{desc: "no-key", wantErr: "failed to create signature verifier"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			sct, err := lc.AddChainAndVerify(context.Background(), chain, test.key)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("AddChainAndVerify()=%v,%v; want nil,err containing %q", sct, err, test.wantErr)
+				}
+				if sct != nil {
+					t.Errorf("AddChainAndVerify()=%v,_; want nil SCT on error", sct)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddChainAndVerify()=nil,%v; want sct,nil", err)
+			}
+			if sct == nil {
+				t.Error("AddChainAndVerify()=nil,nil; want sct,nil")
+			}
+		})
+	}
+}
+
 func TestAddPreChain(t *testing.T) {
 	hs := serveSCTAt(t, "/ct/v1/add-pre-chain", testdata.TestPreCertProof)
 	defer hs.Close()
@@ -531,6 +975,166 @@ func TestAddPreChain(t *testing.T) {
 	}
 }
 
+func TestPinnedPublicKey(t *testing.T) {
+	// pilotPublicKeyDER is the key of the Log which signed the STH in
+	// ValidSTHResponseTreeHeadSignature.
+	pilotPublicKeyDER, err := base64.StdEncoding.DecodeString("MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEfahLEimAoz2t01p3uMziiLOl/fHTDM0YDOhBRuiBARsV4UvxG2LdNgoIGLrtCzWE0J5APC2em4JlvR8EEEFMoA==")
+	if err != nil {
+		t.Fatalf("Failed to decode public key: %v", err)
+	}
+	logPubKey, _, _, err := ct.PublicKeyFromPEM([]byte(testdata.LogPublicKeyPEM))
+	if err != nil {
+		t.Fatalf("Failed to parse log public key: %v", err)
+	}
+	logPublicKeyDER, err := x509.MarshalPKIXPublicKey(logPubKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal log public key: %v", err)
+	}
+	wrongKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	wrongPublicKeyDER, err := x509.MarshalPKIXPublicKey(wrongKey.Public())
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	cert, err := x509util.CertificateFromPEM([]byte(testdata.TestCertPEM))
+	if x509.IsFatal(err) {
+		t.Fatalf("Failed to parse certificate from PEM: %v", err)
+	}
+	precert, err := x509util.CertificateFromPEM([]byte(testdata.TestPreCertPEM))
+	if x509.IsFatal(err) {
+		t.Fatalf("Failed to parse pre-certificate from PEM: %v", err)
+	}
+	issuer, err := x509util.CertificateFromPEM([]byte(testdata.CACertPEM))
+	if x509.IsFatal(err) {
+		t.Fatalf("Failed to parse issuer certificate from PEM: %v", err)
+	}
+
+	sthRsp := fmt.Sprintf(`{"tree_size": %d, "timestamp": %d, "sha256_root_hash": "%s", "tree_head_signature": "%s"}`,
+		ValidSTHResponseTreeSize,
+		int64(ValidSTHResponseTimestamp),
+		ValidSTHResponseSHA256RootHash,
+		ValidSTHResponseTreeHeadSignature)
+	tamperedSTHRsp := fmt.Sprintf(`{"tree_size": %d, "timestamp": %d, "sha256_root_hash": "%s", "tree_head_signature": "%s"}`,
+		ValidSTHResponseTreeSize,
+		int64(ValidSTHResponseTimestamp),
+		base64.StdEncoding.EncodeToString(make([]byte, sha256.Size)),
+		ValidSTHResponseTreeHeadSignature)
+
+	for _, test := range []struct {
+		desc    string
+		path    string
+		rawSCT  []byte
+		sthRsp  string
+		key     []byte
+		call    func(ctx context.Context, lc *client.LogClient) error
+		wantErr string
+	}{
+		{
+			desc:   "add-chain",
+			path:   "/ct/v1/add-chain",
+			rawSCT: testdata.TestCertProof,
+			key:    logPublicKeyDER,
+			call: func(ctx context.Context, lc *client.LogClient) error {
+				_, err := lc.AddChain(ctx, []ct.ASN1Cert{{Data: cert.Raw}})
+				return err
+			},
+		},
+		{
+			desc:   "add-chain-wrong-key",
+			path:   "/ct/v1/add-chain",
+			rawSCT: testdata.TestCertProof,
+			key:    wrongPublicKeyDER,
+			call: func(ctx context.Context, lc *client.LogClient) error {
+				_, err := lc.AddChain(ctx, []ct.ASN1Cert{{Data: cert.Raw}})
+				return err
+			},
+			wantErr: "failed to verify",
+		},
+		{
+			desc:   "add-pre-chain",
+			path:   "/ct/v1/add-pre-chain",
+			rawSCT: testdata.TestPreCertProof,
+			key:    logPublicKeyDER,
+			call: func(ctx context.Context, lc *client.LogClient) error {
+				_, err := lc.AddPreChain(ctx, []ct.ASN1Cert{{Data: precert.Raw}, {Data: issuer.Raw}})
+				return err
+			},
+		},
+		{
+			desc:   "add-pre-chain-wrong-key",
+			path:   "/ct/v1/add-pre-chain",
+			rawSCT: testdata.TestPreCertProof,
+			key:    wrongPublicKeyDER,
+			call: func(ctx context.Context, lc *client.LogClient) error {
+				_, err := lc.AddPreChain(ctx, []ct.ASN1Cert{{Data: precert.Raw}, {Data: issuer.Raw}})
+				return err
+			},
+			wantErr: "failed to verify",
+		},
+		{
+			desc: "get-sth",
+			path: "/ct/v1/get-sth",
+			key:  pilotPublicKeyDER,
+			call: func(ctx context.Context, lc *client.LogClient) error {
+				_, err := lc.GetSTH(ctx)
+				return err
+			},
+		},
+		{
+			desc:   "get-sth-tampered-root-hash",
+			path:   "/ct/v1/get-sth",
+			sthRsp: tamperedSTHRsp,
+			key:    pilotPublicKeyDER,
+			call: func(ctx context.Context, lc *client.LogClient) error {
+				_, err := lc.GetSTH(ctx)
+				return err
+			},
+			wantErr: "failed to verify",
+		},
+		{
+			desc: "get-sth-wrong-key",
+			path: "/ct/v1/get-sth",
+			key:  wrongPublicKeyDER,
+			call: func(ctx context.Context, lc *client.LogClient) error {
+				_, err := lc.GetSTH(ctx)
+				return err
+			},
+			wantErr: "failed to verify",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var hs *httptest.Server
+			switch {
+			case test.rawSCT != nil:
+				hs = serveSCTAt(t, test.path, test.rawSCT)
+			case test.sthRsp != "":
+				hs = serveRspAt(t, test.path, test.sthRsp)
+			default:
+				hs = serveRspAt(t, test.path, sthRsp)
+			}
+			defer hs.Close()
+			lc, err := client.New(hs.URL, &http.Client{}, jsonclient.Options{PublicKeyDER: test.key})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			err = test.call(context.Background(), lc)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("call=%v; want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("call=%v; want error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
 func TestGetSTHConsistency(t *testing.T) {
 	hs := serveRspAt(t, "/ct/v1/get-sth-consistency", GetSTHConsistencyResp)
 	defer hs.Close()
@@ -560,6 +1164,139 @@ func TestGetSTHConsistency(t *testing.T) {
 	}
 }
 
+func TestVerifySTHConsistency(t *testing.T) {
+	tree := testonly.New(rfc6962.DefaultHasher)
+	tree.AppendData(testonly.LeafInputs()...)
+	sth := func(size uint64) *ct.SignedTreeHead {
+		sth := &ct.SignedTreeHead{TreeSize: size}
+		copy(sth.SHA256RootHash[:], tree.HashAt(size))
+		return sth
+	}
+	consistency := func(size1, size2 uint64) [][]byte {
+		pf, err := tree.ConsistencyProof(size1, size2)
+		if err != nil {
+			t.Fatalf("ConsistencyProof(%d, %d)=nil,%v", size1, size2, err)
+		}
+		return pf
+	}
+	tampered := func(pf [][]byte) [][]byte {
+		out := make([][]byte, len(pf))
+		for i, h := range pf {
+			out[i] = append([]byte(nil), h...)
+		}
+		out[0][0] ^= 0x01
+		return out
+	}
+	otherRoot := sth(8)
+	otherRoot.SHA256RootHash[0] ^= 0x01
+
+	tests := []struct {
+		desc          string
+		first, second *ct.SignedTreeHead
+		pf            [][]byte
+		wantErr       bool
+	}{
+		{desc: "ok", first: sth(3), second: sth(8), pf: consistency(3, 8)},
+		{desc: "ok-power-of-two", first: sth(4), second: sth(8), pf: consistency(4, 8)},
+		{desc: "ok-same-size", first: sth(5), second: sth(5), pf: consistency(5, 5)},
+		{desc: "tampered-proof", first: sth(3), second: sth(8), pf: tampered(consistency(3, 8)), wantErr: true},
+		{desc: "truncated-proof", first: sth(3), second: sth(8), pf: consistency(3, 8)[1:], wantErr: true},
+		{desc: "wrong-proof", first: sth(3), second: sth(8), pf: consistency(2, 8), wantErr: true},
+		{desc: "wrong-root", first: sth(3), second: otherRoot, pf: consistency(3, 8), wantErr: true},
+		{desc: "shrinking", first: sth(8), second: sth(3), pf: consistency(3, 8), wantErr: true},
+		{desc: "nil-sth", first: nil, second: sth(8), pf: consistency(3, 8), wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			err := client.VerifySTHConsistency(test.first, test.second, test.pf)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Errorf("VerifySTHConsistency()=%v; want error: %t", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetConsistencyBetween(t *testing.T) {
+	tree := testonly.New(rfc6962.DefaultHasher)
+	tree.AppendData(testonly.LeafInputs()...)
+	sth := func(size uint64) *ct.SignedTreeHead {
+		sth := &ct.SignedTreeHead{TreeSize: size}
+		copy(sth.SHA256RootHash[:], tree.HashAt(size))
+		return sth
+	}
+	otherRoot := func(size uint64) *ct.SignedTreeHead {
+		sth := sth(size)
+		sth.SHA256RootHash[0] ^= 0x01
+		return sth
+	}
+
+	var requests int32
+	ts := serveHandlerAt(t, "/ct/v1/get-sth-consistency", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		first, err := strconv.ParseUint(r.URL.Query().Get("first"), 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		second, err := strconv.ParseUint(r.URL.Query().Get("second"), 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if second > tree.Size() {
+			http.Error(w, "tree size too large", http.StatusBadRequest)
+			return
+		}
+		pf, err := tree.ConsistencyProof(first, second)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(ct.GetSTHConsistencyResponse{Consistency: pf}); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	})
+	defer ts.Close()
+	lc, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	tests := []struct {
+		desc         string
+		old, new     *ct.SignedTreeHead
+		wantErr      string
+		wantRequests int32
+	}{
+		{desc: "consistent", old: sth(3), new: sth(8), wantRequests: 1},
+		{desc: "consistent-power-of-two", old: sth(4), new: sth(8), wantRequests: 1},
+		{desc: "same-tree", old: sth(5), new: sth(5)},
+		{desc: "from-empty-tree", old: sth(0), new: sth(5)},
+		{desc: "old-root-mismatch", old: otherRoot(3), new: sth(8), wantErr: "failed to verify consistency proof", wantRequests: 1},
+		{desc: "new-root-mismatch", old: sth(3), new: otherRoot(8), wantErr: "failed to verify consistency proof", wantRequests: 1},
+		{desc: "same-size-root-mismatch", old: sth(5), new: otherRoot(5), wantErr: "different root hashes"},
+		{desc: "shrinking", old: sth(8), new: sth(3), wantErr: "is larger than"},
+		{desc: "nil-sth", old: nil, new: sth(8), wantErr: "nil STH"},
+		{desc: "log-error", old: sth(3), new: &ct.SignedTreeHead{TreeSize: 100}, wantErr: "400", wantRequests: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+			err := lc.GetConsistencyBetween(context.Background(), test.old, test.new)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("GetConsistencyBetween()=%v; want nil", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("GetConsistencyBetween()=%v; want error containing %q", err, test.wantErr)
+			}
+			if got := atomic.LoadInt32(&requests); got != test.wantRequests {
+				t.Errorf("GetConsistencyBetween() made %d requests; want %d", got, test.wantRequests)
+			}
+		})
+	}
+}
+
 func TestGetSTHConsistencyErrors(t *testing.T) {
 	ctx := context.Background()
 	var tests = []struct {
@@ -630,6 +1367,44 @@ func TestGetProofByHash(t *testing.T) {
 	}
 }
 
+func TestGetAndVerifyInclusionProof(t *testing.T) {
+	hs := serveRspAt(t, "/ct/v1/get-proof-by-hash", ProofByHashResp)
+	defer hs.Close()
+	lc, err := client.New(hs.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	leafHash := dh("4a9e8edbe5ce2d2da69d483edb45186675d4be37b649d40923b156a7d1277463")
+	// Root hash of the tree of size 5 which ProofByHashResp is a proof for.
+	rootHash := dh("5aab9dfa4703b55d7afd01d651372340ac526fc9a2d8212793c412d8c055b269")
+
+	tests := []struct {
+		desc     string
+		leafHash []byte
+		treeSize uint64
+		rootHash []byte
+		wantErr  bool
+	}{
+		{desc: "ok", leafHash: leafHash, treeSize: 5, rootHash: rootHash},
+		{desc: "wrong root", leafHash: leafHash, treeSize: 5, rootHash: dh("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), wantErr: true},
+		{desc: "wrong leaf", leafHash: dh("0000000000000000000000000000000000000000000000000000000000000000"), treeSize: 5, rootHash: rootHash, wantErr: true},
+		{desc: "wrong size", leafHash: leafHash, treeSize: 4, rootHash: rootHash, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			sth := &ct.SignedTreeHead{TreeSize: test.treeSize}
+			copy(sth.SHA256RootHash[:], test.rootHash)
+			rsp, err := lc.GetAndVerifyInclusionProof(context.Background(), test.leafHash, sth)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("GetAndVerifyInclusionProof()=_, %v; want error: %t", err, test.wantErr)
+			}
+			if err == nil && rsp.LeafIndex != 3 {
+				t.Errorf("GetAndVerifyInclusionProof().LeafIndex=%d; want 3", rsp.LeafIndex)
+			}
+		})
+	}
+}
+
 func TestGetProofByHashErrors(t *testing.T) {
 	ctx := context.Background()
 	aHash := dh("4a9e8edbe5ce2d2da69d483edb45186675d4be37b649d40923b156a7d1277463")
@@ -688,6 +1463,148 @@ func TestGetAcceptedRoots(t *testing.T) {
 	}
 }
 
+func TestGetAcceptedRootsParsed(t *testing.T) {
+	var ders [][]byte
+	for _, data := range []string{testdata.CACertPEM, testdata.TestCertPEM} {
+		cert, err := x509util.CertificateFromPEM([]byte(data))
+		if x509.IsFatal(err) {
+			t.Fatalf("Failed to parse certificate from PEM: %v", err)
+		}
+		ders = append(ders, cert.Raw)
+	}
+	rsp := fmt.Sprintf(`{"certificates":[%q,"AAE=",%q]}`, base64.StdEncoding.EncodeToString(ders[0]), base64.StdEncoding.EncodeToString(ders[1]))
+	hs := serveRspAt(t, "/ct/v1/get-roots", rsp)
+	defer hs.Close()
+	lc, err := client.New(hs.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	certs, errs := lc.GetAcceptedRootsParsed(context.Background())
+	if len(certs) != len(ders) {
+		t.Fatalf("GetAcceptedRootsParsed() returned %d roots; want %d", len(certs), len(ders))
+	}
+	for i, cert := range certs {
+		if !bytes.Equal(cert.Raw, ders[i]) {
+			t.Errorf("GetAcceptedRootsParsed() root %d mismatch", i)
+		}
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "root 1") {
+		t.Errorf("GetAcceptedRootsParsed()=_,%v; want a single error for root 1", errs)
+	}
+
+	hs = serveHandlerAt(t, "/ct/v1/get-roots", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer hs.Close()
+	lc, err = client.New(hs.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if certs, errs := lc.GetAcceptedRootsParsed(context.Background()); certs != nil || len(errs) != 1 {
+		t.Errorf("GetAcceptedRootsParsed()=%v,%v; want nil and a single error", certs, errs)
+	}
+}
+
+func TestGetAcceptedRootsWithLimit(t *testing.T) {
+	const rsp = `{"certificates":["AAE=","AAI=","AAM="]}`
+	tests := []struct {
+		desc      string
+		maxRoots  int
+		wantRoots int
+		wantErr   string
+	}{
+		{desc: "no-limit", wantRoots: 3},
+		{desc: "at-limit", maxRoots: 3, wantRoots: 3},
+		{desc: "under-limit", maxRoots: 10, wantRoots: 3},
+		{desc: "over-limit", maxRoots: 2, wantErr: "more than 2"},
+	}
+	hs := serveHandlerAt(t, "/ct/v1/get-roots", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, rsp) // nolint: errcheck
+	})
+	defer hs.Close()
+	lc, err := client.New(hs.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			roots, err := lc.GetAcceptedRootsWithLimit(context.Background(), test.maxRoots)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("GetAcceptedRootsWithLimit()=_,%v; want error containing %q", err, test.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("GetAcceptedRootsWithLimit()=_,%v; want _,nil", err)
+			}
+			if len(roots) != test.wantRoots {
+				t.Errorf("GetAcceptedRootsWithLimit() returned %d roots; want %d", len(roots), test.wantRoots)
+			}
+			for i, root := range roots {
+				if want := []byte{0, byte(i + 1)}; !bytes.Equal(root.Data, want) {
+					t.Errorf("root[%d]=%x; want %x", i, root.Data, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	const userAgent = "ct-go-test/1.0"
+	sctData, err := sctToJSON(testdata.TestCertProof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	gotUA := make(map[string]string)
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gotUA[r.URL.Path] = r.Header.Get("User-Agent")
+		mu.Unlock()
+		var rsp string
+		switch r.URL.Path {
+		case "/ct/v1/get-sth":
+			rsp = fmt.Sprintf(`{"tree_size": %d, "timestamp": %d, "sha256_root_hash": "%s", "tree_head_signature": "%s"}`,
+				ValidSTHResponseTreeSize,
+				int64(ValidSTHResponseTimestamp),
+				ValidSTHResponseSHA256RootHash,
+				ValidSTHResponseTreeHeadSignature)
+		case "/ct/v1/get-roots":
+			rsp = GetRootsResp
+		case "/ct/v1/add-chain":
+			rsp = string(sctData)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, rsp) // nolint: errcheck
+	}))
+	defer hs.Close()
+
+	lc, err := client.New(hs.URL, &http.Client{}, jsonclient.Options{UserAgent: userAgent})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := lc.GetSTH(ctx); err != nil {
+		t.Errorf("GetSTH()=nil,%v; want _,nil", err)
+	}
+	if _, err := lc.GetAcceptedRoots(ctx); err != nil {
+		t.Errorf("GetAcceptedRoots()=nil,%v; want _,nil", err)
+	}
+	if _, err := lc.AddChain(ctx, []ct.ASN1Cert{{Data: []byte("cert")}}); err != nil {
+		t.Errorf("AddChain()=nil,%v; want _,nil", err)
+	}
+
+	for _, path := range []string{"/ct/v1/get-sth", "/ct/v1/get-roots", "/ct/v1/add-chain"} {
+		if got, ok := gotUA[path]; !ok {
+			t.Errorf("No request received for %s", path)
+		} else if got != userAgent {
+			t.Errorf("Request for %s had User-Agent %q; want %q", path, got, userAgent)
+		}
+	}
+}
+
 func TestGetAcceptedRootsErrors(t *testing.T) {
 	ctx := context.Background()
 	var tests = []struct {
@@ -754,6 +1671,81 @@ func TestGetEntryAndProof(t *testing.T) {
 	}
 }
 
+func TestGetLogEntryAndProof(t *testing.T) {
+	cert, err := x509util.CertificateFromPEM([]byte(testdata.TestCertPEM))
+	if x509.IsFatal(err) {
+		t.Fatalf("Failed to parse certificate from PEM: %v", err)
+	}
+	caCert, err := x509util.CertificateFromPEM([]byte(testdata.CACertPEM))
+	if x509.IsFatal(err) {
+		t.Fatalf("Failed to parse CA certificate from PEM: %v", err)
+	}
+	leaf, err := ct.MerkleTreeLeafFromChain([]*x509.Certificate{cert, caCert}, ct.X509LogEntryType, 1234)
+	if err != nil {
+		t.Fatalf("MerkleTreeLeafFromChain()=nil,%v", err)
+	}
+	leafInput, err := tls.Marshal(*leaf)
+	if err != nil {
+		t.Fatalf("tls.Marshal(leaf)=nil,%v", err)
+	}
+	extraData, err := tls.Marshal(ct.CertificateChain{Entries: []ct.ASN1Cert{{Data: caCert.Raw}}})
+	if err != nil {
+		t.Fatalf("tls.Marshal(chain)=nil,%v", err)
+	}
+	auditPath := [][]byte{dh("0102"), dh("0304")}
+	rsp, err := json.Marshal(ct.GetEntryAndProofResponse{LeafInput: leafInput, ExtraData: extraData, AuditPath: auditPath})
+	if err != nil {
+		t.Fatalf("json.Marshal()=nil,%v", err)
+	}
+
+	hs := serveHandlerAt(t, "/ct/v1/get-entry-and-proof", func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.FormValue("leaf_index"), "7"; got != want {
+			t.Errorf("leaf_index=%q; want %q", got, want)
+		}
+		if got, want := r.FormValue("tree_size"), "10"; got != want {
+			t.Errorf("tree_size=%q; want %q", got, want)
+		}
+		w.Write(rsp) // nolint: errcheck
+	})
+	defer hs.Close()
+	lc, err := client.New(hs.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	entry, gotPath, err := lc.GetLogEntryAndProof(context.Background(), 7, 10)
+	if err != nil {
+		t.Fatalf("GetLogEntryAndProof()=nil,nil,%v; want entry,proof,nil", err)
+	}
+	if entry.Index != 7 {
+		t.Errorf("GetLogEntryAndProof().Index=%d; want 7", entry.Index)
+	}
+	if entry.X509Cert == nil || !bytes.Equal(entry.X509Cert.Raw, cert.Raw) {
+		t.Errorf("GetLogEntryAndProof() returned wrong certificate")
+	}
+	if len(entry.Chain) != 1 || !bytes.Equal(entry.Chain[0].Data, caCert.Raw) {
+		t.Errorf("GetLogEntryAndProof() returned wrong chain")
+	}
+	if !reflect.DeepEqual(gotPath, auditPath) {
+		t.Errorf("GetLogEntryAndProof() audit path=%x; want %x", gotPath, auditPath)
+	}
+
+	if _, _, err := lc.GetLogEntryAndProof(context.Background(), math.MaxInt64+1, 10); err == nil {
+		t.Error("GetLogEntryAndProof(MaxInt64+1)=_,_,nil; want error")
+	}
+
+	// The canned response holds extra data which is not a certificate chain.
+	hs2 := serveRspAt(t, "/ct/v1/get-entry-and-proof", GetEntryAndProofResp)
+	defer hs2.Close()
+	lc2, err := client.New(hs2.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if _, _, err := lc2.GetLogEntryAndProof(context.Background(), 1000, 2000); err == nil || !strings.Contains(err.Error(), "CertificateChain") {
+		t.Errorf("GetLogEntryAndProof(bad extra data)=_,_,%v; want CertificateChain error", err)
+	}
+}
+
 func TestGetEntryAndProofErrors(t *testing.T) {
 	ctx := context.Background()
 	var tests = []struct {
@@ -23,9 +23,11 @@ import (
 type AppleCTPolicy struct{}
 
 // LogsByGroup describes submission requirements for embedded SCTs according to
-// https://support.apple.com/en-us/HT205280. Returns an error if it's not
+// https://support.apple.com/en-us/HT205280. Temporally sharded Logs which
+// can't accept cert are left out of the groups. Returns an error if it's not
 // possible to satisfy the policy with the provided loglist.
 func (appleP AppleCTPolicy) LogsByGroup(cert *x509.Certificate, approved *loglist3.LogList) (LogPolicyData, error) {
+	approved = temporallyCompatible(cert, approved)
 	var incCount int
 	switch m := lifetimeInMonths(cert); {
 	case m < 15:
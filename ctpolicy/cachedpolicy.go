@@ -0,0 +1,173 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctpolicy
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"sync"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+// DefaultPolicyCacheSize is the default maximum number of entries held by a
+// CachedCTPolicy.
+const DefaultPolicyCacheSize = 1024
+
+// CachedCTPolicy wraps a CTPolicy, and memoizes the Log-groups it returns.
+//
+// The wrapped policy's grouping must only depend on the cert's issuer and
+// validity period, and on the operators and Logs of the approved log list,
+// including the Logs' temporal intervals, which make up the cache key. This
+// holds for the policies in this package.
+type CachedCTPolicy struct {
+	policy  CTPolicy
+	maxSize int
+	seed    maphash.Seed
+
+	mu    sync.Mutex
+	cache map[uint64]LogPolicyData
+}
+
+// NewCachedCTPolicy returns a CachedCTPolicy wrapping policy, holding at most
+// maxSize entries. If maxSize is not positive, DefaultPolicyCacheSize is used.
+func NewCachedCTPolicy(policy CTPolicy, maxSize int) *CachedCTPolicy {
+	if maxSize <= 0 {
+		maxSize = DefaultPolicyCacheSize
+	}
+	return &CachedCTPolicy{
+		policy:  policy,
+		maxSize: maxSize,
+		seed:    maphash.MakeSeed(),
+		cache:   make(map[uint64]LogPolicyData),
+	}
+}
+
+// LogsByGroup returns the Log-grouping of the wrapped policy, from the cache
+// if possible. The returned LogPolicyData is a copy which the caller may
+// modify, e.g. with SetLogWeights. Errors are not cached.
+func (p *CachedCTPolicy) LogsByGroup(cert *x509.Certificate, approved *loglist3.LogList) (LogPolicyData, error) {
+	key := policyCacheKey(p.seed, cert, approved)
+	p.mu.Lock()
+	groups, ok := p.cache[key]
+	p.mu.Unlock()
+	if ok {
+		return groups.clone(), nil
+	}
+
+	groups, err := p.policy.LogsByGroup(cert, approved)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.cache) >= p.maxSize {
+		// Start afresh rather than tracking usage, the cache refills quickly.
+		p.cache = make(map[uint64]LogPolicyData)
+	}
+	p.cache[key] = groups.clone()
+	return groups, nil
+}
+
+// Invalidate drops all the cached entries, e.g. when the log list changes.
+// This is an optimization only, as entries for a changed log list are not
+// used anyway.
+func (p *CachedCTPolicy) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cache = make(map[uint64]LogPolicyData)
+}
+
+// Name returns label for the wrapped submission policy.
+func (p *CachedCTPolicy) Name() string {
+	return p.policy.Name()
+}
+
+// policyCacheKey hashes the cert fields and log list contents which a policy
+// can depend on.
+func policyCacheKey(seed maphash.Seed, cert *x509.Certificate, ll *loglist3.LogList) uint64 {
+	var h maphash.Hash
+	h.SetSeed(seed)
+	var buf [8]byte
+	writeInt := func(i int64) {
+		binary.BigEndian.PutUint64(buf[:], uint64(i))
+		h.Write(buf[:]) // nolint: errcheck
+	}
+	writeString := func(s string) {
+		writeInt(int64(len(s)))
+		h.WriteString(s) // nolint: errcheck
+	}
+
+	if cert != nil {
+		writeString(string(cert.RawIssuer))
+		writeInt(cert.NotBefore.UnixNano())
+		writeInt(cert.NotAfter.UnixNano())
+	}
+	if ll != nil {
+		for _, op := range ll.Operators {
+			writeString(op.Name)
+			writeInt(int64(len(op.Email)))
+			for _, email := range op.Email {
+				writeString(email)
+			}
+			writeInt(int64(len(op.Logs)))
+			for _, l := range op.Logs {
+				writeString(l.URL)
+				if ti := l.TemporalInterval; ti != nil {
+					writeInt(ti.StartInclusive.UnixNano())
+					writeInt(ti.EndExclusive.UnixNano())
+				} else {
+					writeInt(0)
+				}
+			}
+		}
+	}
+	return h.Sum64()
+}
+
+// clone returns a deep copy of the Log-groups.
+func (groups LogPolicyData) clone() LogPolicyData {
+	c := make(LogPolicyData, len(groups))
+	for name, g := range groups {
+		g.wMu.RLock()
+		gc := &LogGroupInfo{
+			Name:          g.Name,
+			MinInclusions: g.MinInclusions,
+			IsBase:        g.IsBase,
+		}
+		if g.LogURLs != nil {
+			gc.LogURLs = make(map[string]bool, len(g.LogURLs))
+			for u, ok := range g.LogURLs {
+				gc.LogURLs[u] = ok
+			}
+		}
+		if g.LogWeights != nil {
+			gc.LogWeights = make(map[string]float32, len(g.LogWeights))
+			for u, w := range g.LogWeights {
+				gc.LogWeights[u] = w
+			}
+		}
+		if g.LogPriorities != nil {
+			gc.LogPriorities = make(map[string]int, len(g.LogPriorities))
+			for u, p := range g.LogPriorities {
+				gc.LogPriorities[u] = p
+			}
+		}
+		g.wMu.RUnlock()
+		c[name] = gc
+	}
+	return c
+}
@@ -0,0 +1,155 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctpolicy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+// countingPolicy counts the calls to the wrapped policy.
+type countingPolicy struct {
+	CTPolicy
+	calls int
+}
+
+func (p *countingPolicy) LogsByGroup(cert *x509.Certificate, approved *loglist3.LogList) (LogPolicyData, error) {
+	p.calls++
+	return p.CTPolicy.LogsByGroup(cert, approved)
+}
+
+func TestCachedCTPolicy(t *testing.T) {
+	ll := sampleLogList(t)
+	withoutBob := sampleLogList(t)
+	withoutBob.Operators = withoutBob.Operators[:1]
+	certs := []*x509.Certificate{
+		getTestCertPEMShort(),
+		getTestCertPEM2Years(),
+		getTestCertPEM3Years(),
+		getTestCertPEMLongOriginal(),
+	}
+
+	for _, policy := range []CTPolicy{ChromeCTPolicy{}, AppleCTPolicy{}} {
+		t.Run(policy.Name(), func(t *testing.T) {
+			counting := &countingPolicy{CTPolicy: policy}
+			cached := NewCachedCTPolicy(counting, 0)
+			if got, want := cached.Name(), policy.Name(); got != want {
+				t.Errorf("Name()=%q; want %q", got, want)
+			}
+
+			for round := 0; round < 2; round++ {
+				for _, cert := range certs {
+					want, wantErr := policy.LogsByGroup(cert, ll)
+					got, err := cached.LogsByGroup(cert, ll)
+					if (err != nil) != (wantErr != nil) {
+						t.Fatalf("LogsByGroup()=_, %v; want error %v", err, wantErr)
+					}
+					if diff := pretty.Compare(want, got); diff != "" {
+						t.Errorf("LogsByGroup: (-uncached +cached)\n%s", diff)
+					}
+					// Callers may modify the result without affecting the cache.
+					for _, g := range got {
+						for logURL := range g.LogURLs {
+							g.LogWeights[logURL] = 0.5
+						}
+						if g.LogPriorities == nil {
+							g.LogPriorities = make(map[string]int)
+						}
+						g.LogPriorities["https://log.bob.io"] = 1
+						g.LogURLs["https://log.example.com"] = true
+						g.MinInclusions = 100
+					}
+				}
+			}
+			// The certs fall in 4 distinct validity periods, each computed once.
+			if got, want := counting.calls, len(certs); got != want {
+				t.Errorf("Wrapped policy called %d times; want %d", got, want)
+			}
+
+			// A different log list is not served from the cache.
+			want, wantErr := policy.LogsByGroup(certs[0], withoutBob)
+			got, err := cached.LogsByGroup(certs[0], withoutBob)
+			if (err != nil) != (wantErr != nil) {
+				t.Fatalf("LogsByGroup()=_, %v; want error %v", err, wantErr)
+			}
+			if diff := pretty.Compare(want, got); diff != "" {
+				t.Errorf("LogsByGroup with changed list: (-uncached +cached)\n%s", diff)
+			}
+			if got, want := counting.calls, len(certs)+1; got != want {
+				t.Errorf("Wrapped policy called %d times; want %d", got, want)
+			}
+
+			cached.Invalidate()
+			if _, err := cached.LogsByGroup(certs[0], ll); err != nil {
+				t.Fatalf("LogsByGroup()=_, %v", err)
+			}
+			if got, want := counting.calls, len(certs)+2; got != want {
+				t.Errorf("Wrapped policy called %d times after Invalidate; want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestCachedCTPolicyMaxSize(t *testing.T) {
+	ll := sampleLogList(t)
+	counting := &countingPolicy{CTPolicy: ChromeCTPolicy{}}
+	cached := NewCachedCTPolicy(counting, 1)
+	for _, cert := range []*x509.Certificate{getTestCertPEMShort(), getTestCertPEMLongOriginal(), getTestCertPEMShort()} {
+		if _, err := cached.LogsByGroup(cert, ll); err != nil {
+			t.Fatalf("LogsByGroup()=_, %v", err)
+		}
+	}
+	if got, want := counting.calls, 3; got != want {
+		t.Errorf("Wrapped policy called %d times; want %d", got, want)
+	}
+}
+
+func BenchmarkLogsByGroup(b *testing.B) {
+	// Build a log list with 10 operators of 10 Logs each, in the order of
+	// magnitude of the Chrome log list.
+	var ll loglist3.LogList
+	for i := 0; i < 10; i++ {
+		op := &loglist3.Operator{Name: fmt.Sprintf("Operator %d", i)}
+		if i == 0 {
+			op.Email = []string{"google-ct-logs@googlegroups.com"}
+		}
+		for j := 0; j < 10; j++ {
+			op.Logs = append(op.Logs, &loglist3.Log{URL: fmt.Sprintf("https://ct.operator%d.example.com/log%d/", i, j)})
+		}
+		ll.Operators = append(ll.Operators, op)
+	}
+	cert := getTestCertPEMLongOriginal()
+	for _, bm := range []struct {
+		name   string
+		policy CTPolicy
+	}{
+		{name: "Chrome", policy: ChromeCTPolicy{}},
+		{name: "CachedChrome", policy: NewCachedCTPolicy(ChromeCTPolicy{}, 0)},
+		{name: "Apple", policy: AppleCTPolicy{}},
+		{name: "CachedApple", policy: NewCachedCTPolicy(AppleCTPolicy{}, 0)},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := bm.policy.LogsByGroup(cert, &ll); err != nil {
+					b.Fatalf("LogsByGroup()=_, %v", err)
+				}
+			}
+		})
+	}
+}
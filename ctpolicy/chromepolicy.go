@@ -25,8 +25,10 @@ type ChromeCTPolicy struct {
 
 // LogsByGroup describes submission requirements for embedded SCTs according to
 // https://github.com/chromium/ct-policy/blob/master/ct_policy.md#qualifying-certificate.
+// Temporally sharded Logs which can't accept cert are left out of the groups.
 // Returns an error if it's not possible to satisfy the policy with the provided loglist.
 func (chromeP ChromeCTPolicy) LogsByGroup(cert *x509.Certificate, approved *loglist3.LogList) (LogPolicyData, error) {
+	approved = temporallyCompatible(cert, approved)
 	googGroup := LogGroupInfo{Name: "Google-operated", IsBase: false}
 	googGroup.populate(approved, func(op *loglist3.Operator) bool { return op.GoogleOperated() })
 	if err := googGroup.setMinInclusions(1); err != nil {
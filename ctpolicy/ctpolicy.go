@@ -17,6 +17,7 @@ package ctpolicy
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/google/certificate-transparency-go/loglist3"
@@ -35,7 +36,22 @@ type LogGroupInfo struct {
 	MinInclusions int                // Required number of submissions.
 	IsBase        bool               // True only for Log-group covering all logs.
 	LogWeights    map[string]float32 // weights used for submission, default weight is 1
-	wMu           sync.RWMutex       // guards weights
+	LogPriorities map[string]int     // submission priorities, higher first; default priority is 0
+	// LogOperators maps the Log-URLs to the names of their operators. If set,
+	// MinInclusions is the required number of distinct operators, and
+	// further SCTs from an operator which already provided one don't count.
+	LogOperators map[string]string
+	wMu          sync.RWMutex // guards weights
+}
+
+// member returns the key under which an SCT from logURL counts towards the
+// minimal inclusion number: the Log's operator if the group counts distinct
+// operators, or the Log itself otherwise.
+func (group *LogGroupInfo) member(logURL string) string {
+	if group.LogOperators != nil {
+		return group.LogOperators[logURL]
+	}
+	return logURL
 }
 
 func (group *LogGroupInfo) setMinInclusions(i int) error {
@@ -44,6 +60,16 @@ func (group *LogGroupInfo) setMinInclusions(i int) error {
 	}
 	// Assign given number even if it's bigger than group size.
 	group.MinInclusions = i
+	if group.LogOperators != nil {
+		ops := make(map[string]bool)
+		for logURL := range group.LogURLs {
+			ops[group.member(logURL)] = true
+		}
+		if i > len(ops) {
+			return fmt.Errorf("trying to assign %d minimal inclusion number while only %d operators are part of group %q", i, len(ops), group.Name)
+		}
+		return nil
+	}
 	if i > len(group.LogURLs) {
 		return fmt.Errorf("trying to assign %d minimal inclusion number while only %d logs are part of group %q", i, len(group.LogURLs), group.Name)
 	}
@@ -64,13 +90,14 @@ func (group *LogGroupInfo) populate(ll *loglist3.LogList, included func(op *logl
 }
 
 // satisfyMinimalInclusion returns whether number of positive weights is
-// bigger or equal to minimal inclusion number.
+// bigger or equal to minimal inclusion number. For a group counting distinct
+// operators, only one positive weight per operator is counted.
 func (group *LogGroupInfo) satisfyMinimalInclusion(weights map[string]float32) bool {
-	nonZeroNum := 0
+	nonZero := make(map[string]bool)
 	for logURL, w := range weights {
 		if group.LogURLs[logURL] && w > 0.0 {
-			nonZeroNum++
-			if nonZeroNum >= group.MinInclusions {
+			nonZero[group.member(logURL)] = true
+			if len(nonZero) >= group.MinInclusions {
 				return true
 			}
 		}
@@ -129,7 +156,8 @@ func (group *LogGroupInfo) SetLogWeight(logURL string, w float32) error {
 }
 
 // GetSubmissionSession produces list of log-URLs of the Log-group.
-// Order of the list is weighted random defined by Log-weights within the group
+// Logs are ordered by Log-priority, highest first. Order of the Logs sharing
+// a priority is weighted random defined by Log-weights within the group.
 func (group *LogGroupInfo) GetSubmissionSession() []string {
 	if len(group.LogURLs) == 0 {
 		return make([]string, 0)
@@ -148,14 +176,26 @@ func (group *LogGroupInfo) GetSubmissionSession() []string {
 		sampleLog, err := weightedRandomSample(unProcessedWeights)
 		if err != nil {
 			// session still valid, not covering all Logs
+			group.sortByPriority(session)
 			return session
 		}
 		session = append(session, sampleLog)
 		delete(unProcessedWeights, sampleLog)
 	}
+	group.sortByPriority(session)
 	return session
 }
 
+// sortByPriority stably sorts the Logs by descending Log-priority.
+func (group *LogGroupInfo) sortByPriority(session []string) {
+	if len(group.LogPriorities) == 0 {
+		return
+	}
+	sort.SliceStable(session, func(i, j int) bool {
+		return group.LogPriorities[session[i]] > group.LogPriorities[session[j]]
+	})
+}
+
 // LogPolicyData contains info on log-partition and submission requirements
 // for a single cert. Key always matches value Name field.
 type LogPolicyData map[string]*LogGroupInfo
@@ -192,6 +232,13 @@ func BaseGroupFor(approved *loglist3.LogList, incCount int) (*LogGroupInfo, erro
 	return &baseGroup, err
 }
 
+// temporallyCompatible returns the Logs of approved which can accept cert,
+// dropping the temporally sharded ones whose interval excludes its NotAfter.
+func temporallyCompatible(cert *x509.Certificate, approved *loglist3.LogList) *loglist3.LogList {
+	compatible := approved.TemporallyCompatible(cert)
+	return &compatible
+}
+
 // lifetimeInMonths calculates and returns cert lifetime expressed in months
 // flooring incomplete month.
 func lifetimeInMonths(cert *x509.Certificate) int {
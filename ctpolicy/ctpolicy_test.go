@@ -16,6 +16,7 @@ package ctpolicy
 import (
 	"encoding/json"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -48,6 +49,9 @@ func getTestCertPEMLongOriginal() *x509.Certificate {
 	return cert
 }
 
+// sampleLogList returns the sample log list without the temporal intervals of
+// its sharded Logs, so that all of them accept the test certs, whose validity
+// periods are chosen for their lifetimes.
 func sampleLogList(t *testing.T) *loglist3.LogList {
 	t.Helper()
 	var ll loglist3.LogList
@@ -55,6 +59,11 @@ func sampleLogList(t *testing.T) *loglist3.LogList {
 	if err != nil {
 		t.Fatalf("Unable to Unmarshal testdata.SampleLogList3 %v", err)
 	}
+	for _, op := range ll.Operators {
+		for _, l := range op.Logs {
+			l.TemporalInterval = nil
+		}
+	}
 	return &ll
 }
 
@@ -104,6 +113,113 @@ func TestLifetimeInMonths(t *testing.T) {
 	}
 }
 
+// shardedLogList returns a log list with a Google operator running yearly
+// shards for 2020 and 2021 along with an unsharded Log, and another operator
+// running a 2020 shard only.
+func shardedLogList() *loglist3.LogList {
+	shard := func(url string, year int) *loglist3.Log {
+		return &loglist3.Log{URL: url, TemporalInterval: &loglist3.TemporalInterval{
+			StartInclusive: time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndExclusive:   time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC),
+		}}
+	}
+	return &loglist3.LogList{Operators: []*loglist3.Operator{
+		{
+			Name:  "Google",
+			Email: []string{"google-ct-logs@googlegroups.com"},
+			Logs: []*loglist3.Log{
+				shard("https://ct.googleapis.com/2020/", 2020),
+				shard("https://ct.googleapis.com/2021/", 2021),
+				{URL: "https://ct.googleapis.com/all/"},
+			},
+		},
+		{
+			Name:  "Bob",
+			Email: []string{"bob@example.com"},
+			Logs:  []*loglist3.Log{shard("https://log.bob.io/2020/", 2020)},
+		},
+	}}
+}
+
+func TestLogsByGroupTemporalShards(t *testing.T) {
+	certExpiring := func(notAfter time.Time) *x509.Certificate {
+		return &x509.Certificate{NotBefore: notAfter.AddDate(0, -3, 0), NotAfter: notAfter}
+	}
+	// The certs expire on either side of the shard boundary.
+	cert2020 := certExpiring(time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC))
+	cert2021 := certExpiring(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name    string
+		policy  CTPolicy
+		cert    *x509.Certificate
+		want    map[string][]string // group name => Log URLs
+		wantErr bool
+	}{
+		{
+			name:   "Chrome-2020",
+			policy: ChromeCTPolicy{},
+			cert:   cert2020,
+			want: map[string][]string{
+				"Google-operated":     {"https://ct.googleapis.com/2020/", "https://ct.googleapis.com/all/"},
+				"Non-Google-operated": {"https://log.bob.io/2020/"},
+				BaseName:              {"https://ct.googleapis.com/2020/", "https://ct.googleapis.com/all/", "https://log.bob.io/2020/"},
+			},
+		},
+		{
+			// No non-Google Log can accept the cert.
+			name:    "Chrome-2021",
+			policy:  ChromeCTPolicy{},
+			cert:    cert2021,
+			wantErr: true,
+		},
+		{
+			name:   "Apple-2020",
+			policy: AppleCTPolicy{},
+			cert:   cert2020,
+			want: map[string][]string{
+				BaseName: {"https://ct.googleapis.com/2020/", "https://ct.googleapis.com/all/", "https://log.bob.io/2020/"},
+			},
+		},
+		{
+			name:   "Apple-2021",
+			policy: AppleCTPolicy{},
+			cert:   cert2021,
+			want: map[string][]string{
+				BaseName: {"https://ct.googleapis.com/2021/", "https://ct.googleapis.com/all/"},
+			},
+		},
+		{
+			name:    "Diversity-2021",
+			policy:  DiversityCTPolicy{MinSCTs: 2, MinOperators: 2},
+			cert:    cert2021,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			groups, err := test.policy.LogsByGroup(test.cert, shardedLogList())
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("LogsByGroup()=_, %v; want err? %t", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got := make(map[string][]string)
+			for name, g := range groups {
+				for logURL := range g.LogURLs {
+					got[name] = append(got[name], logURL)
+				}
+				sort.Strings(got[name])
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("LogsByGroup()=%v; want %v", got, test.want)
+			}
+		})
+	}
+}
+
 func TestGroupByLogs(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -213,3 +329,22 @@ func TestGroupByLogs(t *testing.T) {
 		})
 	}
 }
+
+func TestGetSubmissionSessionPriorities(t *testing.T) {
+	group := LogGroupInfo{
+		Name:          "a",
+		LogURLs:       map[string]bool{"a1.com": true, "a2.com": true, "a3.com": true, "a4.com": true},
+		MinInclusions: 1,
+		LogWeights:    map[string]float32{"a1.com": 1.0, "a2.com": 1.0, "a3.com": 1.0, "a4.com": 1.0},
+		LogPriorities: map[string]int{"a3.com": 2, "a1.com": 1},
+	}
+	for i := 0; i < 100; i++ {
+		session := group.GetSubmissionSession()
+		if len(session) != 4 {
+			t.Fatalf("GetSubmissionSession() = %v, want 4 Logs", session)
+		}
+		if session[0] != "a3.com" || session[1] != "a1.com" {
+			t.Fatalf("GetSubmissionSession() = %v, want [a3.com a1.com ...]", session)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctpolicy
+
+import (
+	"fmt"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+// DiversityCTPolicy implements a policy requiring at least MinSCTs SCTs,
+// from Logs of at least MinOperators distinct operators.
+type DiversityCTPolicy struct {
+	MinSCTs      int
+	MinOperators int
+}
+
+// DistinctOperatorsName is the name of the group of DiversityCTPolicy
+// requiring SCTs from distinct operators.
+const DistinctOperatorsName = "Distinct-operators"
+
+// LogsByGroup describes submission requirements for the policy. All the Logs
+// form a group requiring SCTs from MinOperators distinct operators, so that
+// SCTs from any MinOperators of the operators satisfy it. Temporally sharded
+// Logs which can't accept cert are left out of the group. Returns an error if
+// it's not possible to satisfy the policy with the provided loglist.
+func (p DiversityCTPolicy) LogsByGroup(cert *x509.Certificate, approved *loglist3.LogList) (LogPolicyData, error) {
+	approved = temporallyCompatible(cert, approved)
+	if p.MinOperators < 0 {
+		return nil, fmt.Errorf("cannot require negative number %d of operators", p.MinOperators)
+	}
+	ops := 0
+	for _, op := range approved.Operators {
+		if len(op.Logs) > 0 {
+			ops++
+		}
+	}
+	if ops < p.MinOperators {
+		return nil, fmt.Errorf("trying to require SCTs from %d distinct operators while only %d operators have Logs", p.MinOperators, ops)
+	}
+
+	groups := make(LogPolicyData)
+	if p.MinOperators > 0 {
+		group := &LogGroupInfo{Name: DistinctOperatorsName, IsBase: false}
+		group.populate(approved, func(op *loglist3.Operator) bool { return true })
+		group.LogOperators = make(map[string]string)
+		for _, op := range approved.Operators {
+			for _, l := range op.Logs {
+				group.LogOperators[l.URL] = op.Name
+			}
+		}
+		if err := group.setMinInclusions(p.MinOperators); err != nil {
+			return nil, err
+		}
+		groups[group.Name] = group
+	}
+	baseGroup, err := BaseGroupFor(approved, p.MinSCTs)
+	if err != nil {
+		return nil, err
+	}
+	groups[baseGroup.Name] = baseGroup
+	return groups, nil
+}
+
+// Name returns label for the submission policy.
+func (p DiversityCTPolicy) Name() string {
+	return "Diversity"
+}
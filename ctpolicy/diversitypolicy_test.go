@@ -0,0 +1,155 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctpolicy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+// groupSummary describes a Log-group by its minimal inclusion number and its
+// sorted Log-URLs.
+func groupSummary(g *LogGroupInfo) string {
+	var urls []string
+	for u := range g.LogURLs {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return fmt.Sprintf("%d of %s", g.MinInclusions, strings.Join(urls, " "))
+}
+
+func TestCheckDiversityPolicy(t *testing.T) {
+	const (
+		bob       = "https://log.bob.io"
+		carol     = "https://log.carol.io"
+		argon     = "https://ct.googleapis.com/logs/argon2020/"
+		aviator   = "https://ct.googleapis.com/aviator/"
+		icarus    = "https://ct.googleapis.com/icarus/"
+		racketeer = "https://ct.googleapis.com/racketeer/"
+		rocketeer = "https://ct.googleapis.com/rocketeer/"
+	)
+	google := strings.Join([]string{aviator, icarus, racketeer, rocketeer, argon}, " ")
+	withCarol := func(ll *loglist3.LogList) {
+		ll.Operators = append(ll.Operators, &loglist3.Operator{
+			Name: "Carol's Logs",
+			Logs: []*loglist3.Log{{URL: carol}},
+		})
+	}
+	withoutBobLogs := func(ll *loglist3.LogList) {
+		ll.Operators[1].Logs = nil
+	}
+
+	tests := []struct {
+		name    string
+		policy  DiversityCTPolicy
+		modify  func(ll *loglist3.LogList)
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:   "TwoOperators",
+			policy: DiversityCTPolicy{MinSCTs: 3, MinOperators: 2},
+			want: map[string]string{
+				DistinctOperatorsName: "2 of " + google + " " + bob,
+				BaseName:              "3 of " + google + " " + bob,
+			},
+		},
+		{
+			name:   "TwoOfThreeOperators",
+			policy: DiversityCTPolicy{MinSCTs: 2, MinOperators: 2},
+			modify: withCarol,
+			want: map[string]string{
+				DistinctOperatorsName: "2 of " + google + " " + bob + " " + carol,
+				BaseName:              "2 of " + google + " " + bob + " " + carol,
+			},
+		},
+		{
+			name:   "ThreeOperators",
+			policy: DiversityCTPolicy{MinSCTs: 4, MinOperators: 3},
+			modify: withCarol,
+			want: map[string]string{
+				DistinctOperatorsName: "3 of " + google + " " + bob + " " + carol,
+				BaseName:              "4 of " + google + " " + bob + " " + carol,
+			},
+		},
+		{
+			name:   "NoOperatorRequirement",
+			policy: DiversityCTPolicy{MinSCTs: 2},
+			want: map[string]string{
+				BaseName: "2 of " + google + " " + bob,
+			},
+		},
+		{
+			name:    "TooFewOperators",
+			policy:  DiversityCTPolicy{MinSCTs: 3, MinOperators: 3},
+			wantErr: "only 2 operators have Logs",
+		},
+		{
+			name:    "OperatorWithoutLogs",
+			policy:  DiversityCTPolicy{MinSCTs: 2, MinOperators: 2},
+			modify:  withoutBobLogs,
+			wantErr: "only 1 operators have Logs",
+		},
+		{
+			name:    "TooManySCTs",
+			policy:  DiversityCTPolicy{MinSCTs: 7, MinOperators: 2},
+			wantErr: "only 6 logs are part of group",
+		},
+		{
+			name:    "NegativeOperators",
+			policy:  DiversityCTPolicy{MinSCTs: 2, MinOperators: -1},
+			wantErr: "negative",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ll := sampleLogList(t)
+			if test.modify != nil {
+				test.modify(ll)
+			}
+			got, err := test.policy.LogsByGroup(getTestCertPEMShort(), ll)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("LogsByGroup()=_, %v; want error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LogsByGroup()=_, %v; want no error", err)
+			}
+			gotSummary := make(map[string]string)
+			for name, g := range got {
+				gotSummary[name] = groupSummary(g)
+			}
+			// Sort the URLs of the wanted groups, like groupSummary does.
+			wantSummary := make(map[string]string)
+			for name, s := range test.want {
+				parts := strings.SplitN(s, " of ", 2)
+				urls := strings.Fields(parts[1])
+				sort.Strings(urls)
+				wantSummary[name] = parts[0] + " of " + strings.Join(urls, " ")
+			}
+			if diff := pretty.Compare(wantSummary, gotSummary); diff != "" {
+				t.Errorf("LogsByGroup: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
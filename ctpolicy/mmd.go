@@ -0,0 +1,35 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctpolicy
+
+import (
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+)
+
+// MergeDeadline returns the time by which a Log should have incorporated an
+// entry into its tree, given the timestamp of the SCT it issued for the entry
+// (in milliseconds since the epoch, as in ct.SignedCertificateTimestamp) and
+// its Maximum Merge Delay (in seconds, as in loglist3.Log). It also returns
+// whether the deadline has passed at the reference time now. A negative MMD is
+// treated as zero.
+func MergeDeadline(sctTimestamp uint64, mmd int32, now time.Time) (time.Time, bool) {
+	if mmd < 0 {
+		mmd = 0
+	}
+	deadline := ct.TimestampToTime(sctTimestamp).Add(time.Duration(mmd) * time.Second)
+	return deadline, now.After(deadline)
+}
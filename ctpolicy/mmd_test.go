@@ -0,0 +1,106 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctpolicy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeDeadline(t *testing.T) {
+	// 2022-01-02T03:04:05.678Z in milliseconds since the epoch.
+	const ts = uint64(1641092645678)
+	sctTime := time.Date(2022, 1, 2, 3, 4, 5, 678000000, time.UTC)
+
+	tests := []struct {
+		name       string
+		timestamp  uint64
+		mmd        int32
+		now        time.Time
+		want       time.Time
+		wantPassed bool
+	}{
+		{
+			name:      "DayMMDWithinDeadline",
+			timestamp: ts,
+			mmd:       86400,
+			now:       sctTime.Add(23 * time.Hour),
+			want:      sctTime.Add(24 * time.Hour),
+		},
+		{
+			name:      "DayMMDAtDeadline",
+			timestamp: ts,
+			mmd:       86400,
+			now:       sctTime.Add(24 * time.Hour),
+			want:      sctTime.Add(24 * time.Hour),
+		},
+		{
+			name:       "DayMMDPastDeadline",
+			timestamp:  ts,
+			mmd:        86400,
+			now:        sctTime.Add(24*time.Hour + time.Millisecond),
+			want:       sctTime.Add(24 * time.Hour),
+			wantPassed: true,
+		},
+		{
+			name:       "HourMMDPastDeadline",
+			timestamp:  ts,
+			mmd:        3600,
+			now:        sctTime.Add(2 * time.Hour),
+			want:       sctTime.Add(time.Hour),
+			wantPassed: true,
+		},
+		{
+			name:      "ZeroMMD",
+			timestamp: ts,
+			now:       sctTime,
+			want:      sctTime,
+		},
+		{
+			name:       "NegativeMMD",
+			timestamp:  ts,
+			mmd:        -60,
+			now:        sctTime.Add(time.Second),
+			want:       sctTime,
+			wantPassed: true,
+		},
+		{
+			name:      "SCTFromTheFuture",
+			timestamp: ts,
+			mmd:       86400,
+			now:       sctTime.Add(-time.Hour),
+			want:      sctTime.Add(24 * time.Hour),
+		},
+		{
+			name:       "EpochTimestamp",
+			timestamp:  0,
+			mmd:        86400,
+			now:        sctTime,
+			want:       time.Unix(86400, 0),
+			wantPassed: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, gotPassed := MergeDeadline(test.timestamp, test.mmd, test.now)
+			if !got.Equal(test.want) {
+				t.Errorf("MergeDeadline(%d, %d, %v)=%v, _; want %v", test.timestamp, test.mmd, test.now, got, test.want)
+			}
+			if gotPassed != test.wantPassed {
+				t.Errorf("MergeDeadline(%d, %d, %v)=_, %v; want %v", test.timestamp, test.mmd, test.now, gotPassed, test.wantPassed)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"fmt"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/tls"
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/certificate-transparency-go/x509util"
+)
+
+// CollectSCTs gathers the SCTs delivered for a certificate through any of the
+// three mechanisms of RFC 6962 section 3.3: embedded in cert, in tlsExt, the
+// TLS-encoded SignedCertificateTimestampList of the TLS extension, and in the
+// SCT list extension of ocspDER, a DER-encoded OCSP response. Sources which
+// are nil or empty are skipped. An SCT delivered more than once is returned
+// once, in the order of its first occurrence, embedded SCTs coming first, then
+// those of the TLS extension, then those of the OCSP response. The SCTs are
+// not verified.
+func CollectSCTs(cert *x509.Certificate, tlsExt []byte, ocspDER []byte) ([]*ct.SignedCertificateTimestamp, error) {
+	var all []*ct.SignedCertificateTimestamp
+	if cert != nil {
+		scts, err := x509util.SCTsFromCertificate(cert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get embedded SCTs: %v", err)
+		}
+		all = append(all, scts...)
+	}
+	if len(tlsExt) > 0 {
+		scts, err := ct.ParseSCTList(tlsExt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get TLS extension SCTs: %v", err)
+		}
+		all = append(all, scts...)
+	}
+	if len(ocspDER) > 0 {
+		scts, err := SCTsFromOCSPResponse(ocspDER)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get OCSP response SCTs: %v", err)
+		}
+		all = append(all, scts...)
+	}
+
+	var unique []*ct.SignedCertificateTimestamp
+	seen := make(map[string]bool)
+	for _, sct := range all {
+		data, err := tls.Marshal(*sct)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal SCT: %v", err)
+		}
+		if seen[string(data)] {
+			continue
+		}
+		seen[string(data)] = true
+		unique = append(unique, sct)
+	}
+	return unique, nil
+}
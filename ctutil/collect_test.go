@@ -0,0 +1,115 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"crypto/x509/pkix"
+	"reflect"
+	"strings"
+	"testing"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/testdata"
+	"github.com/google/certificate-transparency-go/tls"
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/certificate-transparency-go/x509util"
+)
+
+func TestCollectSCTs(t *testing.T) {
+	cert, err := x509util.CertificateFromPEM([]byte(testdata.TestEmbeddedCertPEM))
+	if x509.IsFatal(err) {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	embedded, err := x509util.SCTsFromCertificate(cert)
+	if err != nil || len(embedded) == 0 {
+		t.Fatalf("SCTsFromCertificate()=%v,%v; want SCTs", embedded, err)
+	}
+	var other ct.SignedCertificateTimestamp
+	if _, err := tls.Unmarshal(testdata.TestCertProof, &other); err != nil {
+		t.Fatalf("failed to unmarshal SCT: %v", err)
+	}
+	sctList := func(scts ...*ct.SignedCertificateTimestamp) []byte {
+		t.Helper()
+		list, err := x509util.MarshalSCTsIntoSCTList(scts)
+		if err != nil {
+			t.Fatalf("failed to build SCT list: %v", err)
+		}
+		data, err := tls.Marshal(*list)
+		if err != nil {
+			t.Fatalf("failed to marshal SCT list: %v", err)
+		}
+		return data
+	}
+	plain, err := x509util.CertificateFromPEM([]byte(testdata.TestCertPEM))
+	if x509.IsFatal(err) {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	for _, test := range []struct {
+		desc    string
+		cert    *x509.Certificate
+		tlsExt  []byte
+		ocsp    []byte
+		want    []*ct.SignedCertificateTimestamp
+		wantErr string
+	}{
+		{desc: "none"},
+		{desc: "no-embedded", cert: plain},
+		{desc: "embedded", cert: cert, want: embedded},
+		{
+			desc:   "embedded-and-tls-overlap",
+			cert:   cert,
+			tlsExt: sctList(&other, embedded[0]),
+			want:   append(append([]*ct.SignedCertificateTimestamp{}, embedded...), &other),
+		},
+		{
+			desc:   "tls-and-ocsp-overlap",
+			tlsExt: sctList(&other),
+			ocsp:   makeOCSPResponse(t, []pkix.Extension{sctListExtension(t, sctList(embedded[0], &other))}),
+			want:   []*ct.SignedCertificateTimestamp{&other, embedded[0]},
+		},
+		{
+			desc:   "all-sources",
+			cert:   cert,
+			tlsExt: sctList(embedded[0]),
+			ocsp:   makeOCSPResponse(t, []pkix.Extension{sctListExtension(t, sctList(&other, embedded[0]))}),
+			want:   append(append([]*ct.SignedCertificateTimestamp{}, embedded...), &other),
+		},
+		{desc: "duplicate-in-one-source", tlsExt: sctList(&other, &other), want: []*ct.SignedCertificateTimestamp{&other}},
+		{desc: "bad-tls-ext", cert: cert, tlsExt: []byte{0x00, 0x05, 0x01}, wantErr: "TLS extension SCTs"},
+		{desc: "bad-ocsp", tlsExt: sctList(&other), ocsp: []byte{0x30, 0x03, 0x02, 0x01, 0x00}, wantErr: "OCSP response SCTs"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := CollectSCTs(test.cert, test.tlsExt, test.ocsp)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("CollectSCTs()=%v,%v; want nil,err containing %q", got, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CollectSCTs()=nil,%v; want SCTs,nil", err)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("CollectSCTs() returned %d SCTs; want %d", len(got), len(test.want))
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i], test.want[i]) {
+					t.Errorf("CollectSCTs()[%d]=%v; want %v", i, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
@@ -155,6 +155,20 @@ func VerifySCTWithVerifier(sv *ct.SignatureVerifier, chain []*x509.Certificate,
 	return sv.VerifySCTSignature(*sct, ct.LogEntry{Leaf: *leaf})
 }
 
+// VerifySCTWithVerifierRawChain is like VerifySCTWithVerifier, but takes the
+// certificate chain in DER-encoded form, e.g. as returned by a Log.
+func VerifySCTWithVerifierRawChain(sv *ct.SignatureVerifier, rawChain []ct.ASN1Cert, sct *ct.SignedCertificateTimestamp, embedded bool) error {
+	chain := make([]*x509.Certificate, len(rawChain))
+	for i, c := range rawChain {
+		cert, err := x509.ParseCertificate(c.Data)
+		if x509.IsFatal(err) {
+			return fmt.Errorf("failed to parse chain[%d] cert: %v", i, err)
+		}
+		chain[i] = cert
+	}
+	return VerifySCTWithVerifier(sv, chain, sct, embedded)
+}
+
 func createLeaf(chain []*x509.Certificate, sct *ct.SignedCertificateTimestamp, embedded bool) (*ct.MerkleTreeLeaf, error) {
 	if len(chain) == 0 {
 		return nil, errors.New("chain is empty")
@@ -306,6 +306,97 @@ func TestVerifySCTWithVerifier(t *testing.T) {
 	}
 }
 
+func TestVerifySCTWithVerifierRawChain(t *testing.T) {
+	pk, err := ct.PublicKeyFromB64(testdata.LogPublicKeyB64)
+	if err != nil {
+		t.Fatalf("error parsing public key: %s", err)
+	}
+	sv, err := ct.NewSignatureVerifier(pk)
+	if err != nil {
+		t.Fatalf("couldn't create signature verifier: %s", err)
+	}
+
+	tests := []struct {
+		desc     string
+		chainPEM string
+		sct      []byte
+		tamper   bool
+		embedded bool
+		wantErr  bool
+	}{
+		{
+			desc:     "cert",
+			chainPEM: testdata.TestCertPEM + testdata.CACertPEM,
+			sct:      testdata.TestCertProof,
+		},
+		{
+			desc:     "cert with tampered signature",
+			chainPEM: testdata.TestCertPEM + testdata.CACertPEM,
+			sct:      testdata.TestCertProof,
+			tamper:   true,
+			wantErr:  true,
+		},
+		{
+			desc:     "precert",
+			chainPEM: testdata.TestPreCertPEM + testdata.CACertPEM,
+			sct:      testdata.TestPreCertProof,
+		},
+		{
+			desc:     "precert with tampered signature",
+			chainPEM: testdata.TestPreCertPEM + testdata.CACertPEM,
+			sct:      testdata.TestPreCertProof,
+			tamper:   true,
+			wantErr:  true,
+		},
+		{
+			desc:     "cert with embedded SCT",
+			chainPEM: testdata.TestEmbeddedCertPEM + testdata.CACertPEM,
+			sct:      testdata.TestPreCertProof,
+			embedded: true,
+		},
+		{
+			desc:     "cert with embedded SCT and tampered signature",
+			chainPEM: testdata.TestEmbeddedCertPEM + testdata.CACertPEM,
+			sct:      testdata.TestPreCertProof,
+			tamper:   true,
+			embedded: true,
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			chain, err := x509util.CertificatesFromPEM([]byte(test.chainPEM))
+			if err != nil {
+				t.Fatalf("error parsing certificate chain: %s", err)
+			}
+			rawChain := make([]ct.ASN1Cert, len(chain))
+			for i, c := range chain {
+				rawChain[i] = ct.ASN1Cert{Data: c.Raw}
+			}
+
+			var sct ct.SignedCertificateTimestamp
+			if _, err = tls.Unmarshal(test.sct, &sct); err != nil {
+				t.Fatalf("error tls-unmarshalling sct: %s", err)
+			}
+			if test.tamper {
+				sig := append([]byte(nil), sct.Signature.Signature...)
+				sig[len(sig)-1] ^= 0x01
+				sct.Signature.Signature = sig
+			}
+
+			err = VerifySCTWithVerifierRawChain(sv, rawChain, &sct, test.embedded)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Errorf("VerifySCTWithVerifierRawChain(_,_,_, %t) = %v, want error? %t", test.embedded, err, test.wantErr)
+			}
+		})
+	}
+
+	if err := VerifySCTWithVerifierRawChain(sv, []ct.ASN1Cert{{Data: []byte("garbage")}}, &ct.SignedCertificateTimestamp{}, false); err == nil {
+		t.Error("VerifySCTWithVerifierRawChain(garbage chain) = nil, want error")
+	}
+}
+
 func TestContainsSCT(t *testing.T) {
 	tests := []struct {
 		desc    string
@@ -0,0 +1,94 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/fixchain/ratelimiter"
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+// DefaultHealthCheckRate is the maximum number of get-sth requests per second
+// made by HealthCheck.
+const DefaultHealthCheckRate = 10
+
+// LogHealth is the outcome of probing a Log with HealthCheck.
+type LogHealth struct {
+	Log *loglist3.Log
+	// Reachable is true if the Log served a valid STH.
+	Reachable bool
+	// STH is the STH served by the Log, if any.
+	STH *ct.SignedTreeHead
+	// STHAge is the time elapsed between the STH timestamp and the probe.
+	STHAge time.Duration
+	// Err tells why the Log is not reachable.
+	Err error
+}
+
+// HealthCheck requests the STH of every Log in ll, making at most
+// DefaultHealthCheckRate requests per second, and returns the outcome for
+// each Log in the order of ll. STH signatures are checked against the public
+// keys of the Logs.
+func HealthCheck(ctx context.Context, ll *loglist3.LogList, hc *http.Client) []LogHealth {
+	return healthCheck(ctx, ll, hc, NewLogInfo, ratelimiter.NewLimiter(DefaultHealthCheckRate), time.Now)
+}
+
+func healthCheck(ctx context.Context, ll *loglist3.LogList, hc *http.Client, infoFactory func(*loglist3.Log, *http.Client) (*LogInfo, error), limiter *ratelimiter.Limiter, now func() time.Time) []LogHealth {
+	var report []LogHealth
+	for _, operator := range ll.Operators {
+		for _, log := range operator.Logs {
+			report = append(report, LogHealth{Log: log})
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := range report {
+		health := &report[i]
+		if err := limiter.WaitContext(ctx); err != nil {
+			health.Err = fmt.Errorf("log %q not probed: %v", health.Log.Description, err)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeLog(ctx, health, hc, infoFactory, now)
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// probeLog requests the STH of health.Log, and records the outcome in health.
+func probeLog(ctx context.Context, health *LogHealth, hc *http.Client, infoFactory func(*loglist3.Log, *http.Client) (*LogInfo, error), now func() time.Time) {
+	li, err := infoFactory(health.Log, hc)
+	if err != nil {
+		health.Err = err
+		return
+	}
+	sth, err := li.Client.GetSTH(ctx)
+	if err != nil {
+		health.Err = fmt.Errorf("failed to get STH for %q log: %v", li.Description, err)
+		return
+	}
+	health.Reachable = true
+	health.STH = sth
+	health.STHAge = now().Sub(ct.TimestampToTime(sth.Timestamp))
+}
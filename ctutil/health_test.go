@@ -0,0 +1,116 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/fixchain/ratelimiter"
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+// sthClient serves a fixed STH, or fails.
+type sthClient struct {
+	sth *ct.SignedTreeHead
+	err error
+}
+
+func (c *sthClient) BaseURI() string { return "" }
+
+func (c *sthClient) GetSTH(context.Context) (*ct.SignedTreeHead, error) {
+	return c.sth, c.err
+}
+
+func (c *sthClient) GetSTHConsistency(ctx context.Context, first, second uint64) ([][]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *sthClient) GetProofByHash(ctx context.Context, hash []byte, treeSize uint64) (*ct.GetProofByHashResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestHealthCheck(t *testing.T) {
+	now := time.Unix(1640000000, 0)
+	fresh := &ct.SignedTreeHead{TreeSize: 10, Timestamp: uint64(now.Add(-time.Minute).UnixNano() / int64(time.Millisecond))}
+	stale := &ct.SignedTreeHead{TreeSize: 20, Timestamp: uint64(now.Add(-48*time.Hour).UnixNano() / int64(time.Millisecond))}
+	clients := map[string]*sthClient{
+		"fresh":       {sth: fresh},
+		"stale":       {sth: stale},
+		"unreachable": {err: errors.New("connection refused")},
+	}
+	infoFactory := func(log *loglist3.Log, _ *http.Client) (*LogInfo, error) {
+		lc, ok := clients[log.Description]
+		if !ok {
+			return nil, errors.New("bad log key")
+		}
+		return &LogInfo{Description: log.Description, Client: lc}, nil
+	}
+	ll := &loglist3.LogList{
+		Operators: []*loglist3.Operator{
+			{Name: "A", Logs: []*loglist3.Log{{Description: "fresh"}, {Description: "unreachable"}}},
+			{Name: "B", Logs: []*loglist3.Log{{Description: "stale"}, {Description: "invalid"}}},
+		},
+	}
+	want := []struct {
+		desc      string
+		reachable bool
+		sth       *ct.SignedTreeHead
+		age       time.Duration
+		wantErr   string
+	}{
+		{desc: "fresh", reachable: true, sth: fresh, age: time.Minute},
+		{desc: "unreachable", wantErr: "connection refused"},
+		{desc: "stale", reachable: true, sth: stale, age: 48 * time.Hour},
+		{desc: "invalid", wantErr: "bad log key"},
+	}
+
+	report := healthCheck(context.Background(), ll, nil, infoFactory, ratelimiter.NewLimiter(1000), func() time.Time { return now })
+	if len(report) != len(want) {
+		t.Fatalf("healthCheck() reported on %d logs, want %d", len(report), len(want))
+	}
+	for i, w := range want {
+		got := report[i]
+		if got.Log.Description != w.desc {
+			t.Errorf("healthCheck()[%d] is for log %q, want %q", i, got.Log.Description, w.desc)
+		}
+		if got.Reachable != w.reachable || got.STH != w.sth || got.STHAge != w.age {
+			t.Errorf("healthCheck()[%d]={Reachable: %t, STH: %v, STHAge: %v}, want {%t, %v, %v}", i, got.Reachable, got.STH, got.STHAge, w.reachable, w.sth, w.age)
+		}
+		if w.wantErr == "" {
+			if got.Err != nil {
+				t.Errorf("healthCheck()[%d].Err=%v, want nil", i, got.Err)
+			}
+		} else if got.Err == nil || !strings.Contains(got.Err.Error(), w.wantErr) {
+			t.Errorf("healthCheck()[%d].Err=%v, want error containing %q", i, got.Err, w.wantErr)
+		}
+	}
+
+	// Probes are rate-limited, and not made once the context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter := ratelimiter.NewLimiter(1)
+	limiter.Allow() // Use up the burst.
+	for i, got := range healthCheck(ctx, ll, nil, infoFactory, limiter, time.Now) {
+		if got.Reachable || got.Err == nil || !strings.Contains(got.Err.Error(), "not probed") {
+			t.Errorf("healthCheck(cancelled)[%d]={Reachable: %t, Err: %v}, want unreachable and not probed", i, got.Reachable, got.Err)
+		}
+	}
+}
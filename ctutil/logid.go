@@ -0,0 +1,44 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"fmt"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+// LogIDFromPublicKey returns the ID of the Log with the given public key, i.e.
+// the SHA-256 hash of its DER-encoded SubjectPublicKeyInfo (RFC 6962 s3.2).
+func LogIDFromPublicKey(pub crypto.PublicKey) (ct.LogID, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ct.LogID{}, fmt.Errorf("failed to marshal public key: %v", err)
+	}
+	return ct.LogID{KeyID: sha256.Sum256(der)}, nil
+}
+
+// LogIDFromPublicKeyDER returns the ID of the Log with the given DER-encoded
+// SubjectPublicKeyInfo. The key is parsed first, so that malformed input is
+// rejected rather than silently hashed.
+func LogIDFromPublicKeyDER(der []byte) (ct.LogID, error) {
+	if _, err := x509.ParsePKIXPublicKey(der); err != nil {
+		return ct.LogID{}, fmt.Errorf("failed to parse public key: %v", err)
+	}
+	return ct.LogID{KeyID: sha256.Sum256(der)}, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+func TestLogIDFromPublicKey(t *testing.T) {
+	tests := []struct {
+		desc    string
+		keyB64  string
+		wantB64 string
+	}{
+		{
+			desc:    "icarus",
+			keyB64:  "MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAETtK8v7MICve56qTHHDhhBOuV4IlUaESxZryCfk9QbG9co/CqPvTsgPDbCpp6oFtyAHwlDhnvr7JijXRD9Cb2FA==",
+			wantB64: "KTxRllTIOWW6qlD8WAfUt2+/WHopctykwwz05UVH9Hg=",
+		},
+		{
+			desc:    "rocketeer",
+			keyB64:  "MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEIFsYyDzBi7MxCAC/oJBXK7dHjG+1aLCOkHjpoHPqTyghLpzA9BYbqvnV16mAw04vUjyYASVGJCUoI3ctBcJAeg==",
+			wantB64: "7ku9t3XOYLrhQmkfq+GeZqMPfl+wctiDAMR7iXqo/cs=",
+		},
+		{
+			desc:    "argon2020",
+			keyB64:  "MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAE6Tx2p1yKY4015NyIYvdrk36es0uAc1zA4PQ+TGRY+3ZjUTIYY9Wyu+3q/147JG4vNVKLtDWarZwVqGkg6lAYzA==",
+			wantB64: "sh4FzIuizYogTodm+Su5iiUgZ2va+nDnsklTLe+LkF4=",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			der, err := base64.StdEncoding.DecodeString(test.keyB64)
+			if err != nil {
+				t.Fatalf("failed to decode key: %v", err)
+			}
+			pub, err := x509.ParsePKIXPublicKey(der)
+			if err != nil {
+				t.Fatalf("failed to parse key: %v", err)
+			}
+
+			got, err := LogIDFromPublicKey(pub)
+			if err != nil {
+				t.Fatalf("LogIDFromPublicKey()=_,%v; want _,nil", err)
+			}
+			if gotB64 := base64.StdEncoding.EncodeToString(got.KeyID[:]); gotB64 != test.wantB64 {
+				t.Errorf("LogIDFromPublicKey()=%s; want %s", gotB64, test.wantB64)
+			}
+
+			got, err = LogIDFromPublicKeyDER(der)
+			if err != nil {
+				t.Fatalf("LogIDFromPublicKeyDER()=_,%v; want _,nil", err)
+			}
+			if gotB64 := base64.StdEncoding.EncodeToString(got.KeyID[:]); gotB64 != test.wantB64 {
+				t.Errorf("LogIDFromPublicKeyDER()=%s; want %s", gotB64, test.wantB64)
+			}
+		})
+	}
+}
+
+func TestLogIDFromPublicKeyErrors(t *testing.T) {
+	if _, err := LogIDFromPublicKey("not a key"); err == nil {
+		t.Error("LogIDFromPublicKey(string)=_,nil; want error")
+	}
+	if _, err := LogIDFromPublicKeyDER([]byte("not a key")); err == nil {
+		t.Error("LogIDFromPublicKeyDER(garbage)=_,nil; want error")
+	}
+}
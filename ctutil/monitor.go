@@ -0,0 +1,170 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/client"
+	"github.com/google/certificate-transparency-go/fixchain/ratelimiter"
+)
+
+const (
+	// DefaultMonitorInterval is the time between two polls of a LogMonitor.
+	DefaultMonitorInterval = time.Minute
+	// DefaultMonitorRate is the maximum number of requests per second made by
+	// a LogMonitor.
+	DefaultMonitorRate = 1
+)
+
+// STHStore persists the last verified STH of a Log watched by a LogMonitor,
+// so that monitoring can resume across restarts.
+type STHStore interface {
+	// LoadSTH returns the stored STH, or nil if there is none yet.
+	LoadSTH(ctx context.Context) (*ct.SignedTreeHead, error)
+	// StoreSTH replaces the stored STH with sth.
+	StoreSTH(ctx context.Context, sth *ct.SignedTreeHead) error
+}
+
+// LogMonitorOptions holds the settings of a LogMonitor.
+type LogMonitorOptions struct {
+	// Interval is the time between two polls. Defaults to
+	// DefaultMonitorInterval.
+	Interval time.Duration
+	// Limiter bounds the rate of requests sent to the Log. Defaults to
+	// DefaultMonitorRate requests per second.
+	Limiter *ratelimiter.Limiter
+	// Verifier, if set, is used to check the signature of every STH.
+	Verifier *ct.SignatureVerifier
+	// OnConflict is called for every STH which is inconsistent with the
+	// stored one. Required.
+	OnConflict func(Conflict)
+	// OnError, if set, is called by Run for every poll which fails.
+	OnError func(error)
+}
+
+// LogMonitor periodically fetches the STH of a Log, and checks that the Log
+// is append-only, i.e. that each new STH is consistent with the last one
+// seen. The last consistent STH is kept in an STHStore.
+type LogMonitor struct {
+	client client.CheckLogClient
+	store  STHStore
+	opts   LogMonitorOptions
+}
+
+// NewLogMonitor creates a LogMonitor for the Log served by lc.
+func NewLogMonitor(lc client.CheckLogClient, store STHStore, opts LogMonitorOptions) (*LogMonitor, error) {
+	if lc == nil || store == nil {
+		return nil, errors.New("both client and store are required")
+	}
+	if opts.OnConflict == nil {
+		return nil, errors.New("OnConflict callback is required")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultMonitorInterval
+	}
+	if opts.Limiter == nil {
+		opts.Limiter = ratelimiter.NewLimiter(DefaultMonitorRate)
+	}
+	return &LogMonitor{client: lc, store: store, opts: opts}, nil
+}
+
+// Run polls the Log every Interval until ctx is done, and then returns
+// ctx.Err(). A failed poll is reported to OnError and doesn't stop Run.
+func (m *LogMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Poll(ctx); err != nil && ctx.Err() == nil && m.opts.OnError != nil {
+			m.opts.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches the current STH of the Log once, and checks it against the
+// stored STH, calling OnConflict if they are inconsistent. The STH replaces
+// the stored one if it shows that the tree has grown consistently. An STH
+// with a smaller tree size, as served by a lagging Log frontend, is checked
+// too but never stored. Returns an error if the check couldn't be completed.
+func (m *LogMonitor) Poll(ctx context.Context) error {
+	if err := m.opts.Limiter.WaitContext(ctx); err != nil {
+		return err
+	}
+	sth, err := m.client.GetSTH(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get STH: %v", err)
+	}
+	if m.opts.Verifier != nil {
+		if err := m.opts.Verifier.VerifySTHSignature(*sth); err != nil {
+			return fmt.Errorf("failed to verify STH signature: %v", err)
+		}
+	}
+
+	prev, err := m.store.LoadSTH(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load stored STH: %v", err)
+	}
+	if prev == nil {
+		return m.store.StoreSTH(ctx, sth)
+	}
+
+	switch {
+	case sth.TreeSize == prev.TreeSize:
+		if sth.SHA256RootHash != prev.SHA256RootHash {
+			m.opts.OnConflict(Conflict{Kind: RootHashMismatch, First: prev, Second: sth})
+			return nil
+		}
+		if sth.Timestamp <= prev.Timestamp {
+			return nil
+		}
+	case sth.TreeSize > prev.TreeSize:
+		if ok, err := m.consistent(ctx, prev, sth); err != nil || !ok {
+			return err
+		}
+	default:
+		_, err := m.consistent(ctx, sth, prev)
+		return err
+	}
+	return m.store.StoreSTH(ctx, sth)
+}
+
+// consistent checks that the tree of second extends the tree of first,
+// calling OnConflict and returning false if it doesn't.
+func (m *LogMonitor) consistent(ctx context.Context, first, second *ct.SignedTreeHead) (bool, error) {
+	if first.TreeSize == 0 {
+		return true, nil // Every tree extends the empty tree.
+	}
+	if err := m.opts.Limiter.WaitContext(ctx); err != nil {
+		return false, err
+	}
+	pf, err := m.client.GetSTHConsistency(ctx, first.TreeSize, second.TreeSize)
+	if err != nil {
+		return false, fmt.Errorf("failed to get consistency proof from size %d to %d: %v", first.TreeSize, second.TreeSize, err)
+	}
+	if err := client.VerifySTHConsistency(first, second, pf); err != nil {
+		m.opts.OnConflict(Conflict{Kind: ConsistencyFailure, First: first, Second: second, Err: err})
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,267 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/client"
+	"github.com/google/certificate-transparency-go/fixchain/ratelimiter"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/merkle/testonly"
+)
+
+// scriptedLog serves a sequence of STHs, one per GetSTH call, repeating the
+// last one, and consistency proofs from an in-memory tree.
+type scriptedLog struct {
+	tree     *testonly.Tree
+	sths     []*ct.SignedTreeHead
+	sthErr   error
+	proofErr error
+
+	mu   sync.Mutex
+	next int
+}
+
+func (l *scriptedLog) BaseURI() string { return "" }
+
+func (l *scriptedLog) GetSTH(context.Context) (*ct.SignedTreeHead, error) {
+	if l.sthErr != nil {
+		return nil, l.sthErr
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sth := l.sths[l.next]
+	if l.next < len(l.sths)-1 {
+		l.next++
+	}
+	return sth, nil
+}
+
+func (l *scriptedLog) GetSTHConsistency(_ context.Context, first, second uint64) ([][]byte, error) {
+	if l.proofErr != nil {
+		return nil, l.proofErr
+	}
+	return l.tree.ConsistencyProof(first, second)
+}
+
+func (l *scriptedLog) GetProofByHash(context.Context, []byte, uint64) (*ct.GetProofByHashResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+// memSTHStore is an in-memory STHStore.
+type memSTHStore struct {
+	mu  sync.Mutex
+	sth *ct.SignedTreeHead
+}
+
+func (s *memSTHStore) LoadSTH(context.Context) (*ct.SignedTreeHead, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sth, nil
+}
+
+func (s *memSTHStore) StoreSTH(_ context.Context, sth *ct.SignedTreeHead) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sth = sth
+	return nil
+}
+
+func TestLogMonitorPoll(t *testing.T) {
+	tree := testonly.New(rfc6962.DefaultHasher)
+	tree.AppendData(testonly.LeafInputs()...)
+	forked := testonly.New(rfc6962.DefaultHasher)
+	forked.AppendData(testonly.LeafInputs()[:4]...)
+	forked.AppendData([]byte("fork"), []byte("of"), []byte("the"), []byte("tree"))
+
+	sth := func(tree *testonly.Tree, size uint64, timestamp uint64) *ct.SignedTreeHead {
+		sth := &ct.SignedTreeHead{TreeSize: size, Timestamp: timestamp}
+		copy(sth.SHA256RootHash[:], tree.HashAt(size))
+		return sth
+	}
+	sth0, sth3, sth5, sth8 := sth(tree, 0, 1), sth(tree, 3, 2), sth(tree, 5, 3), sth(tree, 8, 4)
+	sth5Again := sth(tree, 5, 5)
+	fork6, fork8 := sth(forked, 6, 6), sth(forked, 8, 7)
+
+	tests := []struct {
+		desc     string
+		stored   *ct.SignedTreeHead
+		sths     []*ct.SignedTreeHead
+		proofErr error
+		want     []Conflict
+		wantSTH  *ct.SignedTreeHead
+		wantErr  bool
+	}{
+		{desc: "consistent", sths: []*ct.SignedTreeHead{sth3, sth5, sth5Again, sth8}, wantSTH: sth8},
+		{desc: "same-tree-newer-timestamp", sths: []*ct.SignedTreeHead{sth5, sth5Again}, wantSTH: sth5Again},
+		{desc: "same-tree-older-timestamp", sths: []*ct.SignedTreeHead{sth5Again, sth5}, wantSTH: sth5Again},
+		{desc: "from-empty-tree", sths: []*ct.SignedTreeHead{sth0, sth3}, wantSTH: sth3},
+		{desc: "resume-from-store", stored: sth3, sths: []*ct.SignedTreeHead{sth8}, wantSTH: sth8},
+		{desc: "lagging-frontend", sths: []*ct.SignedTreeHead{sth8, sth5}, wantSTH: sth8},
+		{
+			desc:    "forked-same-size",
+			sths:    []*ct.SignedTreeHead{sth8, fork8},
+			want:    []Conflict{{Kind: RootHashMismatch, First: sth8, Second: fork8}},
+			wantSTH: sth8,
+		},
+		{
+			desc:    "forked-larger",
+			sths:    []*ct.SignedTreeHead{sth5, fork8},
+			want:    []Conflict{{Kind: ConsistencyFailure, First: sth5, Second: fork8}},
+			wantSTH: sth5,
+		},
+		{
+			desc:    "forked-smaller",
+			sths:    []*ct.SignedTreeHead{sth8, fork6},
+			want:    []Conflict{{Kind: ConsistencyFailure, First: fork6, Second: sth8}},
+			wantSTH: sth8,
+		},
+		{
+			desc:    "fork-reported-on-every-poll",
+			sths:    []*ct.SignedTreeHead{sth5, fork8, fork8},
+			want:    []Conflict{{Kind: ConsistencyFailure, First: sth5, Second: fork8}, {Kind: ConsistencyFailure, First: sth5, Second: fork8}},
+			wantSTH: sth5,
+		},
+		{
+			desc:     "proof-error",
+			sths:     []*ct.SignedTreeHead{sth3, sth5},
+			proofErr: errors.New("boom"),
+			wantSTH:  sth3,
+			wantErr:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			ctx := context.Background()
+			lc := &scriptedLog{tree: tree, sths: test.sths, proofErr: test.proofErr}
+			store := &memSTHStore{sth: test.stored}
+			var got []Conflict
+			m, err := NewLogMonitor(lc, store, LogMonitorOptions{
+				Limiter:    ratelimiter.NewLimiter(1000),
+				OnConflict: func(c Conflict) { got = append(got, c) },
+			})
+			if err != nil {
+				t.Fatalf("NewLogMonitor(): %v", err)
+			}
+			var pollErr error
+			for range test.sths {
+				if err := m.Poll(ctx); err != nil {
+					pollErr = err
+				}
+			}
+			if gotErr := pollErr != nil; gotErr != test.wantErr {
+				t.Errorf("Poll()=%v; want error: %t", pollErr, test.wantErr)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("OnConflict called with %+v; want %+v", got, test.want)
+			}
+			for i, c := range got {
+				want := test.want[i]
+				if c.Kind != want.Kind || c.First != want.First || c.Second != want.Second {
+					t.Errorf("conflict[%d]=%v (size %d vs %d); want %v (size %d vs %d)", i, c.Kind, c.First.TreeSize, c.Second.TreeSize, want.Kind, want.First.TreeSize, want.Second.TreeSize)
+				}
+			}
+			if store.sth != test.wantSTH {
+				t.Errorf("stored STH=%v; want %v", store.sth, test.wantSTH)
+			}
+		})
+	}
+}
+
+func TestLogMonitorPollSTHError(t *testing.T) {
+	lc := &scriptedLog{sthErr: errors.New("connection refused")}
+	store := &memSTHStore{}
+	m, err := NewLogMonitor(lc, store, LogMonitorOptions{OnConflict: func(Conflict) {}})
+	if err != nil {
+		t.Fatalf("NewLogMonitor(): %v", err)
+	}
+	if err := m.Poll(context.Background()); err == nil {
+		t.Error("Poll()=nil; want error")
+	}
+	if store.sth != nil {
+		t.Errorf("stored STH=%v; want nil", store.sth)
+	}
+}
+
+func TestLogMonitorRun(t *testing.T) {
+	tree := testonly.New(rfc6962.DefaultHasher)
+	tree.AppendData(testonly.LeafInputs()...)
+	var sths []*ct.SignedTreeHead
+	for size := uint64(1); size <= tree.Size(); size++ {
+		sth := &ct.SignedTreeHead{TreeSize: size, Timestamp: size}
+		copy(sth.SHA256RootHash[:], tree.HashAt(size))
+		sths = append(sths, sth)
+	}
+	last := sths[len(sths)-1]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	store := &memSTHStore{}
+	m, err := NewLogMonitor(&scriptedLog{tree: tree, sths: sths}, store, LogMonitorOptions{
+		Interval:   time.Millisecond,
+		Limiter:    ratelimiter.NewLimiter(1000),
+		OnConflict: func(c Conflict) { t.Errorf("unexpected conflict %+v", c) },
+		OnError:    func(err error) { t.Errorf("unexpected error %v", err) },
+	})
+	if err != nil {
+		t.Fatalf("NewLogMonitor(): %v", err)
+	}
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+	for {
+		if sth, _ := store.LoadSTH(ctx); sth == last {
+			break
+		}
+		select {
+		case err := <-done:
+			t.Fatalf("Run()=%v before reaching the last STH", err)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run()=%v; want %v", err, context.Canceled)
+	}
+}
+
+func TestNewLogMonitorErrors(t *testing.T) {
+	onConflict := func(Conflict) {}
+	for _, test := range []struct {
+		desc  string
+		lc    *scriptedLog
+		store STHStore
+		opts  LogMonitorOptions
+	}{
+		{desc: "no-client", store: &memSTHStore{}, opts: LogMonitorOptions{OnConflict: onConflict}},
+		{desc: "no-store", lc: &scriptedLog{}, opts: LogMonitorOptions{OnConflict: onConflict}},
+		{desc: "no-callback", lc: &scriptedLog{}, store: &memSTHStore{}},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var lc client.CheckLogClient
+			if test.lc != nil {
+				lc = test.lc
+			}
+			if _, err := NewLogMonitor(lc, test.store, test.opts); err == nil {
+				t.Error("NewLogMonitor()=_,nil; want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"encoding/asn1"
+	"fmt"
+
+	"golang.org/x/crypto/ocsp"
+
+	ct "github.com/google/certificate-transparency-go"
+)
+
+// OIDExtensionCTOCSPSCTList is the OID of the OCSP single response extension
+// holding an SCT list, from RFC 6962 s3.3.
+var OIDExtensionCTOCSPSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 5}
+
+// SCTsFromOCSPResponse parses the DER-encoded OCSP response der, and returns
+// the SCTs held in its SCT list extension. It returns no SCTs and no error if
+// the response has no such extension.
+//
+// The response is not checked against its issuer: if it embeds a responder
+// certificate, its signature must verify under that certificate, or an error
+// is returned, but neither the responder certificate nor a response without
+// one is checked to come from the issuer of the certificate. Callers which
+// rely on the SCTs being served by the CA must check that themselves, e.g.
+// with ocsp.ParseResponseForCert.
+func SCTsFromOCSPResponse(der []byte) ([]*ct.SignedCertificateTimestamp, error) {
+	resp, err := ocsp.ParseResponse(der, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OCSP response: %v", err)
+	}
+	for _, ext := range resp.Extensions {
+		if !ext.Id.Equal(OIDExtensionCTOCSPSCTList) {
+			continue
+		}
+		var sctList []byte
+		if rest, err := asn1.Unmarshal(ext.Value, &sctList); err != nil {
+			return nil, fmt.Errorf("failed to asn1.Unmarshal SCT list extension: %v", err)
+		} else if len(rest) > 0 {
+			return nil, fmt.Errorf("trailing data (%d bytes) after ASN1-encoded SCT list", len(rest))
+		}
+		return ct.ParseSCTList(sctList)
+	}
+	return nil, nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/testdata"
+	"github.com/google/certificate-transparency-go/tls"
+	"github.com/google/certificate-transparency-go/x509util"
+)
+
+// makeOCSPResponse builds a DER-encoded OCSP response for a certificate issued
+// by testdata.CACertPEM, with the given single response extensions.
+func makeOCSPResponse(t *testing.T, exts []pkix.Extension) []byte {
+	t.Helper()
+	return makeOCSPResponseWithResponder(t, exts, nil)
+}
+
+// makeOCSPResponseWithResponder is like makeOCSPResponse, but embeds the
+// responder certificate returned by responder, called with the key which
+// signs the response, if responder is not nil.
+func makeOCSPResponseWithResponder(t *testing.T, exts []pkix.Extension, responder func(key *ecdsa.PrivateKey) *x509.Certificate) []byte {
+	t.Helper()
+	block, _ := pem.Decode([]byte(testdata.CACertPEM))
+	issuer, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse issuer: %v", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	now := time.Now()
+	template := ocsp.Response{
+		Status:          ocsp.Good,
+		SerialNumber:    big.NewInt(6),
+		ThisUpdate:      now,
+		NextUpdate:      now.Add(time.Hour),
+		ExtraExtensions: exts,
+	}
+	if responder != nil {
+		template.Certificate = responder(key)
+	}
+	der, err := ocsp.CreateResponse(issuer, issuer, template, key)
+	if err != nil {
+		t.Fatalf("failed to create OCSP response: %v", err)
+	}
+	return der
+}
+
+// selfSignedResponder returns a responder certificate for key.
+func selfSignedResponder(t *testing.T, key *ecdsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "OCSP Responder"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("failed to create responder certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse responder certificate: %v", err)
+	}
+	return cert
+}
+
+// sctListExtension builds an OCSP SCT list extension holding the given
+// TLS-encoded list.
+func sctListExtension(t *testing.T, sctList []byte) pkix.Extension {
+	t.Helper()
+	value, err := asn1.Marshal(sctList)
+	if err != nil {
+		t.Fatalf("failed to marshal SCT list: %v", err)
+	}
+	return pkix.Extension{Id: OIDExtensionCTOCSPSCTList, Value: value}
+}
+
+func TestSCTsFromOCSPResponse(t *testing.T) {
+	var scts []*ct.SignedCertificateTimestamp
+	for _, proof := range [][]byte{testdata.TestCertProof, testdata.TestPreCertProof} {
+		var sct ct.SignedCertificateTimestamp
+		if _, err := tls.Unmarshal(proof, &sct); err != nil {
+			t.Fatalf("failed to unmarshal SCT: %v", err)
+		}
+		scts = append(scts, &sct)
+	}
+	list, err := x509util.MarshalSCTsIntoSCTList(scts)
+	if err != nil {
+		t.Fatalf("failed to build SCT list: %v", err)
+	}
+	sctList, err := tls.Marshal(*list)
+	if err != nil {
+		t.Fatalf("failed to marshal SCT list: %v", err)
+	}
+	otherExt := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: []byte{0x05, 0x00}}
+
+	for _, test := range []struct {
+		desc    string
+		der     []byte
+		want    []*ct.SignedCertificateTimestamp
+		wantErr string
+	}{
+		{
+			desc: "scts",
+			der:  makeOCSPResponse(t, []pkix.Extension{otherExt, sctListExtension(t, sctList)}),
+			want: scts,
+		},
+		{
+			desc: "no-extension",
+			der:  makeOCSPResponse(t, []pkix.Extension{otherExt}),
+		},
+		{
+			desc: "responder-signed",
+			der: makeOCSPResponseWithResponder(t, []pkix.Extension{sctListExtension(t, sctList)}, func(key *ecdsa.PrivateKey) *x509.Certificate {
+				return selfSignedResponder(t, key)
+			}),
+			want: scts,
+		},
+		{
+			desc: "responder-signature-mismatch",
+			der: makeOCSPResponseWithResponder(t, []pkix.Extension{sctListExtension(t, sctList)}, func(*ecdsa.PrivateKey) *x509.Certificate {
+				other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				if err != nil {
+					t.Fatalf("failed to generate key: %v", err)
+				}
+				return selfSignedResponder(t, other)
+			}),
+			wantErr: "failed to parse OCSP response",
+		},
+		{
+			desc:    "not-ocsp",
+			der:     []byte{0x30, 0x03, 0x02, 0x01, 0x00},
+			wantErr: "failed to parse OCSP response",
+		},
+		{
+			desc:    "not-octet-string",
+			der:     makeOCSPResponse(t, []pkix.Extension{{Id: OIDExtensionCTOCSPSCTList, Value: []byte{0x05, 0x00}}}),
+			wantErr: "failed to asn1.Unmarshal SCT list extension",
+		},
+		{
+			desc:    "trailing-asn1",
+			der:     makeOCSPResponse(t, []pkix.Extension{{Id: OIDExtensionCTOCSPSCTList, Value: append(sctListExtension(t, sctList).Value, 0x00)}}),
+			wantErr: "trailing data (1 bytes) after ASN1-encoded SCT list",
+		},
+		{
+			desc:    "malformed-list",
+			der:     makeOCSPResponse(t, []pkix.Extension{sctListExtension(t, sctList[:len(sctList)-1])}),
+			wantErr: "failed to parse SCT list",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := SCTsFromOCSPResponse(test.der)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("SCTsFromOCSPResponse()=%v,%v; want nil,err containing %q", got, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SCTsFromOCSPResponse()=nil,%v; want scts,nil", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("SCTsFromOCSPResponse()=%v; want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/ctpolicy"
+	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+// SCTStatus is the outcome of verifying an SCT.
+type SCTStatus int
+
+// SCTStatus values.
+const (
+	// SCTVerified means that the SCT signature is valid for the chain.
+	SCTVerified SCTStatus = iota
+	// SCTUnknownLog means that the SCT's LogID matches no log in the log list.
+	SCTUnknownLog
+	// SCTBadSignature means that the SCT could not be verified with the key
+	// of its log.
+	SCTBadSignature
+)
+
+func (s SCTStatus) String() string {
+	switch s {
+	case SCTVerified:
+		return "Verified"
+	case SCTUnknownLog:
+		return "UnknownLog"
+	case SCTBadSignature:
+		return "BadSignature"
+	default:
+		return fmt.Sprintf("UnknownStatus(%d)", s)
+	}
+}
+
+// SCTVerificationResult holds the outcome of verifying a single SCT.
+type SCTVerificationResult struct {
+	SCT    *ct.SignedCertificateTimestamp
+	Status SCTStatus
+	// Log is the log that issued the SCT, or nil if it is not in the log list.
+	Log *loglist3.Log
+	// Err describes why the SCT did not verify, for SCTBadSignature.
+	Err error
+}
+
+// VerifySCTs verifies each of the given SCTs for the certificate at chain[0],
+// using the key of the log in ll whose LogID matches the SCT's. SCTs embedded
+// in chain[0] are verified as such, and others as issued for chain[0] itself,
+// so the issuer must be provided in chain[1] for precertificates and certs
+// with embedded SCTs.
+//
+// A result is returned for each SCT, in the same order. An error is only
+// returned if the chain cannot be parsed.
+func VerifySCTs(chain []ct.ASN1Cert, scts []*ct.SignedCertificateTimestamp, ll *loglist3.LogList) ([]SCTVerificationResult, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("chain is empty")
+	}
+	certs := make([]*x509.Certificate, len(chain))
+	for i, c := range chain {
+		cert, err := x509.ParseCertificate(c.Data)
+		if x509.IsFatal(err) {
+			return nil, fmt.Errorf("failed to parse chain[%d] cert: %v", i, err)
+		}
+		certs[i] = cert
+	}
+
+	verifiers := make(map[*loglist3.Log]*ct.SignatureVerifier)
+	results := make([]SCTVerificationResult, len(scts))
+	for i, sct := range scts {
+		results[i] = verifySCT(certs, sct, ll, verifiers)
+	}
+	return results, nil
+}
+
+// verifySCT verifies a single SCT for VerifySCTs, caching the verifiers of
+// the logs it looks up.
+func verifySCT(chain []*x509.Certificate, sct *ct.SignedCertificateTimestamp, ll *loglist3.LogList, verifiers map[*loglist3.Log]*ct.SignatureVerifier) SCTVerificationResult {
+	res := SCTVerificationResult{SCT: sct}
+	if sct == nil {
+		res.Status, res.Err = SCTBadSignature, errors.New("sct is nil")
+		return res
+	}
+	if ll != nil {
+		res.Log = ll.FindLogByKeyHash(sct.LogID.KeyID)
+	}
+	if res.Log == nil {
+		res.Status = SCTUnknownLog
+		return res
+	}
+
+	sv, ok := verifiers[res.Log]
+	if !ok {
+		key, err := x509.ParsePKIXPublicKey(res.Log.Key)
+		if err != nil {
+			res.Status, res.Err = SCTBadSignature, fmt.Errorf("failed to parse public key of log %q: %v", res.Log.Description, err)
+			return res
+		}
+		if sv, err = ct.NewSignatureVerifier(key); err != nil {
+			res.Status, res.Err = SCTBadSignature, fmt.Errorf("failed to build verifier for log %q: %v", res.Log.Description, err)
+			return res
+		}
+		verifiers[res.Log] = sv
+	}
+
+	embedded, err := ContainsSCT(chain[0], sct)
+	if err != nil {
+		res.Status, res.Err = SCTBadSignature, err
+		return res
+	}
+	if err := VerifySCTWithVerifier(sv, chain, sct, embedded); err != nil {
+		res.Status, res.Err = SCTBadSignature, err
+		return res
+	}
+	res.Status = SCTVerified
+	return res
+}
+
+// IncorporationDeadline returns the time by which the Log which issued sct,
+// found in ll by its Log ID, must have incorporated the corresponding entry in
+// its tree, as computed by ctpolicy.MergeDeadline from the SCT's timestamp and
+// the Log's Maximum Merge Delay. Like there, a negative MMD counts as zero.
+func IncorporationDeadline(sct *ct.SignedCertificateTimestamp, ll *loglist3.LogList) (time.Time, error) {
+	if sct == nil {
+		return time.Time{}, errors.New("sct is nil")
+	}
+	var log *loglist3.Log
+	if ll != nil {
+		log = ll.FindLogByKeyHash(sct.LogID.KeyID)
+	}
+	if log == nil {
+		return time.Time{}, fmt.Errorf("no log with ID %x in the log list", sct.LogID.KeyID)
+	}
+	deadline, _ := ctpolicy.MergeDeadline(sct.Timestamp, log.MMD, time.Time{})
+	return deadline, nil
+}
@@ -0,0 +1,217 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/certificate-transparency-go/testdata"
+	"github.com/google/certificate-transparency-go/tls"
+	"github.com/google/certificate-transparency-go/x509util"
+)
+
+func mustParseSCT(t *testing.T, data []byte) *ct.SignedCertificateTimestamp {
+	t.Helper()
+	var sct ct.SignedCertificateTimestamp
+	if _, err := tls.Unmarshal(data, &sct); err != nil {
+		t.Fatalf("error tls-unmarshalling sct: %s", err)
+	}
+	return &sct
+}
+
+func mustRawChain(t *testing.T, chainPEM string) []ct.ASN1Cert {
+	t.Helper()
+	chain, err := x509util.CertificatesFromPEM([]byte(chainPEM))
+	if err != nil {
+		t.Fatalf("error parsing certificate chain: %s", err)
+	}
+	raw := make([]ct.ASN1Cert, len(chain))
+	for i, c := range chain {
+		raw[i] = ct.ASN1Cert{Data: c.Raw}
+	}
+	return raw
+}
+
+func TestVerifySCTs(t *testing.T) {
+	key, err := base64.StdEncoding.DecodeString(testdata.LogPublicKeyB64)
+	if err != nil {
+		t.Fatalf("error decoding public key: %s", err)
+	}
+	keyHash := sha256.Sum256(key)
+	log := &loglist3.Log{Description: "Test Log", LogID: keyHash[:], Key: key}
+	ll := &loglist3.LogList{
+		Operators: []*loglist3.Operator{
+			{Name: "Test Operator", Logs: []*loglist3.Log{log}},
+		},
+	}
+
+	unknownLog := func(sct *ct.SignedCertificateTimestamp) *ct.SignedCertificateTimestamp {
+		sct.LogID.KeyID[0] ^= 0x01
+		return sct
+	}
+	tampered := func(sct *ct.SignedCertificateTimestamp) *ct.SignedCertificateTimestamp {
+		sct.Signature.Signature[len(sct.Signature.Signature)-1] ^= 0x01
+		return sct
+	}
+
+	tests := []struct {
+		desc     string
+		chainPEM string
+		ll       *loglist3.LogList
+		scts     []*ct.SignedCertificateTimestamp
+		want     []SCTStatus
+	}{
+		{
+			desc:     "cert",
+			chainPEM: testdata.TestCertPEM + testdata.CACertPEM,
+			ll:       ll,
+			scts: []*ct.SignedCertificateTimestamp{
+				mustParseSCT(t, testdata.TestCertProof),
+				unknownLog(mustParseSCT(t, testdata.TestCertProof)),
+				tampered(mustParseSCT(t, testdata.TestCertProof)),
+				mustParseSCT(t, testdata.TestPreCertProof),
+			},
+			want: []SCTStatus{SCTVerified, SCTUnknownLog, SCTBadSignature, SCTBadSignature},
+		},
+		{
+			desc:     "precert",
+			chainPEM: testdata.TestPreCertPEM + testdata.CACertPEM,
+			ll:       ll,
+			scts: []*ct.SignedCertificateTimestamp{
+				tampered(mustParseSCT(t, testdata.TestPreCertProof)),
+				mustParseSCT(t, testdata.TestPreCertProof),
+			},
+			want: []SCTStatus{SCTBadSignature, SCTVerified},
+		},
+		{
+			desc:     "embedded",
+			chainPEM: testdata.TestEmbeddedCertPEM + testdata.CACertPEM,
+			ll:       ll,
+			scts: []*ct.SignedCertificateTimestamp{
+				mustParseSCT(t, testdata.TestPreCertProof),
+				unknownLog(mustParseSCT(t, testdata.TestPreCertProof)),
+			},
+			want: []SCTStatus{SCTVerified, SCTUnknownLog},
+		},
+		{
+			desc:     "no log list",
+			chainPEM: testdata.TestCertPEM + testdata.CACertPEM,
+			scts:     []*ct.SignedCertificateTimestamp{mustParseSCT(t, testdata.TestCertProof)},
+			want:     []SCTStatus{SCTUnknownLog},
+		},
+		{
+			desc:     "nil SCT",
+			chainPEM: testdata.TestCertPEM + testdata.CACertPEM,
+			ll:       ll,
+			scts:     []*ct.SignedCertificateTimestamp{nil},
+			want:     []SCTStatus{SCTBadSignature},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := VerifySCTs(mustRawChain(t, test.chainPEM), test.scts, test.ll)
+			if err != nil {
+				t.Fatalf("VerifySCTs()=nil,%v; want _,nil", err)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("VerifySCTs() returned %d results; want %d", len(got), len(test.want))
+			}
+			for i, res := range got {
+				if res.Status != test.want[i] {
+					t.Errorf("result[%d].Status=%v; want %v (err: %v)", i, res.Status, test.want[i], res.Err)
+				}
+				if res.SCT != test.scts[i] {
+					t.Errorf("result[%d].SCT is not the input SCT", i)
+				}
+				if gotErr := res.Err != nil; gotErr != (res.Status == SCTBadSignature) {
+					t.Errorf("result[%d].Err=%v with status %v", i, res.Err, res.Status)
+				}
+				if res.SCT != nil && res.Status != SCTUnknownLog && res.Log != log {
+					t.Errorf("result[%d].Log=%v; want %v", i, res.Log, log)
+				}
+			}
+		})
+	}
+
+	if _, err := VerifySCTs(nil, nil, ll); err == nil {
+		t.Error("VerifySCTs(empty chain)=_,nil; want error")
+	}
+	if _, err := VerifySCTs([]ct.ASN1Cert{{Data: []byte("garbage")}}, nil, ll); err == nil {
+		t.Error("VerifySCTs(garbage chain)=_,nil; want error")
+	}
+}
+
+func TestIncorporationDeadline(t *testing.T) {
+	dayLog := &loglist3.Log{Description: "Day Log", LogID: make([]byte, sha256.Size), MMD: 86400}
+	dayLog.LogID[0] = 0x01
+	hourLog := &loglist3.Log{Description: "Hour Log", LogID: make([]byte, sha256.Size), MMD: 3600}
+	hourLog.LogID[0] = 0x02
+	noMMDLog := &loglist3.Log{Description: "No MMD Log", LogID: make([]byte, sha256.Size)}
+	noMMDLog.LogID[0] = 0x03
+	negativeMMDLog := &loglist3.Log{Description: "Negative MMD Log", LogID: make([]byte, sha256.Size), MMD: -1}
+	negativeMMDLog.LogID[0] = 0x05
+	ll := &loglist3.LogList{
+		Operators: []*loglist3.Operator{
+			{Name: "Operator A", Logs: []*loglist3.Log{dayLog, noMMDLog, negativeMMDLog}},
+			{Name: "Operator B", Logs: []*loglist3.Log{hourLog}},
+		},
+	}
+	sctFor := func(id byte) *ct.SignedCertificateTimestamp {
+		sct := &ct.SignedCertificateTimestamp{Timestamp: 1600000000000}
+		sct.LogID.KeyID[0] = id
+		return sct
+	}
+	issued := time.Unix(1600000000, 0)
+
+	tests := []struct {
+		desc    string
+		sct     *ct.SignedCertificateTimestamp
+		ll      *loglist3.LogList
+		want    time.Time
+		wantErr string
+	}{
+		{desc: "day", sct: sctFor(0x01), ll: ll, want: issued.Add(24 * time.Hour)},
+		{desc: "hour", sct: sctFor(0x02), ll: ll, want: issued.Add(time.Hour)},
+		{desc: "no-mmd", sct: sctFor(0x03), ll: ll, want: issued},
+		{desc: "negative-mmd", sct: sctFor(0x05), ll: ll, want: issued},
+		{desc: "unknown-log", sct: sctFor(0x04), ll: ll, wantErr: "no log with ID"},
+		{desc: "nil-list", sct: sctFor(0x01), wantErr: "no log with ID"},
+		{desc: "nil-sct", ll: ll, wantErr: "sct is nil"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := IncorporationDeadline(test.sct, test.ll)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("IncorporationDeadline()=%v,%v; want error containing %q", got, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("IncorporationDeadline()=_,%v; want _,nil", err)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("IncorporationDeadline()=%v; want %v", got, test.want)
+			}
+		})
+	}
+}
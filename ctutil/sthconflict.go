@@ -0,0 +1,129 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/client"
+)
+
+// ConflictKind describes how two STHs of a Log conflict.
+type ConflictKind int
+
+// ConflictKind values.
+const (
+	// RootHashMismatch means that the STHs have the same tree size, but
+	// different root hashes.
+	RootHashMismatch ConflictKind = iota
+	// ConsistencyFailure means that the consistency proof between the STHs,
+	// of different tree sizes, does not verify.
+	ConsistencyFailure
+)
+
+func (k ConflictKind) String() string {
+	switch k {
+	case RootHashMismatch:
+		return "RootHashMismatch"
+	case ConsistencyFailure:
+		return "ConsistencyFailure"
+	default:
+		return fmt.Sprintf("UnknownConflict(%d)", k)
+	}
+}
+
+// Conflict describes two STHs of a Log which show that the Log has presented
+// different views of its tree, e.g. to different observers.
+type Conflict struct {
+	Kind ConflictKind
+	// First and Second are the conflicting STHs. For a ConsistencyFailure,
+	// First is the STH with the smaller tree size.
+	First, Second *ct.SignedTreeHead
+	// Err is the consistency proof verification error, for a
+	// ConsistencyFailure.
+	Err error
+}
+
+// ConsistencyProver provides consistency proofs between tree sizes of a Log.
+// It is implemented by client.LogClient.
+type ConsistencyProver interface {
+	GetSTHConsistency(ctx context.Context, first, second uint64) ([][]byte, error)
+}
+
+// DetectConflict checks STHs of a single Log, e.g. as seen by different
+// observers, for evidence that the Log has presented different views of its
+// tree. STHs of the same tree size must have the same root hash, and prover
+// is used to check that the trees of STHs with consecutive tree sizes are
+// consistent. Returns an error if the STHs have different tree sizes and
+// prover is nil, or if a proof cannot be obtained. The STH signatures are not
+// checked.
+func DetectConflict(ctx context.Context, sths []*ct.SignedTreeHead, prover ConsistencyProver) ([]Conflict, error) {
+	// Keep a single STH for each distinct tree head, grouped by tree size.
+	heads := make(map[uint64][]*ct.SignedTreeHead)
+	for i, sth := range sths {
+		if sth == nil {
+			return nil, fmt.Errorf("nil STH at index %d", i)
+		}
+		dup := false
+		for _, h := range heads[sth.TreeSize] {
+			if h.SHA256RootHash == sth.SHA256RootHash {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			heads[sth.TreeSize] = append(heads[sth.TreeSize], sth)
+		}
+	}
+	sizes := make([]uint64, 0, len(heads))
+	for size := range heads {
+		sizes = append(sizes, size)
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+
+	var conflicts []Conflict
+	for _, size := range sizes {
+		hs := heads[size]
+		for i := range hs {
+			for j := i + 1; j < len(hs); j++ {
+				conflicts = append(conflicts, Conflict{Kind: RootHashMismatch, First: hs[i], Second: hs[j]})
+			}
+		}
+	}
+	for i := 1; i < len(sizes); i++ {
+		first, second := sizes[i-1], sizes[i]
+		if first == 0 {
+			continue // Every tree extends the empty tree.
+		}
+		if prover == nil {
+			return nil, fmt.Errorf("no consistency prover for STHs of tree sizes %d and %d", first, second)
+		}
+		pf, err := prover.GetSTHConsistency(ctx, first, second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get consistency proof from size %d to %d: %v", first, second, err)
+		}
+		for _, h1 := range heads[first] {
+			for _, h2 := range heads[second] {
+				if err := client.VerifySTHConsistency(h1, h2, pf); err != nil {
+					conflicts = append(conflicts, Conflict{Kind: ConsistencyFailure, First: h1, Second: h2, Err: err})
+				}
+			}
+		}
+	}
+	return conflicts, nil
+}
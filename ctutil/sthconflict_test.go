@@ -0,0 +1,115 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ctutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"github.com/transparency-dev/merkle/testonly"
+)
+
+// treeProver serves consistency proofs from an in-memory tree.
+type treeProver struct {
+	tree *testonly.Tree
+	err  error
+}
+
+func (p *treeProver) GetSTHConsistency(_ context.Context, first, second uint64) ([][]byte, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.tree.ConsistencyProof(first, second)
+}
+
+func TestDetectConflict(t *testing.T) {
+	tree := testonly.New(rfc6962.DefaultHasher)
+	tree.AppendData(testonly.LeafInputs()...)
+	// forked has the same leaves as tree up to size 4, and different ones
+	// after that.
+	forked := testonly.New(rfc6962.DefaultHasher)
+	forked.AppendData(testonly.LeafInputs()[:4]...)
+	forked.AppendData([]byte("fork"), []byte("of"), []byte("the"), []byte("tree"))
+
+	sth := func(tree *testonly.Tree, size uint64, timestamp uint64) *ct.SignedTreeHead {
+		sth := &ct.SignedTreeHead{TreeSize: size, Timestamp: timestamp}
+		copy(sth.SHA256RootHash[:], tree.HashAt(size))
+		return sth
+	}
+	sth3, sth5, sth8 := sth(tree, 3, 1), sth(tree, 5, 2), sth(tree, 8, 3)
+	sth5Again := sth(tree, 5, 4)
+	fork6, fork8 := sth(forked, 6, 5), sth(forked, 8, 6)
+
+	tests := []struct {
+		desc    string
+		sths    []*ct.SignedTreeHead
+		prover  ConsistencyProver
+		want    []Conflict
+		wantErr bool
+	}{
+		{desc: "empty"},
+		{desc: "consistent", sths: []*ct.SignedTreeHead{sth8, sth3, sth5, sth5Again}, prover: &treeProver{tree: tree}},
+		{desc: "same-size-no-prover", sths: []*ct.SignedTreeHead{sth5, sth5Again}},
+		{desc: "different-sizes-no-prover", sths: []*ct.SignedTreeHead{sth3, sth5}, wantErr: true},
+		{desc: "empty-tree-no-prover", sths: []*ct.SignedTreeHead{sth(tree, 0, 1), sth3}},
+		{desc: "from-empty-tree", sths: []*ct.SignedTreeHead{sth(tree, 0, 1), sth3}, prover: &treeProver{tree: tree}},
+		{
+			desc: "forked-same-size",
+			sths: []*ct.SignedTreeHead{sth8, fork8},
+			want: []Conflict{{Kind: RootHashMismatch, First: sth8, Second: fork8}},
+		},
+		{
+			desc:   "forked-different-sizes",
+			sths:   []*ct.SignedTreeHead{sth5, fork6},
+			prover: &treeProver{tree: tree},
+			want:   []Conflict{{Kind: ConsistencyFailure, First: sth5, Second: fork6}},
+		},
+		{
+			desc:   "forked-both",
+			sths:   []*ct.SignedTreeHead{sth3, sth8, fork8},
+			prover: &treeProver{tree: tree},
+			want: []Conflict{
+				{Kind: RootHashMismatch, First: sth8, Second: fork8},
+				{Kind: ConsistencyFailure, First: sth3, Second: fork8},
+			},
+		},
+		{desc: "prover-error", sths: []*ct.SignedTreeHead{sth3, sth5}, prover: &treeProver{err: errors.New("boom")}, wantErr: true},
+		{desc: "nil-sth", sths: []*ct.SignedTreeHead{sth3, nil}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := DetectConflict(context.Background(), test.sths, test.prover)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("DetectConflict()=_,%v; want error: %t", err, test.wantErr)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("DetectConflict()=%+v; want %+v", got, test.want)
+			}
+			for i, c := range got {
+				want := test.want[i]
+				if c.Kind != want.Kind || c.First != want.First || c.Second != want.Second {
+					t.Errorf("conflict[%d]=%v (size %d vs %d); want %v (size %d vs %d)", i, c.Kind, c.First.TreeSize, c.Second.TreeSize, want.Kind, want.First.TreeSize, want.Second.TreeSize)
+				}
+				if gotErr := c.Err != nil; gotErr != (c.Kind == ConsistencyFailure) {
+					t.Errorf("conflict[%d].Err=%v for %v", i, c.Err, c.Kind)
+				}
+			}
+
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ct
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/certificate-transparency-go/tls"
+)
+
+// The CtExtensions of RFC 6962 are opaque, but later specifications (RFC 9162
+// section 4.5, and the Static CT API) encode them as a sequence of:
+//
+//	struct {
+//	    ExtensionType extension_type;
+//	    opaque extension_data<0..2^16-1>;
+//	} Extension;
+
+// SCTExtensionType represents the ExtensionType enum of an Extension:
+//
+//	enum { leaf_index(0), (255) } ExtensionType;
+type SCTExtensionType tls.Enum // tls:"maxval:255"
+
+// SCTExtensionType constants.
+const (
+	LeafIndexExtensionType SCTExtensionType = 0
+)
+
+func (t SCTExtensionType) String() string {
+	switch t {
+	case LeafIndexExtensionType:
+		return "LeafIndex"
+	default:
+		return fmt.Sprintf("UnknownExtensionType(%d)", t)
+	}
+}
+
+// SCTExtension is a single Extension held in CTExtensions.
+type SCTExtension struct {
+	ExtensionType SCTExtensionType `tls:"maxval:255"`
+	ExtensionData []byte           `tls:"minlen:0,maxlen:65535"`
+}
+
+// ParsedCTExtensions holds the extensions decoded from CTExtensions.
+type ParsedCTExtensions struct {
+	// LeafIndex is the index of the entry in the Log, from the leaf_index
+	// extension, or nil if absent. The extension holds a 40-bit integer.
+	LeafIndex *uint64
+	// Unknown holds the extensions of unknown types, in order.
+	Unknown []SCTExtension
+}
+
+// Parse decodes the extensions held in e. Unknown extension types are kept
+// undecoded in the result. Empty extensions give an empty result.
+func (e CTExtensions) Parse() (*ParsedCTExtensions, error) {
+	var parsed ParsedCTExtensions
+	for rest := []byte(e); len(rest) > 0; {
+		var ext SCTExtension
+		var err error
+		if rest, err = tls.Unmarshal(rest, &ext); err != nil {
+			return nil, fmt.Errorf("failed to parse extension: %v", err)
+		}
+		switch ext.ExtensionType {
+		case LeafIndexExtensionType:
+			if parsed.LeafIndex != nil {
+				return nil, errors.New("duplicate leaf_index extension")
+			}
+			if len(ext.ExtensionData) != 5 {
+				return nil, fmt.Errorf("leaf_index extension has %d bytes, want 5", len(ext.ExtensionData))
+			}
+			var index uint64
+			for _, b := range ext.ExtensionData {
+				index = index<<8 | uint64(b)
+			}
+			parsed.LeafIndex = &index
+		default:
+			parsed.Unknown = append(parsed.Unknown, ext)
+		}
+	}
+	return &parsed, nil
+}
+
+// LeafIndex returns the index of the entry in the Log, as found in the
+// leaf_index extension of e, e.g. in the SCTs of Static CT API Logs. It
+// reports false if there is no such extension, and an error if e is malformed.
+func (e CTExtensions) LeafIndex() (uint64, bool, error) {
+	parsed, err := e.Parse()
+	if err != nil {
+		return 0, false, err
+	}
+	if parsed.LeafIndex == nil {
+		return 0, false, nil
+	}
+	return *parsed.LeafIndex, true, nil
+}
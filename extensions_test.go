@@ -0,0 +1,172 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ct
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/certificate-transparency-go/tls"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCTExtensionsParse(t *testing.T) {
+	index := func(i uint64) *uint64 { return &i }
+	var tests = []struct {
+		desc   string
+		in     string // hex string
+		want   *ParsedCTExtensions
+		errstr string
+	}{
+		{desc: "Empty", in: "", want: &ParsedCTExtensions{}},
+		{desc: "LeafIndex", in: "00" + "0005" + "0102030405", want: &ParsedCTExtensions{LeafIndex: index(0x0102030405)}},
+		{desc: "LeafIndexMax", in: "00" + "0005" + "ffffffffff", want: &ParsedCTExtensions{LeafIndex: index(1<<40 - 1)}},
+		{
+			desc: "Unknown",
+			in:   "2a" + "0003" + "abcdef",
+			want: &ParsedCTExtensions{Unknown: []SCTExtension{{ExtensionType: 42, ExtensionData: dh("abcdef")}}},
+		},
+		{
+			desc: "Mixed",
+			in:   "01" + "0000" + "00" + "0005" + "0000000010" + "ff" + "0001" + "99",
+			want: &ParsedCTExtensions{
+				LeafIndex: index(16),
+				Unknown: []SCTExtension{
+					{ExtensionType: 1, ExtensionData: []byte{}},
+					{ExtensionType: 255, ExtensionData: dh("99")},
+				},
+			},
+		},
+		{desc: "LeafIndexShort", in: "00" + "0004" + "01020304", errstr: "has 4 bytes"},
+		{desc: "LeafIndexLong", in: "00" + "0006" + "010203040506", errstr: "has 6 bytes"},
+		{desc: "LeafIndexDuplicate", in: "00" + "0005" + "0000000001" + "00" + "0005" + "0000000002", errstr: "duplicate"},
+		{desc: "TruncatedHeader", in: "00" + "00", errstr: "failed to parse"},
+		{desc: "TruncatedData", in: "2a" + "0003" + "abcd", errstr: "failed to parse"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := CTExtensions(dh(test.in)).Parse()
+			if err != nil {
+				if test.errstr == "" {
+					t.Fatalf("Parse(%s)=nil,%v; want _,nil", test.in, err)
+				}
+				if !strings.Contains(err.Error(), test.errstr) {
+					t.Errorf("Parse(%s)=nil,%v; want error containing %q", test.in, err, test.errstr)
+				}
+				return
+			}
+			if test.errstr != "" {
+				t.Fatalf("Parse(%s)=%+v,nil; want error containing %q", test.in, got, test.errstr)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("Parse(%s) diff (-got +want):\n%s", test.in, diff)
+			}
+		})
+	}
+}
+
+func TestSCTExtensionsParse(t *testing.T) {
+	var tests = []struct {
+		desc string
+		exts string // hex string
+		want *ParsedCTExtensions
+	}{
+		{
+			desc: "Known",
+			exts: "00" + "0005" + "000000abcd",
+			want: &ParsedCTExtensions{LeafIndex: func() *uint64 { i := uint64(0xabcd); return &i }()},
+		},
+		{
+			desc: "Unknown",
+			exts: "07" + "0004" + "deadbeef",
+			want: &ParsedCTExtensions{Unknown: []SCTExtension{{ExtensionType: 7, ExtensionData: dh("deadbeef")}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			in := SignedCertificateTimestamp{
+				SCTVersion: V1,
+				Timestamp:  1234,
+				Extensions: CTExtensions(dh(test.exts)),
+				Signature: DigitallySigned{
+					Algorithm: tls.SignatureAndHashAlgorithm{Hash: tls.SHA256, Signature: tls.ECDSA},
+					Signature: []byte{0x01},
+				},
+			}
+			data, err := tls.Marshal(in)
+			if err != nil {
+				t.Fatalf("tls.Marshal(SCT)=nil,%v", err)
+			}
+			var sct SignedCertificateTimestamp
+			if rest, err := tls.Unmarshal(data, &sct); err != nil || len(rest) > 0 {
+				t.Fatalf("tls.Unmarshal(SCT)=%x,%v; want no error and no trailing data", rest, err)
+			}
+			got, err := sct.Extensions.Parse()
+			if err != nil {
+				t.Fatalf("Parse()=nil,%v", err)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("Parse() diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSCTExtensionTypeString(t *testing.T) {
+	for typ, want := range map[SCTExtensionType]string{
+		LeafIndexExtensionType: "LeafIndex",
+		42:                     "UnknownExtensionType(42)",
+	} {
+		if got := typ.String(); got != want {
+			t.Errorf("SCTExtensionType(%d).String()=%q, want %q", typ, got, want)
+		}
+	}
+}
+
+func TestSCTLeafIndex(t *testing.T) {
+	// TLS encoding of an SCT with the given CtExtensions.
+	rawSCT := func(exts string) string {
+		return "00" + strings.Repeat("aa", 32) + "0000017f00000000" + fmt.Sprintf("%04x", len(exts)/2) + exts + "0403" + "0001" + "01"
+	}
+	var tests = []struct {
+		desc    string
+		in      string // hex string
+		want    uint64
+		wantOK  bool
+		wantErr bool
+	}{
+		{desc: "LeafIndex", in: rawSCT("00" + "0005" + "000001e240"), want: 123456, wantOK: true},
+		{desc: "LeafIndexAmongOthers", in: rawSCT("05" + "0001" + "ff" + "00" + "0005" + "0000000007"), want: 7, wantOK: true},
+		{desc: "NoExtensions", in: rawSCT("")},
+		{desc: "OtherExtension", in: rawSCT("05" + "0001" + "ff")},
+		{desc: "Malformed", in: rawSCT("00" + "0002" + "0000"), wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var sct SignedCertificateTimestamp
+			if rest, err := tls.Unmarshal(dh(test.in), &sct); err != nil || len(rest) > 0 {
+				t.Fatalf("tls.Unmarshal(%s)=%x,%v; want no error and no trailing data", test.in, rest, err)
+			}
+			got, ok, err := sct.Extensions.LeafIndex()
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("LeafIndex()=_,_,%v; want err? %t", err, test.wantErr)
+			}
+			if got != test.want || ok != test.wantOK {
+				t.Errorf("LeafIndex()=%d,%t,_; want %d,%t", got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
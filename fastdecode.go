@@ -0,0 +1,111 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ct
+
+import (
+	"encoding/binary"
+)
+
+// The structures below are decoded from every entry a Log returns, so they
+// implement tls.FastUnmarshaler to avoid the cost of decoding by reflection.
+// Each decoder must accept exactly the encodings accepted by the reflective
+// decoder for the tls tags of the structure, with the same results.
+
+// UnmarshalTLS decodes a MerkleTreeLeaf, implementing tls.FastUnmarshaler.
+func (m *MerkleTreeLeaf) UnmarshalTLS(data []byte) ([]byte, bool) {
+	if len(data) < 2 {
+		return nil, false
+	}
+	leafType := MerkleLeafType(data[1])
+	if leafType != TimestampedEntryLeafType {
+		return nil, false
+	}
+	var entry TimestampedEntry
+	rest, ok := entry.UnmarshalTLS(data[2:])
+	if !ok {
+		return nil, false
+	}
+	*m = MerkleTreeLeaf{
+		Version:          Version(data[0]),
+		LeafType:         leafType,
+		TimestampedEntry: &entry,
+	}
+	return rest, true
+}
+
+// UnmarshalTLS decodes a TimestampedEntry, implementing tls.FastUnmarshaler.
+func (t *TimestampedEntry) UnmarshalTLS(data []byte) ([]byte, bool) {
+	if len(data) < 10 {
+		return nil, false
+	}
+	entry := TimestampedEntry{
+		Timestamp: binary.BigEndian.Uint64(data),
+		EntryType: LogEntryType(binary.BigEndian.Uint16(data[8:])),
+	}
+	rest := data[10:]
+	var ok bool
+	switch entry.EntryType {
+	case X509LogEntryType:
+		var cert ASN1Cert
+		if cert.Data, rest, ok = readVector(rest, 3, 1, 16777215); !ok {
+			return nil, false
+		}
+		entry.X509Entry = &cert
+	case PrecertLogEntryType:
+		var precert PreCert
+		if len(rest) < len(precert.IssuerKeyHash) {
+			return nil, false
+		}
+		copy(precert.IssuerKeyHash[:], rest)
+		rest = rest[len(precert.IssuerKeyHash):]
+		if precert.TBSCertificate, rest, ok = readVector(rest, 3, 1, 16777215); !ok {
+			return nil, false
+		}
+		entry.PrecertEntry = &precert
+	case 32768: // Selects JSONEntry, as per its tls tag.
+		var jsonEntry JSONDataEntry
+		if jsonEntry.Data, rest, ok = readVector(rest, 3, 0, 1677215); !ok {
+			return nil, false
+		}
+		entry.JSONEntry = &jsonEntry
+	default:
+		return nil, false
+	}
+	if entry.Extensions, rest, ok = readVector(rest, 2, 0, 65535); !ok {
+		return nil, false
+	}
+	*t = entry
+	return rest, true
+}
+
+// readVector reads a variable-length opaque vector with a big-endian length
+// prefix of lenSize bytes, which must be between minLen and maxLen. Returns a
+// copy of the vector's contents and the data which follows it.
+func readVector(data []byte, lenSize int, minLen, maxLen uint64) ([]byte, []byte, bool) {
+	if len(data) < lenSize {
+		return nil, nil, false
+	}
+	var n uint64
+	for _, b := range data[:lenSize] {
+		n = n<<8 | uint64(b)
+	}
+	data = data[lenSize:]
+	if n < minLen || n > maxLen || n > uint64(len(data)) {
+		return nil, nil, false
+	}
+	vec := make([]byte, n)
+	copy(vec, data)
+	return vec, data[n:], true
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ct
+
+import (
+	"encoding/pem"
+	"reflect"
+	"testing"
+
+	"github.com/google/certificate-transparency-go/testdata"
+	"github.com/google/certificate-transparency-go/tls"
+)
+
+// reflectiveLeaf and reflectiveEntry mirror MerkleTreeLeaf and
+// TimestampedEntry but lack their UnmarshalTLS methods, so are decoded by
+// reflection.
+type reflectiveLeaf struct {
+	Version          Version          `tls:"maxval:255"`
+	LeafType         MerkleLeafType   `tls:"maxval:255"`
+	TimestampedEntry *reflectiveEntry `tls:"selector:LeafType,val:0"`
+}
+
+type reflectiveEntry TimestampedEntry
+
+func (r *reflectiveLeaf) toLeaf() *MerkleTreeLeaf {
+	leaf := &MerkleTreeLeaf{Version: r.Version, LeafType: r.LeafType}
+	if r.TimestampedEntry != nil {
+		entry := TimestampedEntry(*r.TimestampedEntry)
+		leaf.TimestampedEntry = &entry
+	}
+	return leaf
+}
+
+func sampleLeafEncodings(t testing.TB) map[string][]byte {
+	t.Helper()
+	block, _ := pem.Decode([]byte(testdata.TestCertPEM))
+	if block == nil {
+		t.Fatal("failed to decode PEM")
+	}
+	leaves := map[string]MerkleTreeLeaf{
+		"x509": {
+			LeafType: TimestampedEntryLeafType,
+			TimestampedEntry: &TimestampedEntry{
+				Timestamp: 1469185273000,
+				EntryType: X509LogEntryType,
+				X509Entry: &ASN1Cert{Data: block.Bytes},
+			},
+		},
+		"x509-extensions": {
+			LeafType: TimestampedEntryLeafType,
+			TimestampedEntry: &TimestampedEntry{
+				Timestamp:  1469185273000,
+				EntryType:  X509LogEntryType,
+				X509Entry:  &ASN1Cert{Data: []byte{0x01}},
+				Extensions: CTExtensions{0x01, 0x02, 0x03},
+			},
+		},
+		"precert": {
+			LeafType: TimestampedEntryLeafType,
+			TimestampedEntry: &TimestampedEntry{
+				Timestamp: 1469185273000,
+				EntryType: PrecertLogEntryType,
+				PrecertEntry: &PreCert{
+					IssuerKeyHash:  [32]byte{0x01, 0x02, 0x03},
+					TBSCertificate: []byte{0x30, 0x00},
+				},
+			},
+		},
+		"json": {
+			LeafType: TimestampedEntryLeafType,
+			TimestampedEntry: &TimestampedEntry{
+				Timestamp: 1469185273000,
+				EntryType: 32768,
+				JSONEntry: &JSONDataEntry{Data: []byte(`{"a":1}`)},
+			},
+		},
+		"json-empty": {
+			Version:  V1,
+			LeafType: TimestampedEntryLeafType,
+			TimestampedEntry: &TimestampedEntry{
+				EntryType: 32768,
+				JSONEntry: &JSONDataEntry{},
+			},
+		},
+	}
+	encodings := make(map[string][]byte)
+	for name, leaf := range leaves {
+		data, err := tls.Marshal(leaf)
+		if err != nil {
+			t.Fatalf("tls.Marshal(%s)=nil,%v", name, err)
+		}
+		encodings[name] = data
+	}
+	return encodings
+}
+
+// mutations returns variants of data which are truncated, extended, or have
+// a single byte changed.
+func mutations(data []byte) [][]byte {
+	var out [][]byte
+	for i := 0; i <= len(data); i++ {
+		out = append(out, data[:i])
+	}
+	out = append(out, append(append([]byte{}, data...), 0x00, 0x01))
+	for i := range data {
+		for _, b := range []byte{0x00, 0x01, 0x80, 0xff} {
+			mutated := append([]byte{}, data...)
+			mutated[i] = b
+			out = append(out, mutated)
+		}
+	}
+	return out
+}
+
+func TestMerkleTreeLeafUnmarshalTLS(t *testing.T) {
+	for name, data := range sampleLeafEncodings(t) {
+		t.Run(name, func(t *testing.T) {
+			var leaf MerkleTreeLeaf
+			if _, ok := leaf.UnmarshalTLS(data); !ok {
+				t.Fatal("UnmarshalTLS()=_,false; want _,true")
+			}
+			for _, in := range mutations(data) {
+				var want reflectiveLeaf
+				wantRest, wantErr := tls.Unmarshal(in, &want)
+
+				var fast MerkleTreeLeaf
+				if rest, ok := fast.UnmarshalTLS(in); ok {
+					if wantErr != nil {
+						t.Fatalf("UnmarshalTLS(%x) succeeded; reflective decoding failed: %v", in, wantErr)
+					}
+					if !reflect.DeepEqual(&fast, want.toLeaf()) || !reflect.DeepEqual(rest, wantRest) {
+						t.Fatalf("UnmarshalTLS(%x)=%+v,%x; reflective decoding gives %+v,%x", in, fast, rest, want.toLeaf(), wantRest)
+					}
+				}
+
+				var got MerkleTreeLeaf
+				rest, err := tls.Unmarshal(in, &got)
+				if !reflect.DeepEqual(err, wantErr) {
+					t.Fatalf("tls.Unmarshal(%x)=_,%v; want _,%v", in, err, wantErr)
+				}
+				if err == nil && (!reflect.DeepEqual(&got, want.toLeaf()) || !reflect.DeepEqual(rest, wantRest)) {
+					t.Fatalf("tls.Unmarshal(%x)=%+v,%x; want %+v,%x", in, got, rest, want.toLeaf(), wantRest)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshalMerkleTreeLeaf(b *testing.B) {
+	data := sampleLeafEncodings(b)["x509"]
+	b.Run("fast", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var leaf MerkleTreeLeaf
+			if _, err := tls.Unmarshal(data, &leaf); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("reflective", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var leaf reflectiveLeaf
+			if _, err := tls.Unmarshal(data, &leaf); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -17,6 +17,7 @@ package fixchain
 
 import (
 	"encoding/pem"
+	"fmt"
 	"net/http"
 
 	"github.com/google/certificate-transparency-go/x509"
@@ -28,23 +29,49 @@ import (
 // presence of FixErrors does not mean the fix was unsuccessful.  Callers should
 // check for returned chains to determine success.
 func Fix(cert *x509.Certificate, chain []*x509.Certificate, roots *x509.CertPool, client *http.Client) ([][]*x509.Certificate, []*FixError) {
+	return FixWithOptions(cert, chain, roots, client, FixOptions{})
+}
+
+// DefaultMaxChainDepth is the maximum length of the chains built by following
+// AIA URLs, if no other maximum is specified.
+const DefaultMaxChainDepth = 20
+
+// FixOptions holds the settings used when fixing chains.
+type FixOptions struct {
+	// Cache stores the certificates fetched from AIA URLs, which are looked
+	// up there first.  If nil, a new in-memory cache is used.
+	Cache CertificateCache
+	// MaxChainDepth is the maximum length of the chains built by following
+	// AIA URLs, counting the leaf.  If zero, DefaultMaxChainDepth is used.
+	MaxChainDepth int
+}
+
+// FixWithOptions is like Fix, but uses the given options.
+func FixWithOptions(cert *x509.Certificate, chain []*x509.Certificate, roots *x509.CertPool, client *http.Client, opts FixOptions) ([][]*x509.Certificate, []*FixError) {
 	fix := &toFix{
-		cert:  cert,
-		chain: newDedupedChain(chain),
-		roots: roots,
-		cache: newURLCache(client, false),
+		cert:     cert,
+		chain:    newDedupedChain(chain),
+		roots:    roots,
+		cache:    newURLCache(client, opts.Cache, false),
+		maxDepth: opts.MaxChainDepth,
 	}
 	return fix.handleChain()
 }
 
-const maxChainLength = 20
-
 type toFix struct {
-	cert  *x509.Certificate
-	chain *dedupedChain
-	roots *x509.CertPool
-	opts  *x509.VerifyOptions
-	cache *urlCache
+	cert     *x509.Certificate
+	chain    *dedupedChain
+	roots    *x509.CertPool
+	opts     *x509.VerifyOptions
+	cache    *urlCache
+	maxDepth int // if zero, DefaultMaxChainDepth is used
+}
+
+func (fix *toFix) maxChainDepth() int {
+	if fix.maxDepth <= 0 {
+		return DefaultMaxChainDepth
+	}
+	return fix.maxDepth
 }
 
 func (fix *toFix) handleChain() ([][]*x509.Certificate, []*FixError) {
@@ -117,12 +144,13 @@ func (fix *toFix) fixChain() ([][]*x509.Certificate, []*FixError) {
 		}
 
 		seen := make(map[[hashSize]byte]bool)
+		path := make(map[[hashSize]byte]bool)
 		// Build all the chains possible that begin from this certificate,
 		// and add each certificate found along the way to the pool of
 		// intermediates against which to verify fix.cert.  If the addition of
 		// these intermediates causes chains for fix.cert to be verified,
 		// fix.augmentIntermediates() will return those chains.
-		chains, ferrs := fix.augmentIntermediates(cert, 1, seen)
+		chains, ferrs := fix.augmentIntermediates(cert, 1, seen, path)
 		if ferrs != nil {
 			retferrs = append(retferrs, ferrs...)
 		}
@@ -166,15 +194,19 @@ func (fix *toFix) fixChain() ([][]*x509.Certificate, []*FixError) {
 // discovered. length represents the position of the current given cert in the
 // larger chain, and is used to impose a max length to which chains can be
 // explored.  seen is a slice in which all certs that are encountered during the
-// search are noted down.
-func (fix *toFix) augmentIntermediates(cert *x509.Certificate, length int, seen map[[hashSize]byte]bool) ([][]*x509.Certificate, []*FixError) {
-	// If this cert takes the chain past maxChainLength, or if this cert has
-	// already been explored, return.
-	if length > maxChainLength || seen[hash(cert)] {
+// search are noted down.  path holds the certs in the chain currently being
+// built, and is used to detect AIA URLs that lead back into that chain.
+func (fix *toFix) augmentIntermediates(cert *x509.Certificate, length int, seen, path map[[hashSize]byte]bool) ([][]*x509.Certificate, []*FixError) {
+	// If this cert has already been explored, as part of another chain,
+	// return.
+	h := hash(cert)
+	if seen[h] {
 		return nil, nil
 	}
-	// Mark this cert as already explored.
-	seen[hash(cert)] = true
+	// Mark this cert as already explored, and as part of the current chain.
+	seen[h] = true
+	path[h] = true
+	defer delete(path, h)
 
 	// Add this cert to the pool of intermediates.  If this results in successful
 	// verification of one or more chains for fix.cert, return the chains.
@@ -190,6 +222,15 @@ func (fix *toFix) augmentIntermediates(cert *x509.Certificate, length int, seen
 	// every cert addition, and returning verified chains of fix.cert as soon
 	// as thay are found.
 	var retferrs []*FixError
+	if maxDepth := fix.maxChainDepth(); len(cert.IssuingCertificateURL) > 0 && length >= maxDepth {
+		return nil, []*FixError{{
+			Type:  MaxDepthExceeded,
+			Cert:  fix.cert,
+			Chain: fix.chain.certs,
+			URL:   cert.IssuingCertificateURL[0],
+			Error: fmt.Errorf("chain reached maximum depth %d at %q", maxDepth, cert.Subject.CommonName),
+		}}
+	}
 	for _, url := range cert.IssuingCertificateURL {
 		icerts, ferr := fix.getIntermediates(url)
 		if ferr != nil {
@@ -197,7 +238,18 @@ func (fix *toFix) augmentIntermediates(cert *x509.Certificate, length int, seen
 		}
 
 		for _, icert := range icerts {
-			chains, ferrs := fix.augmentIntermediates(icert, length+1, seen)
+			if path[hash(icert)] {
+				retferrs = append(retferrs, &FixError{
+					Type:  AIALoop,
+					Cert:  fix.cert,
+					Chain: fix.chain.certs,
+					URL:   url,
+					Bad:   icert.Raw,
+					Error: fmt.Errorf("AIA URL of %q leads back to %q, already in the chain", cert.Subject.CommonName, icert.Subject.CommonName),
+				})
+				continue
+			}
+			chains, ferrs := fix.augmentIntermediates(icert, length+1, seen, path)
 			if ferrs != nil {
 				retferrs = append(retferrs, ferrs...)
 			}
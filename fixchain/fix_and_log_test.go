@@ -58,7 +58,7 @@ var newFixAndLogTests = []fixAndLogTest{
 		chain: []string{testC, testB, testA},
 
 		function:     "QueueChain",
-		expectedErrs: []errorType{VerifyFailed, FixFailed},
+		expectedErrs: []errorType{VerifyFailed, AIALoop, FixFailed},
 	},
 	{ // Incomplete chain successfully logged.
 		url:   "https://ct.googleapis.com/pilot",
@@ -136,9 +136,9 @@ var newFixAndLogTests = []fixAndLogTest{
 
 		function: "QueueAllCertsInChain",
 		expectedErrs: []errorType{
-			VerifyFailed, FixFailed,
-			VerifyFailed, FixFailed,
-			VerifyFailed, FixFailed,
+			VerifyFailed, AIALoop, FixFailed,
+			VerifyFailed, AIALoop, FixFailed,
+			VerifyFailed, AIALoop, FixFailed,
 		},
 	},
 	{ // Incomplete chain successfully logged.
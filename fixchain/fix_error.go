@@ -34,6 +34,8 @@ const (
 	FixFailed
 	LogPostFailed // Posting to log failed
 	VerifyFailed
+	AIALoop          // Following AIA URLs led back to a cert already in the chain
+	MaxDepthExceeded // Following AIA URLs would exceed the maximum chain depth
 )
 
 // FixError is the struct with which errors in the fixing process are reported
@@ -95,6 +97,10 @@ func (e FixError) TypeString() string {
 		return "LogPostFailed"
 	case VerifyFailed:
 		return "VerifyFailed"
+	case AIALoop:
+		return "AIALoop"
+	case MaxDepthExceeded:
+		return "MaxDepthExceeded"
 	default:
 		return fmt.Sprintf("Type %d", e.Type)
 	}
@@ -178,6 +184,10 @@ func UnmarshalJSON(b []byte) (*FixError, error) {
 		ferr.Type = LogPostFailed
 	case "VerifyFailed":
 		ferr.Type = VerifyFailed
+	case "AIALoop":
+		ferr.Type = AIALoop
+	case "MaxDepthExceeded":
+		ferr.Type = MaxDepthExceeded
 	default:
 		return nil, errors.New("cannot parse FixError Type")
 	}
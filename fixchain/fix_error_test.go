@@ -241,6 +241,14 @@ func TestTypeString(t *testing.T) {
 			FixError{Type: VerifyFailed},
 			"VerifyFailed",
 		},
+		{
+			FixError{Type: AIALoop},
+			"AIALoop",
+		},
+		{
+			FixError{Type: MaxDepthExceeded},
+			"MaxDepthExceeded",
+		},
 		{
 			FixError{},
 			"None",
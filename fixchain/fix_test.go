@@ -15,10 +15,20 @@
 package fixchain
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
 	"net/http"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/certificate-transparency-go/x509/pkix"
 )
 
 var constructChainTests = []fixTest{
@@ -99,7 +109,7 @@ var fixChainTests = []fixTest{
 		chain: []string{testB, testA},
 
 		function:     "fixChain",
-		expectedErrs: []errorType{FixFailed},
+		expectedErrs: []errorType{AIALoop, FixFailed},
 	},
 	{ // Incomplete chain returns fixed chain
 		cert:  googleLeaf,
@@ -164,7 +174,7 @@ func setUpFix(t *testing.T, i int, ft *fixTest) *toFix {
 		cert:  GetTestCertificateFromPEM(t, ft.cert),
 		chain: newDedupedChain(extractTestChain(t, i, ft.chain)),
 		roots: extractTestRoots(t, i, ft.roots),
-		cache: newURLCache(&http.Client{Transport: &testRoundTripper{}}, false),
+		cache: newURLCache(&http.Client{Transport: &testRoundTripper{}}, nil, false),
 	}
 
 	intermediates := x509.NewCertPool()
@@ -224,3 +234,171 @@ func TestFix(t *testing.T) {
 		matchTestErrorList(t, i, test.expectedErrs, ferrs)
 	}
 }
+
+// recordingCache is a CertificateCache which records the URLs looked up in it.
+type recordingCache struct {
+	CertificateCache
+	mu     sync.Mutex
+	hits   []string
+	misses []string
+	puts   []string
+}
+
+func (c *recordingCache) Get(url string) ([]byte, bool) {
+	data, ok := c.CertificateCache.Get(url)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ok {
+		c.hits = append(c.hits, url)
+	} else {
+		c.misses = append(c.misses, url)
+	}
+	return data, ok
+}
+
+func (c *recordingCache) Put(url string, data []byte) {
+	c.mu.Lock()
+	c.puts = append(c.puts, url)
+	c.mu.Unlock()
+	c.CertificateCache.Put(url, data)
+}
+
+// failingRoundTripper fails every request.
+type failingRoundTripper struct{}
+
+func (failingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("unexpected request for %s", request.URL)
+}
+
+func TestFixWithOptionsCache(t *testing.T) {
+	const thawteURL = "http://www.thawte.com/repository/Thawte_SGC_CA.crt"
+	cache := &recordingCache{CertificateCache: NewMemoryCertificateCache()}
+	want := [][]string{{"Google", "Thawte", "VeriSign"}}
+
+	// The first fix has to fetch the missing intermediate.
+	chains, ferrs := FixWithOptions(GetTestCertificateFromPEM(t, googleLeaf), nil,
+		extractTestRoots(t, 0, []string{verisignRoot}),
+		&http.Client{Transport: &testRoundTripper{}}, FixOptions{Cache: cache})
+	matchTestChainList(t, 0, want, chains)
+	if len(cache.hits) != 0 || len(cache.misses) != 1 || len(cache.puts) != 1 || cache.puts[0] != thawteURL {
+		t.Errorf("after first fix: hits=%v, misses=%v, puts=%v; want one miss and one put of %s", cache.hits, cache.misses, cache.puts, thawteURL)
+	}
+	for _, ferr := range ferrs {
+		if ferr.Type != VerifyFailed {
+			t.Errorf("first fix: unexpected error %v", ferr)
+		}
+	}
+
+	// The second fix must be served from the cache, without any requests.
+	chains, ferrs = FixWithOptions(GetTestCertificateFromPEM(t, googleLeaf), nil,
+		extractTestRoots(t, 1, []string{verisignRoot}),
+		&http.Client{Transport: failingRoundTripper{}}, FixOptions{Cache: cache})
+	matchTestChainList(t, 1, want, chains)
+	if len(cache.hits) != 1 || cache.hits[0] != thawteURL || len(cache.puts) != 1 {
+		t.Errorf("after second fix: hits=%v, misses=%v, puts=%v; want one hit of %s", cache.hits, cache.misses, cache.puts, thawteURL)
+	}
+	for _, ferr := range ferrs {
+		if ferr.Type != VerifyFailed {
+			t.Errorf("second fix: unexpected error %v", ferr)
+		}
+	}
+}
+
+// mapRoundTripper serves the contents of a map, keyed by URL.
+type mapRoundTripper map[string][]byte
+
+func (m mapRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	body, ok := m[request.URL.String()]
+	if !ok {
+		return nil, fmt.Errorf("can't reach url %s", request.URL)
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: 200,
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    request,
+	}, nil
+}
+
+// makeAIACert returns a certificate with the given common name and AIA URL,
+// signed by parent, or self-signed if parent is nil.
+func makeAIACert(t *testing.T, cn, aiaURL string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey()=nil,%v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Unix(1600000000, 0),
+		NotAfter:              time.Unix(1700000000, 0),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	if aiaURL != "" {
+		template.IssuingCertificateURL = []string{aiaURL}
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate()=nil,%v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if x509.IsFatal(err) {
+		t.Fatalf("x509.ParseCertificate()=nil,%v", err)
+	}
+	return cert, key
+}
+
+func TestFixAIALoop(t *testing.T) {
+	const xURL, yURL = "http://example.com/x.crt", "http://example.com/y.crt"
+	leaf, _ := makeAIACert(t, "Leaf", xURL, nil, nil)
+	x, _ := makeAIACert(t, "X", yURL, nil, nil)
+	y, _ := makeAIACert(t, "Y", xURL, nil, nil)
+	client := &http.Client{Transport: mapRoundTripper{xURL: x.Raw, yURL: y.Raw}}
+
+	chains, ferrs := FixWithOptions(leaf, nil, x509.NewCertPool(), client, FixOptions{})
+	if len(chains) != 0 {
+		t.Errorf("FixWithOptions() returned %d chains; want none", len(chains))
+	}
+	matchTestErrorList(t, 0, []errorType{VerifyFailed, AIALoop, FixFailed}, ferrs)
+	for _, ferr := range ferrs {
+		if ferr.Type != AIALoop {
+			continue
+		}
+		if ferr.URL != xURL || !bytes.Equal(ferr.Bad, x.Raw) {
+			t.Errorf("AIALoop error for URL %q with cert %x; want URL %q with cert X", ferr.URL, ferr.Bad, xURL)
+		}
+	}
+}
+
+func TestFixMaxChainDepth(t *testing.T) {
+	const rootURL, i2URL, i1URL = "http://example.com/root.crt", "http://example.com/i2.crt", "http://example.com/i1.crt"
+	root, rootKey := makeAIACert(t, "Root", "", nil, nil)
+	i2, i2Key := makeAIACert(t, "Intermediate2", rootURL, root, rootKey)
+	i1, i1Key := makeAIACert(t, "Intermediate1", i2URL, i2, i2Key)
+	leaf, _ := makeAIACert(t, "Leaf", i1URL, i1, i1Key)
+	client := &http.Client{Transport: mapRoundTripper{rootURL: root.Raw, i2URL: i2.Raw, i1URL: i1.Raw}}
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	tests := []struct {
+		maxDepth   int
+		wantChains [][]string
+		wantErrs   []errorType
+	}{
+		{maxDepth: 0, wantChains: [][]string{{"Leaf", "Intermediate1", "Intermediate2", "Root"}}, wantErrs: []errorType{VerifyFailed}},
+		{maxDepth: 3, wantChains: [][]string{{"Leaf", "Intermediate1", "Intermediate2", "Root"}}, wantErrs: []errorType{VerifyFailed}},
+		{maxDepth: 2, wantErrs: []errorType{VerifyFailed, MaxDepthExceeded, FixFailed}},
+		{maxDepth: 1, wantErrs: []errorType{VerifyFailed, MaxDepthExceeded, FixFailed}},
+	}
+	for i, test := range tests {
+		chains, ferrs := FixWithOptions(leaf, nil, roots, client, FixOptions{MaxChainDepth: test.maxDepth})
+		matchTestChainList(t, i, test.wantChains, chains)
+		matchTestErrorList(t, i, test.wantErrs, ferrs)
+	}
+}
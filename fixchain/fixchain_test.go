@@ -48,7 +48,7 @@ var handleChainTests = []fixTest{
 		chain: []string{testB, testA},
 
 		function:     "handleChain",
-		expectedErrs: []errorType{VerifyFailed, FixFailed},
+		expectedErrs: []errorType{VerifyFailed, AIALoop, FixFailed},
 	},
 	{ // Incomplete chain returns a fixed chain
 		cert:  googleLeaf,
@@ -43,8 +43,9 @@ type Fixer struct {
 	validChainsProduced uint32
 	validChainsOut      uint32
 
-	wg    sync.WaitGroup
-	cache *urlCache
+	wg       sync.WaitGroup
+	cache    *urlCache
+	maxDepth int
 }
 
 // QueueChain adds the given cert and chain to the queue to be fixed by the
@@ -52,10 +53,11 @@ type Fixer struct {
 // order of cert --> root.
 func (f *Fixer) QueueChain(cert *x509.Certificate, chain []*x509.Certificate, roots *x509.CertPool) {
 	f.toFix <- &toFix{
-		cert:  cert,
-		chain: newDedupedChain(chain),
-		roots: roots,
-		cache: f.cache,
+		cert:     cert,
+		chain:    newDedupedChain(chain),
+		roots:    roots,
+		cache:    f.cache,
+		maxDepth: f.maxDepth,
 	}
 }
 
@@ -258,11 +260,17 @@ func (f *Fixer) logStats() {
 // chains are pushed to the chains channel.  client is used to try to get any
 // missing certificates that are needed when attempting to fix chains.
 func NewFixer(workerCount int, chains chan<- []*x509.Certificate, errors chan<- *FixError, client *http.Client, logStats bool) *Fixer {
+	return NewFixerWithOptions(workerCount, chains, errors, client, FixOptions{}, logStats)
+}
+
+// NewFixerWithOptions is like NewFixer, but uses the given options.
+func NewFixerWithOptions(workerCount int, chains chan<- []*x509.Certificate, errors chan<- *FixError, client *http.Client, opts FixOptions, logStats bool) *Fixer {
 	f := &Fixer{
-		toFix:  make(chan *toFix),
-		chains: chains,
-		errors: errors,
-		cache:  newURLCache(client, logStats),
+		toFix:    make(chan *toFix),
+		chains:   chains,
+		errors:   errors,
+		cache:    newURLCache(client, opts.Cache, logStats),
+		maxDepth: opts.MaxChainDepth,
 	}
 
 	f.newFixServerPool(workerCount)
@@ -26,7 +26,9 @@ const hashSize = sha256.Size
 var newHash = sha256.New
 
 func hash(c *x509.Certificate) (hash [hashSize]byte) {
-	copy(hash[:], newHash().Sum(c.Raw))
+	h := newHash()
+	h.Write(c.Raw)
+	copy(hash[:], h.Sum(nil))
 	return
 }
 
@@ -15,9 +15,29 @@
 package fixchain
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"testing"
+
+	"github.com/google/certificate-transparency-go/x509"
 )
 
+func TestHash(t *testing.T) {
+	leaf := GetTestCertificateFromPEM(t, googleLeaf)
+	// A certificate which only differs from leaf past its first 32 bytes,
+	// e.g. in its serial number or signature, is a different certificate.
+	raw := bytes.Repeat([]byte{0xff}, 64)
+	copy(raw, leaf.Raw[:32])
+	other := &x509.Certificate{Raw: raw}
+
+	if got, want := hash(leaf), sha256.Sum256(leaf.Raw); got != want {
+		t.Errorf("hash(leaf)=%x; want SHA-256 of the certificate %x", got, want)
+	}
+	if hash(leaf) == hash(other) {
+		t.Error("hash match between certs sharing only their first 32 bytes")
+	}
+}
+
 func TestHashBag(t *testing.T) {
 	hashBagTests := []struct {
 		certList1 []string
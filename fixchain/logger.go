@@ -163,7 +163,10 @@ func (l *Logger) postChain(p *toPost) {
 		derChain = append(derChain, ct.ASN1Cert{Data: cert.Raw})
 	}
 
-	l.limiter.Wait()
+	if err := l.wait(); err != nil {
+		// The Logger is shutting down.
+		return
+	}
 	atomic.AddUint32(&l.posted, 1)
 	_, err := l.client.AddChain(l.ctx, derChain)
 	if err != nil {
@@ -179,6 +182,18 @@ func (l *Logger) postChain(p *toPost) {
 	l.postCertCache.set(h, true)
 }
 
+// wait blocks on the Logger's rate limiter. It returns an error if the
+// Logger's context is done, aborting early if the limiter supports it.
+func (l *Logger) wait() error {
+	if cl, ok := l.limiter.(interface {
+		WaitContext(context.Context) error
+	}); ok {
+		return cl.WaitContext(l.ctx)
+	}
+	l.limiter.Wait()
+	return l.ctx.Err()
+}
+
 func (l *Logger) postServer() {
 	for {
 		c := <-l.toPost
@@ -0,0 +1,53 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+)
+
+// HostLimiter rate limits operations separately for each host, so that
+// requests to one host don't use up the rate available to the others.
+type HostLimiter struct {
+	limit int
+
+	mu       sync.Mutex
+	limiters map[string]*Limiter
+}
+
+// NewHostLimiter creates a new HostLimiter with a rate of limit per second for
+// each host.
+func NewHostLimiter(limit int) *HostLimiter {
+	return &HostLimiter{limit: limit, limiters: make(map[string]*Limiter)}
+}
+
+// Limiter returns the Limiter for the given host, creating it on first use.
+func (h *HostLimiter) Limiter(host string) *Limiter {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	l, ok := h.limiters[host]
+	if !ok {
+		l = NewLimiter(h.limit)
+		h.limiters[host] = l
+	}
+	return l
+}
+
+// WaitForHost blocks for the amount of time required by the host's Limiter so
+// as to not exceed its rate. Returns an error if ctx is done first.
+func (h *HostLimiter) WaitForHost(ctx context.Context, host string) error {
+	return h.Limiter(host).WaitContext(ctx)
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitAll calls WaitForHost concurrently for each of the hosts, and returns
+// how long it took for all of them to complete.
+func waitAll(t *testing.T, h *HostLimiter, hosts []string) time.Duration {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make(chan error, len(hosts))
+	start := time.Now()
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			errs <- h.WaitForHost(context.Background(), host)
+		}(host)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("WaitForHost() = %v, want nil", err)
+		}
+	}
+	return elapsed
+}
+
+func TestHostLimiterSameHost(t *testing.T) {
+	const limit, numOps = 10, 6
+	h := NewHostLimiter(limit)
+	hosts := make([]string, numOps)
+	for i := range hosts {
+		hosts[i] = "ca.example.com"
+	}
+	// The first op is free, the rest are spread at 1/limit intervals.
+	want := time.Duration(numOps-1) * time.Second / limit
+	if elapsed := waitAll(t, h, hosts); elapsed < want*8/10 {
+		t.Errorf("%d ops on one host took %v, want at least ~%v", numOps, elapsed, want)
+	}
+}
+
+func TestHostLimiterDifferentHosts(t *testing.T) {
+	const limit, numOps = 1, 20
+	h := NewHostLimiter(limit)
+	hosts := make([]string, numOps)
+	for i := range hosts {
+		hosts[i] = fmt.Sprintf("ca%d.example.com", i)
+	}
+	// Each host has a fresh bucket, so nobody waits for anybody else.
+	if elapsed := waitAll(t, h, hosts); elapsed > 500*time.Millisecond {
+		t.Errorf("%d ops on distinct hosts took %v, want them to return immediately", numOps, elapsed)
+	}
+}
+
+func TestHostLimiterSharesLimiter(t *testing.T) {
+	h := NewHostLimiter(1)
+	var wg sync.WaitGroup
+	got := make([]*Limiter, 10)
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i] = h.Limiter("ca.example.com")
+		}(i)
+	}
+	wg.Wait()
+	for i, l := range got {
+		if l != got[0] {
+			t.Errorf("Limiter() #%d = %p, want the same Limiter %p for every call", i, l, got[0])
+		}
+	}
+	if h.Limiter("other.example.com") == got[0] {
+		t.Error("Limiter() returned the same Limiter for different hosts")
+	}
+}
+
+func TestHostLimiterCancelled(t *testing.T) {
+	h := NewHostLimiter(1)
+	if err := h.WaitForHost(context.Background(), "ca.example.com"); err != nil {
+		t.Fatalf("WaitForHost() = %v, want nil", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := h.WaitForHost(ctx, "ca.example.com"); !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitForHost(cancelled) = %v, want error wrapping %v", err, context.Canceled)
+	}
+}
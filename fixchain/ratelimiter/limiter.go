@@ -33,8 +33,41 @@ func (l *Limiter) Wait() {
 	l.bucket.Wait(l.ctx)
 }
 
+// WaitContext blocks like Wait, but returns early with an error if ctx is
+// cancelled or its deadline would pass before the wait completes. The error
+// wraps ctx.Err() in the former case.
+func (l *Limiter) WaitContext(ctx context.Context) error {
+	return l.bucket.Wait(ctx)
+}
+
+// WaitN blocks like WaitContext, but for n operations at once, e.g. for a
+// batch which counts as several requests. It returns an error without waiting
+// if n exceeds the burst size of the Limiter, or if ctx's deadline would pass
+// before the n operations are allowed.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	return l.bucket.WaitN(ctx, n)
+}
+
+// Allow reports whether an operation may happen now, consuming a token if so.
+// It never blocks.
+func (l *Limiter) Allow() bool {
+	return l.bucket.Allow()
+}
+
+// Reserve reserves a token for a future operation and returns a Reservation
+// reporting how long the caller must wait before proceeding.
+func (l *Limiter) Reserve() *rate.Reservation {
+	return l.bucket.Reserve()
+}
+
 // NewLimiter creates a new Limiter with a rate of limit per second.
 func NewLimiter(limit int) *Limiter {
+	return NewLimiterWithBurst(limit, 1)
+}
+
+// NewLimiterWithBurst creates a new Limiter with a rate of limit per second,
+// which allows bursts of up to burst operations at once.
+func NewLimiterWithBurst(limit, burst int) *Limiter {
 	return &Limiter{ctx: context.Background(),
-		bucket: rate.NewLimiter(rate.Limit(limit), 1)}
+		bucket: rate.NewLimiter(rate.Limit(limit), burst)}
 }
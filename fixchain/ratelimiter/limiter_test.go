@@ -15,6 +15,8 @@
 package ratelimiter
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -80,3 +82,122 @@ func TestRateLimiterGoroutines(t *testing.T) {
 		})
 	}
 }
+
+func TestRateLimiterBurst(t *testing.T) {
+	const limit, burst = 10, 5
+	l := NewLimiterWithBurst(limit, burst)
+
+	start := time.Now()
+	for i := 0; i < burst; i++ {
+		l.Wait()
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("First %d waits took %v, want them to return immediately", burst, elapsed)
+	}
+
+	start = time.Now()
+	l.Wait()
+	// The bucket is empty, so the next token arrives after 1/limit seconds.
+	if elapsed, want := time.Since(start), time.Second/limit; elapsed < want*8/10 {
+		t.Errorf("Wait() after a burst of %d took %v, want ~%v", burst, elapsed, want)
+	}
+}
+
+func TestWaitContextCancelled(t *testing.T) {
+	l := NewLimiter(1)
+	// Use up the only token so that the next wait has to block.
+	if err := l.WaitContext(context.Background()); err != nil {
+		t.Fatalf("WaitContext() = %v, want nil", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.WaitContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitContext(cancelled) = %v, want error wrapping %v", err, context.Canceled)
+	}
+}
+
+func TestWaitN(t *testing.T) {
+	const limit, burst = 10, 5
+	l := NewLimiterWithBurst(limit, burst)
+	ctx := context.Background()
+
+	start := time.Now()
+	if err := l.WaitN(ctx, burst); err != nil {
+		t.Fatalf("WaitN(%d) = %v, want nil", burst, err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("WaitN(%d) on full bucket took %v, want it to return immediately", burst, elapsed)
+	}
+
+	// The bucket is empty, so 3 tokens arrive after 3/limit seconds.
+	const n = 3
+	start = time.Now()
+	if err := l.WaitN(ctx, n); err != nil {
+		t.Fatalf("WaitN(%d) = %v, want nil", n, err)
+	}
+	if elapsed, want := time.Since(start), n*time.Second/limit; elapsed < want*8/10 || elapsed > want*3 {
+		t.Errorf("WaitN(%d) on empty bucket took %v, want ~%v", n, elapsed, want)
+	}
+}
+
+func TestWaitNErrors(t *testing.T) {
+	const limit, burst = 10, 5
+	l := NewLimiterWithBurst(limit, burst)
+
+	start := time.Now()
+	if err := l.WaitN(context.Background(), burst+1); err == nil {
+		t.Errorf("WaitN(%d) with burst %d = nil, want error", burst+1, burst)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("WaitN(%d) with burst %d took %v, want it to fail immediately", burst+1, burst, elapsed)
+	}
+
+	// Drain the bucket, so that waiting for it to refill takes 1/2s.
+	if err := l.WaitN(context.Background(), burst); err != nil {
+		t.Fatalf("WaitN(%d) = %v, want nil", burst, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if err := l.WaitN(ctx, burst); err == nil {
+		t.Error("WaitN() past deadline = nil, want error")
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("WaitN() past deadline took %v, want it to fail immediately", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := l.WaitN(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitN(cancelled) = %v, want error wrapping %v", err, context.Canceled)
+	}
+}
+
+func TestAllow(t *testing.T) {
+	const burst = 3
+	l := NewLimiterWithBurst(1, burst)
+	for i := 0; i < burst; i++ {
+		if !l.Allow() {
+			t.Fatalf("Allow() #%d = false, want true", i)
+		}
+	}
+	if l.Allow() {
+		t.Error("Allow() on drained bucket = true, want false")
+	}
+}
+
+func TestReserve(t *testing.T) {
+	l := NewLimiter(1)
+	if r := l.Reserve(); !r.OK() || r.Delay() != 0 {
+		t.Errorf("Reserve() on full bucket = (ok: %t, delay: %v), want (true, 0)", r.OK(), r.Delay())
+	}
+	r := l.Reserve()
+	if !r.OK() {
+		t.Fatal("Reserve() on saturated bucket: not OK")
+	}
+	if r.Delay() <= 0 {
+		t.Errorf("Reserve() on saturated bucket has delay %v, want > 0", r.Delay())
+	}
+	r.Cancel()
+}
@@ -24,19 +24,31 @@ import (
 	"time"
 )
 
+// CertificateCache stores the data fetched from the URLs of issuing
+// certificates (e.g. taken from the AIA extension of a certificate), so that
+// each URL only needs to be fetched once.  Implementations must be safe for
+// concurrent use.
+type CertificateCache interface {
+	// Get returns the data previously stored for url, and whether there was
+	// any.
+	Get(url string) ([]byte, bool)
+	// Put stores the data fetched from url.
+	Put(url string, data []byte)
+}
+
 type lockedCache struct {
 	m map[string][]byte
 	sync.RWMutex
 }
 
-func (c *lockedCache) get(str string) ([]byte, bool) {
+func (c *lockedCache) Get(str string) ([]byte, bool) {
 	c.RLock()
 	defer c.RUnlock()
 	b, ok := c.m[str]
 	return b, ok
 }
 
-func (c *lockedCache) set(str string, b []byte) {
+func (c *lockedCache) Put(str string, b []byte) {
 	c.Lock()
 	defer c.Unlock()
 	c.m[str] = b
@@ -46,9 +58,15 @@ func newLockedCache() *lockedCache {
 	return &lockedCache{m: make(map[string][]byte)}
 }
 
+// NewMemoryCertificateCache returns a CertificateCache which holds all the
+// fetched data in memory.  This is the cache used if none is specified.
+func NewMemoryCertificateCache() CertificateCache {
+	return newLockedCache()
+}
+
 type urlCache struct {
 	client *http.Client
-	cache  *lockedCache
+	cache  CertificateCache
 
 	hit       uint32
 	miss      uint32
@@ -58,7 +76,7 @@ type urlCache struct {
 }
 
 func (u *urlCache) getURL(url string) ([]byte, error) {
-	r, ok := u.cache.get(url)
+	r, ok := u.cache.Get(url)
 	if ok {
 		atomic.AddUint32(&u.hit, 1)
 		return r, nil
@@ -80,21 +98,26 @@ func (u *urlCache) getURL(url string) ([]byte, error) {
 		return nil, err
 	}
 	atomic.AddUint32(&u.miss, 1)
-	u.cache.set(url, r)
+	u.cache.Put(url, r)
 	return r, nil
 }
 
-func newURLCache(c *http.Client, logStats bool) *urlCache {
-	u := &urlCache{cache: newLockedCache(), client: c}
+// newURLCache returns a urlCache which fetches URLs with c and stores the
+// results in cache.  If cache is nil, an in-memory cache is used.
+func newURLCache(c *http.Client, cache CertificateCache, logStats bool) *urlCache {
+	if cache == nil {
+		cache = newLockedCache()
+	}
+	u := &urlCache{cache: cache, client: c}
 
 	if logStats {
 		t := time.NewTicker(time.Second)
 		go func() {
 			for range t.C {
 				log.Printf("url cache: %d hits, %d misses, %d errors, "+
-					"%d bad status, %d read fail, %d cached", u.hit,
-					u.miss, u.errors, u.badStatus, u.readFail,
-					len(u.cache.m))
+					"%d bad status, %d read fail", atomic.LoadUint32(&u.hit),
+					atomic.LoadUint32(&u.miss), atomic.LoadUint32(&u.errors),
+					atomic.LoadUint32(&u.badStatus), atomic.LoadUint32(&u.readFail))
 			}
 		}()
 	}
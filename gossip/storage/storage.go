@@ -0,0 +1,134 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storage holds storage for the data exchanged by the gossip
+// protocols of draft-ietf-trans-gossip: SCT feedback, and STH pollination.
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	ct "github.com/google/certificate-transparency-go"
+)
+
+// SCTFeedback holds SCTs observed for a certificate, along with the chain
+// they were served with.
+type SCTFeedback struct {
+	// X509Chain holds the DER-encoded certificate chain, leaf first.
+	X509Chain [][]byte `json:"x509_chain"`
+	// SCTData holds the TLS-encoded SCTs.
+	SCTData [][]byte `json:"sct_data"`
+}
+
+// FeedbackStore persists SCT feedback and pollinated STHs. Adding an entry
+// identical to one already stored has no effect. Implementations must be
+// safe for concurrent use.
+type FeedbackStore interface {
+	// AddSCTFeedback stores fb, and returns whether it was not stored
+	// already.
+	AddSCTFeedback(ctx context.Context, fb *SCTFeedback) (bool, error)
+	// SCTFeedback returns all the SCT feedback stored, in insertion order.
+	SCTFeedback(ctx context.Context) ([]*SCTFeedback, error)
+	// AddSTH stores an STH for pollination, and returns whether it was not
+	// stored already.
+	AddSTH(ctx context.Context, sth *ct.SignedTreeHead) (bool, error)
+	// STHs returns the STHs stored for the Log with the given ID, in
+	// insertion order.
+	STHs(ctx context.Context, logID ct.SHA256Hash) ([]*ct.SignedTreeHead, error)
+}
+
+// MemoryStore is a FeedbackStore keeping entries in memory.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	feedback []*SCTFeedback
+	sths     map[ct.SHA256Hash][]*ct.SignedTreeHead
+	// seen holds the hashes of the entries stored, to drop duplicates.
+	seen map[[sha256.Size]byte]bool
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sths: make(map[ct.SHA256Hash][]*ct.SignedTreeHead),
+		seen: make(map[[sha256.Size]byte]bool),
+	}
+}
+
+// entryHash returns the hash identifying an entry of the given kind.
+func entryHash(kind string, entry interface{}) ([sha256.Size]byte, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to marshal %s: %v", kind, err)
+	}
+	return sha256.Sum256(append([]byte(kind+":"), data...)), nil
+}
+
+// AddSCTFeedback stores fb, and returns whether it was not stored already.
+// fb must not be modified afterwards.
+func (s *MemoryStore) AddSCTFeedback(ctx context.Context, fb *SCTFeedback) (bool, error) {
+	if fb == nil || len(fb.X509Chain) == 0 || len(fb.SCTData) == 0 {
+		return false, fmt.Errorf("SCT feedback needs both a chain and SCTs")
+	}
+	key, err := entryHash("sct_feedback", fb)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[key] {
+		return false, nil
+	}
+	s.seen[key] = true
+	s.feedback = append(s.feedback, fb)
+	return true, nil
+}
+
+// SCTFeedback returns all the SCT feedback stored, in insertion order.
+func (s *MemoryStore) SCTFeedback(ctx context.Context) ([]*SCTFeedback, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*SCTFeedback(nil), s.feedback...), nil
+}
+
+// AddSTH stores an STH for pollination, and returns whether it was not stored
+// already. sth must not be modified afterwards.
+func (s *MemoryStore) AddSTH(ctx context.Context, sth *ct.SignedTreeHead) (bool, error) {
+	if sth == nil {
+		return false, fmt.Errorf("nil STH")
+	}
+	key, err := entryHash("sth", sth)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[key] {
+		return false, nil
+	}
+	s.seen[key] = true
+	s.sths[sth.LogID] = append(s.sths[sth.LogID], sth)
+	return true, nil
+}
+
+// STHs returns the STHs stored for the Log with the given ID, in insertion
+// order.
+func (s *MemoryStore) STHs(ctx context.Context, logID ct.SHA256Hash) ([]*ct.SignedTreeHead, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*ct.SignedTreeHead(nil), s.sths[logID]...), nil
+}
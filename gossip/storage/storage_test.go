@@ -0,0 +1,122 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	ct "github.com/google/certificate-transparency-go"
+)
+
+func TestMemoryStoreSCTFeedback(t *testing.T) {
+	ctx := context.Background()
+	var store FeedbackStore = NewMemoryStore()
+
+	fb1 := &SCTFeedback{X509Chain: [][]byte{[]byte("leaf"), []byte("root")}, SCTData: [][]byte{[]byte("sct1")}}
+	fb2 := &SCTFeedback{X509Chain: [][]byte{[]byte("leaf"), []byte("root")}, SCTData: [][]byte{[]byte("sct2")}}
+	fb1Copy := &SCTFeedback{X509Chain: [][]byte{[]byte("leaf"), []byte("root")}, SCTData: [][]byte{[]byte("sct1")}}
+
+	for _, test := range []struct {
+		desc    string
+		fb      *SCTFeedback
+		wantNew bool
+		wantErr bool
+	}{
+		{desc: "first", fb: fb1, wantNew: true},
+		{desc: "other-sct", fb: fb2, wantNew: true},
+		{desc: "duplicate", fb: fb1Copy, wantNew: false},
+		{desc: "nil", fb: nil, wantErr: true},
+		{desc: "no-sct", fb: &SCTFeedback{X509Chain: [][]byte{[]byte("leaf")}}, wantErr: true},
+		{desc: "no-chain", fb: &SCTFeedback{SCTData: [][]byte{[]byte("sct1")}}, wantErr: true},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			gotNew, err := store.AddSCTFeedback(ctx, test.fb)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("AddSCTFeedback()=_,%v; want err? %t", err, test.wantErr)
+			}
+			if gotNew != test.wantNew {
+				t.Errorf("AddSCTFeedback()=%t,_; want %t", gotNew, test.wantNew)
+			}
+		})
+	}
+
+	got, err := store.SCTFeedback(ctx)
+	if err != nil {
+		t.Fatalf("SCTFeedback()=_,%v; want _,nil", err)
+	}
+	if diff := cmp.Diff([]*SCTFeedback{fb1, fb2}, got); diff != "" {
+		t.Errorf("SCTFeedback() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestMemoryStoreSTHs(t *testing.T) {
+	ctx := context.Background()
+	var store FeedbackStore = NewMemoryStore()
+
+	logA := ct.SHA256Hash{0x01}
+	logB := ct.SHA256Hash{0x02}
+	sth := func(logID ct.SHA256Hash, size uint64) *ct.SignedTreeHead {
+		return &ct.SignedTreeHead{
+			Version:        ct.V1,
+			TreeSize:       size,
+			Timestamp:      1000 + size,
+			SHA256RootHash: ct.SHA256Hash{byte(size)},
+			LogID:          logID,
+		}
+	}
+
+	for _, test := range []struct {
+		desc    string
+		sth     *ct.SignedTreeHead
+		wantNew bool
+		wantErr bool
+	}{
+		{desc: "first", sth: sth(logA, 10), wantNew: true},
+		{desc: "bigger", sth: sth(logA, 20), wantNew: true},
+		{desc: "other-log", sth: sth(logB, 10), wantNew: true},
+		{desc: "duplicate", sth: sth(logA, 10), wantNew: false},
+		{desc: "nil", wantErr: true},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			gotNew, err := store.AddSTH(ctx, test.sth)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("AddSTH()=_,%v; want err? %t", err, test.wantErr)
+			}
+			if gotNew != test.wantNew {
+				t.Errorf("AddSTH()=%t,_; want %t", gotNew, test.wantNew)
+			}
+		})
+	}
+
+	for _, test := range []struct {
+		logID ct.SHA256Hash
+		want  []*ct.SignedTreeHead
+	}{
+		{logID: logA, want: []*ct.SignedTreeHead{sth(logA, 10), sth(logA, 20)}},
+		{logID: logB, want: []*ct.SignedTreeHead{sth(logB, 10)}},
+		{logID: ct.SHA256Hash{0x03}},
+	} {
+		got, err := store.STHs(ctx, test.logID)
+		if err != nil {
+			t.Fatalf("STHs(%x)=_,%v; want _,nil", test.logID, err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("STHs(%x) diff (-want +got):\n%s", test.logID, diff)
+		}
+	}
+}
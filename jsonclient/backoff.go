@@ -23,6 +23,18 @@ type backoff struct {
 	mu         sync.RWMutex
 	multiplier uint
 	notBefore  time.Time
+	// jitter, if set, maps each computed backoff interval to the randomized
+	// interval actually waited. It isn't applied to override intervals.
+	jitter func(time.Duration) time.Duration
+}
+
+// fullJitter returns a jitter function which picks a uniformly random
+// duration between zero and the given interval, using rnd as the source of
+// randomness.
+func fullJitter(rnd func(n int64) int64) func(time.Duration) time.Duration {
+	return func(d time.Duration) time.Duration {
+		return time.Duration(rnd(int64(d) + 1))
+	}
 }
 
 const (
@@ -52,6 +64,9 @@ func (b *backoff) set(override *time.Duration) time.Duration {
 			b.multiplier++
 		}
 		wait = time.Second * time.Duration(1<<(b.multiplier-1))
+		if b.jitter != nil {
+			wait = b.jitter(wait)
+		}
 	}
 	b.notBefore = time.Now().Add(wait)
 	return wait
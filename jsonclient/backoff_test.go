@@ -16,6 +16,7 @@ package jsonclient
 
 import (
 	"math"
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -109,3 +110,40 @@ func TestBackoffOverride(t *testing.T) {
 		}
 	}
 }
+
+func TestBackoffFullJitter(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	b := backoff{jitter: fullJitter(rnd.Int63n)}
+
+	var waits []time.Duration
+	for i := uint(0); i < maxMultiplier+2; i++ {
+		interval := b.set(nil)
+		limit := time.Second << i
+		if i >= maxMultiplier {
+			limit = time.Second << (maxMultiplier - 1)
+		}
+		if interval < 0 || interval > limit {
+			t.Fatalf("backoff.set(nil)#%d=%v; want within [0, %v]", i, interval, limit)
+		}
+		waits = append(waits, interval)
+
+		// reset notBefore
+		b.notBefore = time.Time{}
+	}
+
+	// The same seed must produce the same waits.
+	rnd.Seed(42)
+	b = backoff{jitter: fullJitter(rnd.Int63n)}
+	for i, want := range waits {
+		if got := b.set(nil); got != want {
+			t.Errorf("backoff.set(nil)#%d=%v with reseeded source; want %v", i, got, want)
+		}
+		b.notBefore = time.Time{}
+	}
+
+	// Override intervals are not jittered.
+	override := 3 * time.Second
+	if got := b.set(&override); got != override {
+		t.Errorf("backoff.set(%v)=%v; want %v", override, got, override)
+	}
+}
@@ -60,6 +60,7 @@ type JSONClient struct {
 	logger     Logger                // interface to use for logging warnings and errors
 	backoff    backoffer             // object used to store and calculate backoff information
 	userAgent  string                // If set, this is sent as the UserAgent header.
+	retry      RetryConfig           // configures backoff between retries
 }
 
 // Logger is a simple logging interface used to log internal errors and warnings
@@ -79,6 +80,74 @@ type Options struct {
 	PublicKeyDER []byte
 	// UserAgent, if set, will be sent as the User-Agent header with each request.
 	UserAgent string
+	// Retry configures the backoff between retries of PostAndParseWithRetry.
+	Retry RetryConfig
+	// Transport, if set, is used to make the HTTP requests, e.g. to go
+	// through a proxy or to pin TLS certificates. It replaces the transport
+	// of the http.Client passed to New, whose other settings such as the
+	// timeout still apply. The passed http.Client is not modified.
+	Transport http.RoundTripper
+	// Conn tunes the connections of the HTTP transport.
+	Conn ConnConfig
+}
+
+// RetryConfig configures how a JSONClient backs off between retries.
+type RetryConfig struct {
+	// FullJitter, if set, makes the client wait a uniformly random duration
+	// between zero and the current backoff interval, rather than the whole
+	// interval plus up to 250ms. This spreads out the retries of many clients
+	// backing off at the same time. Waits requested by the server through a
+	// Retry-After header are always honoured in full, up to MaxRetryAfter.
+	FullJitter bool
+	// MaxRetryAfter caps the wait requested by a server's Retry-After
+	// header. Zero means no cap.
+	MaxRetryAfter time.Duration
+}
+
+// ConnConfig tunes the connections made by a JSONClient, e.g. to avoid
+// connection churn when submitting at a high rate. Zero values keep the
+// settings of the transport in use. Tuning applies to an *http.Transport: the
+// one in Options.Transport or in the http.Client passed to New, else a clone of
+// http.DefaultTransport. It is an error to tune another kind of transport.
+type ConnConfig struct {
+	// MaxIdleConnsPerHost is the number of idle connections kept open to the
+	// Log, for reuse by later requests.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept open.
+	IdleConnTimeout time.Duration
+	// ForceHTTP2 makes the client attempt HTTP/2 even if the transport has a
+	// custom TLS configuration or dialer, which otherwise disables it.
+	ForceHTTP2 bool
+}
+
+func (c ConnConfig) empty() bool {
+	return c == ConnConfig{}
+}
+
+// tunedTransport returns a copy of rt, or of http.DefaultTransport if rt is
+// nil, with the settings of c applied.
+func (c ConnConfig) tunedTransport(rt http.RoundTripper) (*http.Transport, error) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	base, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("cannot tune connections of transport type %T", rt)
+	}
+	t := base.Clone()
+	if c.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+		if t.MaxIdleConns > 0 && t.MaxIdleConns < c.MaxIdleConnsPerHost {
+			t.MaxIdleConns = c.MaxIdleConnsPerHost
+		}
+	}
+	if c.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = c.IdleConnTimeout
+	}
+	if c.ForceHTTP2 {
+		t.ForceAttemptHTTP2 = true
+	}
+	return t, nil
 }
 
 // ParsePublicKey parses and returns the public key contained in opts.
@@ -115,6 +184,9 @@ type RspError struct {
 	Err        error
 	StatusCode int
 	Body       []byte
+	// RetryAfter is the delay requested by the server's Retry-After header,
+	// if the error was caused by running out of time while honouring it.
+	RetryAfter time.Duration
 }
 
 // Error formats the RspError instance, focusing on the error.
@@ -122,8 +194,56 @@ func (e RspError) Error() string {
 	return e.Err.Error()
 }
 
+// Unwrap returns the underlying error.
+func (e RspError) Unwrap() error {
+	return e.Err
+}
+
+// ClientError is the underlying error of an RspError for an HTTP 4xx
+// response, e.g. when a Log rejects a submitted chain. Such requests are
+// generally not worth retrying as they are.
+type ClientError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *ClientError) Error() string {
+	return fmt.Sprintf("got HTTP status %q", e.Status)
+}
+
+// ServerError is the underlying error of an RspError for an HTTP 5xx
+// response, e.g. when a Log is unavailable. Such requests may succeed if
+// retried later.
+type ServerError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("got HTTP status %q", e.Status)
+}
+
+// statusError returns an RspError for an unexpected HTTP response, wrapping a
+// *ClientError or *ServerError depending on its status code.
+func statusError(rsp *http.Response, body []byte) RspError {
+	var err error
+	switch {
+	case rsp.StatusCode >= 400 && rsp.StatusCode < 500:
+		err = &ClientError{StatusCode: rsp.StatusCode, Status: rsp.Status, Body: body}
+	case rsp.StatusCode >= 500 && rsp.StatusCode < 600:
+		err = &ServerError{StatusCode: rsp.StatusCode, Status: rsp.Status, Body: body}
+	default:
+		err = fmt.Errorf("got HTTP status %q", rsp.Status)
+	}
+	return RspError{Err: err, StatusCode: rsp.StatusCode, Body: body}
+}
+
 // New constructs a new JSONClient instance, for the given base URI, using the
-// given http.Client object (if provided) and the Options object.
+// given http.Client object (if provided) and the Options object. If
+// opts.Transport is set, it overrides the transport of the http.Client; if
+// opts.Conn is set, a tuned copy of the transport is used instead.
 // If opts does not specify a public key, signatures will not be verified.
 func New(uri string, hc *http.Client, opts Options) (*JSONClient, error) {
 	pubkey, err := opts.ParsePublicKey()
@@ -143,17 +263,36 @@ func New(uri string, hc *http.Client, opts Options) (*JSONClient, error) {
 	if hc == nil {
 		hc = new(http.Client)
 	}
+	if opts.Transport != nil || !opts.Conn.empty() {
+		rt := opts.Transport
+		if !opts.Conn.empty() {
+			if rt == nil {
+				rt = hc.Transport
+			}
+			if rt, err = opts.Conn.tunedTransport(rt); err != nil {
+				return nil, err
+			}
+		}
+		c := *hc
+		c.Transport = rt
+		hc = &c
+	}
 	logger := opts.Logger
 	if logger == nil {
 		logger = &basicLogger{}
 	}
+	b := &backoff{}
+	if opts.Retry.FullJitter {
+		b.jitter = fullJitter(rand.Int63n)
+	}
 	return &JSONClient{
 		uri:        strings.TrimRight(uri, "/"),
 		httpClient: hc,
 		Verifier:   verifier,
 		logger:     logger,
-		backoff:    &backoff{},
+		backoff:    b,
 		userAgent:  opts.UserAgent,
+		retry:      opts.Retry,
 	}, nil
 }
 
@@ -162,11 +301,25 @@ func (c *JSONClient) BaseURI() string {
 	return c.uri
 }
 
+// CloseIdleConnections closes the idle connections of the HTTP transport of
+// the JSONClient, e.g. when it is no longer in use. Connections in use are not
+// interrupted.
+func (c *JSONClient) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
 // GetAndParse makes a HTTP GET call to the given path, and attempts to parse
 // the response as a JSON representation of the rsp structure.  Returns the
 // http.Response, the body of the response, and an error (which may be of
-// type RspError if the HTTP response was available).
+// type RspError if the HTTP response was available, wrapping a *ClientError or
+// *ServerError for a 4xx or 5xx status).
 func (c *JSONClient) GetAndParse(ctx context.Context, path string, params map[string]string, rsp interface{}) (*http.Response, []byte, error) {
+	return c.GetAndParseWithHeaders(ctx, path, params, nil, rsp)
+}
+
+// GetAndParseWithHeaders is like GetAndParse, but also sends the given HTTP
+// headers with the request, e.g. to make it conditional.
+func (c *JSONClient) GetAndParseWithHeaders(ctx context.Context, path string, params map[string]string, hdr http.Header, rsp interface{}) (*http.Response, []byte, error) {
 	if ctx == nil {
 		return nil, nil, errors.New("context.Context required")
 	}
@@ -181,6 +334,11 @@ func (c *JSONClient) GetAndParse(ctx context.Context, path string, params map[st
 	if err != nil {
 		return nil, nil, err
 	}
+	for k, vs := range hdr {
+		for _, v := range vs {
+			httpReq.Header.Add(k, v)
+		}
+	}
 	if len(c.userAgent) != 0 {
 		httpReq.Header.Set("User-Agent", c.userAgent)
 	}
@@ -198,7 +356,7 @@ func (c *JSONClient) GetAndParse(ctx context.Context, path string, params map[st
 	}
 
 	if httpRsp.StatusCode != http.StatusOK {
-		return nil, nil, RspError{Err: fmt.Errorf("got HTTP Status %q", httpRsp.Status), StatusCode: httpRsp.StatusCode, Body: body}
+		return nil, nil, statusError(httpRsp, body)
 	}
 
 	if err := json.NewDecoder(bytes.NewReader(body)).Decode(rsp); err != nil {
@@ -258,7 +416,11 @@ func (c *JSONClient) PostAndParse(ctx context.Context, path string, req, rsp int
 // waitForBackoff blocks until the defined backoff interval or context has expired, if the returned
 // not before time is in the past it returns immediately.
 func (c *JSONClient) waitForBackoff(ctx context.Context) error {
-	dur := time.Until(c.backoff.until().Add(time.Millisecond * time.Duration(rand.Intn(int(maxJitter.Seconds()*1000)))))
+	until := c.backoff.until()
+	if !c.retry.FullJitter {
+		until = until.Add(time.Millisecond * time.Duration(rand.Intn(int(maxJitter.Seconds()*1000))))
+	}
+	dur := time.Until(until)
 	if dur < 0 {
 		dur = 0
 	}
@@ -273,12 +435,18 @@ func (c *JSONClient) waitForBackoff(ctx context.Context) error {
 
 // PostAndParseWithRetry makes a HTTP POST call, but retries (with backoff) on
 // retriable errors; the caller should set a deadline on the provided context
-// to prevent infinite retries.  Return values are as for PostAndParse.
+// to prevent infinite retries.  Return values are as for PostAndParse, except
+// that a non-retriable HTTP status is reported as for GetAndParse.
+// Waits requested by HTTP 429 and 503 responses through a Retry-After header
+// are honoured; if the context expires during such a wait, the returned
+// RspError reports the requested delay.
 func (c *JSONClient) PostAndParseWithRetry(ctx context.Context, path string, req, rsp interface{}) (*http.Response, []byte, error) {
 	if ctx == nil {
 		return nil, nil, errors.New("context.Context required")
 	}
 	for {
+		// lastErr describes a response which requested a Retry-After delay.
+		var lastErr *RspError
 		httpRsp, body, err := c.PostAndParse(ctx, path, req, rsp)
 		if err != nil {
 			// Don't retry context errors.
@@ -309,16 +477,23 @@ func (c *JSONClient) PostAndParseWithRetry(ctx context.Context, path string, req
 						backoff = &b
 					}
 				}
+				if backoff != nil {
+					if limit := c.retry.MaxRetryAfter; limit > 0 && *backoff > limit {
+						*backoff = limit
+					}
+					lastErr = &RspError{StatusCode: httpRsp.StatusCode, Body: body, RetryAfter: *backoff}
+				}
 				wait := c.backoff.set(backoff)
 				c.logger.Printf("Request to %s failed, backing-off for %s: got HTTP status %s", c.uri, wait, httpRsp.Status)
 			default:
-				return nil, nil, RspError{
-					StatusCode: httpRsp.StatusCode,
-					Body:       body,
-					Err:        fmt.Errorf("got HTTP status %q", httpRsp.Status)}
+				return nil, nil, statusError(httpRsp, body)
 			}
 		}
 		if err := c.waitForBackoff(ctx); err != nil {
+			if lastErr != nil {
+				lastErr.Err = fmt.Errorf("gave up waiting %s as requested by Retry-After: %w", lastErr.RetryAfter, err)
+				return nil, nil, *lastErr
+			}
 			return nil, nil, err
 		}
 	}
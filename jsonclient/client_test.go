@@ -18,7 +18,9 @@ import (
 	"context"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -457,6 +459,73 @@ func TestPostAndParseWithRetry(t *testing.T) {
 	}
 }
 
+func TestPostAndParseWithRetryAfter(t *testing.T) {
+	tests := []struct {
+		uri           string
+		retryAfter    int
+		maxRetryAfter time.Duration
+		wantBackoff   time.Duration
+	}{
+		{uri: "/retry", retryAfter: 5, wantBackoff: 5 * time.Second},
+		{uri: "/retry", retryAfter: 5, maxRetryAfter: 2 * time.Second, wantBackoff: 2 * time.Second},
+		{uri: "/retry-rfc1123", retryAfter: 5, wantBackoff: 5 * time.Second},
+		{uri: "/retry-rfc1123", retryAfter: 5, maxRetryAfter: 2 * time.Second, wantBackoff: 2 * time.Second},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s,max=%s", strings.TrimPrefix(test.uri, "/"), test.maxRetryAfter), func(t *testing.T) {
+			ts := MockServer(t, 1, test.retryAfter)
+			defer ts.Close()
+
+			logClient, err := New(ts.URL, &http.Client{}, Options{Retry: RetryConfig{MaxRetryAfter: test.maxRetryAfter}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			mb := mockBackoff{}
+			logClient.backoff = &mb
+
+			var got TestStruct
+			if _, _, err := logClient.PostAndParseWithRetry(context.Background(), test.uri, nil, &got); err != nil {
+				t.Fatalf("PostAndParseWithRetry()=nil,%q; want no error", err.Error())
+			}
+			if !fuzzyDurationEquals(test.wantBackoff, mb.override, time.Second) {
+				t.Errorf("Unexpected backoff override set: got: %s, wanted: %s", mb.override, test.wantBackoff)
+			}
+		})
+	}
+}
+
+func TestPostAndParseWithRetryAfterExhausted(t *testing.T) {
+	for _, uri := range []string{"/retry", "/retry-rfc1123"} {
+		t.Run(strings.TrimPrefix(uri, "/"), func(t *testing.T) {
+			ts := MockServer(t, 1, 60)
+			defer ts.Close()
+
+			logClient, err := New(ts.URL, &http.Client{}, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			var got TestStruct
+			_, _, err = logClient.PostAndParseWithRetry(ctx, uri, nil, &got)
+			rspErr, ok := err.(RspError)
+			if !ok {
+				t.Fatalf("PostAndParseWithRetry()=%T(%v); want RspError", err, err)
+			}
+			if rspErr.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("PostAndParseWithRetry().StatusCode=%d; want %d", rspErr.StatusCode, http.StatusServiceUnavailable)
+			}
+			if want := time.Minute; !fuzzyDurationEquals(want, rspErr.RetryAfter, time.Second) {
+				t.Errorf("PostAndParseWithRetry().RetryAfter=%s; want %s", rspErr.RetryAfter, want)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("PostAndParseWithRetry()=%v; want error wrapping %v", err, context.DeadlineExceeded)
+			}
+		})
+	}
+}
+
 // nolint:staticcheck
 func TestContextRequired(t *testing.T) {
 	ts := MockServer(t, -1, 0)
@@ -505,3 +574,184 @@ func TestCancelledContext(t *testing.T) {
 		t.Errorf("PostAndParseWithRetry() = (_,_,%v), want %q", err, context.Canceled)
 	}
 }
+
+// recordingTransport records the requests made through it, and replies to
+// them itself with a canned response, or by blocking until the request is
+// cancelled.
+type recordingTransport struct {
+	block bool
+
+	mu   sync.Mutex
+	reqs []*http.Request
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	rt.reqs = append(rt.reqs, req)
+	rt.mu.Unlock()
+	if rt.block {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(`{"tree_size": 11, "timestamp": 99}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestTransport(t *testing.T) {
+	ctx := context.Background()
+	rt := &recordingTransport{}
+	hc := &http.Client{Timeout: time.Minute}
+	// No server listens at this URL; the transport handles the requests.
+	logClient, err := New("https://ct.example.com/log", hc, Options{Transport: rt, UserAgent: "transport-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hc.Transport != nil {
+		t.Error("New() modified the passed http.Client")
+	}
+
+	var result TestStruct
+	if _, _, err := logClient.GetAndParse(ctx, "/struct/path", map[string]string{"a": "b"}, &result); err != nil {
+		t.Fatalf("GetAndParse()=_,_,%v; want nil", err)
+	}
+	if result.TreeSize != 11 {
+		t.Errorf("GetAndParse() got tree size %d; want 11", result.TreeSize)
+	}
+	if _, _, err := logClient.PostAndParse(ctx, "/struct/path", &TestParams{}, &result); err != nil {
+		t.Fatalf("PostAndParse()=_,_,%v; want nil", err)
+	}
+
+	want := []string{"GET https://ct.example.com/log/struct/path?a=b", "POST https://ct.example.com/log/struct/path"}
+	var got []string
+	for _, req := range rt.reqs {
+		got = append(got, req.Method+" "+req.URL.String())
+		if ua := req.Header.Get("User-Agent"); ua != "transport-test" {
+			t.Errorf("%s request had User-Agent %q; want %q", req.Method, ua, "transport-test")
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Transport got requests %v; want %v", got, want)
+	}
+
+	// The timeout of the http.Client still applies to the custom transport.
+	rt = &recordingTransport{block: true}
+	logClient, err = New("https://ct.example.com/log", &http.Client{Timeout: 10 * time.Millisecond}, Options{Transport: rt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := logClient.GetAndParse(ctx, "/struct/path", nil, &result); err == nil {
+		t.Error("GetAndParse()=_,_,nil with blocking transport; want timeout error")
+	}
+	if len(rt.reqs) != 1 {
+		t.Errorf("Blocking transport got %d requests; want 1", len(rt.reqs))
+	}
+}
+
+// idleClosingTransport counts the calls to CloseIdleConnections.
+type idleClosingTransport struct {
+	http.RoundTripper
+	closed int
+}
+
+func (t *idleClosingTransport) CloseIdleConnections() {
+	t.closed++
+}
+
+func TestCloseIdleConnections(t *testing.T) {
+	rt := &idleClosingTransport{}
+	logClient, err := New("https://ct.example.com/log", nil, Options{Transport: rt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logClient.CloseIdleConnections()
+	if rt.closed != 1 {
+		t.Errorf("CloseIdleConnections() closed the transport connections %d times; want 1", rt.closed)
+	}
+}
+
+func TestConnConfig(t *testing.T) {
+	custom := &http.Transport{MaxIdleConns: 10, IdleConnTimeout: time.Second}
+	for _, test := range []struct {
+		desc      string
+		hc        *http.Client
+		opts      Options
+		wantPer   int
+		wantMax   int
+		wantIdle  time.Duration
+		wantHTTP2 bool
+		wantErr   bool
+	}{
+		{
+			desc:      "default-transport",
+			opts:      Options{Conn: ConnConfig{MaxIdleConnsPerHost: 50, IdleConnTimeout: time.Minute, ForceHTTP2: true}},
+			wantPer:   50,
+			wantMax:   100,
+			wantIdle:  time.Minute,
+			wantHTTP2: true,
+		},
+		{
+			desc:     "client-transport",
+			hc:       &http.Client{Transport: custom},
+			opts:     Options{Conn: ConnConfig{MaxIdleConnsPerHost: 20}},
+			wantPer:  20,
+			wantMax:  20,
+			wantIdle: time.Second,
+		},
+		{
+			desc:     "options-transport",
+			hc:       &http.Client{Transport: &http.Transport{}},
+			opts:     Options{Transport: custom, Conn: ConnConfig{IdleConnTimeout: time.Hour}},
+			wantMax:  10,
+			wantIdle: time.Hour,
+		},
+		{
+			desc:    "untunable-transport",
+			opts:    Options{Transport: &recordingTransport{}, Conn: ConnConfig{ForceHTTP2: true}},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var orig http.RoundTripper
+			if test.hc != nil {
+				orig = test.hc.Transport
+			}
+			logClient, err := New("https://ct.example.com/log", test.hc, test.opts)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("New()=_,%v; want err? %t", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if test.hc != nil && test.hc.Transport != orig {
+				t.Error("New() modified the passed http.Client")
+			}
+			tr, ok := logClient.httpClient.Transport.(*http.Transport)
+			if !ok {
+				t.Fatalf("New() used transport %T; want *http.Transport", logClient.httpClient.Transport)
+			}
+			if tr == custom || tr == http.DefaultTransport {
+				t.Error("New() tuned a shared transport; want a copy")
+			}
+			if tr.MaxIdleConnsPerHost != test.wantPer {
+				t.Errorf("MaxIdleConnsPerHost=%d; want %d", tr.MaxIdleConnsPerHost, test.wantPer)
+			}
+			if tr.MaxIdleConns != test.wantMax {
+				t.Errorf("MaxIdleConns=%d; want %d", tr.MaxIdleConns, test.wantMax)
+			}
+			if tr.IdleConnTimeout != test.wantIdle {
+				t.Errorf("IdleConnTimeout=%v; want %v", tr.IdleConnTimeout, test.wantIdle)
+			}
+			if tr.ForceAttemptHTTP2 != test.wantHTTP2 {
+				t.Errorf("ForceAttemptHTTP2=%t; want %t", tr.ForceAttemptHTTP2, test.wantHTTP2)
+			}
+		})
+	}
+	if custom.MaxIdleConnsPerHost != 0 || custom.IdleConnTimeout != time.Second {
+		t.Error("New() modified the passed transport")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loglist3
+
+// LogChange describes a log present in two versions of a log list, whose
+// state or URL differ between them.
+type LogChange struct {
+	// Old and New are the log's entries in the old and new log lists.
+	Old, New *Log
+	// StateChanged is true if the log's status differs.
+	StateChanged bool
+	// URLChanged is true if the log's URL differs.
+	URLChanged bool
+}
+
+// ListDiff holds the differences between two versions of a log list.
+type ListDiff struct {
+	// Added holds the logs of the new list missing from the old one.
+	Added []*Log
+	// Removed holds the logs of the old list missing from the new one.
+	Removed []*Log
+	// Changed holds the logs present in both lists with a different state or
+	// URL.
+	Changed []LogChange
+}
+
+// Empty returns true if there are no differences.
+func (d ListDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares two versions of a log list, matching their logs by log ID.
+// Added and changed logs are listed in the order of the new list, and removed
+// logs in the order of the old list. A nil list is treated as empty.
+func Diff(old, new *LogList) ListDiff {
+	oldLogs := logsByID(old)
+	newLogs := logsByID(new)
+
+	var diff ListDiff
+	forEachLog(new, func(log *Log) {
+		prev, ok := oldLogs[string(log.LogID)]
+		if !ok {
+			diff.Added = append(diff.Added, log)
+			return
+		}
+		change := LogChange{
+			Old:          prev,
+			New:          log,
+			StateChanged: prev.State.LogStatus() != log.State.LogStatus(),
+			URLChanged:   prev.URL != log.URL,
+		}
+		if change.StateChanged || change.URLChanged {
+			diff.Changed = append(diff.Changed, change)
+		}
+	})
+	forEachLog(old, func(log *Log) {
+		if _, ok := newLogs[string(log.LogID)]; !ok {
+			diff.Removed = append(diff.Removed, log)
+		}
+	})
+	return diff
+}
+
+func forEachLog(ll *LogList, fn func(log *Log)) {
+	if ll == nil {
+		return
+	}
+	for _, op := range ll.Operators {
+		for _, log := range op.Logs {
+			fn(log)
+		}
+	}
+}
+
+func logsByID(ll *LogList) map[string]*Log {
+	logs := make(map[string]*Log)
+	forEachLog(ll, func(log *Log) {
+		logs[string(log.LogID)] = log
+	})
+	return logs
+}
@@ -0,0 +1,132 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loglist3
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// copyLogList returns a deep copy of ll.
+func copyLogList(t *testing.T, ll *LogList) *LogList {
+	t.Helper()
+	data, err := json.Marshal(ll)
+	if err != nil {
+		t.Fatalf("json.Marshal()=nil,%v", err)
+	}
+	var cp LogList
+	if err := json.Unmarshal(data, &cp); err != nil {
+		t.Fatalf("json.Unmarshal()=%v", err)
+	}
+	return &cp
+}
+
+func logDescriptions(logs []*Log) []string {
+	var descs []string
+	for _, log := range logs {
+		descs = append(descs, log.Description)
+	}
+	return descs
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDiff(t *testing.T) {
+	old := copyLogList(t, &sampleLogList)
+	updated := copyLogList(t, &sampleLogList)
+	google, bob := updated.Operators[0], updated.Operators[1]
+	// Bob's Dubious Log is removed.
+	bob.Logs = nil
+	// A new log is added.
+	google.Logs = append(google.Logs, &Log{
+		Description: "Google 'Argon2030' log",
+		LogID:       deb64("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+		URL:         "https://ct.googleapis.com/logs/argon2030/",
+		State:       &LogStates{Pending: &LogState{Timestamp: time.Unix(1640000000, 0).UTC()}},
+	})
+	for _, log := range google.Logs {
+		switch log.Description {
+		case "Google 'Racketeer' log":
+			// The state changes.
+			log.State = &LogStates{Usable: &LogState{Timestamp: time.Unix(1640000000, 0).UTC()}}
+		case "Google 'Rocketeer' log":
+			// The URL changes.
+			log.URL = "https://ct.googleapis.com/logs/rocketeer/"
+		case "Google 'Icarus' log":
+			// The state timestamp changes, but not the state itself.
+			log.State.Usable.Timestamp = time.Unix(1640000000, 0).UTC()
+		}
+	}
+
+	diff := Diff(old, updated)
+	if got, want := logDescriptions(diff.Added), []string{"Google 'Argon2030' log"}; !equalStrings(got, want) {
+		t.Errorf("Diff().Added=%v; want %v", got, want)
+	}
+	if got, want := logDescriptions(diff.Removed), []string{"Bob's Dubious Log"}; !equalStrings(got, want) {
+		t.Errorf("Diff().Removed=%v; want %v", got, want)
+	}
+	wantChanged := []struct {
+		desc                 string
+		wantState, wantURL   bool
+		oldStatus, newStatus LogStatus
+	}{
+		{desc: "Google 'Racketeer' log", wantState: true, oldStatus: UndefinedLogStatus, newStatus: UsableLogStatus},
+		{desc: "Google 'Rocketeer' log", wantURL: true, oldStatus: UndefinedLogStatus, newStatus: UndefinedLogStatus},
+	}
+	if len(diff.Changed) != len(wantChanged) {
+		t.Fatalf("Diff().Changed=%+v; want %d changes", diff.Changed, len(wantChanged))
+	}
+	for i, want := range wantChanged {
+		got := diff.Changed[i]
+		if got.Old.Description != want.desc || got.New.Description != want.desc {
+			t.Errorf("Diff().Changed[%d] is for %q -> %q; want %q", i, got.Old.Description, got.New.Description, want.desc)
+		}
+		if got.StateChanged != want.wantState || got.URLChanged != want.wantURL {
+			t.Errorf("Diff().Changed[%d]: StateChanged=%t, URLChanged=%t; want %t, %t", i, got.StateChanged, got.URLChanged, want.wantState, want.wantURL)
+		}
+		if got.Old.State.LogStatus() != want.oldStatus || got.New.State.LogStatus() != want.newStatus {
+			t.Errorf("Diff().Changed[%d]: status %v -> %v; want %v -> %v", i, got.Old.State.LogStatus(), got.New.State.LogStatus(), want.oldStatus, want.newStatus)
+		}
+	}
+	if diff.Empty() {
+		t.Error("Diff().Empty()=true; want false")
+	}
+
+	for _, test := range []struct {
+		desc     string
+		old, new *LogList
+	}{
+		{desc: "same", old: old, new: copyLogList(t, old)},
+		{desc: "both-nil"},
+	} {
+		if diff := Diff(test.old, test.new); !diff.Empty() {
+			t.Errorf("%s: Diff()=%+v; want empty", test.desc, diff)
+		}
+	}
+	if diff := Diff(nil, old); len(diff.Added) != 6 || len(diff.Removed) != 0 {
+		t.Errorf("Diff(nil, list) added %d and removed %d logs; want 6 and 0", len(diff.Added), len(diff.Removed))
+	}
+}
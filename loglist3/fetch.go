@@ -0,0 +1,135 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loglist3
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fetch downloads the log list at the given URL and parses it. If client is
+// nil, http.DefaultClient is used.
+func Fetch(ctx context.Context, client *http.Client, url string) (*LogList, error) {
+	llData, err := fetchURL(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromJSON(llData)
+}
+
+// FetchSigned downloads the log list at the given URL and its signature at
+// sigURL, and parses the list once the signature is verified with pubKey.
+// If client is nil, http.DefaultClient is used.
+func FetchSigned(ctx context.Context, client *http.Client, url, sigURL string, pubKey crypto.PublicKey) (*LogList, error) {
+	llData, err := fetchURL(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+	rawSig, err := fetchURL(ctx, client, sigURL)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromSignedJSON(llData, rawSig, pubKey)
+}
+
+// fetchURL returns the body of a successful GET request to url.
+func fetchURL(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %v", url, err)
+	}
+	defer rsp.Body.Close()
+	body, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", url, err)
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: got HTTP status %q", url, rsp.Status)
+	}
+	return body, nil
+}
+
+// Fetcher downloads a log list, and caches it for a while.
+type Fetcher struct {
+	// Client is used for the downloads. If nil, http.DefaultClient is used.
+	Client *http.Client
+	// URL of the log list. If empty, LogListURL is used.
+	URL string
+	// PubKey, if set, is used to verify the signature over the log list.
+	PubKey crypto.PublicKey
+	// SignatureURL of the log list signature. If empty, it is derived from
+	// URL by replacing its .json extension with .sig, which matches
+	// LogListSignatureURL for LogListURL.
+	SignatureURL string
+	// TTL is how long a downloaded log list is reused for. If zero, the log
+	// list is downloaded on every call.
+	TTL time.Duration
+
+	now func() time.Time
+
+	mu      sync.Mutex
+	cached  *LogList
+	expires time.Time
+}
+
+// Get returns the log list, downloading it unless a cached copy is still
+// fresh. The returned LogList is shared with other callers, and should not be
+// modified.
+func (f *Fetcher) Get(ctx context.Context) (*LogList, error) {
+	now := time.Now
+	if f.now != nil {
+		now = f.now
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cached != nil && now().Before(f.expires) {
+		return f.cached, nil
+	}
+
+	url := f.URL
+	if url == "" {
+		url = LogListURL
+	}
+	var ll *LogList
+	var err error
+	if f.PubKey != nil {
+		sigURL := f.SignatureURL
+		if sigURL == "" {
+			sigURL = strings.TrimSuffix(url, ".json") + ".sig"
+		}
+		ll, err = FetchSigned(ctx, f.Client, url, sigURL, f.PubKey)
+	} else {
+		ll, err = Fetch(ctx, f.Client, url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.cached, f.expires = ll, now().Add(f.TTL)
+	return ll, nil
+}
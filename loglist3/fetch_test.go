@@ -0,0 +1,176 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loglist3
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logListServer serves the sample log list at /log_list.json, with its
+// signature at /log_list.sig, and counts the requests for each path.
+type logListServer struct {
+	*httptest.Server
+	pubKey crypto.PublicKey
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newLogListServer(t *testing.T, sigData func(llData, sig []byte) []byte) *logListServer {
+	t.Helper()
+	llData, err := json.Marshal(&sampleLogList)
+	if err != nil {
+		t.Fatalf("json.Marshal()=nil,%v", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey()=nil,%v", err)
+	}
+	hash := sha256.Sum256(llData)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatalf("ecdsa.SignASN1()=nil,%v", err)
+	}
+	if sigData != nil {
+		sig = sigData(llData, sig)
+	}
+
+	s := &logListServer{pubKey: &key.PublicKey, requests: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		s.mu.Unlock()
+		switch r.URL.Path {
+		case "/log_list.json":
+			w.Write(llData) // nolint: errcheck
+		case "/log_list.sig":
+			w.Write(sig) // nolint: errcheck
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return s
+}
+
+func (s *logListServer) count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func TestFetch(t *testing.T) {
+	s := newLogListServer(t, nil)
+	defer s.Close()
+	ctx := context.Background()
+
+	ll, err := Fetch(ctx, s.Client(), s.URL+"/log_list.json")
+	if err != nil {
+		t.Fatalf("Fetch()=nil,%v; want _,nil", err)
+	}
+	if got, want := len(ll.Operators), len(sampleLogList.Operators); got != want {
+		t.Errorf("Fetch() got %d operators; want %d", got, want)
+	}
+
+	if _, err := Fetch(ctx, s.Client(), s.URL+"/missing.json"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Fetch(missing)=_,%v; want 404 error", err)
+	}
+
+	if _, err := FetchSigned(ctx, s.Client(), s.URL+"/log_list.json", s.URL+"/log_list.sig", s.pubKey); err != nil {
+		t.Errorf("FetchSigned()=nil,%v; want _,nil", err)
+	}
+}
+
+func TestFetcher(t *testing.T) {
+	// otherKeySig signs the log list with a key other than the expected one.
+	otherKeySig := func(llData, _ []byte) []byte {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("ecdsa.GenerateKey()=nil,%v", err)
+		}
+		hash := sha256.Sum256(llData)
+		sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+		if err != nil {
+			t.Fatalf("ecdsa.SignASN1()=nil,%v", err)
+		}
+		return sig
+	}
+
+	tests := []struct {
+		name     string
+		sigData  func(llData, sig []byte) []byte
+		verify   bool
+		ttl      time.Duration
+		wantList int // number of downloads of the list expected
+		wantSig  int // number of downloads of the signature expected
+		wantErr  string
+	}{
+		{name: "NoCache", wantList: 3},
+		{name: "Cached", ttl: time.Hour, wantList: 2},
+		{name: "Verified", verify: true, ttl: time.Hour, wantList: 2, wantSig: 2},
+		{name: "BadSignature", sigData: otherKeySig, verify: true, ttl: time.Hour, wantList: 3, wantSig: 3, wantErr: "failed to verify signature"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newLogListServer(t, test.sigData)
+			defer s.Close()
+			now := time.Unix(1600000000, 0)
+			f := &Fetcher{
+				Client: s.Client(),
+				URL:    s.URL + "/log_list.json",
+				TTL:    test.ttl,
+				now:    func() time.Time { return now },
+			}
+			if test.verify {
+				f.PubKey = s.pubKey
+			}
+
+			// Two calls close together, then one after the TTL.
+			for _, elapsed := range []time.Duration{0, time.Minute, 2 * time.Hour} {
+				now = now.Add(elapsed)
+				ll, err := f.Get(context.Background())
+				if test.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+						t.Errorf("Get()=_,%v; want err containing %q", err, test.wantErr)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("Get()=nil,%v; want _,nil", err)
+				}
+				if got, want := len(ll.Operators), len(sampleLogList.Operators); got != want {
+					t.Errorf("Get() got %d operators; want %d", got, want)
+				}
+			}
+			if got := s.count("/log_list.json"); got != test.wantList {
+				t.Errorf("Log list downloaded %d times; want %d", got, test.wantList)
+			}
+			if got := s.count("/log_list.sig"); got != test.wantSig {
+				t.Errorf("Signature downloaded %d times; want %d", got, test.wantSig)
+			}
+		})
+	}
+}
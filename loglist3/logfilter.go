@@ -15,6 +15,8 @@
 package loglist3
 
 import (
+	"strings"
+
 	"github.com/google/certificate-transparency-go/x509"
 	"github.com/google/certificate-transparency-go/x509util"
 	"k8s.io/klog/v2"
@@ -53,6 +55,20 @@ func (ll *LogList) SelectByStatus(lstats []LogStatus) LogList {
 	return active
 }
 
+// SelectByOperator creates a new LogList containing only the logs of the
+// operator with the given name, compared case-insensitively.
+func (ll *LogList) SelectByOperator(name string) LogList {
+	var selected LogList
+	for _, op := range ll.Operators {
+		if strings.EqualFold(op.Name, name) && len(op.Logs) > 0 {
+			selectedOp := *op
+			selectedOp.Logs = append([]*Log{}, op.Logs...)
+			selected.Operators = append(selected.Operators, &selectedOp)
+		}
+	}
+	return selected
+}
+
 // RootCompatible creates a new LogList containing only the logs of original
 // LogList that are compatible with the provided cert, according to
 // the passed in collection of per-log roots. Logs that are missing from
@@ -109,11 +125,7 @@ func (ll *LogList) TemporallyCompatible(cert *x509.Certificate) LogList {
 		compatibleOp := *op
 		compatibleOp.Logs = []*Log{}
 		for _, l := range op.Logs {
-			if l.TemporalInterval == nil {
-				compatibleOp.Logs = append(compatibleOp.Logs, l)
-				continue
-			}
-			if cert.NotAfter.Before(l.TemporalInterval.EndExclusive) && (cert.NotAfter.After(l.TemporalInterval.StartInclusive) || cert.NotAfter.Equal(l.TemporalInterval.StartInclusive)) {
+			if logAcceptsCert(l, cert) {
 				compatibleOp.Logs = append(compatibleOp.Logs, l)
 			}
 		}
@@ -123,3 +135,14 @@ func (ll *LogList) TemporallyCompatible(cert *x509.Certificate) LogList {
 	}
 	return compatible
 }
+
+// logAcceptsCert returns whether the cert's NotAfter date falls within the
+// Log's temporal interval. Logs which are not temporally sharded accept any
+// cert.
+func logAcceptsCert(log *Log, cert *x509.Certificate) bool {
+	if log.TemporalInterval == nil {
+		return true
+	}
+	notAfter := cert.NotAfter
+	return notAfter.Before(log.TemporalInterval.EndExclusive) && !notAfter.Before(log.TemporalInterval.StartInclusive)
+}
@@ -93,6 +93,64 @@ func TestSelectPendingAndQualified(t *testing.T) {
 	}
 }
 
+func TestSelectByOperator(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator string
+		stats    []LogStatus
+		want     LogList
+	}{
+		{
+			name:     "Bob",
+			operator: "Bob's CT Log Shop",
+			want:     subLogList(map[string]bool{"https://log.bob.io": true}),
+		},
+		{
+			name:     "CaseInsensitive",
+			operator: "gOOGLE",
+			want: subLogList(map[string]bool{
+				"https://ct.googleapis.com/aviator/":        true,
+				"https://ct.googleapis.com/icarus/":         true,
+				"https://ct.googleapis.com/racketeer/":      true,
+				"https://ct.googleapis.com/rocketeer/":      true,
+				"https://ct.googleapis.com/logs/argon2020/": true,
+			}),
+		},
+		{
+			name:     "Unknown",
+			operator: "Alice's Shady Log",
+			want:     LogList{},
+		},
+		{
+			name:     "UsableByGoogle",
+			operator: "Google",
+			stats:    []LogStatus{UsableLogStatus, QualifiedLogStatus},
+			want: subLogList(map[string]bool{
+				"https://ct.googleapis.com/icarus/":         true,
+				"https://ct.googleapis.com/logs/argon2020/": true,
+			}),
+		},
+		{
+			name:     "RetiredByGoogle",
+			operator: "Google",
+			stats:    []LogStatus{RetiredLogStatus},
+			want:     LogList{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := sampleLogList.SelectByOperator(test.operator)
+			if test.stats != nil {
+				got = got.SelectByStatus(test.stats)
+			}
+			if diff := pretty.Compare(test.want, got); diff != "" {
+				t.Errorf("Selecting logs of %q diff: (-want +got)\n%s", test.operator, diff)
+			}
+		})
+	}
+}
+
 func artificialRoots(source string) LogRoots {
 	roots := LogRoots{
 		"https://log.bob.io":                        x509util.NewPEMCertPool(),
@@ -229,6 +287,39 @@ func TestTemporallyCompatible(t *testing.T) {
 	}
 }
 
+func TestLogAcceptsCert(t *testing.T) {
+	shard2020 := &Log{URL: "https://ct.example.com/2020/", TemporalInterval: &TemporalInterval{
+		StartInclusive: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndExclusive:   time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}
+	shard2021 := &Log{URL: "https://ct.example.com/2021/", TemporalInterval: &TemporalInterval{
+		StartInclusive: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndExclusive:   time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}
+	unsharded := &Log{URL: "https://ct.example.com/all/"}
+
+	tests := []struct {
+		name     string
+		log      *Log
+		notAfter time.Time
+		want     bool
+	}{
+		{name: "InsideShard", log: shard2020, notAfter: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "OutsideShard", log: shard2021, notAfter: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "StartInclusive", log: shard2021, notAfter: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "EndExclusive", log: shard2020, notAfter: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "NotSharded", log: unsharded, notAfter: time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC), want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cert := &x509.Certificate{NotAfter: test.notAfter}
+			if got := logAcceptsCert(test.log, cert); got != test.want {
+				t.Errorf("logAcceptsCert(%s, NotAfter=%v) = %t, want %t", test.log.URL, test.notAfter, got, test.want)
+			}
+		})
+	}
+}
+
 func TestCompatible(t *testing.T) {
 	cert, _ := x509util.CertificateFromPEM([]byte(testdata.TestPreCertPEM))
 	caCert, _ := x509util.CertificateFromPEM([]byte(testdata.CACertPEM))
@@ -240,6 +240,17 @@ func NewFromJSON(llData []byte) (*LogList, error) {
 // signature along the way. The signature data should be provided as the
 // raw signature data.
 func NewFromSignedJSON(llData, rawSig []byte, pubKey crypto.PublicKey) (*LogList, error) {
+	if err := VerifySignature(llData, rawSig, pubKey); err != nil {
+		return nil, err
+	}
+	return NewFromJSON(llData)
+}
+
+// VerifySignature checks the detached signature over JSON encoded log list
+// data, as published at LogListSignatureURL for the list at LogListURL. The
+// signature is a SHA-256 based one, RSA PKCS#1 v1.5 or ECDSA depending on the
+// type of pubKey, and should be provided as the raw signature data.
+func VerifySignature(llData, rawSig []byte, pubKey crypto.PublicKey) error {
 	var sigAlgo tls.SignatureAlgorithm
 	switch pkType := pubKey.(type) {
 	case *rsa.PublicKey:
@@ -247,7 +258,7 @@ func NewFromSignedJSON(llData, rawSig []byte, pubKey crypto.PublicKey) (*LogList
 	case *ecdsa.PublicKey:
 		sigAlgo = tls.ECDSA
 	default:
-		return nil, fmt.Errorf("unsupported public key type %v", pkType)
+		return fmt.Errorf("unsupported public key type %T", pkType)
 	}
 	tlsSig := tls.DigitallySigned{
 		Algorithm: tls.SignatureAndHashAlgorithm{
@@ -257,9 +268,9 @@ func NewFromSignedJSON(llData, rawSig []byte, pubKey crypto.PublicKey) (*LogList
 		Signature: rawSig,
 	}
 	if err := tls.VerifySignature(pubKey, llData, tlsSig); err != nil {
-		return nil, fmt.Errorf("failed to verify signature: %v", err)
+		return fmt.Errorf("failed to verify signature: %v", err)
 	}
-	return NewFromJSON(llData)
+	return nil
 }
 
 // FindLogByName returns all logs whose names contain the given string.
@@ -327,6 +338,33 @@ func (ll *LogList) FindLogByKey(key []byte) *Log {
 	return nil
 }
 
+// GroupByKey clusters the logs sharing the same public key, such as a log and
+// its mirrors, so that they can be counted once when assessing log diversity.
+// Logs are compared by the SHA-256 hash of their key, or by their LogID if
+// they have no key. Every log belongs to exactly one group, and groups are
+// ordered by their first log in the list.
+func (ll *LogList) GroupByKey() [][]*Log {
+	var groups [][]*Log
+	index := make(map[string]int)
+	for _, op := range ll.Operators {
+		for _, log := range op.Logs {
+			id := string(log.LogID)
+			if len(log.Key) > 0 {
+				h := sha256.Sum256(log.Key)
+				id = string(h[:])
+			}
+			i, ok := index[id]
+			if !ok {
+				i = len(groups)
+				index[id] = i
+				groups = append(groups, nil)
+			}
+			groups[i] = append(groups[i], log)
+		}
+	}
+	return groups
+}
+
 var hexDigits = regexp.MustCompile("^[0-9a-fA-F]+$")
 
 // FuzzyFindLog tries to find logs that match the given unspecified input,
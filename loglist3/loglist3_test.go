@@ -16,6 +16,12 @@ package loglist3
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
@@ -355,6 +361,53 @@ func TestFindLogByKey(t *testing.T) {
 	}
 }
 
+func TestGroupByKey(t *testing.T) {
+	icarus := sampleLogList.Operators[0].Logs[1]
+	rocketeer := sampleLogList.Operators[0].Logs[3]
+	// A mirror of Icarus run elsewhere, with the same key.
+	mirror := &Log{
+		Description: "Icarus mirror",
+		LogID:       icarus.LogID,
+		Key:         icarus.Key,
+		URL:         "https://ct.example.com/icarus-mirror/",
+		MMD:         86400,
+	}
+	// A log only identified by Rocketeer's LogID.
+	keyless := &Log{
+		Description: "Keyless Rocketeer",
+		LogID:       rocketeer.LogID,
+		URL:         "https://ct.example.com/keyless/",
+	}
+	ll := LogList{Operators: append(append([]*Operator{}, sampleLogList.Operators...), &Operator{
+		Name: "Mirrors R Us",
+		Logs: []*Log{mirror, keyless},
+	})}
+
+	var got [][]string
+	for _, group := range ll.GroupByKey() {
+		var names []string
+		for _, log := range group {
+			names = append(names, log.Description)
+		}
+		got = append(got, names)
+	}
+	want := [][]string{
+		{"Google 'Aviator' log"},
+		{"Google 'Icarus' log", "Icarus mirror"},
+		{"Google 'Racketeer' log"},
+		{"Google 'Rocketeer' log", "Keyless Rocketeer"},
+		{"Google 'Argon2020' log"},
+		{"Bob's Dubious Log"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupByKey()=%q, want %q", got, want)
+	}
+
+	if got := (&LogList{}).GroupByKey(); len(got) != 0 {
+		t.Errorf("GroupByKey() of empty list=%v, want none", got)
+	}
+}
+
 func TestFuzzyFindLog(t *testing.T) {
 	var tests = []struct {
 		name, in string
@@ -548,3 +601,71 @@ func deb64(b string) []byte {
 	}
 	return data
 }
+
+func TestVerifySignature(t *testing.T) {
+	llData, err := json.Marshal(&sampleLogList)
+	if err != nil {
+		t.Fatalf("json.Marshal()=nil,%v", err)
+	}
+	tampered := bytes.Replace(llData, []byte("Google"), []byte("Gooogle"), 1)
+	if bytes.Equal(tampered, llData) {
+		t.Fatal("failed to tamper with log list")
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey()=nil,%v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey()=nil,%v", err)
+	}
+	hash := sha256.Sum256(llData)
+	rsaSig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatalf("rsa.SignPKCS1v15()=nil,%v", err)
+	}
+	ecSig, err := ecdsa.SignASN1(rand.Reader, ecKey, hash[:])
+	if err != nil {
+		t.Fatalf("ecdsa.SignASN1()=nil,%v", err)
+	}
+
+	tests := []struct {
+		desc    string
+		data    []byte
+		sig     []byte
+		pubKey  crypto.PublicKey
+		wantErr string
+	}{
+		{desc: "rsa", data: llData, sig: rsaSig, pubKey: &rsaKey.PublicKey},
+		{desc: "ecdsa", data: llData, sig: ecSig, pubKey: &ecKey.PublicKey},
+		{desc: "rsa tampered", data: tampered, sig: rsaSig, pubKey: &rsaKey.PublicKey, wantErr: "failed to verify signature"},
+		{desc: "ecdsa tampered", data: tampered, sig: ecSig, pubKey: &ecKey.PublicKey, wantErr: "failed to verify signature"},
+		{desc: "wrong key", data: llData, sig: rsaSig, pubKey: &ecKey.PublicKey, wantErr: "failed to verify signature"},
+		{desc: "unsupported key", data: llData, sig: rsaSig, pubKey: ed25519.PublicKey{}, wantErr: "unsupported public key type"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			err := VerifySignature(test.data, test.sig, test.pubKey)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("VerifySignature()=%v; want nil", err)
+				}
+				ll, err := NewFromSignedJSON(test.data, test.sig, test.pubKey)
+				if err != nil {
+					t.Fatalf("NewFromSignedJSON()=nil,%v; want _,nil", err)
+				}
+				if got, want := len(ll.Operators), len(sampleLogList.Operators); got != want {
+					t.Errorf("NewFromSignedJSON() got %d operators; want %d", got, want)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("VerifySignature()=%v; want err containing %q", err, test.wantErr)
+			}
+			if _, err := NewFromSignedJSON(test.data, test.sig, test.pubKey); err == nil {
+				t.Error("NewFromSignedJSON()=_,nil; want error")
+			}
+		})
+	}
+}
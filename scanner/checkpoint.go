@@ -0,0 +1,99 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Checkpoint persists the progress of a scan, so that it can be resumed.
+// The index saved is that of the first entry not yet processed, i.e. all the
+// entries before it have been processed.
+type Checkpoint interface {
+	// Load returns the last saved index, or 0 if none was saved yet.
+	Load() (int64, error)
+	// Save stores the index.
+	Save(index int64) error
+}
+
+// FileCheckpoint is a Checkpoint storing the index in a file.
+type FileCheckpoint struct {
+	path string
+}
+
+// NewFileCheckpoint returns a Checkpoint backed by the file at path. The file
+// is created on the first Save.
+func NewFileCheckpoint(path string) *FileCheckpoint {
+	return &FileCheckpoint{path: path}
+}
+
+// Load reads the index from the file, returning 0 if the file doesn't exist.
+func (c *FileCheckpoint) Load() (int64, error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	index, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid checkpoint in %s: %v", c.path, err)
+	}
+	return index, nil
+}
+
+// Save writes the index to the file. The file is replaced atomically, so a
+// crash while saving leaves the previous index in place.
+func (c *FileCheckpoint) Save(index int64) error {
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(index, 10)+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// progress tracks which batches of a scan have been processed, and the index
+// below which all the entries have been processed.
+type progress struct {
+	mu   sync.Mutex
+	next int64           // all entries before next have been processed
+	done map[int64]int64 // start => end of processed batches after next
+}
+
+func newProgress(start int64) *progress {
+	return &progress{next: start, done: make(map[int64]int64)}
+}
+
+// batchDone records the entries in [start, end) as processed, and returns
+// the index below which all entries have been processed. Must be called with
+// p.mu held.
+func (p *progress) batchDone(start, end int64) int64 {
+	p.done[start] = end
+	for {
+		end, ok := p.done[p.next]
+		if !ok {
+			break
+		}
+		delete(p.done, p.next)
+		p.next = end
+	}
+	return p.next
+}
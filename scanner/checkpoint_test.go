@@ -0,0 +1,197 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/client"
+	"github.com/google/certificate-transparency-go/jsonclient"
+)
+
+// serveLog returns a test HTTP server for a Log holding the given entries.
+func serveLog(t testing.TB, entries []ct.LeafEntry) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ct/v1/get-sth":
+			fmt.Fprintf(w, `{"tree_size":%d,"timestamp":1396877652123,"sha256_root_hash":"0JBu0CkZnKXc1niEndDaqqgCRHucCfVt1/WBAXs/5T8=","tree_head_signature":"AAAACXNpZ25hdHVyZQ=="}`, len(entries))
+		case "/ct/v1/get-entries":
+			start, err := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+			if err != nil {
+				t.Errorf("Invalid start parameter: %v", err)
+			}
+			end, err := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+			if err != nil {
+				t.Errorf("Invalid end parameter: %v", err)
+			}
+			if err := json.NewEncoder(w).Encode(ct.GetEntriesResponse{Entries: entries[start : end+1]}); err != nil {
+				t.Errorf("Failed to write get-entries response: %v", err)
+			}
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+}
+
+// fourEntries returns the entries held in FourEntries.
+func fourEntries(t testing.TB) []ct.LeafEntry {
+	t.Helper()
+	var rsp ct.GetEntriesResponse
+	if err := json.Unmarshal([]byte(FourEntries), &rsp); err != nil {
+		t.Fatalf("Failed to parse FourEntries: %v", err)
+	}
+	return rsp.Entries
+}
+
+func TestFileCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	cp := NewFileCheckpoint(path)
+	if got, err := cp.Load(); err != nil || got != 0 {
+		t.Fatalf("Load()=%d, %v; want 0, nil", got, err)
+	}
+	for _, index := range []int64{10, 1000} {
+		if err := cp.Save(index); err != nil {
+			t.Fatalf("Save(%d)=%v", index, err)
+		}
+		if got, err := NewFileCheckpoint(path).Load(); err != nil || got != index {
+			t.Errorf("Load()=%d, %v; want %d, nil", got, err, index)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("garbage"), 0o644); err != nil {
+		t.Fatalf("WriteFile()=%v", err)
+	}
+	if got, err := cp.Load(); err == nil {
+		t.Errorf("Load()=%d, nil; want error", got)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	p := newProgress(10)
+	for _, tc := range []struct {
+		start, end int64
+		want       int64
+	}{
+		{start: 20, end: 30, want: 10},
+		{start: 30, end: 35, want: 10},
+		{start: 10, end: 20, want: 35},
+		{start: 40, end: 50, want: 35},
+		{start: 35, end: 35, want: 35},
+		{start: 35, end: 40, want: 50},
+	} {
+		if got := p.batchDone(tc.start, tc.end); got != tc.want {
+			t.Errorf("batchDone(%d, %d)=%d; want %d", tc.start, tc.end, got, tc.want)
+		}
+	}
+}
+
+// crashingCheckpoint drops all saves once crashed, like a process which died.
+type crashingCheckpoint struct {
+	Checkpoint
+	mu      sync.Mutex
+	crashed bool
+}
+
+func (c *crashingCheckpoint) Save(index int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.crashed {
+		return nil
+	}
+	return c.Checkpoint.Save(index)
+}
+
+func (c *crashingCheckpoint) crash() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.crashed = true
+}
+
+func TestScannerResumesFromCheckpoint(t *testing.T) {
+	ts := serveLog(t, fourEntries(t))
+	defer ts.Close()
+	logClient, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "checkpoint")
+
+	// scan runs a scan until the entry at crashAt is seen, if any, and returns
+	// the indices of all entries seen.
+	scan := func(cp Checkpoint, crashAt int64, crash func()) []int64 {
+		t.Helper()
+		opts := ScannerOptions{
+			FetcherOptions: FetcherOptions{BatchSize: 1, ParallelFetch: 1},
+			Matcher:        &MatchAll{},
+			NumWorkers:     1,
+			Checkpoint:     cp,
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var mu sync.Mutex
+		var seen []int64
+		found := func(e *ct.RawLogEntry) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen = append(seen, e.Index)
+			if e.Index == crashAt {
+				crash()
+				cancel()
+			}
+		}
+		// The crashed scan is cancelled.
+		if err := NewScanner(logClient, opts).Scan(ctx, found, found); err != nil && (crashAt < 0 || !errors.Is(err, context.Canceled)) {
+			t.Fatalf("Scan()=%v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sort.Slice(seen, func(i, j int) bool { return seen[i] < seen[j] })
+		return seen
+	}
+
+	cp := &crashingCheckpoint{Checkpoint: NewFileCheckpoint(path)}
+	if seen := scan(cp, 2, cp.crash); len(seen) == 0 || seen[0] != 0 {
+		t.Fatalf("First scan saw entries %v; want to start at 0", seen)
+	}
+	saved, err := NewFileCheckpoint(path).Load()
+	if err != nil {
+		t.Fatalf("Load()=%v", err)
+	}
+	if saved != 2 {
+		t.Fatalf("Saved checkpoint %d before the crash; want 2", saved)
+	}
+
+	// The restarted scan must pick up where the first one crashed.
+	seen := scan(NewFileCheckpoint(path), -1, func() {})
+	if want := []int64{2, 3}; fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Errorf("Resumed scan saw entries %v; want %v", seen, want)
+	}
+	if saved, err := NewFileCheckpoint(path).Load(); err != nil || saved != 4 {
+		t.Errorf("Load()=%d, %v after complete scan; want 4, nil", saved, err)
+	}
+}
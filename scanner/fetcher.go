@@ -23,7 +23,6 @@ import (
 	ct "github.com/google/certificate-transparency-go"
 	"github.com/google/certificate-transparency-go/jsonclient"
 	"github.com/google/trillian/client/backoff"
-	"k8s.io/klog/v2"
 )
 
 // LogClient implements the subset of CT log API that the Fetcher uses.
@@ -49,6 +48,10 @@ type FetcherOptions struct {
 	// Continuous determines whether Fetcher should run indefinitely after
 	// reaching EndIndex.
 	Continuous bool
+
+	// Logger receives the log messages of the Fetcher, and of the Scanner
+	// using it. Defaults to logging with klog.
+	Logger Logger
 }
 
 // DefaultFetcherOptions returns new FetcherOptions with sensible defaults.
@@ -70,6 +73,8 @@ type Fetcher struct {
 	client LogClient
 	// Configuration options for this Fetcher instance.
 	opts *FetcherOptions
+	// Destination of log messages.
+	log Logger
 
 	// Current STH of the Log this Fetcher sends queries to.
 	sth *ct.SignedTreeHead
@@ -97,10 +102,15 @@ type fetchRange struct {
 // taking configuration options from opts.
 func NewFetcher(client LogClient, opts *FetcherOptions) *Fetcher {
 	cancel := func() {} // Protect against calling Stop before Run.
+	var log Logger = klogLogger{}
+	if opts.Logger != nil {
+		log = opts.Logger
+	}
 	return &Fetcher{
 		uri:    client.BaseURI(),
 		client: client,
 		opts:   opts,
+		log:    log,
 		cancel: cancel,
 	}
 }
@@ -114,13 +124,13 @@ func (f *Fetcher) Prepare(ctx context.Context) (*ct.SignedTreeHead, error) {
 
 	sth, err := f.client.GetSTH(ctx)
 	if err != nil {
-		klog.Errorf("%s: GetSTH() failed: %v", f.uri, err)
+		f.log.Errorf("%s: GetSTH() failed: %v", f.uri, err)
 		return nil, err
 	}
-	klog.Infof("%s: Got STH with %d certs", f.uri, sth.TreeSize)
+	f.log.Infof(0, "%s: Got STH with %d certs", f.uri, sth.TreeSize)
 
 	if size := int64(sth.TreeSize); f.opts.EndIndex == 0 || f.opts.EndIndex > size {
-		klog.Infof("%s: Reset EndIndex from %d to %d", f.uri, f.opts.EndIndex, size)
+		f.log.Infof(0, "%s: Reset EndIndex from %d to %d", f.uri, f.opts.EndIndex, size)
 		f.opts.EndIndex = size
 	}
 	f.sth = sth
@@ -131,7 +141,7 @@ func (f *Fetcher) Prepare(ctx context.Context) (*ct.SignedTreeHead, error) {
 // passed in context is canceled, or Stop is called (and pending work is
 // finished). For each successfully fetched batch, runs the fn callback.
 func (f *Fetcher) Run(ctx context.Context, fn func(EntryBatch)) error {
-	klog.V(1).Infof("%s: Starting up Fetcher...", f.uri)
+	f.log.Infof(1, "%s: Starting up Fetcher...", f.uri)
 	if _, err := f.Prepare(ctx); err != nil {
 		return err
 	}
@@ -154,14 +164,14 @@ func (f *Fetcher) Run(ctx context.Context, fn func(EntryBatch)) error {
 		wg.Add(1)
 		go func(idx int) {
 			defer wg.Done()
-			klog.V(1).Infof("%s: Fetcher worker %d starting...", f.uri, idx)
+			f.log.Infof(1, "%s: Fetcher worker %d starting...", f.uri, idx)
 			f.runWorker(ctx, ranges, fn)
-			klog.V(1).Infof("%s: Fetcher worker %d finished", f.uri, idx)
+			f.log.Infof(1, "%s: Fetcher worker %d finished", f.uri, idx)
 		}(w)
 	}
 	wg.Wait()
 
-	klog.V(1).Infof("%s: Fetcher terminated", f.uri)
+	f.log.Infof(1, "%s: Fetcher terminated", f.uri)
 	return nil
 }
 
@@ -182,8 +192,8 @@ func (f *Fetcher) genRanges(ctx context.Context) <-chan fetchRange {
 	ranges := make(chan fetchRange)
 
 	go func() {
-		klog.V(1).Infof("%s: Range generator starting", f.uri)
-		defer klog.V(1).Infof("%s: Range generator finished", f.uri)
+		f.log.Infof(1, "%s: Range generator starting", f.uri)
+		defer f.log.Infof(1, "%s: Range generator finished", f.uri)
 		defer close(ranges)
 		start, end := f.opts.StartIndex, f.opts.EndIndex
 
@@ -192,7 +202,7 @@ func (f *Fetcher) genRanges(ctx context.Context) <-chan fetchRange {
 			// including, possibly, the very first iteration.
 			if start == end { // Implies f.opts.Continuous == true.
 				if err := f.updateSTH(ctx); err != nil {
-					klog.Warningf("%s: Failed to obtain bigger STH: %v", f.uri, err)
+					f.log.Warningf("%s: Failed to obtain bigger STH: %v", f.uri, err)
 					return
 				}
 				end = f.opts.EndIndex
@@ -202,7 +212,7 @@ func (f *Fetcher) genRanges(ctx context.Context) <-chan fetchRange {
 			next := fetchRange{start, batchEnd - 1}
 			select {
 			case <-ctx.Done():
-				klog.Warningf("%s: Cancelling genRanges: %v", f.uri, ctx.Err())
+				f.log.Warningf("%s: Cancelling genRanges: %v", f.uri, ctx.Err())
 				return
 			case ranges <- next:
 			}
@@ -240,7 +250,7 @@ func (f *Fetcher) updateSTH(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
-		klog.V(2).Infof("%s: Got STH with %d certs", f.uri, sth.TreeSize)
+		f.log.Infof(2, "%s: Got STH with %d certs", f.uri, sth.TreeSize)
 
 		quick := time.Now().Before(quickDeadline)
 		if sth.TreeSize <= lastSize || quick && sth.TreeSize < targetSize {
@@ -286,10 +296,13 @@ func (f *Fetcher) runWorker(ctx context.Context, ranges <-chan fetchRange, fn fu
 				resp, err = f.client.GetRawEntries(ctx, r.start, r.end)
 				return err
 			}); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
 				if rspErr, isRspErr := err.(jsonclient.RspError); isRspErr && rspErr.StatusCode == http.StatusTooManyRequests {
-					klog.V(2).Infof("%s: GetRawEntries() failed: %v", f.uri, err)
+					f.log.Infof(2, "%s: GetRawEntries() failed: %v", f.uri, err)
 				} else {
-					klog.Errorf("%s: GetRawEntries() failed: %v", f.uri, err)
+					f.log.Errorf("%s: GetRawEntries() failed: %v", f.uri, err)
 				}
 				// There is no error reporting yet for this worker, so just retry again.
 				continue
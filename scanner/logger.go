@@ -0,0 +1,53 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scanner
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+)
+
+// Logger receives the log messages of a Fetcher or Scanner, such as fetch
+// progress, errors and retries, so that callers can route them to their own
+// logging system. It must be safe for concurrent use.
+type Logger interface {
+	// Infof logs an informational message at the given verbosity level: 0
+	// for notable events such as a new STH, 1 for progress, and 2 for
+	// details such as retried requests.
+	Infof(level int, format string, args ...interface{})
+	// Warningf logs a recoverable problem.
+	Warningf(format string, args ...interface{})
+	// Errorf logs an error.
+	Errorf(format string, args ...interface{})
+}
+
+// klogLogger is the default Logger, which logs with klog, using the Infof
+// level as klog verbosity.
+type klogLogger struct{}
+
+func (klogLogger) Infof(level int, format string, args ...interface{}) {
+	if v := klog.V(klog.Level(level)); v.Enabled() {
+		v.InfoDepth(1, fmt.Sprintf(format, args...))
+	}
+}
+
+func (klogLogger) Warningf(format string, args ...interface{}) {
+	klog.WarningDepth(1, fmt.Sprintf(format, args...))
+}
+
+func (klogLogger) Errorf(format string, args ...interface{}) {
+	klog.ErrorDepth(1, fmt.Sprintf(format, args...))
+}
@@ -117,22 +117,70 @@ func (m MatchSubjectRegex) PrecertificateMatches(p *ct.Precertificate) bool {
 	return false
 }
 
-// MatchIssuerRegex matches on issuer CN (common name) by regex
+// MatchIssuerRegex matches on issuer CN (common name) by regex.  This is
+// useful for finding all [pre-]certificates issued by a given CA.
 type MatchIssuerRegex struct {
 	CertificateIssuerRegex    *regexp.Regexp
 	PrecertificateIssuerRegex *regexp.Regexp
 }
 
-// CertificateMatches returns true if the given cert's CN matches.
+// CertificateMatches returns true if the given cert's issuer CN matches.
 func (m MatchIssuerRegex) CertificateMatches(c *x509.Certificate) bool {
 	return m.CertificateIssuerRegex.FindStringIndex(c.Issuer.CommonName) != nil
 }
 
-// PrecertificateMatches returns true if the given precert's CN matches.
+// PrecertificateMatches returns true if the given precert's issuer CN matches.
 func (m MatchIssuerRegex) PrecertificateMatches(p *ct.Precertificate) bool {
 	return m.PrecertificateIssuerRegex.FindStringIndex(p.TBSCertificate.Issuer.CommonName) != nil
 }
 
+// MatchSubjectAltNameRegex is a Matcher which will use CertificateSANRegex and
+// PrecertificateSANRegex to determine whether Certificates and Precertificates
+// are interesting.  Unlike MatchSubjectRegex, the Subject CN is ignored and the
+// regexes are tested against every Subject Alternative Name: DNS names, email
+// addresses, IP addresses and URIs.  A nil regex matches nothing.
+type MatchSubjectAltNameRegex struct {
+	CertificateSANRegex    *regexp.Regexp
+	PrecertificateSANRegex *regexp.Regexp
+}
+
+// CertificateMatches returns true if any SAN of c matches m.CertificateSANRegex.
+func (m MatchSubjectAltNameRegex) CertificateMatches(c *x509.Certificate) bool {
+	return sanMatches(m.CertificateSANRegex, c)
+}
+
+// PrecertificateMatches returns true if any SAN of p matches m.PrecertificateSANRegex.
+func (m MatchSubjectAltNameRegex) PrecertificateMatches(p *ct.Precertificate) bool {
+	return sanMatches(m.PrecertificateSANRegex, p.TBSCertificate)
+}
+
+func sanMatches(re *regexp.Regexp, c *x509.Certificate) bool {
+	if re == nil || c == nil {
+		return false
+	}
+	for _, alt := range c.DNSNames {
+		if re.MatchString(alt) {
+			return true
+		}
+	}
+	for _, alt := range c.EmailAddresses {
+		if re.MatchString(alt) {
+			return true
+		}
+	}
+	for _, ip := range c.IPAddresses {
+		if re.MatchString(ip.String()) {
+			return true
+		}
+	}
+	for _, uri := range c.URIs {
+		if re.MatchString(uri.String()) {
+			return true
+		}
+	}
+	return false
+}
+
 // MatchSCTTimestamp is a matcher which matches leaf entries with the specified Timestamp.
 type MatchSCTTimestamp struct {
 	Timestamp uint64
@@ -244,8 +292,8 @@ func (m CertVerifyFailMatcher) Matches(leaf *ct.LeafEntry) bool {
 			log.Printf("Precert fails to parse as of %v: %v", opts.CurrentTime, err)
 			return true
 		}
-		// Ignore unhandled poison extension.
-		dropUnhandledExtension(precert, x509.OIDExtensionCTPoison)
+		// Allow the poison extension.
+		opts.AcceptPrecertificates = true
 
 		for i := 1; i < len(chain); i++ {
 			// PolicyConstraints is legal (and critical) but unparsed.
@@ -0,0 +1,108 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scanner
+
+import "sync"
+
+// reorderer delivers the results of entries processed out of order in the
+// order of their indices. It admits only a window of entries beyond the next
+// one to deliver, which bounds the number of results it buffers. Deliveries
+// are made one at a time by a goroutine of its own, without holding the lock,
+// so that a slow delivery doesn't hold up the processing of later entries.
+type reorderer struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	next     int64            // index of the next entry to deliver
+	window   int64            // number of entries admitted from next onwards
+	pending  map[int64]func() // index => delivery of processed entries
+	stopped  bool
+	finished bool          // set once no more entries will be done
+	idle     chan struct{} // closed once the delivery goroutine returns
+}
+
+func newReorderer(start, window int64) *reorderer {
+	if window < 1 {
+		window = 1
+	}
+	r := &reorderer{next: start, window: window, pending: make(map[int64]func()), idle: make(chan struct{})}
+	r.cond = sync.NewCond(&r.mu)
+	go r.deliver()
+	return r
+}
+
+// admit blocks until the entry at index fits in the window. Returns false if
+// the reorderer has been stopped.
+func (r *reorderer) admit(index int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for !r.stopped && index >= r.next+r.window {
+		r.cond.Wait()
+	}
+	return !r.stopped
+}
+
+// done records the entry at index as processed. The deliver function is
+// called once all the preceding entries have been delivered.
+func (r *reorderer) done(index int64, deliver func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending[index] = deliver
+	if index == r.next {
+		r.cond.Broadcast()
+	}
+}
+
+// deliver calls the delivery functions in order, until the reorderer is
+// stopped, or finished and out of deliverable entries.
+func (r *reorderer) deliver() {
+	defer close(r.idle)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for !r.stopped {
+		deliver, ok := r.pending[r.next]
+		if !ok {
+			if r.finished {
+				return
+			}
+			r.cond.Wait()
+			continue
+		}
+		delete(r.pending, r.next)
+		r.mu.Unlock()
+		deliver()
+		r.mu.Lock()
+		r.next++
+		r.cond.Broadcast()
+	}
+}
+
+// finish notes that no more entries will be done, and waits for the
+// deliveries of the entries done so far.
+func (r *reorderer) finish() {
+	r.mu.Lock()
+	r.finished = true
+	r.cond.Broadcast()
+	r.mu.Unlock()
+	<-r.idle
+}
+
+// stop releases all the callers blocked in admit, and stops the deliveries.
+// A delivery in progress is not interrupted.
+func (r *reorderer) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+	r.cond.Broadcast()
+}
@@ -0,0 +1,147 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ProgressReport describes the progress of a scan.
+type ProgressReport struct {
+	// Processed is the number of entries processed since the scan started.
+	Processed int64
+	// Index is the index the scan has reached, i.e. the scan's start index
+	// plus Processed. Entries are processed concurrently, so some entries
+	// before Index may still be in flight.
+	Index int64
+	// Rate is the number of entries processed per second since the
+	// previous report.
+	Rate float64
+}
+
+// ProgressReporter periodically reports the progress of a scan. A report is
+// made every Every entries, and every Interval, even if the scan is stalled.
+// Either trigger is disabled if not positive.
+type ProgressReporter struct {
+	Every    int64
+	Interval time.Duration
+	// Report is called with each report. Calls are serialized.
+	Report func(ProgressReport)
+
+	// now and newTicker are the clock of the reporter, replaced in tests.
+	now       func() time.Time
+	newTicker func(d time.Duration) (<-chan time.Time, func())
+
+	mu        sync.Mutex
+	start     int64     // start index of the scan
+	lastTime  time.Time // time of the previous report
+	lastCount int64     // entries processed at the previous report
+}
+
+// NewWriterProgressReporter returns a ProgressReporter which writes a line to
+// w every interval.
+func NewWriterProgressReporter(w io.Writer, interval time.Duration) *ProgressReporter {
+	return &ProgressReporter{
+		Interval: interval,
+		Report: func(p ProgressReport) {
+			fmt.Fprintf(w, "Processed %d entries (to index %d), %.2f entries/sec\n", p.Processed, p.Index, p.Rate)
+		},
+	}
+}
+
+// NewStderrProgressReporter returns a ProgressReporter which writes a line to
+// stderr every interval.
+func NewStderrProgressReporter(interval time.Duration) *ProgressReporter {
+	return NewWriterProgressReporter(os.Stderr, interval)
+}
+
+func (r *ProgressReporter) timeNow() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+func (r *ProgressReporter) ticker(d time.Duration) (<-chan time.Time, func()) {
+	if r.newTicker != nil {
+		return r.newTicker(d)
+	}
+	t := time.NewTicker(d)
+	return t.C, t.Stop
+}
+
+// begin prepares the reporter for a scan starting at the given index, and
+// starts making a report every Interval of the number of entries processed
+// returned by count. Returns a function stopping the reports by time.
+func (r *ProgressReporter) begin(start int64, count func() int64) func() {
+	r.mu.Lock()
+	r.start = start
+	r.lastTime = r.timeNow()
+	r.lastCount = 0
+	r.mu.Unlock()
+	if r.Interval <= 0 {
+		return func() {}
+	}
+
+	ticks, stopTicker := r.ticker(r.Interval)
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticks:
+				r.report(count(), true)
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+		stopTicker()
+	}
+}
+
+// processed notes that count entries have been processed so far, and makes a
+// report if one is due by count. It is cheap when no report is due.
+func (r *ProgressReporter) processed(count int64) {
+	if r.Every > 0 && count%r.Every == 0 {
+		r.report(count, false)
+	}
+}
+
+// report makes a report of count entries processed. Unless byTime is set, the
+// report is skipped if a concurrent one already reported this far.
+func (r *ProgressReporter) report(count int64, byTime bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !byTime && count <= r.lastCount {
+		return
+	}
+	now := r.timeNow()
+	rate := 0.0
+	if elapsed := now.Sub(r.lastTime).Seconds(); elapsed > 0 {
+		rate = float64(count-r.lastCount) / elapsed
+	}
+	r.lastTime, r.lastCount = now, count
+	if r.Report != nil {
+		r.Report(ProgressReport{Processed: count, Index: r.start + count, Rate: rate})
+	}
+}
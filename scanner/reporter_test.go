@@ -0,0 +1,200 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/client"
+	"github.com/google/certificate-transparency-go/jsonclient"
+)
+
+func TestProgressReporter(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	// step is either an entry being processed, or the ticker firing.
+	type step struct {
+		at        time.Duration // time elapsed since the start
+		processed int64         // entries processed so far
+		tick      bool
+	}
+	entries := func(at ...time.Duration) []step {
+		steps := make([]step, len(at))
+		for i, d := range at {
+			steps[i] = step{at: d, processed: int64(i + 1)}
+		}
+		return steps
+	}
+	for _, tc := range []struct {
+		desc     string
+		every    int64
+		interval time.Duration
+		steps    []step
+		want     []ProgressReport
+	}{
+		{
+			desc:  "every",
+			every: 2,
+			steps: entries(1*time.Second, 2*time.Second, 3*time.Second, 4*time.Second, 5*time.Second),
+			want: []ProgressReport{
+				{Processed: 2, Index: 102, Rate: 1},
+				{Processed: 4, Index: 104, Rate: 1},
+			},
+		},
+		{
+			desc:     "interval",
+			interval: 10 * time.Second,
+			steps: []step{
+				{at: 4 * time.Second, processed: 1},
+				{at: 8 * time.Second, processed: 2},
+				{at: 9 * time.Second, processed: 3},
+				{at: 10 * time.Second, processed: 3, tick: true},
+				{at: 12 * time.Second, processed: 4},
+				{at: 15 * time.Second, processed: 5},
+				{at: 19 * time.Second, processed: 6},
+				{at: 20 * time.Second, processed: 6, tick: true},
+			},
+			want: []ProgressReport{
+				{Processed: 3, Index: 103, Rate: 0.3},
+				{Processed: 6, Index: 106, Rate: 0.3},
+			},
+		},
+		{
+			desc:     "stalled",
+			interval: 10 * time.Second,
+			steps: []step{
+				{at: 4 * time.Second, processed: 1},
+				{at: 10 * time.Second, processed: 1, tick: true},
+				{at: 20 * time.Second, processed: 1, tick: true},
+				{at: 30 * time.Second, processed: 1, tick: true},
+			},
+			want: []ProgressReport{
+				{Processed: 1, Index: 101, Rate: 0.1},
+				{Processed: 1, Index: 101, Rate: 0},
+				{Processed: 1, Index: 101, Rate: 0},
+			},
+		},
+		{
+			desc:     "both",
+			every:    4,
+			interval: 3 * time.Second,
+			steps: []step{
+				{at: 1 * time.Second, processed: 1},
+				{at: 2 * time.Second, processed: 2},
+				{at: 2 * time.Second, processed: 3},
+				{at: 2 * time.Second, processed: 4},
+				{at: 3 * time.Second, processed: 4, tick: true},
+				{at: 4 * time.Second, processed: 5},
+				{at: 5 * time.Second, processed: 6},
+				{at: 6 * time.Second, processed: 6, tick: true},
+			},
+			want: []ProgressReport{
+				{Processed: 4, Index: 104, Rate: 2},
+				{Processed: 4, Index: 104, Rate: 0},
+				{Processed: 6, Index: 106, Rate: 2.0 / 3},
+			},
+		},
+		{
+			desc:  "disabled",
+			steps: entries(1*time.Second, 2*time.Second, 3*time.Second),
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			// now and processed are only read by the reporter after a tick is
+			// sent, or from this goroutine.
+			now := start
+			var processed int64
+			var got []ProgressReport
+			reported := make(chan struct{}, len(tc.steps))
+			ticks := make(chan time.Time)
+			tickerStopped := false
+			r := &ProgressReporter{
+				Every:    tc.every,
+				Interval: tc.interval,
+				Report: func(p ProgressReport) {
+					got = append(got, p)
+					reported <- struct{}{}
+				},
+				now: func() time.Time { return now },
+				newTicker: func(d time.Duration) (<-chan time.Time, func()) {
+					if d != tc.interval {
+						t.Errorf("Ticker started with interval %v; want %v", d, tc.interval)
+					}
+					return ticks, func() { tickerStopped = true }
+				},
+			}
+			stop := r.begin(100, func() int64 { return processed })
+			for _, s := range tc.steps {
+				now, processed = start.Add(s.at), s.processed
+				if !s.tick {
+					r.processed(s.processed)
+					continue
+				}
+				ticks <- now
+				select {
+				case <-reported:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("No report after a tick at %v", s.at)
+				}
+			}
+			stop()
+			if tc.interval > 0 && !tickerStopped {
+				t.Error("Ticker not stopped")
+			}
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("Got reports %+v; want %+v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("Report %d: got %+v; want %+v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScannerProgress(t *testing.T) {
+	ts := serveLog(t, manyEntries(t, 10))
+	defer ts.Close()
+	logClient, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	reporter := NewWriterProgressReporter(&buf, 0)
+	reporter.Every = 5
+	opts := ScannerOptions{
+		FetcherOptions: FetcherOptions{BatchSize: 3, ParallelFetch: 2, StartIndex: 2},
+		Matcher:        &MatchAll{},
+		NumWorkers:     2,
+		Progress:       reporter,
+	}
+	found := func(*ct.RawLogEntry) {}
+	if err := NewScanner(logClient, opts).Scan(context.Background(), found, found); err != nil {
+		t.Fatalf("Scan()=%v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "Processed 5 entries (to index 7)") {
+		t.Errorf("Got progress output %q; want a single report of 5 entries", buf.String())
+	}
+}
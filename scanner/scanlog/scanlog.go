@@ -40,10 +40,12 @@ const (
 )
 
 var (
-	logURI = flag.String("log_uri", "https://ct.googleapis.com/aviator", "CT log base URI")
+	logURI    = flag.String("log_uri", "https://ct.googleapis.com/aviator", "CT log base URI")
+	userAgent = flag.String("user_agent", "ct-go-scanlog/1.0", "User-Agent header to send with requests to the log")
 
 	matchSubjectRegex = flag.String("match_subject_regex", ".*", "Regex to match CN/SAN")
 	matchIssuerRegex  = flag.String("match_issuer_regex", "", "Regex to match in issuer CN")
+	matchSANRegex     = flag.String("match_san_regex", "", "Regex to match in any SAN (DNS name, email, IP address or URI)")
 	precertsOnly      = flag.Bool("precerts_only", false, "Only match precerts")
 	serialNumber      = flag.String("serial_number", "", "Serial number of certificate of interest")
 	sctTimestamp      = flag.Uint64("sct_timestamp_ms", 0, "Timestamp of logged SCT")
@@ -163,6 +165,12 @@ func createMatcherFromFlags(logClient *client.LogClient) (interface{}, error) {
 			CertificateIssuerRegex:    certRegex,
 			PrecertificateIssuerRegex: precertRegex}, nil
 	}
+	if *matchSANRegex != "" {
+		certRegex, precertRegex := createRegexes(*matchSANRegex)
+		return scanner.MatchSubjectAltNameRegex{
+			CertificateSANRegex:    certRegex,
+			PrecertificateSANRegex: precertRegex}, nil
+	}
 	if *serialNumber != "" {
 		log.Printf("Using SerialNumber matcher on %s", *serialNumber)
 		var sn big.Int
@@ -196,7 +204,7 @@ func main() {
 			IdleConnTimeout:       90 * time.Second,
 			ExpectContinueTimeout: 1 * time.Second,
 		},
-	}, jsonclient.Options{UserAgent: "ct-go-scanlog/1.0"})
+	}, jsonclient.Options{UserAgent: *userAgent})
 	if err != nil {
 		log.Fatal(err)
 	}
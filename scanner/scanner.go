@@ -17,6 +17,7 @@ package scanner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -24,7 +25,6 @@ import (
 
 	ct "github.com/google/certificate-transparency-go"
 	"github.com/google/certificate-transparency-go/x509"
-	"k8s.io/klog/v2"
 )
 
 // ScannerOptions holds configuration options for the Scanner.
@@ -39,11 +39,52 @@ type ScannerOptions struct { // nolint:revive
 	// Match precerts only (Matcher still applies to precerts).
 	PrecertOnly bool
 
-	// Number of concurrent matchers to run.
+	// Match final certs only (Matcher still applies to certs). Mutually
+	// exclusive with PrecertOnly.
+	//
+	// Entries of the type excluded by PrecertOnly or CertOnly are skipped
+	// before their certificate is parsed.
+	CertOnly bool
+
+	// Number of concurrent matchers to run. The entries are fetched by
+	// ParallelFetch concurrent workers, independently of the matchers.
 	NumWorkers int
 
-	// Number of fetched entries to buffer on their way to the callbacks.
+	// FetchWorkers and ProcessWorkers, if positive, override ParallelFetch
+	// and NumWorkers respectively: they are the sizes of the pools of workers
+	// fetching entries and matching them, which run independently of each
+	// other, linked by a buffer of BufferSize entries.
+	FetchWorkers   int
+	ProcessWorkers int
+
+	// Number of fetched entries to buffer on their way to the matchers. When
+	// the buffer is full, fetching pauses until the matchers catch up.
 	BufferSize int
+
+	// Ordered makes the callbacks be called one at a time, in the order of
+	// the entries' indices, from a single goroutine. The entries are still
+	// fetched and matched concurrently, with up to BufferSize+NumWorkers
+	// entries in flight, and a slow callback doesn't hold up the matchers
+	// until they fill the window. Otherwise the callbacks are called
+	// concurrently, in any order.
+	Ordered bool
+
+	// Checkpoint, if set, persists the scan progress. A scan resumes from
+	// the saved index if it is beyond StartIndex.
+	Checkpoint Checkpoint
+
+	// Minimum interval between two saves of the Checkpoint. If zero, the
+	// progress is saved whenever it advances.
+	CheckpointInterval time.Duration
+
+	// OnError, if set, is called for each entry which fails to be processed,
+	// with the entry's index, raw leaf input and the error. Returning true
+	// skips the entry and continues the scan, false aborts the scan.
+	// If not set, failing entries are skipped.
+	OnError func(index int64, raw []byte, err error) bool
+
+	// Progress, if set, receives periodic reports of the scan progress.
+	Progress *ProgressReporter
 }
 
 // DefaultScannerOptions returns a new ScannerOptions with sensible defaults.
@@ -71,10 +112,21 @@ type Scanner struct {
 	unparsableEntries         int64
 	entriesWithNonFatalErrors int64
 
+	// Set to non-zero when the scan is aborted by OnError.
+	aborted int32
+
 	fetcher *Fetcher
+	// Destination of log messages, shared with the fetcher.
+	log Logger
 
 	// Configuration options for this Scanner instance.
 	opts ScannerOptions
+
+	// Progress of the current scan, and time it was last saved. Used only
+	// if a Checkpoint is configured.
+	progress  *progress
+	lastSaved time.Time
+	saved     int64
 }
 
 // entryInfo represents information about a log entry.
@@ -83,6 +135,14 @@ type entryInfo struct {
 	index int64
 	// The log entry returned by the log server.
 	entry ct.LeafEntry
+	// The batch the entry belongs to, if progress is tracked.
+	batch *batchInfo
+}
+
+// batchInfo tracks the processing of a batch of entries.
+type batchInfo struct {
+	start, end int64 // [start, end) range of the batch
+	pending    int64 // number of entries not processed yet
 }
 
 // Takes the error returned by either x509.ParseCertificate() or
@@ -99,15 +159,25 @@ func (s *Scanner) isCertErrorFatal(err error, logEntry *ct.LogEntry, index int64
 	} else if !x509.IsFatal(err) {
 		atomic.AddInt64(&s.entriesWithNonFatalErrors, 1)
 		// We'll make a note, but continue.
-		klog.V(1).Infof("Non-fatal error in %v at index %d: %v", logEntry.Leaf.TimestampedEntry.EntryType, index, err)
+		s.log.Infof(1, "Non-fatal error in %v at index %d: %v", logEntry.Leaf.TimestampedEntry.EntryType, index, err)
 		return false
 	}
 	return true
 }
 
+// skipEntryType returns whether entries of the given type are excluded by
+// PrecertOnly or CertOnly.
+func (s *Scanner) skipEntryType(eType ct.LogEntryType) bool {
+	return (s.opts.PrecertOnly && eType != ct.PrecertLogEntryType) ||
+		(s.opts.CertOnly && eType != ct.X509LogEntryType)
+}
+
 // Processes the given entry in the specified log.
 func (s *Scanner) processEntry(info entryInfo, foundCert func(*ct.RawLogEntry), foundPrecert func(*ct.RawLogEntry)) error {
-	atomic.AddInt64(&s.certsProcessed, 1)
+	count := atomic.AddInt64(&s.certsProcessed, 1)
+	if s.opts.Progress != nil {
+		s.opts.Progress.processed(count)
+	}
 
 	switch matcher := s.opts.Matcher.(type) {
 	case Matcher:
@@ -124,6 +194,10 @@ func (s *Scanner) processMatcherEntry(matcher Matcher, info entryInfo, foundCert
 	if err != nil {
 		return fmt.Errorf("failed to build raw log entry %d: %v", info.index, err)
 	}
+	if s.skipEntryType(rawLogEntry.Leaf.TimestampedEntry.EntryType) {
+		// Not interested in this type of entry, early-out before parsing.
+		return nil
+	}
 	// Matcher instances need the parsed [pre-]certificate.
 	logEntry, err := rawLogEntry.ToLogEntry()
 	if s.isCertErrorFatal(err, logEntry, info.index) {
@@ -132,10 +206,6 @@ func (s *Scanner) processMatcherEntry(matcher Matcher, info entryInfo, foundCert
 
 	switch {
 	case logEntry.X509Cert != nil:
-		if s.opts.PrecertOnly {
-			// Only interested in precerts and this is an X.509 cert, early-out.
-			return nil
-		}
 		if matcher.CertificateMatches(logEntry.X509Cert) {
 			atomic.AddInt64(&s.certsMatched, 1)
 			foundCert(rawLogEntry)
@@ -161,12 +231,13 @@ func (s *Scanner) processMatcherLeafEntry(matcher LeafMatcher, info entryInfo, f
 	if rawLogEntry == nil {
 		return fmt.Errorf("failed to build raw log entry %d: %v", info.index, err)
 	}
-	switch eType := rawLogEntry.Leaf.TimestampedEntry.EntryType; eType {
+	eType := rawLogEntry.Leaf.TimestampedEntry.EntryType
+	if s.skipEntryType(eType) {
+		// Not interested in this type of entry, early-out.
+		return nil
+	}
+	switch eType {
 	case ct.X509LogEntryType:
-		if s.opts.PrecertOnly {
-			// Only interested in precerts and this is an X.509 cert, early-out.
-			return nil
-		}
 		foundCert(rawLogEntry)
 	case ct.PrecertLogEntryType:
 		foundPrecert(rawLogEntry)
@@ -180,15 +251,90 @@ func (s *Scanner) processMatcherLeafEntry(matcher LeafMatcher, info entryInfo, f
 // Worker function to match certs.
 // Accepts MatcherJobs over the entries channel, and processes them.
 // Returns true over the done channel when the entries channel is closed.
-func (s *Scanner) matcherJob(entries <-chan entryInfo, foundCert func(*ct.RawLogEntry), foundPrecert func(*ct.RawLogEntry)) {
+// Calls abort if OnError requests to stop the scan. If order is set, the
+// callbacks are deferred until the preceding entries have been delivered.
+// Once ctx is done, the remaining entries are drained without processing.
+func (s *Scanner) matcherJob(ctx context.Context, entries <-chan entryInfo, foundCert func(*ct.RawLogEntry), foundPrecert func(*ct.RawLogEntry), abort func(error), order *reorderer) {
 	for e := range entries {
-		if err := s.processEntry(e, foundCert, foundPrecert); err != nil {
-			atomic.AddInt64(&s.unparsableEntries, 1)
-			klog.Errorf("Failed to parse entry at index %d: %s", e.index, err.Error())
+		if atomic.LoadInt32(&s.aborted) != 0 || ctx.Err() != nil {
+			continue // Drain the remaining entries.
 		}
+		if order == nil {
+			err := s.processEntry(e, foundCert, foundPrecert)
+			s.entryDone(e, err, abort)
+			continue
+		}
+
+		var found []func()
+		deferCall := func(fn func(*ct.RawLogEntry)) func(*ct.RawLogEntry) {
+			return func(rle *ct.RawLogEntry) { found = append(found, func() { fn(rle) }) }
+		}
+		err := s.processEntry(e, deferCall(foundCert), deferCall(foundPrecert))
+		e := e
+		order.done(e.index, func() {
+			if atomic.LoadInt32(&s.aborted) != 0 {
+				return
+			}
+			for _, fn := range found {
+				fn()
+			}
+			s.entryDone(e, err, abort)
+		})
+	}
+}
+
+// entryDone handles the outcome of processing an entry, and records its batch
+// as processed once all the batch entries are.
+func (s *Scanner) entryDone(e entryInfo, err error, abort func(error)) {
+	if err != nil {
+		atomic.AddInt64(&s.unparsableEntries, 1)
+		s.log.Errorf("Failed to parse entry at index %d: %s", e.index, err.Error())
+		if s.opts.OnError != nil && !s.opts.OnError(e.index, e.entry.LeafInput, err) {
+			abort(fmt.Errorf("scan aborted at entry %d: %v", e.index, err))
+			return
+		}
+	}
+	if e.batch != nil && atomic.AddInt64(&e.batch.pending, -1) == 0 {
+		s.batchDone(e.batch)
+	}
+}
+
+// batchDone records a batch as processed, and saves the progress if it is due.
+func (s *Scanner) batchDone(b *batchInfo) {
+	s.progress.mu.Lock()
+	defer s.progress.mu.Unlock()
+	next := s.progress.batchDone(b.start, b.end)
+	if next > s.saved && time.Since(s.lastSaved) >= s.opts.CheckpointInterval {
+		s.saveProgress(next)
 	}
 }
 
+// saveProgress saves the index of the first unprocessed entry to the
+// Checkpoint. Must be called with s.progress.mu held.
+func (s *Scanner) saveProgress(next int64) {
+	if err := s.opts.Checkpoint.Save(next); err != nil {
+		s.log.Warningf("Failed to save scan checkpoint %d: %v", next, err)
+		return
+	}
+	s.saved, s.lastSaved = next, time.Now()
+}
+
+// resume moves the start of the scan to the saved checkpoint, if it is
+// beyond the configured StartIndex.
+func (s *Scanner) resume() error {
+	index, err := s.opts.Checkpoint.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load scan checkpoint: %v", err)
+	}
+	if index > s.opts.StartIndex {
+		s.log.Infof(0, "Resuming scan from checkpoint at index %d", index)
+		s.opts.StartIndex = index
+	}
+	s.progress = newProgress(s.opts.StartIndex)
+	s.saved, s.lastSaved = s.opts.StartIndex, time.Time{}
+	return nil
+}
+
 // Pretty prints the passed in duration into a human readable string.
 func humanTime(dur time.Duration) string {
 	hours := int(dur / time.Hour)
@@ -237,7 +383,7 @@ func (s *Scanner) logThroughput(treeSize int64, stop <-chan bool) {
 			remainingCerts := treeSize - int64(s.opts.StartIndex) - certsCnt
 			remainingSeconds := int(float64(remainingCerts) / throughput)
 			remainingString := humanTime(time.Duration(remainingSeconds) * time.Second)
-			klog.V(1).Infof("Processed: %d certs (to index %d), matched %d (%2.2f%%). Throughput (last %ds): %3.2f ETA: %s\n",
+			s.log.Infof(1, "Processed: %d certs (to index %d), matched %d (%2.2f%%). Throughput (last %ds): %3.2f ETA: %s\n",
 				certsCnt, s.opts.StartIndex+certsCnt, certsMatched,
 				(100.0*float64(certsMatched))/float64(certsCnt),
 				filled, throughput, remainingString)
@@ -251,24 +397,58 @@ func (s *Scanner) logThroughput(treeSize int64, stop <-chan bool) {
 // LogEntry, which includes the index of the entry and the certificate.
 // For each precert found, calls foundPrecert with the corresponding LogEntry,
 // which includes the index of the entry and the precert.
+//
+// The callbacks are called as each entry is matched, rather than once the
+// scan is complete, so matches are never accumulated: at most BufferSize +
+// NumWorkers entries are held between fetching and delivery, plus the
+// batches being fetched. A slow callback slows down the scan instead.
 func (s *Scanner) Scan(ctx context.Context, foundCert func(*ct.RawLogEntry), foundPrecert func(*ct.RawLogEntry)) error {
 	_, err := s.ScanLog(ctx, foundCert, foundPrecert)
 	return err
 }
 
 // ScanLog performs a scan against the Log, returning the count of scanned entries.
+// If ctx is cancelled, the in-flight requests and processing stop and ScanLog
+// returns the context's error.
 func (s *Scanner) ScanLog(ctx context.Context, foundCert func(*ct.RawLogEntry), foundPrecert func(*ct.RawLogEntry)) (int64, error) {
-	klog.V(1).Infof("Starting up Scanner...")
+	s.log.Infof(1, "Starting up Scanner...")
+	if s.opts.PrecertOnly && s.opts.CertOnly {
+		return -1, errors.New("PrecertOnly and CertOnly are mutually exclusive")
+	}
 	s.certsProcessed = 0
 	s.certsMatched = 0
 	s.precertsSeen = 0
 	s.unparsableEntries = 0
 	s.entriesWithNonFatalErrors = 0
 
+	if s.opts.Checkpoint != nil {
+		if err := s.resume(); err != nil {
+			return -1, err
+		}
+	}
+
 	sth, err := s.fetcher.Prepare(ctx)
 	if err != nil {
 		return -1, err
 	}
+	if s.opts.Progress != nil {
+		stop := s.opts.Progress.begin(s.opts.StartIndex, func() int64 { return atomic.LoadInt64(&s.certsProcessed) })
+		defer stop()
+	}
+
+	// Aborting the scan cancels the fetcher through cctx.
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	atomic.StoreInt32(&s.aborted, 0)
+	var abortOnce sync.Once
+	var abortErr error
+	abort := func(err error) {
+		abortOnce.Do(func() {
+			abortErr = err
+			atomic.StoreInt32(&s.aborted, 1)
+			cancel()
+		})
+	}
 
 	startTime := time.Now()
 	stop := make(chan bool)
@@ -278,6 +458,15 @@ func (s *Scanner) ScanLog(ctx context.Context, foundCert func(*ct.RawLogEntry),
 		close(stop)
 	}()
 
+	var order *reorderer
+	if s.opts.Ordered {
+		order = newReorderer(s.opts.StartIndex, int64(s.opts.BufferSize+s.opts.NumWorkers))
+		go func() {
+			<-cctx.Done()
+			order.stop()
+		}()
+	}
+
 	// Start matcher workers.
 	var wg sync.WaitGroup
 	entries := make(chan entryInfo, s.opts.BufferSize)
@@ -285,28 +474,62 @@ func (s *Scanner) ScanLog(ctx context.Context, foundCert func(*ct.RawLogEntry),
 		wg.Add(1)
 		go func(idx int) {
 			defer wg.Done()
-			klog.V(1).Infof("Matcher %d starting", idx)
-			s.matcherJob(entries, foundCert, foundPrecert)
-			klog.V(1).Infof("Matcher %d finished", idx)
+			s.log.Infof(1, "Matcher %d starting", idx)
+			s.matcherJob(cctx, entries, foundCert, foundPrecert, abort, order)
+			s.log.Infof(1, "Matcher %d finished", idx)
 		}(w)
 	}
 
 	flatten := func(b EntryBatch) {
+		var batch *batchInfo
+		if s.progress != nil {
+			end := b.Start + int64(len(b.Entries))
+			if len(b.Entries) == 0 {
+				s.batchDone(&batchInfo{start: b.Start, end: end})
+				return
+			}
+			batch = &batchInfo{start: b.Start, end: end, pending: int64(len(b.Entries))}
+		}
 		for i, e := range b.Entries {
-			entries <- entryInfo{index: b.Start + int64(i), entry: e}
+			index := b.Start + int64(i)
+			if order != nil && !order.admit(index) {
+				return
+			}
+			select {
+			case entries <- entryInfo{index: index, entry: e, batch: batch}:
+			case <-cctx.Done(): // Avoid blocking on busy matchers when stopping.
+				return
+			}
 		}
 	}
-	err = s.fetcher.Run(ctx, flatten)
+	err = s.fetcher.Run(cctx, flatten)
 	close(entries) // Causes matcher workers to terminate.
 	wg.Wait()      // Wait until they terminate.
+	if order != nil {
+		order.finish() // Wait for the callbacks of the matched entries.
+	}
 	if err != nil {
 		return -1, err
 	}
+	if abortErr != nil {
+		return -1, abortErr
+	}
+	// The Fetcher returns nil when stopped by the cancellation of ctx.
+	if err := ctx.Err(); err != nil {
+		return -1, err
+	}
+	if s.progress != nil {
+		s.progress.mu.Lock()
+		if s.progress.next > s.saved {
+			s.saveProgress(s.progress.next)
+		}
+		s.progress.mu.Unlock()
+	}
 
-	klog.V(1).Infof("Completed %d certs in %s", atomic.LoadInt64(&s.certsProcessed), humanTime(time.Since(startTime)))
-	klog.V(1).Infof("Saw %d precerts", atomic.LoadInt64(&s.precertsSeen))
-	klog.V(1).Infof("Saw %d unparsable entries", atomic.LoadInt64(&s.unparsableEntries))
-	klog.V(1).Infof("Saw %d non-fatal errors", atomic.LoadInt64(&s.entriesWithNonFatalErrors))
+	s.log.Infof(1, "Completed %d certs in %s", atomic.LoadInt64(&s.certsProcessed), humanTime(time.Since(startTime)))
+	s.log.Infof(1, "Saw %d precerts", atomic.LoadInt64(&s.precertsSeen))
+	s.log.Infof(1, "Saw %d unparsable entries", atomic.LoadInt64(&s.unparsableEntries))
+	s.log.Infof(1, "Saw %d non-fatal errors", atomic.LoadInt64(&s.entriesWithNonFatalErrors))
 
 	return int64(s.fetcher.opts.EndIndex), nil
 }
@@ -316,7 +539,14 @@ func (s *Scanner) ScanLog(ctx context.Context, foundCert func(*ct.RawLogEntry),
 func NewScanner(client LogClient, opts ScannerOptions) *Scanner {
 	var scanner Scanner
 	scanner.opts = opts
+	if opts.FetchWorkers > 0 {
+		scanner.opts.ParallelFetch = opts.FetchWorkers
+	}
+	if opts.ProcessWorkers > 0 {
+		scanner.opts.NumWorkers = opts.ProcessWorkers
+	}
 	scanner.fetcher = NewFetcher(client, &scanner.opts.FetcherOptions)
+	scanner.log = scanner.fetcher.log
 
 	// Set a default match-everything regex if none was provided.
 	if opts.Matcher == nil {
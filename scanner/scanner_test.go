@@ -17,16 +17,31 @@ package scanner
 import (
 	"container/list"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	ct "github.com/google/certificate-transparency-go"
 	"github.com/google/certificate-transparency-go/client"
 	"github.com/google/certificate-transparency-go/jsonclient"
+	"github.com/google/certificate-transparency-go/testdata"
+	"github.com/google/certificate-transparency-go/tls"
 	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/certificate-transparency-go/x509/pkix"
+	"github.com/google/certificate-transparency-go/x509util"
 )
 
 func TestScannerMatchAll(t *testing.T) {
@@ -160,6 +175,96 @@ func TestScannerMatchSubjectRegexMatchesPrecertificateSAN(t *testing.T) {
 	}
 }
 
+func TestScannerMatchIssuerRegex(t *testing.T) {
+	certs := map[string]*x509.Certificate{
+		"example": {Issuer: pkix.Name{CommonName: "Example Issuing CA 1"}},
+		"other":   {Issuer: pkix.Name{CommonName: "Other Root CA"}},
+		"no-cn":   {Issuer: pkix.Name{Organization: []string{"Example"}}},
+	}
+	for _, test := range []struct {
+		regex string
+		want  []string
+	}{
+		{regex: `^Example Issuing CA \d+$`, want: []string{"example"}},
+		{regex: `CA`, want: []string{"example", "other"}},
+		{regex: `^Let's Encrypt`},
+	} {
+		t.Run(test.regex, func(t *testing.T) {
+			re := regexp.MustCompile(test.regex)
+			m := MatchIssuerRegex{CertificateIssuerRegex: re, PrecertificateIssuerRegex: re}
+			for name, cert := range certs {
+				want := containsString(test.want, name)
+				if got := m.CertificateMatches(cert); got != want {
+					t.Errorf("CertificateMatches(%s)=%v, want %v", name, got, want)
+				}
+				if got := m.PrecertificateMatches(&ct.Precertificate{TBSCertificate: cert}); got != want {
+					t.Errorf("PrecertificateMatches(%s)=%v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestScannerMatchSubjectAltNameRegex(t *testing.T) {
+	certs := map[string]*x509.Certificate{
+		"dns": {
+			Subject:  pkix.Name{CommonName: "www.example.org"},
+			DNSNames: []string{"www.example.com", "mail.example.com"},
+		},
+		"email": {EmailAddresses: []string{"admin@example.net"}},
+		"ip":    {IPAddresses: []net.IP{net.ParseIP("192.0.2.1")}},
+		"uri":   {URIs: []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/service"}}},
+		"cn-only": {
+			Subject: pkix.Name{CommonName: "www.example.com"},
+		},
+	}
+	for _, test := range []struct {
+		regex string
+		want  []string
+	}{
+		{regex: `^mail\.example\.com$`, want: []string{"dns"}},
+		{regex: `example\.(com|net)`, want: []string{"dns", "email", "uri"}},
+		{regex: `^192\.0\.2\.`, want: []string{"ip"}},
+		{regex: `^spiffe://`, want: []string{"uri"}},
+		// The Subject CN is not a SAN, so must not be matched.
+		{regex: `example\.org`},
+	} {
+		t.Run(test.regex, func(t *testing.T) {
+			re := regexp.MustCompile(test.regex)
+			m := MatchSubjectAltNameRegex{CertificateSANRegex: re, PrecertificateSANRegex: re}
+			for name, cert := range certs {
+				want := containsString(test.want, name)
+				if got := m.CertificateMatches(cert); got != want {
+					t.Errorf("CertificateMatches(%s)=%v, want %v", name, got, want)
+				}
+				if got := m.PrecertificateMatches(&ct.Precertificate{TBSCertificate: cert}); got != want {
+					t.Errorf("PrecertificateMatches(%s)=%v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestScannerMatchSubjectAltNameRegexNil(t *testing.T) {
+	cert := &x509.Certificate{DNSNames: []string{"www.example.com"}}
+	m := MatchSubjectAltNameRegex{CertificateSANRegex: regexp.MustCompile(`example`)}
+	if !m.CertificateMatches(cert) {
+		t.Error("CertificateMatches()=false, want true")
+	}
+	if m.PrecertificateMatches(&ct.Precertificate{TBSCertificate: cert}) {
+		t.Error("PrecertificateMatches() with nil regex=true, want false")
+	}
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 func TestScannerEndToEnd(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -258,3 +363,586 @@ func TestDefaultScannerOptions(t *testing.T) {
 		t.Fatalf("Expected StartIndex to be 0, but was %d", opts.StartIndex)
 	}
 }
+
+func TestScannerOnError(t *testing.T) {
+	entries := fourEntries(t)
+	entries[1].LeafInput = []byte("corrupt")
+	ts := serveLog(t, entries)
+	defer ts.Close()
+	logClient, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		cont     bool
+		wantSeen []int64
+		wantErr  bool
+	}{
+		{desc: "continue", cont: true, wantSeen: []int64{0, 2, 3}},
+		{desc: "abort", cont: false, wantSeen: []int64{0}, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var mu sync.Mutex
+			var seen, failed []int64
+			opts := ScannerOptions{
+				// All entries are in a single batch, processed in order.
+				FetcherOptions: FetcherOptions{BatchSize: 4, ParallelFetch: 1},
+				Matcher:        &MatchAll{},
+				NumWorkers:     1,
+				OnError: func(index int64, raw []byte, err error) bool {
+					mu.Lock()
+					defer mu.Unlock()
+					failed = append(failed, index)
+					if got, want := string(raw), "corrupt"; got != want {
+						t.Errorf("OnError(%d) got raw entry %q; want %q", index, got, want)
+					}
+					return tc.cont
+				},
+			}
+			found := func(e *ct.RawLogEntry) {
+				mu.Lock()
+				defer mu.Unlock()
+				seen = append(seen, e.Index)
+			}
+			err := NewScanner(logClient, opts).Scan(context.Background(), found, found)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("Scan()=%v; want error: %v", err, tc.wantErr)
+			}
+			if want := []int64{1}; fmt.Sprint(failed) != fmt.Sprint(want) {
+				t.Errorf("OnError called for entries %v; want %v", failed, want)
+			}
+			if fmt.Sprint(seen) != fmt.Sprint(tc.wantSeen) {
+				t.Errorf("Scan() delivered entries %v; want %v", seen, tc.wantSeen)
+			}
+		})
+	}
+}
+
+// slowMatcher matches all certificates, taking a random time for each.
+type slowMatcher struct {
+	MatchAll
+	max time.Duration
+}
+
+func (m slowMatcher) CertificateMatches(c *x509.Certificate) bool {
+	time.Sleep(time.Duration(rand.Int63n(int64(m.max))))
+	return true
+}
+
+// manyEntries returns n entries, copied from FourEntries.
+func manyEntries(t testing.TB, n int) []ct.LeafEntry {
+	four := fourEntries(t)
+	entries := make([]ct.LeafEntry, n)
+	for i := range entries {
+		entries[i] = four[i%len(four)]
+	}
+	return entries
+}
+
+func TestScannerOrdered(t *testing.T) {
+	const numEntries = 50
+	ts := serveLog(t, manyEntries(t, numEntries))
+	defer ts.Close()
+	logClient, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := ScannerOptions{
+		FetcherOptions: FetcherOptions{BatchSize: 3},
+		Matcher:        slowMatcher{max: time.Millisecond},
+		FetchWorkers:   4,
+		ProcessWorkers: 8,
+		BufferSize:     5,
+		Ordered:        true,
+	}
+
+	var mu sync.Mutex
+	var seen []int64
+	inCallback := false
+	found := func(e *ct.RawLogEntry) {
+		mu.Lock()
+		if inCallback {
+			t.Errorf("Callback for entry %d called concurrently", e.Index)
+		}
+		inCallback = true
+		seen = append(seen, e.Index)
+		mu.Unlock()
+
+		time.Sleep(time.Duration(rand.Int63n(int64(100 * time.Microsecond))))
+
+		mu.Lock()
+		inCallback = false
+		mu.Unlock()
+	}
+	if err := NewScanner(logClient, opts).Scan(context.Background(), found, found); err != nil {
+		t.Fatalf("Scan()=%v", err)
+	}
+
+	if len(seen) != numEntries {
+		t.Fatalf("Scan() delivered %d entries; want %d", len(seen), numEntries)
+	}
+	for i, index := range seen {
+		if index != int64(i) {
+			t.Fatalf("Scan() delivered entries out of order: %v", seen)
+		}
+	}
+}
+
+func TestScannerOrderedSlowCallback(t *testing.T) {
+	const numEntries, bufferSize, workers = 50, 5, 4
+	ts := serveLog(t, manyEntries(t, numEntries))
+	defer ts.Close()
+	logClient, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	matcher := &countingMatcher{}
+	opts := ScannerOptions{
+		FetcherOptions: FetcherOptions{BatchSize: 3},
+		Matcher:        matcher,
+		FetchWorkers:   2,
+		ProcessWorkers: workers,
+		BufferSize:     bufferSize,
+		Ordered:        true,
+	}
+	matched := func() int {
+		matcher.mu.Lock()
+		defer matcher.mu.Unlock()
+		return matcher.certs + matcher.precerts
+	}
+
+	// While the callback of the first entry is blocked, the matchers keep
+	// going until they fill the window of entries in flight.
+	const window = bufferSize + workers
+	found := func(e *ct.RawLogEntry) {
+		if e.Index != 0 {
+			return
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for matched() < window {
+			if time.Now().After(deadline) {
+				t.Errorf("Matchers stalled at %d entries during a callback; want %d", matched(), window)
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if err := NewScanner(logClient, opts).Scan(context.Background(), found, found); err != nil {
+		t.Fatalf("Scan()=%v", err)
+	}
+	if got := matched(); got != numEntries {
+		t.Errorf("Scan() matched %d entries; want %d", got, numEntries)
+	}
+}
+
+func TestScannerStreamsMatches(t *testing.T) {
+	const numEntries, batchSize = 400, 10
+	entries := manyEntries(t, numEntries)
+	precert := precertEntry(t)
+	for i := 0; i < numEntries; i += 3 {
+		entries[i] = precert
+	}
+	var served int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ct/v1/get-sth":
+			fmt.Fprintf(w, `{"tree_size":%d,"timestamp":1396877652123,"sha256_root_hash":"0JBu0CkZnKXc1niEndDaqqgCRHucCfVt1/WBAXs/5T8=","tree_head_signature":"AAAACXNpZ25hdHVyZQ=="}`, numEntries)
+		case "/ct/v1/get-entries":
+			start, _ := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+			end, _ := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+			atomic.AddInt64(&served, end-start+1)
+			if err := json.NewEncoder(w).Encode(ct.GetEntriesResponse{Entries: entries[start : end+1]}); err != nil {
+				t.Errorf("Failed to write get-entries response: %v", err)
+			}
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	logClient, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ordered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ordered=%t", ordered), func(t *testing.T) {
+			atomic.StoreInt64(&served, 0)
+			opts := ScannerOptions{
+				FetcherOptions: FetcherOptions{BatchSize: batchSize, ParallelFetch: 2},
+				Matcher:        &MatchAll{},
+				NumWorkers:     1,
+				BufferSize:     5,
+				Ordered:        ordered,
+			}
+
+			// The first callback blocks until released, which must hold up
+			// the scan rather than have the matches accumulate.
+			blocked, release := make(chan struct{}), make(chan struct{})
+			var certs, precerts int64
+			check := func(rle *ct.RawLogEntry, want ct.LogEntryType, count *int64) {
+				leaf, err := rle.ToLogEntry()
+				if err != nil {
+					t.Errorf("ToLogEntry(%d)=%v", rle.Index, err)
+				} else if got := leaf.Leaf.TimestampedEntry.EntryType; got != want {
+					t.Errorf("Entry %d of type %v delivered as %v", rle.Index, got, want)
+				}
+				if atomic.AddInt64(&certs, 0)+atomic.AddInt64(&precerts, 0) == 0 {
+					close(blocked)
+					<-release
+				}
+				atomic.AddInt64(count, 1)
+			}
+			foundCert := func(rle *ct.RawLogEntry) { check(rle, ct.X509LogEntryType, &certs) }
+			foundPrecert := func(rle *ct.RawLogEntry) { check(rle, ct.PrecertLogEntryType, &precerts) }
+
+			done := make(chan error, 1)
+			go func() {
+				done <- NewScanner(logClient, opts).Scan(context.Background(), foundCert, foundPrecert)
+			}()
+
+			<-blocked
+			time.Sleep(200 * time.Millisecond)
+			select {
+			case err := <-done:
+				t.Fatalf("Scan()=%v while a callback is blocked; want it to wait", err)
+			default:
+			}
+			// The fetchers may each hold a batch, on top of the buffered and
+			// in-process entries.
+			if got, limit := atomic.LoadInt64(&served), int64(4*batchSize+opts.BufferSize+opts.NumWorkers); got > limit {
+				t.Errorf("Log served %d entries while a callback is blocked; want at most %d", got, limit)
+			}
+
+			close(release)
+			if err := <-done; err != nil {
+				t.Fatalf("Scan()=%v", err)
+			}
+			if got := certs + precerts; got != numEntries {
+				t.Errorf("Scan() delivered %d entries; want %d", got, numEntries)
+			}
+			if certs == 0 || precerts == 0 {
+				t.Errorf("Scan() delivered %d certs and %d precerts; want some of each", certs, precerts)
+			}
+		})
+	}
+}
+
+func BenchmarkScanner(b *testing.B) {
+	const numEntries = 2000
+	ts := serveLog(b, manyEntries(b, numEntries))
+	defer ts.Close()
+	logClient, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, ordered := range []bool{false, true} {
+		b.Run(fmt.Sprintf("ordered=%t", ordered), func(b *testing.B) {
+			opts := ScannerOptions{
+				FetcherOptions: FetcherOptions{BatchSize: 100},
+				Matcher:        &MatchAll{},
+				FetchWorkers:   4,
+				ProcessWorkers: 4,
+				BufferSize:     100,
+				Ordered:        ordered,
+			}
+			found := func(e *ct.RawLogEntry) {}
+			for i := 0; i < b.N; i++ {
+				if err := NewScanner(logClient, opts).Scan(context.Background(), found, found); err != nil {
+					b.Fatalf("Scan()=%v", err)
+				}
+			}
+		})
+	}
+}
+
+// countingMatcher matches all entries, counting the calls for each type.
+type countingMatcher struct {
+	mu              sync.Mutex
+	certs, precerts int
+}
+
+func (m *countingMatcher) CertificateMatches(*x509.Certificate) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.certs++
+	return true
+}
+
+func (m *countingMatcher) PrecertificateMatches(*ct.Precertificate) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.precerts++
+	return true
+}
+
+// garbageEntry returns an entry of the given type, holding a [pre-]certificate
+// which fails to parse.
+// precertEntry returns an entry for testdata.TestPreCertPEM.
+func precertEntry(t *testing.T) ct.LeafEntry {
+	t.Helper()
+	precert, err := x509util.CertificateFromPEM([]byte(testdata.TestPreCertPEM))
+	if x509.IsFatal(err) {
+		t.Fatalf("Failed to parse precert: %v", err)
+	}
+	issuer, err := x509util.CertificateFromPEM([]byte(testdata.CACertPEM))
+	if x509.IsFatal(err) {
+		t.Fatalf("Failed to parse issuer: %v", err)
+	}
+	leaf, err := ct.MerkleTreeLeafFromChain([]*x509.Certificate{precert, issuer}, ct.PrecertLogEntryType, 1469185273000)
+	if err != nil {
+		t.Fatalf("Failed to build leaf: %v", err)
+	}
+	leafData, err := tls.Marshal(*leaf)
+	if err != nil {
+		t.Fatalf("Failed to marshal leaf: %v", err)
+	}
+	extraData, err := tls.Marshal(ct.PrecertChainEntry{
+		PreCertificate:   ct.ASN1Cert{Data: precert.Raw},
+		CertificateChain: []ct.ASN1Cert{{Data: issuer.Raw}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal extra data: %v", err)
+	}
+	return ct.LeafEntry{LeafInput: leafData, ExtraData: extraData}
+}
+
+func garbageEntry(t *testing.T, eType ct.LogEntryType) ct.LeafEntry {
+	t.Helper()
+	te := ct.TimestampedEntry{EntryType: eType}
+	var extra interface{}
+	switch eType {
+	case ct.X509LogEntryType:
+		te.X509Entry = &ct.ASN1Cert{Data: []byte("garbage")}
+		extra = ct.CertificateChain{}
+	case ct.PrecertLogEntryType:
+		te.PrecertEntry = &ct.PreCert{TBSCertificate: []byte("garbage")}
+		extra = ct.PrecertChainEntry{PreCertificate: ct.ASN1Cert{Data: []byte("garbage")}}
+	}
+	leaf, err := tls.Marshal(ct.MerkleTreeLeaf{Version: ct.V1, LeafType: ct.TimestampedEntryLeafType, TimestampedEntry: &te})
+	if err != nil {
+		t.Fatalf("Failed to marshal leaf: %v", err)
+	}
+	extraData, err := tls.Marshal(extra)
+	if err != nil {
+		t.Fatalf("Failed to marshal extra data: %v", err)
+	}
+	return ct.LeafEntry{LeafInput: leaf, ExtraData: extraData}
+}
+
+func TestScannerEntryTypeFilter(t *testing.T) {
+	entries := []ct.LeafEntry{
+		fourEntries(t)[0],
+		garbageEntry(t, ct.X509LogEntryType),
+		garbageEntry(t, ct.PrecertLogEntryType),
+	}
+	ts := serveLog(t, entries)
+	defer ts.Close()
+	logClient, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		desc                  string
+		precertOnly, certOnly bool
+		wantCerts             []int64
+		wantErrs              []int64 // entries which failed to parse
+		wantMatcherCerts      int
+		wantErr               bool
+	}{
+		{desc: "all", wantCerts: []int64{0}, wantErrs: []int64{1, 2}, wantMatcherCerts: 1},
+		{desc: "precert-only", precertOnly: true, wantErrs: []int64{2}},
+		{desc: "cert-only", certOnly: true, wantCerts: []int64{0}, wantErrs: []int64{1}, wantMatcherCerts: 1},
+		{desc: "both", precertOnly: true, certOnly: true, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var mu sync.Mutex
+			var certs, precerts, errs []int64
+			matcher := &countingMatcher{}
+			opts := ScannerOptions{
+				FetcherOptions: FetcherOptions{BatchSize: 10, ParallelFetch: 1},
+				Matcher:        matcher,
+				NumWorkers:     1,
+				PrecertOnly:    tc.precertOnly,
+				CertOnly:       tc.certOnly,
+				OnError: func(index int64, _ []byte, _ error) bool {
+					mu.Lock()
+					defer mu.Unlock()
+					errs = append(errs, index)
+					return true
+				},
+			}
+			record := func(indices *[]int64) func(*ct.RawLogEntry) {
+				return func(e *ct.RawLogEntry) {
+					mu.Lock()
+					defer mu.Unlock()
+					*indices = append(*indices, e.Index)
+				}
+			}
+			err := NewScanner(logClient, opts).Scan(context.Background(), record(&certs), record(&precerts))
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Scan()=%v; want error: %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if fmt.Sprint(certs) != fmt.Sprint(tc.wantCerts) {
+				t.Errorf("Scan() delivered certs %v; want %v", certs, tc.wantCerts)
+			}
+			if len(precerts) != 0 {
+				t.Errorf("Scan() delivered precerts %v; want none", precerts)
+			}
+			if fmt.Sprint(errs) != fmt.Sprint(tc.wantErrs) {
+				t.Errorf("Scan() failed to parse entries %v; want %v", errs, tc.wantErrs)
+			}
+			if matcher.certs != tc.wantMatcherCerts || matcher.precerts != 0 {
+				t.Errorf("Matcher called for %d certs and %d precerts; want %d and 0", matcher.certs, matcher.precerts, tc.wantMatcherCerts)
+			}
+		})
+	}
+}
+
+func TestScannerCancel(t *testing.T) {
+	const numEntries, servedEntries = 1000, 100
+	entries := manyEntries(t, numEntries)
+	// The Log hangs on requests beyond the first entries, until the client
+	// gives up.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ct/v1/get-sth":
+			fmt.Fprintf(w, `{"tree_size":%d,"timestamp":1396877652123,"sha256_root_hash":"0JBu0CkZnKXc1niEndDaqqgCRHucCfVt1/WBAXs/5T8=","tree_head_signature":"AAAACXNpZ25hdHVyZQ=="}`, numEntries)
+		case "/ct/v1/get-entries":
+			start, _ := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+			end, _ := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+			if start >= servedEntries {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(10 * time.Second):
+				}
+				return
+			}
+			// The client may have given up already, so write errors are ignored.
+			json.NewEncoder(w).Encode(ct.GetEntriesResponse{Entries: entries[start : end+1]}) // nolint: errcheck
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	logClient, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ordered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ordered=%t", ordered), func(t *testing.T) {
+			opts := ScannerOptions{
+				FetcherOptions: FetcherOptions{BatchSize: 10, ParallelFetch: 2},
+				Matcher:        &MatchAll{},
+				NumWorkers:     2,
+				BufferSize:     5,
+				Ordered:        ordered,
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var found int64
+			cancelMidScan := func(*ct.RawLogEntry) {
+				if atomic.AddInt64(&found, 1) == servedEntries/2 {
+					cancel()
+				}
+			}
+
+			start := time.Now()
+			err := NewScanner(logClient, opts).Scan(ctx, cancelMidScan, cancelMidScan)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Scan()=%v; want %v", err, context.Canceled)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Scan() took %v after cancellation; want prompt return", elapsed)
+			}
+			if got := atomic.LoadInt64(&found); got >= numEntries {
+				t.Errorf("Scan() delivered %d entries after cancellation; want fewer than %d", got, numEntries)
+			}
+		})
+	}
+}
+
+// logEvent is a message received by recordingLogger.
+type logEvent struct {
+	severity string
+	level    int
+	msg      string
+}
+
+// recordingLogger is a Logger which records the messages it receives.
+type recordingLogger struct {
+	mu     sync.Mutex
+	events []logEvent
+}
+
+func (l *recordingLogger) add(severity string, level int, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, logEvent{severity: severity, level: level, msg: fmt.Sprintf(format, args...)})
+}
+
+func (l *recordingLogger) Infof(level int, format string, args ...interface{}) {
+	l.add("info", level, format, args...)
+}
+
+func (l *recordingLogger) Warningf(format string, args ...interface{}) {
+	l.add("warning", 0, format, args...)
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.add("error", 0, format, args...)
+}
+
+// find returns whether an event with the given severity and level, and a
+// message containing substr, was recorded.
+func (l *recordingLogger) find(severity string, level int, substr string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range l.events {
+		if e.severity == severity && e.level == level && strings.Contains(e.msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestScannerLogger(t *testing.T) {
+	entries := append(fourEntries(t), garbageEntry(t, ct.X509LogEntryType))
+	ts := serveLog(t, entries)
+	defer ts.Close()
+	logClient, err := client.New(ts.URL, &http.Client{}, jsonclient.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := &recordingLogger{}
+	opts := ScannerOptions{
+		FetcherOptions: FetcherOptions{BatchSize: 10, ParallelFetch: 1, Logger: logger},
+		Matcher:        &MatchAll{},
+		NumWorkers:     1,
+	}
+	scanner := NewScanner(logClient, opts)
+	if err := scanner.Scan(context.Background(), func(*ct.RawLogEntry) {}, func(*ct.RawLogEntry) {}); err != nil {
+		t.Fatalf("Scan()=%v", err)
+	}
+
+	for _, want := range []logEvent{
+		{severity: "info", level: 0, msg: "Got STH with 5 certs"},
+		{severity: "info", level: 1, msg: "Starting up Fetcher"},
+		{severity: "info", level: 1, msg: "Fetcher terminated"},
+		{severity: "error", level: 0, msg: "Failed to parse entry at index 4"},
+		{severity: "info", level: 1, msg: "Completed 5 certs"},
+		{severity: "info", level: 1, msg: "Saw 1 unparsable entries"},
+	} {
+		if !logger.find(want.severity, want.level, want.msg) {
+			t.Errorf("Logger did not receive %s message %q at level %d", want.severity, want.msg, want.level)
+		}
+	}
+}
@@ -15,6 +15,7 @@
 package ct
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/sha256"
 	"fmt"
@@ -160,6 +161,31 @@ func MerkleTreeLeafFromChain(chain []*x509.Certificate, etype LogEntryType, time
 	return &leaf, nil
 }
 
+// MerkleTreeLeafFromPrecert generates a MerkleTreeLeaf from a DER-encoded
+// precertificate, the certificate of its issuer and an SCT timestamp. The
+// issuer must have signed the precertificate, and must not be a Precertificate
+// Signing Certificate, as the key hash of the final issuer would be needed
+// (use MerkleTreeLeafFromChain for that case).
+func MerkleTreeLeafFromPrecert(precertDER []byte, issuer *x509.Certificate, timestamp uint64) (*MerkleTreeLeaf, error) {
+	if issuer == nil {
+		return nil, fmt.Errorf("no issuer cert available for precert leaf building")
+	}
+	precert, err := x509.ParseCertificate(precertDER)
+	if x509.IsFatal(err) {
+		return nil, fmt.Errorf("failed to parse precert: %v", err)
+	}
+	if !precert.IsPrecertificate() {
+		return nil, fmt.Errorf("cert is not a precert: no poison extension")
+	}
+	if IsPreIssuer(issuer) {
+		return nil, fmt.Errorf("issuer is a pre-issuer, the final issuer is needed")
+	}
+	if err := precert.CheckSignatureFrom(issuer); err != nil {
+		return nil, fmt.Errorf("precert not signed by issuer: %v", err)
+	}
+	return MerkleTreeLeafFromChain([]*x509.Certificate{precert, issuer}, PrecertLogEntryType, timestamp)
+}
+
 // MerkleTreeLeafForEmbeddedSCT generates a MerkleTreeLeaf from a chain and an
 // SCT timestamp, where the leaf certificate at chain[0] is a certificate that
 // contains embedded SCTs.  It is assumed that the timestamp provided is from
@@ -207,6 +233,12 @@ func LeafHashForLeaf(leaf *MerkleTreeLeaf) ([sha256.Size]byte, error) {
 	return leafHash, nil
 }
 
+// LeafHash returns the Merkle leaf hash of the entry, e.g. to request an
+// inclusion proof for it with get-proof-by-hash.
+func (e *LogEntry) LeafHash() ([sha256.Size]byte, error) {
+	return LeafHashForLeaf(&e.Leaf)
+}
+
 // IsPreIssuer indicates whether a certificate is a pre-cert issuer with the specific
 // certificate transparency extended key usage.
 func IsPreIssuer(issuer *x509.Certificate) bool {
@@ -294,6 +326,50 @@ func (rle *RawLogEntry) ToLogEntry() (*LogEntry, error) {
 	return &entry, err
 }
 
+// CheckExtraData checks that the extra data of the entry, i.e. its Chain and,
+// for a precertificate, the submitted Cert, is consistent with its Merkle tree
+// leaf. For a certificate, the first certificate of the chain, if any, must
+// have signed the leaf certificate. For a precertificate, the leaf rebuilt
+// from the submitted precertificate and its chain must have the same TBS
+// certificate and issuer key hash as the logged leaf.
+func (rle *RawLogEntry) CheckExtraData() error {
+	switch eType := rle.Leaf.TimestampedEntry.EntryType; eType {
+	case X509LogEntryType:
+		if len(rle.Chain) == 0 {
+			return nil
+		}
+		cert, err := x509.ParseCertificate(rle.Cert.Data)
+		if x509.IsFatal(err) {
+			return fmt.Errorf("failed to parse certificate: %v", err)
+		}
+		issuer, err := x509.ParseCertificate(rle.Chain[0].Data)
+		if x509.IsFatal(err) {
+			return fmt.Errorf("failed to parse chain[0] cert: %v", err)
+		}
+		if err := cert.CheckSignatureFrom(issuer); err != nil {
+			return fmt.Errorf("certificate not signed by chain[0]: %v", err)
+		}
+
+	case PrecertLogEntryType:
+		chain := append([]ASN1Cert{rle.Cert}, rle.Chain...)
+		leaf, err := MerkleTreeLeafFromRawChain(chain, PrecertLogEntryType, rle.Leaf.TimestampedEntry.Timestamp)
+		if err != nil {
+			return fmt.Errorf("failed to rebuild leaf from extra data: %v", err)
+		}
+		got, want := leaf.TimestampedEntry.PrecertEntry, rle.Leaf.TimestampedEntry.PrecertEntry
+		if got.IssuerKeyHash != want.IssuerKeyHash {
+			return fmt.Errorf("issuer key hash mismatch: extra data gives %x, leaf has %x", got.IssuerKeyHash, want.IssuerKeyHash)
+		}
+		if !bytes.Equal(got.TBSCertificate, want.TBSCertificate) {
+			return fmt.Errorf("TBS certificate of the submitted precertificate doesn't match the leaf")
+		}
+
+	default:
+		return fmt.Errorf("unknown entry type: %v", eType)
+	}
+	return nil
+}
+
 // LogEntryFromLeaf converts a LeafEntry object (which has the raw leaf data
 // after JSON parsing) into a LogEntry object (which includes x509.Certificate
 // objects, after TLS and ASN.1 parsing).
@@ -308,6 +384,31 @@ func LogEntryFromLeaf(index int64, leaf *LeafEntry) (*LogEntry, error) {
 	return rle.ToLogEntry()
 }
 
+// ParseSCTList parses a TLS-encoded SignedCertificateTimestampList (RFC 6962
+// s3.3), as carried in the X.509, OCSP and TLS extensions for SCTs, and
+// returns the SCTs it contains.
+func ParseSCTList(raw []byte) ([]*SignedCertificateTimestamp, error) {
+	var sctList x509.SignedCertificateTimestampList
+	rest, err := tls.Unmarshal(raw, &sctList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SCT list: %v", err)
+	} else if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data (%d bytes) after SCT list", len(rest))
+	}
+	scts := make([]*SignedCertificateTimestamp, 0, len(sctList.SCTList))
+	for i, serialized := range sctList.SCTList {
+		var sct SignedCertificateTimestamp
+		rest, err := tls.Unmarshal(serialized.Val, &sct)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SCT %d of list: %v", i, err)
+		} else if len(rest) > 0 {
+			return nil, fmt.Errorf("trailing data (%d bytes) after SCT %d of list", len(rest), i)
+		}
+		scts = append(scts, &sct)
+	}
+	return scts, nil
+}
+
 // TimestampToTime converts a timestamp in the style of RFC 6962 (milliseconds
 // since UNIX epoch) to a Go Time.
 func TimestampToTime(ts uint64) time.Time {
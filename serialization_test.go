@@ -16,6 +16,7 @@ package ct
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
 	"os"
@@ -23,7 +24,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/google/certificate-transparency-go/testdata"
 	"github.com/google/certificate-transparency-go/tls"
+	"github.com/google/certificate-transparency-go/x509"
 )
 
 func dh(h string) []byte {
@@ -321,6 +324,71 @@ func TestUnmarshalSCT(t *testing.T) {
 	}
 }
 
+// sctList builds a TLS-encoded SignedCertificateTimestampList holding the
+// given serialized SCTs.
+func sctList(scts ...[]byte) []byte {
+	var list []byte
+	for _, sct := range scts {
+		list = append(list, byte(len(sct)>>8), byte(len(sct)))
+		list = append(list, sct...)
+	}
+	return append([]byte{byte(len(list) >> 8), byte(len(list))}, list...)
+}
+
+func TestParseSCTList(t *testing.T) {
+	var certSCT, preCertSCT SignedCertificateTimestamp
+	if _, err := tls.Unmarshal(testdata.TestCertProof, &certSCT); err != nil {
+		t.Fatalf("failed to unmarshal TestCertProof: %v", err)
+	}
+	if _, err := tls.Unmarshal(testdata.TestPreCertProof, &preCertSCT); err != nil {
+		t.Fatalf("failed to unmarshal TestPreCertProof: %v", err)
+	}
+	valid := sctList(testdata.TestCertProof, testdata.TestPreCertProof)
+
+	for _, test := range []struct {
+		desc    string
+		raw     []byte
+		want    []*SignedCertificateTimestamp
+		wantErr string
+	}{
+		{
+			desc: "single",
+			raw:  sctList(testdata.TestCertProof),
+			want: []*SignedCertificateTimestamp{&certSCT},
+		},
+		{
+			desc: "multiple",
+			raw:  valid,
+			want: []*SignedCertificateTimestamp{&certSCT, &preCertSCT},
+		},
+		{desc: "nil", raw: nil, wantErr: "failed to parse SCT list"},
+		{desc: "empty-list", raw: sctList(), wantErr: "failed to parse SCT list"},
+		{desc: "truncated-length", raw: valid[:1], wantErr: "failed to parse SCT list"},
+		{desc: "truncated-list", raw: valid[:len(valid)-1], wantErr: "failed to parse SCT list"},
+		{desc: "trailing-data", raw: append(sctList(testdata.TestCertProof), 0x00), wantErr: "trailing data (1 bytes) after SCT list"},
+		{desc: "empty-sct", raw: sctList(testdata.TestCertProof, []byte{}), wantErr: "failed to parse SCT list"},
+		{desc: "malformed-sct", raw: sctList(testdata.TestCertProof, []byte{0x00, 0x01, 0x02}), wantErr: "failed to parse SCT 1 of list"},
+		{desc: "truncated-sct", raw: sctList(testdata.TestCertProof[:len(testdata.TestCertProof)-1]), wantErr: "failed to parse SCT 0 of list"},
+		{desc: "sct-trailing-data", raw: sctList(append(append([]byte{}, testdata.TestCertProof...), 0x00)), wantErr: "trailing data (1 bytes) after SCT 0 of list"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := ParseSCTList(test.raw)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("ParseSCTList()=%v,%v; want nil,err containing %q", got, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSCTList()=nil,%v; want scts,nil", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseSCTList()=%v; want %v", got, test.want)
+			}
+		})
+	}
+}
+
 func TestX509MerkleTreeLeafHash(t *testing.T) {
 	certFile := "./testdata/test-cert.pem"
 	sctFile := "./testdata/test-cert.proof"
@@ -480,3 +548,168 @@ func TestLogEntryFromLeaf(t *testing.T) {
 		}
 	}
 }
+
+func TestMerkleTreeLeafFromPrecert(t *testing.T) {
+	pemToDER := func(pemData string) []byte {
+		t.Helper()
+		block, _ := pem.Decode([]byte(pemData))
+		if block == nil {
+			t.Fatalf("Failed to decode PEM")
+		}
+		return block.Bytes
+	}
+	parse := func(pemData string) *x509.Certificate {
+		t.Helper()
+		cert, err := x509.ParseCertificate(pemToDER(pemData))
+		if err != nil {
+			t.Fatalf("Failed to parse cert: %v", err)
+		}
+		return cert
+	}
+	var sct SignedCertificateTimestamp
+	if _, err := tls.Unmarshal(testdata.TestPreCertProof, &sct); err != nil {
+		t.Fatalf("Failed to deserialize SCT: %v", err)
+	}
+	precertDER := pemToDER(testdata.TestPreCertPEM)
+	issuer := parse(testdata.CACertPEM)
+
+	tests := []struct {
+		name       string
+		precertDER []byte
+		issuer     *x509.Certificate
+		wantHash   string
+		wantErr    string
+	}{
+		{
+			name:       "valid",
+			precertDER: precertDER,
+			issuer:     issuer,
+			wantHash:   testdata.TestPreCertB64LeafHash,
+		},
+		{
+			name:       "wrong issuer",
+			precertDER: precertDER,
+			issuer:     parse(testdata.TestCertPEM),
+			wantErr:    "not signed by issuer",
+		},
+		{
+			name:       "no issuer",
+			precertDER: precertDER,
+			wantErr:    "no issuer cert",
+		},
+		{
+			name:       "not a precert",
+			precertDER: pemToDER(testdata.TestCertPEM),
+			issuer:     issuer,
+			wantErr:    "not a precert",
+		},
+		{
+			name:       "garbage",
+			precertDER: []byte("garbage"),
+			issuer:     issuer,
+			wantErr:    "failed to parse precert",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			leaf, err := MerkleTreeLeafFromPrecert(test.precertDER, test.issuer, sct.Timestamp)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("MerkleTreeLeafFromPrecert()=_, %v; want error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MerkleTreeLeafFromPrecert()=_, %v; want no error", err)
+			}
+			hash, err := LeafHashForLeaf(leaf)
+			if err != nil {
+				t.Fatalf("LeafHashForLeaf()=_, %v", err)
+			}
+			if got := base64.StdEncoding.EncodeToString(hash[:]); got != test.wantHash {
+				t.Errorf("MerkleTreeLeafFromPrecert() leaf hash %s; want %s", got, test.wantHash)
+			}
+		})
+	}
+}
+
+func TestLogEntryLeafHash(t *testing.T) {
+	parse := func(pemData string) *x509.Certificate {
+		t.Helper()
+		block, _ := pem.Decode([]byte(pemData))
+		if block == nil {
+			t.Fatalf("Failed to decode PEM")
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf("Failed to parse cert: %v", err)
+		}
+		return cert
+	}
+	timestamp := func(proof []byte) uint64 {
+		t.Helper()
+		var sct SignedCertificateTimestamp
+		if _, err := tls.Unmarshal(proof, &sct); err != nil {
+			t.Fatalf("Failed to deserialize SCT: %v", err)
+		}
+		return sct.Timestamp
+	}
+	marshal := func(val interface{}) []byte {
+		t.Helper()
+		data, err := tls.Marshal(val)
+		if err != nil {
+			t.Fatalf("tls.Marshal()=nil,%v", err)
+		}
+		return data
+	}
+	cert := parse(testdata.TestCertPEM)
+	precert := parse(testdata.TestPreCertPEM)
+	issuer := parse(testdata.CACertPEM)
+
+	certLeaf, err := MerkleTreeLeafFromChain([]*x509.Certificate{cert, issuer}, X509LogEntryType, timestamp(testdata.TestCertProof))
+	if err != nil {
+		t.Fatalf("MerkleTreeLeafFromChain()=nil,%v", err)
+	}
+	precertLeaf, err := MerkleTreeLeafFromPrecert(precert.Raw, issuer, timestamp(testdata.TestPreCertProof))
+	if err != nil {
+		t.Fatalf("MerkleTreeLeafFromPrecert()=nil,%v", err)
+	}
+
+	tests := []struct {
+		name     string
+		leaf     LeafEntry
+		wantHash string
+	}{
+		{
+			name: "cert",
+			leaf: LeafEntry{
+				LeafInput: marshal(*certLeaf),
+				ExtraData: marshal(CertificateChain{Entries: []ASN1Cert{{Data: issuer.Raw}}}),
+			},
+			wantHash: testdata.TestCertB64LeafHash,
+		},
+		{
+			name: "precert",
+			leaf: LeafEntry{
+				LeafInput: marshal(*precertLeaf),
+				ExtraData: marshal(PrecertChainEntry{PreCertificate: ASN1Cert{Data: precert.Raw}, CertificateChain: []ASN1Cert{{Data: issuer.Raw}}}),
+			},
+			wantHash: testdata.TestPreCertB64LeafHash,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry, err := LogEntryFromLeaf(1, &test.leaf)
+			if x509.IsFatal(err) {
+				t.Fatalf("LogEntryFromLeaf()=nil,%v", err)
+			}
+			hash, err := entry.LeafHash()
+			if err != nil {
+				t.Fatalf("LeafHash()=_,%v", err)
+			}
+			if got := base64.StdEncoding.EncodeToString(hash[:]); got != test.wantHash {
+				t.Errorf("LeafHash()=%s; want %s", got, test.wantHash)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package submission
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+// ChainErrorReason tells why a chain failed validation.
+type ChainErrorReason int
+
+// ChainErrorReason values.
+const (
+	// ChainMalformed means that the chain is empty or a certificate in it
+	// cannot be parsed.
+	ChainMalformed ChainErrorReason = iota
+	// ChainMisordered means that a certificate is not issued by the one
+	// following it, but by another certificate of the chain.
+	ChainMisordered
+	// ChainSignatureBreak means that a certificate is not issued by any
+	// certificate following it in the chain.
+	ChainSignatureBreak
+	// ChainUnknownRoot means that the last certificate of the chain is
+	// neither one of the roots nor issued by one of them.
+	ChainUnknownRoot
+)
+
+func (r ChainErrorReason) String() string {
+	switch r {
+	case ChainMalformed:
+		return "ChainMalformed"
+	case ChainMisordered:
+		return "ChainMisordered"
+	case ChainSignatureBreak:
+		return "ChainSignatureBreak"
+	case ChainUnknownRoot:
+		return "ChainUnknownRoot"
+	default:
+		return fmt.Sprintf("UnknownChainErrorReason(%d)", r)
+	}
+}
+
+// ChainError is returned by ValidateChainOrder for a chain which Logs would
+// reject.
+type ChainError struct {
+	Reason ChainErrorReason
+	// Index is the position in the chain of the offending certificate.
+	Index int
+	// Err holds the underlying parsing or verification error, if any.
+	Err error
+}
+
+func (e *ChainError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%v at chain index %d: %v", e.Reason, e.Index, e.Err)
+	}
+	return fmt.Sprintf("%v at chain index %d", e.Reason, e.Index)
+}
+
+func (e *ChainError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateChainOrder checks that rawChain is well-formed for submission to a
+// Log: it starts with the leaf, each certificate is issued by the one
+// following it, and the last one is either a member of roots or issued by
+// one. As for Log submissions, validity periods are not checked. Returns a
+// *ChainError describing the first problem found.
+func ValidateChainOrder(rawChain [][]byte, roots *x509.CertPool) error {
+	if len(rawChain) == 0 {
+		return &ChainError{Reason: ChainMalformed, Err: fmt.Errorf("empty chain")}
+	}
+	chain := make([]*x509.Certificate, 0, len(rawChain))
+	for i, certBytes := range rawChain {
+		cert, err := x509.ParseCertificate(certBytes)
+		if x509.IsFatal(err) {
+			return &ChainError{Reason: ChainMalformed, Index: i, Err: err}
+		}
+		chain = append(chain, cert)
+	}
+
+	for i := 0; i+1 < len(chain); i++ {
+		err := chain[i].CheckSignatureFrom(chain[i+1])
+		if err == nil {
+			continue
+		}
+		for j := i + 2; j < len(chain); j++ {
+			if chain[i].CheckSignatureFrom(chain[j]) == nil {
+				return &ChainError{Reason: ChainMisordered, Index: i, Err: fmt.Errorf("issued by the certificate at index %d", j)}
+			}
+		}
+		return &ChainError{Reason: ChainSignatureBreak, Index: i, Err: err}
+	}
+
+	last := len(chain) - 1
+	if roots == nil {
+		return &ChainError{Reason: ChainUnknownRoot, Index: last, Err: fmt.Errorf("no roots")}
+	}
+	opts := x509.VerifyOptions{
+		Roots:                          roots,
+		DisableTimeChecks:              true,
+		DisableCriticalExtensionChecks: true,
+		DisableNameChecks:              true,
+		DisableEKUChecks:               true,
+		DisablePathLenChecks:           true,
+		DisableNameConstraintChecks:    true,
+		KeyUsages:                      []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if _, err := chain[last].Verify(opts); err != nil {
+		return &ChainError{Reason: ChainUnknownRoot, Index: last, Err: err}
+	}
+	return nil
+}
+
+// DERChainFromPEM decodes a chain of PEM-encoded certificates, leaf first,
+// into the DER form expected by the Distributor. It fails with a
+// *ChainError of reason ChainMalformed if pemChain holds no certificate, a
+// block which is not a certificate, or anything other than whitespace outside
+// of the PEM blocks.
+func DERChainFromPEM(pemChain string) ([][]byte, error) {
+	var rawChain [][]byte
+	rest := []byte(pemChain)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, &ChainError{Reason: ChainMalformed, Index: len(rawChain), Err: fmt.Errorf("unexpected PEM block type %q", block.Type)}
+		}
+		rawChain = append(rawChain, block.Bytes)
+	}
+	if len(bytes.TrimSpace(rest)) != 0 {
+		return nil, &ChainError{Reason: ChainMalformed, Index: len(rawChain), Err: fmt.Errorf("invalid PEM data")}
+	}
+	if len(rawChain) == 0 {
+		return nil, &ChainError{Reason: ChainMalformed, Err: fmt.Errorf("no certificates in PEM data")}
+	}
+	return rawChain, nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package submission
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/go-cmp/cmp"
+)
+
+func rootPoolFromFile(t *testing.T, filename string) *x509.CertPool {
+	t.Helper()
+	pool := x509.NewCertPool()
+	for _, der := range pemFileToDERChain(filename) {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatalf("x509.ParseCertificate(%q) = _, %v", filename, err)
+		}
+		pool.AddCert(cert)
+	}
+	return pool
+}
+
+func TestValidateChainOrder(t *testing.T) {
+	roots := rootPoolFromFile(t, "../trillian/testdata/fake-ca.cert")
+	otherRoots := rootPoolFromFile(t, "../trillian/testdata/fake-ca-1.cert")
+	chain := pemFileToDERChain("../trillian/testdata/subleaf.chain")
+	preChain := pemFileToDERChain("../trillian/testdata/subleaf-pre.chain")
+
+	testCases := []struct {
+		name       string
+		rawChain   [][]byte
+		roots      *x509.CertPool
+		wantReason ChainErrorReason
+		wantIndex  int
+		wantErr    bool
+	}{
+		{name: "Valid", rawChain: chain, roots: roots},
+		{name: "ValidPreChain", rawChain: preChain, roots: roots},
+		{name: "ValidWithoutRoot", rawChain: chain[:3], roots: roots},
+		{
+			name:       "Misordered",
+			rawChain:   pemFileToDERChain("../trillian/testdata/subleaf.misordered.chain"),
+			roots:      roots,
+			wantReason: ChainMisordered,
+			wantIndex:  0,
+			wantErr:    true,
+		},
+		{
+			name:       "MisorderedPreChain",
+			rawChain:   pemFileToDERChain("../trillian/testdata/subleaf-pre.misordered.chain"),
+			roots:      roots,
+			wantReason: ChainMisordered,
+			wantIndex:  0,
+			wantErr:    true,
+		},
+		{
+			name:       "SignatureBreak",
+			rawChain:   [][]byte{chain[0], chain[2], chain[3]},
+			roots:      roots,
+			wantReason: ChainSignatureBreak,
+			wantIndex:  0,
+			wantErr:    true,
+		},
+		{
+			name:       "UnknownRoot",
+			rawChain:   chain,
+			roots:      otherRoots,
+			wantReason: ChainUnknownRoot,
+			wantIndex:  3,
+			wantErr:    true,
+		},
+		{
+			name:       "NoRoots",
+			rawChain:   chain[:2],
+			wantReason: ChainUnknownRoot,
+			wantIndex:  1,
+			wantErr:    true,
+		},
+		{
+			name:       "Empty",
+			roots:      roots,
+			wantReason: ChainMalformed,
+			wantErr:    true,
+		},
+		{
+			name:       "Garbage",
+			rawChain:   [][]byte{chain[0], []byte("garbage")},
+			roots:      roots,
+			wantReason: ChainMalformed,
+			wantIndex:  1,
+			wantErr:    true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateChainOrder(tc.rawChain, tc.roots)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("ValidateChainOrder() = %v, want err? %t", err, tc.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			var chainErr *ChainError
+			if !errors.As(err, &chainErr) {
+				t.Fatalf("ValidateChainOrder() = %v, want *ChainError", err)
+			}
+			if chainErr.Reason != tc.wantReason || chainErr.Index != tc.wantIndex {
+				t.Errorf("ValidateChainOrder() = %v at index %d, want %v at index %d", chainErr.Reason, chainErr.Index, tc.wantReason, tc.wantIndex)
+			}
+		})
+	}
+}
+
+func TestDERChainFromPEM(t *testing.T) {
+	pemChain, err := os.ReadFile("../trillian/testdata/subleaf.chain")
+	if err != nil {
+		t.Fatalf("failed to read chain: %v", err)
+	}
+	wantChain := pemFileToDERChain("../trillian/testdata/subleaf.chain")
+	keyPEM := "-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n"
+
+	testCases := []struct {
+		name      string
+		pemChain  string
+		want      [][]byte
+		wantIndex int
+		wantErr   bool
+	}{
+		{name: "Valid", pemChain: string(pemChain), want: wantChain},
+		{name: "Empty", pemChain: "", wantErr: true},
+		{name: "Garbage", pemChain: "garbage", wantErr: true},
+		{name: "TrailingGarbage", pemChain: string(pemChain) + "garbage", wantIndex: len(wantChain), wantErr: true},
+		{name: "NotCertificate", pemChain: string(pemChain) + keyPEM, wantIndex: len(wantChain), wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := DERChainFromPEM(tc.pemChain)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("DERChainFromPEM() = _, %v, want err? %t", err, tc.wantErr)
+			}
+			if err != nil {
+				var chainErr *ChainError
+				if !errors.As(err, &chainErr) {
+					t.Fatalf("DERChainFromPEM() = %v, want *ChainError", err)
+				}
+				if chainErr.Reason != ChainMalformed || chainErr.Index != tc.wantIndex {
+					t.Errorf("DERChainFromPEM() = %v at index %d, want %v at index %d", chainErr.Reason, chainErr.Index, ChainMalformed, tc.wantIndex)
+				}
+				return
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("DERChainFromPEM(): diff -want +got\n%s", diff)
+			}
+		})
+	}
+}
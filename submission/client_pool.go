@@ -0,0 +1,90 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package submission
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/google/certificate-transparency-go/client"
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+// ClientPool caches the clients of Logs by URL, so that every user of a Log
+// shares a single client and its connections, instead of building its own.
+// The client of a Log is rebuilt if the Log's public key changes. It is safe
+// for concurrent use.
+type ClientPool struct {
+	builder LogClientBuilder
+
+	mu      sync.Mutex
+	clients map[string]*pooledClient // Keyed by Log URL.
+}
+
+// sharedLogClient wraps a pooled client, leaving out any methods beyond
+// client.AddLogClient, so that users of the pool such as Distributor.Close
+// can't close the idle connections other users rely on.
+type sharedLogClient struct {
+	client.AddLogClient
+}
+
+// pooledClient is the client of a Log, which is ready once the ready channel
+// is closed. key is the public key of the Log the client was built for.
+type pooledClient struct {
+	key   []byte
+	ready chan struct{}
+	lc    client.AddLogClient
+	err   error
+}
+
+// NewClientPool creates a ClientPool which builds clients with builder, or
+// BuildLogClient if builder is nil.
+func NewClientPool(builder LogClientBuilder) *ClientPool {
+	if builder == nil {
+		builder = BuildLogClient
+	}
+	return &ClientPool{builder: builder, clients: make(map[string]*pooledClient)}
+}
+
+// Get returns the client for the given Log, building it on first use. Callers
+// racing for the same Log wait for a single client to be built. A failed
+// build is not cached, so the next call retries it. Get is a LogClientBuilder,
+// e.g. for passing to NewDistributor. The clients returned don't support
+// CloseIdleConnections, as they are shared.
+func (p *ClientPool) Get(log *loglist3.Log) (client.AddLogClient, error) {
+	p.mu.Lock()
+	pc, ok := p.clients[log.URL]
+	if ok && bytes.Equal(pc.key, log.Key) {
+		p.mu.Unlock()
+		<-pc.ready
+		return pc.lc, pc.err
+	}
+	pc = &pooledClient{key: log.Key, ready: make(chan struct{})}
+	p.clients[log.URL] = pc
+	p.mu.Unlock()
+
+	pc.lc, pc.err = p.builder(log)
+	if pc.err != nil {
+		p.mu.Lock()
+		if p.clients[log.URL] == pc {
+			delete(p.clients, log.URL)
+		}
+		p.mu.Unlock()
+	} else {
+		pc.lc = sharedLogClient{pc.lc}
+	}
+	close(pc.ready)
+	return pc.lc, pc.err
+}
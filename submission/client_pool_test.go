@@ -0,0 +1,202 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package submission
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/certificate-transparency-go/client"
+	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/trillian/monitoring"
+)
+
+func TestClientPoolConcurrentGet(t *testing.T) {
+	var mu sync.Mutex
+	builds := make(map[string]int)
+	builder := func(log *loglist3.Log) (client.AddLogClient, error) {
+		mu.Lock()
+		builds[log.URL]++
+		mu.Unlock()
+		// Give racing callers the time to pile up.
+		time.Sleep(10 * time.Millisecond)
+		return &stubLogClient{logURL: log.URL}, nil
+	}
+	pool := NewClientPool(builder)
+
+	const numLogs, numCallers = 3, 50
+	got := make([][]client.AddLogClient, numLogs)
+	for i := range got {
+		got[i] = make([]client.AddLogClient, numCallers)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < numLogs; i++ {
+		log := &loglist3.Log{URL: fmt.Sprintf("ct.example.com/log%d/", i)}
+		for j := 0; j < numCallers; j++ {
+			wg.Add(1)
+			go func(i, j int) {
+				defer wg.Done()
+				lc, err := pool.Get(log)
+				if err != nil {
+					t.Errorf("Get(%s)=_,%v; want nil", log.URL, err)
+				}
+				got[i][j] = lc
+			}(i, j)
+		}
+	}
+	wg.Wait()
+
+	if len(builds) != numLogs {
+		t.Errorf("built clients for %d Logs; want %d", len(builds), numLogs)
+	}
+	for url, n := range builds {
+		if n != 1 {
+			t.Errorf("built %d clients for %s; want 1", n, url)
+		}
+	}
+	for i, lcs := range got {
+		for j, lc := range lcs {
+			if lc == nil || lc != lcs[0] {
+				t.Errorf("Get() #%d for Log %d returned a different client", j, i)
+			}
+		}
+		if i > 0 && lcs[0] == got[0][0] {
+			t.Errorf("Get() for Log %d returned the client of Log 0", i)
+		}
+	}
+}
+
+func TestClientPoolRetriesFailedBuild(t *testing.T) {
+	var calls int32
+	builder := func(log *loglist3.Log) (client.AddLogClient, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return nil, errors.New("transient failure")
+		}
+		return &stubLogClient{logURL: log.URL}, nil
+	}
+	pool := NewClientPool(builder)
+	log := &loglist3.Log{URL: "ct.example.com/log/"}
+
+	if _, err := pool.Get(log); err == nil {
+		t.Fatal("Get()=_,nil on failing build; want error")
+	}
+	first, err := pool.Get(log)
+	if err != nil {
+		t.Fatalf("Get()=_,%v after failed build; want nil", err)
+	}
+	second, err := pool.Get(log)
+	if err != nil {
+		t.Fatalf("Get()=_,%v; want nil", err)
+	}
+	if first != second {
+		t.Error("Get() returned different clients for the same Log")
+	}
+	if got, want := atomic.LoadInt32(&calls), int32(2); got != want {
+		t.Errorf("builder called %d times; want %d", got, want)
+	}
+}
+
+func TestClientPoolWithDistributor(t *testing.T) {
+	var builds int32
+	pool := NewClientPool(func(log *loglist3.Log) (client.AddLogClient, error) {
+		atomic.AddInt32(&builds, 1)
+		return newLocalStubLogClient(log)
+	})
+	var want int32
+	for i := 0; i < 2; i++ {
+		if _, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), pool.Get, monitoring.InertMetricFactory{}, DistributorOptions{}); err != nil {
+			t.Fatalf("NewDistributor(): %v", err)
+		}
+		if i == 0 {
+			want = atomic.LoadInt32(&builds)
+		}
+	}
+	if want == 0 {
+		t.Fatal("first Distributor built no clients")
+	}
+	if got := atomic.LoadInt32(&builds); got != want {
+		t.Errorf("built %d clients for two Distributors; want %d", got, want)
+	}
+}
+
+func TestClientPoolDistributorCloseKeepsConnections(t *testing.T) {
+	var mu sync.Mutex
+	var lcs []*closableStubLogClient
+	pool := NewClientPool(func(log *loglist3.Log) (client.AddLogClient, error) {
+		lc := &closableStubLogClient{stubLogClient: stubLogClient{logURL: log.URL}}
+		mu.Lock()
+		lcs = append(lcs, lc)
+		mu.Unlock()
+		return lc, nil
+	})
+	dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), pool.Get, monitoring.InertMetricFactory{}, DistributorOptions{})
+	if err != nil {
+		t.Fatalf("NewDistributor(): %v", err)
+	}
+	dist.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(lcs) == 0 {
+		t.Fatal("Distributor built no clients")
+	}
+	for _, lc := range lcs {
+		if got := atomic.LoadInt32(&lc.closed); got != 0 {
+			t.Errorf("CloseIdleConnections() of pooled %q called %d times, want 0", lc.logURL, got)
+		}
+	}
+}
+
+func TestClientPoolRebuildsOnKeyChange(t *testing.T) {
+	var builds int32
+	pool := NewClientPool(func(log *loglist3.Log) (client.AddLogClient, error) {
+		atomic.AddInt32(&builds, 1)
+		return &stubLogClient{logURL: log.URL}, nil
+	})
+	const url = "ct.example.com/log/"
+
+	tests := []struct {
+		desc      string
+		key       []byte
+		wantBuilt bool
+	}{
+		{desc: "first", key: []byte{1}, wantBuilt: true},
+		{desc: "same-key", key: []byte{1}},
+		{desc: "new-key", key: []byte{2}, wantBuilt: true},
+		{desc: "new-key-again", key: []byte{2}},
+		{desc: "old-key", key: []byte{1}, wantBuilt: true},
+	}
+	var prev client.AddLogClient
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			before := atomic.LoadInt32(&builds)
+			lc, err := pool.Get(&loglist3.Log{URL: url, Key: test.key})
+			if err != nil {
+				t.Fatalf("Get()=_,%v; want nil", err)
+			}
+			if built := atomic.LoadInt32(&builds) != before; built != test.wantBuilt {
+				t.Errorf("Get() built a client: %t; want %t", built, test.wantBuilt)
+			}
+			if reused := lc == prev; reused == test.wantBuilt {
+				t.Errorf("Get() reused the previous client: %t; want %t", reused, !test.wantBuilt)
+			}
+			prev = lc
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package submission
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is the source of time of a Distributor, used for scheduling roots
+// refreshes, expiring roots and cached SCTs, and timing out and retrying
+// requests to Logs. Tests can provide a fake one to control time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel receiving the current time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock backed by the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// withClockTimeout is like context.WithTimeout, but the timeout is measured
+// by clock.
+func withClockTimeout(ctx context.Context, clock Clock, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := clock.(systemClock); ok {
+		return context.WithTimeout(ctx, timeout)
+	}
+	deadline := clock.Now().Add(timeout)
+	// Register the timer before returning, so that the clock knows of it.
+	timer := clock.After(timeout)
+	cctx, cancel := context.WithCancel(ctx)
+	tctx := &timeoutContext{Context: cctx, deadline: deadline}
+	go func() {
+		select {
+		case <-timer:
+			atomic.StoreInt32(&tctx.expired, 1)
+			cancel()
+		case <-cctx.Done():
+		}
+	}()
+	return tctx, cancel
+}
+
+// timeoutContext is a context cancelled by withClockTimeout, reporting
+// context.DeadlineExceeded once its deadline has passed.
+type timeoutContext struct {
+	context.Context
+	deadline time.Time
+	expired  int32 // accessed atomically
+}
+
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	if parent, ok := c.Context.Deadline(); ok && parent.Before(c.deadline) {
+		return parent, true
+	}
+	return c.deadline, true
+}
+
+func (c *timeoutContext) Err() error {
+	if atomic.LoadInt32(&c.expired) == 1 {
+		return context.DeadlineExceeded
+	}
+	return c.Context.Err()
+}
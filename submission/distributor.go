@@ -16,46 +16,33 @@ package submission
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/rand"
 	"net/http"
 	"net/url"
-	"strconv"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/certificate-transparency-go/client"
 	"github.com/google/certificate-transparency-go/ctpolicy"
+	"github.com/google/certificate-transparency-go/fixchain/ratelimiter"
 	"github.com/google/certificate-transparency-go/jsonclient"
 	"github.com/google/certificate-transparency-go/loglist3"
 	"github.com/google/certificate-transparency-go/trillian/ctfe"
 	"github.com/google/certificate-transparency-go/x509"
 	"github.com/google/certificate-transparency-go/x509util"
+	"github.com/google/trillian/client/backoff"
 	"github.com/google/trillian/monitoring"
 	"k8s.io/klog/v2"
 
 	ct "github.com/google/certificate-transparency-go"
 )
 
-var (
-	// Metrics are per-log, per-endpoint and some per-response-status code.
-	distOnce      sync.Once
-	reqsCounter   monitoring.Counter   // logurl, ep => value
-	rspsCounter   monitoring.Counter   // logurl, ep, sc => value
-	errCounter    monitoring.Counter   // logurl, ep, status => value
-	logRspLatency monitoring.Histogram // logurl, ep => value
-	// Per-log
-	lastGetRootsSuccess monitoring.Gauge // Unix time
-)
-
-// distInitMetrics initializes all the exported metrics.
-func distInitMetrics(mf monitoring.MetricFactory) {
-	reqsCounter = mf.NewCounter("http_reqs", "Number of requests", "logurl", "ep")
-	rspsCounter = mf.NewCounter("http_rsps", "Number of responses", "logurl", "ep", "httpstatus")
-	errCounter = mf.NewCounter("err_count", "Number of errors", "logurl", "ep", "errtype")
-	logRspLatency = mf.NewHistogram("http_log_latency", "Latency of responses in seconds", "logurl", "ep")
-	lastGetRootsSuccess = mf.NewGauge("last_get_roots_success", "Unix timestamp for last successful get-roots request", "logurl")
-}
-
 const (
 	// GetRootsTimeout timeout used for external requests within root-updates.
 	getRootsTimeout = time.Second * 10
@@ -88,18 +75,264 @@ type Distributor struct {
 	logClients map[string]client.AddLogClient
 	logRoots   loglist3.LogRoots
 	rootPool   *x509util.PEMCertPool
+	// rootsFetched holds the time each Log's roots were last fetched
+	// successfully.
+	rootsFetched map[string]time.Time
 
 	rootDataFull bool
 
 	policy            ctpolicy.CTPolicy
 	pendingLogsPolicy ctpolicy.CTPolicy
+
+	// perLogTimeout bounds each individual add-(pre-)chain request; zero
+	// means requests are bounded only by the caller's context.
+	perLogTimeout time.Duration
+	retry         RetryConfig
+	rootsTTL      time.Duration
+	logPriority   map[string]int
+	maxRefresh    int
+	// sctCache holds recently issued SCTs, nil if caching is disabled.
+	// llVersion identifies the Logs of ll for the cached SCTs.
+	sctCache  *sctCache
+	llVersion string
+	// logAttrs maps Log URLs to the operator and state of the Log.
+	logAttrs map[string]logAttributes
+	// perLogQPS is the rate limit of the add-(pre-)chain requests to each
+	// Log, zero if unlimited. logLimiters holds the limiter of each Log.
+	perLogQPS   int
+	logLimiters map[string]*ratelimiter.Limiter
+
+	refreshJitter time.Duration
+	minRefresh    time.Duration
+	clock         Clock
+	minOperators  int
+	metrics       SubmissionMetrics
+
+	// closeMu guards closed, which is set by Close. done is closed by Close
+	// to stop the Run loops, which are tracked by runs.
+	closeMu sync.Mutex
+	closed  bool
+	done    chan struct{}
+	runs    sync.WaitGroup
+}
+
+// logAttributes holds the details of a Log reported in AssignedSCTs.
+type logAttributes struct {
+	operator string
+	state    loglist3.LogStatus
+}
+
+// DistributorOptions holds optional settings for a Distributor.
+type DistributorOptions struct {
+	// PerLogTimeout is the maximum duration of a single add-chain or
+	// add-pre-chain request to one Log. A Log exceeding it is treated as
+	// failed, without affecting requests to the other Logs. Zero means no
+	// per-Log limit beyond the caller's context.
+	PerLogTimeout time.Duration
+	// Retry configures retries of failed requests to a single Log. The zero
+	// value disables retries.
+	Retry RetryConfig
+	// RootsTTL is how long the last successfully fetched roots of a Log keep
+	// being used while refreshing them fails. Zero means they are kept until
+	// the next successful refresh.
+	RootsTTL time.Duration
+	// LogPriority maps Log URLs to submission priorities. Within each policy
+	// group, Logs are tried in descending priority order: Logs of a lower
+	// priority are only contacted once all requests to Logs of higher
+	// priorities are done, and the group still needs SCTs. Logs missing from
+	// the map have priority 0.
+	LogPriority map[string]int
+	// MaxConcurrentRefresh bounds the number of get-roots requests in flight
+	// while refreshing Log roots. Zero means no limit.
+	MaxConcurrentRefresh int
+	// SCTCacheSize is the number of certificates whose SCTs are cached, so
+	// that repeated submissions of a certificate are answered without
+	// contacting the Logs. Submissions only share SCTs if they agree on
+	// asPreChain and loadPendingLogs. Zero disables the cache. A Proxy keeps
+	// the cached SCTs across log list updates, unless Logs are added, removed
+	// or change state.
+	SCTCacheSize int
+	// SCTCacheTTL is how long cached SCTs are served. Zero means cached SCTs
+	// are only evicted to make room for newer ones.
+	SCTCacheTTL time.Duration
+	// RefreshJitter randomly shifts each interval between the roots refreshes
+	// of Run by up to this duration either way, so that instances started
+	// together don't query the Logs in sync. Zero keeps a fixed cadence.
+	RefreshJitter time.Duration
+	// MinRefreshInterval is the shortest interval between the roots refreshes
+	// of Run, whatever the refresh interval and jitter.
+	MinRefreshInterval time.Duration
+	// Clock is the source of time of the Distributor. Nil means the system
+	// clock.
+	Clock Clock
+	// RequireDistinctOperators is the minimal number of distinct operators
+	// whose Logs must have issued the SCTs collected for a submission,
+	// whatever the policy. A submission falling short fails with a
+	// *NotEnoughOperatorsError. Zero disables the check.
+	RequireDistinctOperators int
+	// PerLogQPS is the maximum number of add-chain and add-pre-chain requests
+	// sent to each Log per second. Each Log has its own limit, and requests
+	// exceeding it wait for their turn, bounded by the caller's context. Each
+	// retry configured by Retry uses up the budget like a new request, while
+	// the retries made within the Log client don't. Zero means no limit.
+	PerLogQPS int
+	// Metrics records the requests sent to the Logs. Nil means metrics
+	// created by NewSubmissionMetrics with the MetricFactory passed to
+	// NewDistributor.
+	Metrics SubmissionMetrics
+}
+
+// NotEnoughOperatorsError is returned when the SCTs collected for a chain come
+// from fewer distinct Log operators than required by the
+// RequireDistinctOperators option. The SCTs that were obtained are still
+// returned alongside it.
+type NotEnoughOperatorsError struct {
+	// Operators is the number of distinct operators of the SCTs.
+	Operators int
+	// Required is the minimal number of distinct operators.
+	Required int
+}
+
+func (e *NotEnoughOperatorsError) Error() string {
+	return fmt.Sprintf("SCTs come from %d distinct Log operator(s), want at least %d", e.Operators, e.Required)
+}
+
+// RetryConfig describes how a failed request to a Log is retried. Only
+// transient failures are retried: HTTP 5xx responses, timeouts and transport
+// errors. HTTP 4xx responses and invalid SCTs fail straight away.
+//
+// These retries stack on top of those of the Log client. The LogClient of
+// the client package already retries transport errors and HTTP 408, 429 and
+// 503 responses itself, until its context is done, so with it the requests
+// retried here are those failing with other HTTP 5xx statuses, and those cut
+// short by PerLogTimeout. Each retry gets a PerLogTimeout of its own.
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of requests sent to a Log for one
+	// submission, including the first one. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the pause before the first retry. The pause doubles
+	// with each subsequent retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the pause between retries.
+	MaxBackoff time.Duration
+	// Jitter randomizes each pause to spread out retries.
+	Jitter bool
+}
+
+// isRetryable reports whether a failed request to a Log is worth retrying.
+// Rejections by the Log are not, unless it asks the client to slow down.
+func isRetryable(err error) bool {
+	var clientErr *client.ClientError
+	if errors.As(err, &clientErr) {
+		return clientErr.StatusCode == http.StatusRequestTimeout || clientErr.StatusCode == http.StatusTooManyRequests
+	}
+	var serverErr *client.ServerError
+	if errors.As(err, &serverErr) {
+		return true
+	}
+	var rspErr client.RspError
+	if !errors.As(err, &rspErr) {
+		// Timeout, e.g. of PerLogTimeout, or transport error of a client
+		// which doesn't retry these itself; no HTTP response available.
+		return true
+	}
+	return rspErr.StatusCode >= http.StatusInternalServerError || rspErr.StatusCode == http.StatusRequestTimeout
+}
+
+// Run fetches roots from all the Logs, then keeps refreshing them until ctx
+// is done or Close is called. Each refresh starts the refresh interval after
+// the previous one completed, adjusted by the RefreshJitter and
+// MinRefreshInterval options.
+func (d *Distributor) Run(ctx context.Context, refresh time.Duration) {
+	ctx, stop, ok := d.runContext(ctx)
+	if !ok {
+		return
+	}
+	defer stop()
+	for ctx.Err() == nil {
+		for _, err := range d.RefreshRoots(ctx) {
+			klog.Warning(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.clock.After(d.refreshInterval(refresh)):
+		}
+	}
+}
+
+// runContext registers a Run loop, returning a context derived from ctx which
+// is also cancelled by Close, and a func to call once the loop is over. It
+// reports false if d is already closed.
+func (d *Distributor) runContext(ctx context.Context) (context.Context, func(), bool) {
+	d.closeMu.Lock()
+	defer d.closeMu.Unlock()
+	if d.closed {
+		return nil, nil, false
+	}
+	d.runs.Add(1)
+	ctx, cancel := context.WithCancel(ctx)
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		select {
+		case <-d.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	stop := func() {
+		cancel()
+		<-watched
+		d.runs.Done()
+	}
+	return ctx, stop, true
+}
+
+// Close stops the Run loops of the Distributor, waiting for them to return,
+// including any roots refresh in progress, then closes the idle connections
+// of the Log clients which support it. Clients obtained from a ClientPool
+// are shared with other users, and are left alone. Later calls to Run return
+// at once.
+// Submissions are not affected, but the Distributor should no longer be used.
+// Close is idempotent.
+func (d *Distributor) Close() {
+	d.closeMu.Lock()
+	if d.closed {
+		d.closeMu.Unlock()
+		return
+	}
+	d.closed = true
+	close(d.done)
+	d.closeMu.Unlock()
+
+	d.runs.Wait()
+	for _, lc := range d.logClients {
+		if c, ok := lc.(interface{ CloseIdleConnections() }); ok {
+			c.CloseIdleConnections()
+		}
+	}
+}
+
+// refreshInterval returns the pause before the next roots refresh: refresh,
+// randomly shifted by up to refreshJitter and raised to minRefresh if needed.
+func (d *Distributor) refreshInterval(refresh time.Duration) time.Duration {
+	interval := refresh
+	if d.refreshJitter > 0 {
+		interval += time.Duration(rand.Int63n(2*int64(d.refreshJitter)+1)) - d.refreshJitter
+	}
+	if interval < d.minRefresh {
+		interval = d.minRefresh
+	}
+	return interval
 }
 
 // RefreshRoots requests roots from Logs and updates local copy.
 // Returns error map keyed by log-URL for any Log experiencing roots retrieval
 // problems
 // If at least one root was successfully parsed for a log, log roots set gets
-// the update.
+// the update. If roots couldn't be retrieved from a log, its previous roots
+// set is kept until it gets older than the configured RootsTTL.
 func (d *Distributor) RefreshRoots(ctx context.Context) map[string]error {
 	type RootsResult struct {
 		LogURL string
@@ -111,29 +344,31 @@ func (d *Distributor) RefreshRoots(ctx context.Context) map[string]error {
 	rctx, cancel := context.WithTimeout(ctx, getRootsTimeout)
 	defer cancel()
 
+	// sem limits the number of concurrent get-roots requests, if configured.
+	var sem chan struct{}
+	if d.maxRefresh > 0 {
+		sem = make(chan struct{}, d.maxRefresh)
+	}
+
 	for logURL, lc := range d.logClients {
 		go func(logURL string, lc client.AddLogClient) {
 			res := RootsResult{LogURL: logURL}
 
-			roots, err := lc.GetAcceptedRoots(rctx)
-			if err != nil {
-				res.Err = fmt.Errorf("roots refresh for %s: couldn't collect roots. %s", logURL, err)
-				ch <- res
-				return
-			}
-			res.Roots = x509util.NewPEMCertPool()
-			for _, r := range roots {
-				parsed, err := x509.ParseCertificate(r.Data)
-				if x509.IsFatal(err) {
-					errS := fmt.Errorf("roots refresh for %s: unable to parse root cert: %s", logURL, err)
-					if res.Err != nil {
-						res.Err = fmt.Errorf("%s\n%s", res.Err, errS)
-					} else {
-						res.Err = errS
-					}
-					continue
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+				case <-rctx.Done():
+					res.Err = fmt.Errorf("roots refresh for %s: couldn't collect roots. %s", logURL, rctx.Err())
+					ch <- res
+					return
 				}
-				res.Roots.AddCert(parsed)
+			}
+
+			roots, err := fetchRoots(rctx, logURL, lc)
+			res.Roots = roots
+			if err != nil {
+				res.Err = fmt.Errorf("roots refresh: %v", err)
 			}
 			ch <- res
 		}(logURL, lc)
@@ -142,6 +377,7 @@ func (d *Distributor) RefreshRoots(ctx context.Context) map[string]error {
 	// Collect get-roots results for every Log-client.
 	freshRoots := make(loglist3.LogRoots)
 	errors := make(map[string]error)
+	fetched := make(map[string]time.Time)
 	for range d.logClients {
 		r := <-ch
 		// update roots
@@ -150,15 +386,31 @@ func (d *Distributor) RefreshRoots(ctx context.Context) map[string]error {
 		}
 		// Roots get update even if some returned roots couldn't get parsed.
 		if r.Roots != nil {
+			now := d.clock.Now()
 			freshRoots[r.LogURL] = r.Roots
-			lastGetRootsSuccess.Set(float64(time.Now().Unix()), r.LogURL)
+			fetched[r.LogURL] = now
+			d.metrics.SetLastGetRootsSuccess(r.LogURL, now)
 		}
 	}
 
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	// Keep serving the last known roots of Logs which failed to provide them.
+	for logURL, pool := range d.logRoots {
+		if _, ok := freshRoots[logURL]; ok {
+			continue
+		}
+		if d.rootsTTL > 0 && d.clock.Now().Sub(d.rootsFetched[logURL]) > d.rootsTTL {
+			klog.Warningf("roots refresh for %s: dropping roots fetched at %v", logURL, d.rootsFetched[logURL])
+			continue
+		}
+		freshRoots[logURL] = pool
+		fetched[logURL] = d.rootsFetched[logURL]
+	}
+
 	d.logRoots = freshRoots
+	d.rootsFetched = fetched
 	d.rootDataFull = len(d.logRoots) == len(d.logClients)
 	// Merge individual root-pools into a unified one
 	d.rootPool = x509util.NewPEMCertPool()
@@ -171,8 +423,8 @@ func (d *Distributor) RefreshRoots(ctx context.Context) map[string]error {
 	return errors
 }
 
-// incRspsCounter extracts HTTP status code and increments corresponding rspsCounter.
-func incRspsCounter(logURL string, endpoint string, rspErr error) {
+// incRspsCounter extracts HTTP status code and counts the response with it.
+func (d *Distributor) incRspsCounter(logURL string, endpoint string, rspErr error) {
 	status := http.StatusOK
 	if rspErr != nil {
 		status = http.StatusBadRequest // default to this if status code unavailable
@@ -180,12 +432,12 @@ func incRspsCounter(logURL string, endpoint string, rspErr error) {
 			status = err.StatusCode
 		}
 	}
-	rspsCounter.Inc(logURL, endpoint, strconv.Itoa(status))
+	d.metrics.IncResponses(logURL, endpoint, status)
 }
 
-// incErrCounter increments corresponding errCounter if any error occurred during
+// incErrCounter counts the error by type if any error occurred during
 // submission to a Log.
-func incErrCounter(logURL string, endpoint string, rspErr error) {
+func (d *Distributor) incErrCounter(logURL string, endpoint string, rspErr error) {
 	if rspErr == nil {
 		return
 	}
@@ -193,13 +445,13 @@ func incErrCounter(logURL string, endpoint string, rspErr error) {
 	switch {
 	case !ok:
 		klog.Errorf("unknown_error (%s, %s) => %v", logURL, endpoint, rspErr)
-		errCounter.Inc(logURL, endpoint, "unknown_error")
+		d.metrics.IncErrors(logURL, endpoint, "unknown_error")
 	case err.Err != nil && err.StatusCode == http.StatusOK:
 		klog.Errorf("invalid_sct (%s, %s) => HTTP details: status=%d, body:\n%s", logURL, endpoint, err.StatusCode, err.Body)
-		errCounter.Inc(logURL, endpoint, "invalid_sct")
+		d.metrics.IncErrors(logURL, endpoint, "invalid_sct")
 	case err.Err != nil: // err.StatusCode != http.StatusOK.
 		klog.Errorf("connection_error (%s, %s) => HTTP details: status=%d, body:\n%s", logURL, endpoint, err.StatusCode, err.Body)
-		errCounter.Inc(logURL, endpoint, "connection_error")
+		d.metrics.IncErrors(logURL, endpoint, "connection_error")
 	}
 }
 
@@ -216,18 +468,57 @@ func (d *Distributor) SubmitToLog(ctx context.Context, logURL string, chain []ct
 		endpoint = string(ctfe.AddPreChainName)
 	}
 
-	defer func(start time.Time) {
-		logRspLatency.Observe(time.Since(start).Seconds(), logURL, endpoint)
-	}(time.Now())
-	reqsCounter.Inc(logURL, endpoint)
 	addChain := lc.AddChain
 	if asPreChain {
 		addChain = lc.AddPreChain
 	}
-	sct, err := addChain(ctx, chain)
-	incRspsCounter(logURL, endpoint, err)
-	incErrCounter(logURL, endpoint, err)
-	return sct, err
+	// A single attempt, with the metrics and per-Log timeout applied. The
+	// latency observed leaves out the backoff and rate limit waits between
+	// attempts.
+	attempt := func() (*ct.SignedCertificateTimestamp, error) {
+		defer func(start time.Time) {
+			d.metrics.ObserveLatency(logURL, endpoint, time.Since(start))
+		}(time.Now())
+		d.metrics.IncRequests(logURL, endpoint)
+		ctx := ctx
+		if d.perLogTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = withClockTimeout(ctx, d.clock, d.perLogTimeout)
+			defer cancel()
+		}
+		sct, err := addChain(ctx, chain)
+		d.incRspsCounter(logURL, endpoint, err)
+		d.incErrCounter(logURL, endpoint, err)
+		return sct, err
+	}
+
+	bo := &backoff.Backoff{
+		Min:    d.retry.InitialBackoff,
+		Max:    d.retry.MaxBackoff,
+		Factor: 2,
+		Jitter: d.retry.Jitter && d.retry.InitialBackoff > 0,
+	}
+	if bo.Max < bo.Min {
+		bo.Max = bo.Min
+	}
+	for attempts := 1; ; attempts++ {
+		if l := d.logLimiters[logURL]; l != nil {
+			if err := l.WaitContext(ctx); err != nil {
+				return nil, fmt.Errorf("%s: rate limit: %w", logURL, err)
+			}
+		}
+		sct, err := attempt()
+		if err == nil || attempts >= d.retry.MaxAttempts || !isRetryable(err) {
+			return sct, err
+		}
+		pause := bo.Duration()
+		klog.V(1).Infof("%s: %s attempt %d failed, retrying in %v: %v", logURL, endpoint, attempts, pause, err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-d.clock.After(pause):
+		}
+	}
 }
 
 // parseRawChain reads cert chain from bytes into x509.Certificate format.
@@ -243,43 +534,45 @@ func parseRawChain(rawChain [][]byte) ([]*x509.Certificate, error) {
 	return parsedChain, nil
 }
 
-// addSomeChain is helper calling one of AddChain or AddPreChain based
-// on asPreChain param.
-func (d *Distributor) addSomeChain(ctx context.Context, rawChain [][]byte, loadPendingLogs bool, asPreChain bool) ([]*AssignedSCT, error) {
+// compatibleChain parses and validates rawChain, and returns it along with the
+// root it was matched to, if any, and the Logs which would accept it.
+// asPreChain tells whether rawChain is expected to be a pre-certificate chain.
+func (d *Distributor) compatibleChain(rawChain [][]byte, asPreChain bool) ([]*x509.Certificate, *x509.Certificate, loglist3.LogList, error) {
 	if len(rawChain) == 0 {
-		return nil, fmt.Errorf("distributor unable to process empty chain")
+		return nil, nil, loglist3.LogList{}, fmt.Errorf("distributor unable to process empty chain")
 	}
 
 	// Helper function establishing responsibility of locking while determining log list and root chain.
-	compatibleLogsAndChain := func() (loglist3.LogList, []*x509.Certificate, error) {
+	compatibleLogsAndChain := func() (loglist3.LogList, []*x509.Certificate, *x509.Certificate, error) {
 		d.mu.RLock()
 		defer d.mu.RUnlock()
 		vOpts := ctfe.NewCertValidationOpts(d.rootPool, time.Time{}, false, false, nil, nil, false, nil)
 		rootedChain, err := ctfe.ValidateChain(rawChain, vOpts)
 		if err == nil {
-			return d.usableLl.Compatible(rootedChain[0], rootedChain[len(rootedChain)-1], d.logRoots), rootedChain, nil
+			root := rootedChain[len(rootedChain)-1]
+			return d.usableLl.Compatible(rootedChain[0], root, d.logRoots), rootedChain, root, nil
 		}
 		if d.rootDataFull {
 			// Could not verify the chain while root info for logs is complete.
-			return loglist3.LogList{}, nil, fmt.Errorf("distributor unable to process cert-chain: %v", err)
+			return loglist3.LogList{}, nil, nil, fmt.Errorf("distributor unable to process cert-chain: %v", err)
 		}
 
 		// Chain might be rooted to the Log which has no root-info yet.
 		parsedChain, err := parseRawChain(rawChain)
 		if err != nil {
-			return loglist3.LogList{}, nil, fmt.Errorf("distributor unable to parse cert-chain: %v", err)
+			return loglist3.LogList{}, nil, nil, fmt.Errorf("distributor unable to parse cert-chain: %v", err)
 		}
-		return d.usableLl.Compatible(parsedChain[0], nil, d.logRoots), parsedChain, nil
+		return d.usableLl.Compatible(parsedChain[0], nil, d.logRoots), parsedChain, nil, nil
 	}
-	compatibleLogs, parsedChain, err := compatibleLogsAndChain()
+	compatibleLogs, parsedChain, root, err := compatibleLogsAndChain()
 	if err != nil {
-		return nil, err
+		return nil, nil, loglist3.LogList{}, err
 	}
 
 	// Distinguish between precerts and certificates.
 	isPrecert, err := ctfe.IsPrecertificate(parsedChain[0])
 	if err != nil {
-		return nil, fmt.Errorf("distributor unable to check certificate %v: \n%v", parsedChain[0], err)
+		return nil, nil, loglist3.LogList{}, fmt.Errorf("distributor unable to check certificate %v: \n%v", parsedChain[0], err)
 	}
 	if isPrecert != asPreChain {
 		var methodType, inputType string
@@ -289,48 +582,305 @@ func (d *Distributor) addSomeChain(ctx context.Context, rawChain [][]byte, loadP
 		if isPrecert {
 			inputType = "pre-"
 		}
-		return nil, fmt.Errorf("add-%schain method expected %scertificate, got %scertificate", methodType, methodType, inputType)
+		return nil, nil, loglist3.LogList{}, fmt.Errorf("add-%schain method expected %scertificate, got %scertificate", methodType, methodType, inputType)
 	}
+	return parsedChain, root, compatibleLogs, nil
+}
 
-	// Set up policy structs.
-	groups, err := d.policy.LogsByGroup(parsedChain[0], &compatibleLogs)
+// logGroups sets up the Log-groups cert is to be submitted to, according to
+// the Distributor's policy.
+func (d *Distributor) logGroups(cert *x509.Certificate, compatibleLogs *loglist3.LogList) (ctpolicy.LogPolicyData, error) {
+	groups, err := d.policy.LogsByGroup(cert, compatibleLogs)
 	if err != nil {
 		return nil, fmt.Errorf("distributor does not have enough compatible Logs to comply with the policy: %v", err)
 	}
+	if len(d.logPriority) > 0 {
+		for _, g := range groups {
+			g.LogPriorities = d.logPriority
+		}
+	}
+	return groups, nil
+}
+
+// AddChainResult holds the outcome of a submission by AddChainVerbose or
+// AddPreChainVerbose.
+type AddChainResult struct {
+	// SCTs holds the SCTs collected, as returned by AddChain and AddPreChain.
+	SCTs []*AssignedSCT
+	// RootSubject is the subject of the root certificate, among the roots
+	// accepted by the Logs, that the chain was matched to. It is empty if the
+	// chain matched none, which only happens while roots of some Logs are
+	// unknown.
+	RootSubject string
+}
+
+// addSomeChain is helper calling one of AddChain or AddPreChain based
+// on asPreChain param.
+func (d *Distributor) addSomeChain(ctx context.Context, rawChain [][]byte, loadPendingLogs bool, asPreChain bool) (*AddChainResult, error) {
+	parsedChain, root, compatibleLogs, err := d.compatibleChain(rawChain, asPreChain)
+	if err != nil {
+		return nil, err
+	}
+	res := &AddChainResult{}
+	if root != nil {
+		res.RootSubject = root.Subject.String()
+	}
+
+	cacheKey := newSCTCacheKey(parsedChain[0].Raw, asPreChain, loadPendingLogs)
+	if d.sctCache != nil {
+		if scts, ok := d.sctCache.get(cacheKey, d.llVersion); ok {
+			res.SCTs = scts
+			return res, nil
+		}
+	}
+
+	// Set up policy structs.
+	groups, err := d.logGroups(parsedChain[0], &compatibleLogs)
+	if err != nil {
+		return res, err
+	}
 	chain := make([]ct.ASN1Cert, len(parsedChain))
 	for i, c := range parsedChain {
 		chain[i] = ct.ASN1Cert{Data: c.Raw}
 	}
 	if loadPendingLogs {
 		go func() {
-			pendingGroup, err := d.pendingLogsPolicy.LogsByGroup(parsedChain[0], d.pendingQualifiedLl)
+			// Skip temporally sharded Logs which would reject the cert.
+			pendingLl := d.pendingQualifiedLl.TemporallyCompatible(parsedChain[0])
+			pendingGroup, err := d.pendingLogsPolicy.LogsByGroup(parsedChain[0], &pendingLl)
 			if err != nil {
 				return
 			}
 			GetSCTs(ctx, d, chain, asPreChain, pendingGroup)
 		}()
 	}
-	return GetSCTs(ctx, d, chain, asPreChain, groups)
+	res.SCTs, err = GetSCTs(ctx, d, chain, asPreChain, groups)
+	d.attributeSCTs(res.SCTs)
+	if err == nil {
+		err = d.checkOperators(res.SCTs)
+	}
+	if err == nil && d.sctCache != nil {
+		d.sctCache.put(cacheKey, d.llVersion, res.SCTs)
+	}
+	return res, err
+}
+
+// scts returns the SCTs of res, which may be nil.
+func (res *AddChainResult) scts() []*AssignedSCT {
+	if res == nil {
+		return nil
+	}
+	return res.SCTs
+}
+
+// attributeSCTs fills in the operator and state of the Log which issued each
+// of scts.
+func (d *Distributor) attributeSCTs(scts []*AssignedSCT) {
+	for _, sct := range scts {
+		if attrs, ok := d.logAttrs[sct.LogURL]; ok {
+			sct.Operator = attrs.operator
+			sct.LogState = attrs.state
+		}
+	}
+}
+
+// checkOperators returns a *NotEnoughOperatorsError if scts come from fewer
+// distinct operators than required. SCTs of unknown operators don't count.
+func (d *Distributor) checkOperators(scts []*AssignedSCT) error {
+	if d.minOperators <= 0 {
+		return nil
+	}
+	operators := make(map[string]bool)
+	for _, sct := range scts {
+		if sct.Operator != "" {
+			operators[sct.Operator] = true
+		}
+	}
+	if len(operators) < d.minOperators {
+		return &NotEnoughOperatorsError{Operators: len(operators), Required: d.minOperators}
+	}
+	return nil
 }
 
 // AddPreChain runs add-pre-chain calls across subset of logs according to
 // Distributor's policy. May emit both SCTs array and error when SCTs
-// collected do not satisfy the policy.
+// collected do not satisfy the policy; the error is then a
+// *PolicyNotSatisfiedError.
 func (d *Distributor) AddPreChain(ctx context.Context, rawChain [][]byte, loadPendingLogs bool) ([]*AssignedSCT, error) {
+	res, err := d.addSomeChain(ctx, rawChain, loadPendingLogs, true)
+	return res.scts(), err
+}
+
+// AddPreChainVerbose is like AddPreChain, but also reports the root the chain
+// was matched to. The result is nil if the chain could not be processed.
+func (d *Distributor) AddPreChainVerbose(ctx context.Context, rawChain [][]byte, loadPendingLogs bool) (*AddChainResult, error) {
 	return d.addSomeChain(ctx, rawChain, loadPendingLogs, true)
 }
 
 // AddChain runs add-chain calls across subset of logs according to
 // Distributor's policy. May emit both SCTs array and error when SCTs
-// collected do not satisfy the policy.
+// collected do not satisfy the policy; the error is then a
+// *PolicyNotSatisfiedError.
 func (d *Distributor) AddChain(ctx context.Context, rawChain [][]byte, loadPendingLogs bool) ([]*AssignedSCT, error) {
+	res, err := d.addSomeChain(ctx, rawChain, loadPendingLogs, false)
+	return res.scts(), err
+}
+
+// AddChainVerbose is like AddChain, but also reports the root the chain was
+// matched to. The result is nil if the chain could not be processed.
+func (d *Distributor) AddChainVerbose(ctx context.Context, rawChain [][]byte, loadPendingLogs bool) (*AddChainResult, error) {
 	return d.addSomeChain(ctx, rawChain, loadPendingLogs, false)
 }
 
+// AddPEMChain is like AddChain, or AddPreChain if asPreChain is set, but
+// takes the chain PEM-encoded, leaf first. A chain which can't be decoded is
+// rejected with a *ChainError.
+func (d *Distributor) AddPEMChain(ctx context.Context, pemChain string, asPreChain, loadPendingLogs bool) ([]*AssignedSCT, error) {
+	rawChain, err := DERChainFromPEM(pemChain)
+	if err != nil {
+		return nil, err
+	}
+	res, err := d.addSomeChain(ctx, rawChain, loadPendingLogs, asPreChain)
+	return res.scts(), err
+}
+
+// AddPreChainToLogs submits rawChain with add-pre-chain to exactly the Logs
+// with the given URLs, bypassing the Distributor's policy, e.g. to obtain the
+// SCT of a Log which was missed by an earlier submission. Each Log must be
+// known to the Distributor and, if its roots are known, accept the root of
+// the chain. Returns the SCTs collected, along with the error of each Log
+// which didn't provide one, keyed by URL. The final error is set instead if
+// the chain itself can't be processed.
+func (d *Distributor) AddPreChainToLogs(ctx context.Context, rawChain [][]byte, logURLs []string) ([]*AssignedSCT, map[string]error, error) {
+	if len(logURLs) == 0 {
+		return nil, nil, errors.New("no Logs to submit to")
+	}
+	parsedChain, root, _, err := d.compatibleChain(rawChain, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	chain := make([]ct.ASN1Cert, len(parsedChain))
+	for i, c := range parsedChain {
+		chain[i] = ct.ASN1Cert{Data: c.Raw}
+	}
+
+	logErrs := make(map[string]error)
+	var targets []string
+	seen := make(map[string]bool)
+	d.mu.RLock()
+	for _, logURL := range logURLs {
+		if seen[logURL] {
+			continue
+		}
+		seen[logURL] = true
+		if _, ok := d.logClients[logURL]; !ok {
+			logErrs[logURL] = fmt.Errorf("no client registered for Log with URL %q", logURL)
+			continue
+		}
+		if roots, ok := d.logRoots[logURL]; ok && (root == nil || !roots.Included(root)) {
+			logErrs[logURL] = fmt.Errorf("root of the chain not accepted by Log %q", logURL)
+			continue
+		}
+		targets = append(targets, logURL)
+	}
+	d.mu.RUnlock()
+
+	type result struct {
+		logURL string
+		sct    *ct.SignedCertificateTimestamp
+		err    error
+	}
+	results := make(chan result, len(targets))
+	for _, logURL := range targets {
+		go func(logURL string) {
+			sct, err := d.SubmitToLog(ctx, logURL, chain, true)
+			results <- result{logURL: logURL, sct: sct, err: err}
+		}(logURL)
+	}
+	var scts []*AssignedSCT
+	for range targets {
+		r := <-results
+		if r.err != nil {
+			logErrs[r.logURL] = r.err
+			continue
+		}
+		scts = append(scts, &AssignedSCT{LogURL: r.logURL, SCT: r.sct})
+	}
+	d.attributeSCTs(scts)
+	return scts, logErrs, nil
+}
+
+// fetchRoots requests the roots accepted by the Log at logURL, and returns a
+// pool of the ones which parse. The pool is returned along with an error
+// listing the unparseable roots, if any; it is nil if the request fails.
+func fetchRoots(ctx context.Context, logURL string, lc client.AddLogClient) (*x509util.PEMCertPool, error) {
+	roots, err := lc.GetAcceptedRoots(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't collect roots of %s: %s", logURL, err)
+	}
+	pool := x509util.NewPEMCertPool()
+	var errs []string
+	for _, r := range roots {
+		parsed, err := x509.ParseCertificate(r.Data)
+		if x509.IsFatal(err) {
+			errs = append(errs, fmt.Sprintf("unable to parse root cert of %s: %s", logURL, err))
+			continue
+		}
+		pool.AddCert(parsed)
+	}
+	if len(errs) > 0 {
+		return pool, errors.New(strings.Join(errs, "\n"))
+	}
+	return pool, nil
+}
+
+// RootPoolFor requests the roots accepted by log through lc, and returns them
+// as a pool suitable for pre-validating chains with ValidateChainOrder. If
+// some of the roots don't parse, the pool of the others is returned along with
+// an error.
+func RootPoolFor(ctx context.Context, log *loglist3.Log, lc client.AddLogClient) (*x509.CertPool, error) {
+	roots, err := fetchRoots(ctx, log.URL, lc)
+	if roots == nil {
+		return nil, err
+	}
+	return roots.CertPool(), err
+}
+
+// RootsForLog returns a copy of the pool of roots last fetched from the Log
+// at logURL, and false if no roots are known for it.
+func (d *Distributor) RootsForLog(logURL string) (*x509.CertPool, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	roots, ok := d.logRoots[logURL]
+	if !ok {
+		return nil, false
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range roots.RawCertificates() {
+		pool.AddCert(cert)
+	}
+	return pool, true
+}
+
+// ValidateChain checks rawChain the way AddChain, or AddPreChain if asPreChain
+// is set, does, without submitting it anywhere. It returns the Log-groups the
+// chain would be submitted to according to Distributor's policy, or an error
+// if the chain is invalid or the policy cannot be satisfied by the Logs which
+// would accept it. Like AddChain, it relies on the Log roots fetched so far.
+func (d *Distributor) ValidateChain(rawChain [][]byte, asPreChain bool) (ctpolicy.LogPolicyData, error) {
+	parsedChain, _, compatibleLogs, err := d.compatibleChain(rawChain, asPreChain)
+	if err != nil {
+		return nil, err
+	}
+	return d.logGroups(parsedChain[0], &compatibleLogs)
+}
+
 // LogClientBuilder builds client-interface instance for a given Log.
 type LogClientBuilder func(*loglist3.Log) (client.AddLogClient, error)
 
-// BuildLogClient is default (non-mock) LogClientBuilder.
+// BuildLogClient is default (non-mock) LogClientBuilder. Each client gets its
+// own HTTP transport, so that closing its idle connections doesn't affect
+// other users of http.DefaultTransport.
 func BuildLogClient(log *loglist3.Log) (client.AddLogClient, error) {
 	u, err := url.Parse(log.URL)
 	if err != nil {
@@ -339,7 +889,10 @@ func BuildLogClient(log *loglist3.Log) (client.AddLogClient, error) {
 	if u.Scheme == "" {
 		u.Scheme = "https"
 	}
-	hc := &http.Client{Timeout: time.Second * 10}
+	hc := &http.Client{
+		Timeout:   time.Second * 10,
+		Transport: http.DefaultTransport.(*http.Transport).Clone(),
+	}
 	return client.New(u.String(), hc, jsonclient.Options{PublicKeyDER: log.Key})
 }
 
@@ -347,10 +900,32 @@ func BuildLogClient(log *loglist3.Log) (client.AddLogClient, error) {
 // The Distributor will asynchronously fetch the latest roots from all of the
 // logs when active. Call Run() to fetch roots and init regular updates to keep
 // the local copy of the roots up-to-date.
-func NewDistributor(ll *loglist3.LogList, plc ctpolicy.CTPolicy, lcBuilder LogClientBuilder, mf monitoring.MetricFactory) (*Distributor, error) {
+// Unless opts.Metrics is set, the metrics of the Distributor are registered
+// with mf, if not nil. Callers creating several Distributors with the same
+// mf should rather pass the same NewSubmissionMetrics(mf) to all of them.
+func NewDistributor(ll *loglist3.LogList, plc ctpolicy.CTPolicy, lcBuilder LogClientBuilder, mf monitoring.MetricFactory, opts DistributorOptions) (*Distributor, error) {
 	var d Distributor
+	d.done = make(chan struct{})
+	d.perLogTimeout = opts.PerLogTimeout
+	d.retry = opts.Retry
+	d.rootsTTL = opts.RootsTTL
+	d.logPriority = opts.LogPriority
+	d.maxRefresh = opts.MaxConcurrentRefresh
+	d.refreshJitter = opts.RefreshJitter
+	d.minRefresh = opts.MinRefreshInterval
+	d.minOperators = opts.RequireDistinctOperators
+	d.perLogQPS = opts.PerLogQPS
+	d.clock = opts.Clock
+	if d.clock == nil {
+		d.clock = systemClock{}
+	}
+	if opts.SCTCacheSize > 0 {
+		d.sctCache = newSCTCache(opts.SCTCacheSize, opts.SCTCacheTTL)
+		d.sctCache.now = d.clock.Now
+	}
 	// Divide Logs by statuses.
 	d.ll = ll
+	d.llVersion = logListVersion(ll)
 	usableStat := []loglist3.LogStatus{loglist3.UsableLogStatus}
 	active := ll.SelectByStatus(usableStat)
 	d.usableLl = &active
@@ -363,7 +938,10 @@ func NewDistributor(ll *loglist3.LogList, plc ctpolicy.CTPolicy, lcBuilder LogCl
 	d.pendingLogsPolicy = pendingLogsPolicy{}
 	d.logClients = make(map[string]client.AddLogClient)
 	d.logRoots = make(loglist3.LogRoots)
+	d.rootsFetched = make(map[string]time.Time)
 	d.rootPool = x509util.NewPEMCertPool()
+	d.logAttrs = make(map[string]logAttributes)
+	d.logLimiters = make(map[string]*ratelimiter.Limiter)
 
 	// Build clients for each of the Logs. Also build log-to-id map.
 	if err := d.buildLogClients(lcBuilder, d.usableLl); err != nil {
@@ -371,13 +949,37 @@ func NewDistributor(ll *loglist3.LogList, plc ctpolicy.CTPolicy, lcBuilder LogCl
 	}
 	d.buildLogClients(lcBuilder, d.pendingQualifiedLl)
 
-	if mf == nil {
-		mf = monitoring.InertMetricFactory{}
+	d.metrics = opts.Metrics
+	if d.metrics == nil {
+		d.metrics = NewSubmissionMetrics(mf)
 	}
-	distOnce.Do(func() { distInitMetrics(mf) })
 	return &d, nil
 }
 
+// logListVersion returns a digest of the URLs and states of the Logs in ll,
+// which changes whenever Logs are added, removed or change state.
+func logListVersion(ll *loglist3.LogList) string {
+	var logs []string
+	for _, op := range ll.Operators {
+		for _, log := range op.Logs {
+			logs = append(logs, fmt.Sprintf("%s %v", log.URL, log.State.LogStatus()))
+		}
+	}
+	sort.Strings(logs)
+	h := sha256.Sum256([]byte(strings.Join(logs, "\n")))
+	return hex.EncodeToString(h[:])
+}
+
+// reuseSCTCache makes d share the SCT cache of prev, the Distributor it
+// replaces, if both cache SCTs. SCTs cached by prev remain available as long
+// as the Logs of both log lists are the same, and are dropped otherwise.
+func (d *Distributor) reuseSCTCache(prev *Distributor) {
+	if prev == nil || prev.sctCache == nil || d.sctCache == nil {
+		return
+	}
+	d.sctCache = prev.sctCache
+}
+
 // buildLogClients builds clients for every Log provided and adds them into
 // Distributor internals.
 func (d *Distributor) buildLogClients(lcBuilder LogClientBuilder, ll *loglist3.LogList) error {
@@ -388,6 +990,10 @@ func (d *Distributor) buildLogClients(lcBuilder LogClientBuilder, ll *loglist3.L
 				return fmt.Errorf("failed to create log client for %s: %v", log.URL, err)
 			}
 			d.logClients[log.URL] = lc
+			d.logAttrs[log.URL] = logAttributes{operator: op.Name, state: log.State.LogStatus()}
+			if d.perLogQPS > 0 {
+				d.logLimiters[log.URL] = ratelimiter.NewLimiter(d.perLogQPS)
+			}
 		}
 	}
 	return nil
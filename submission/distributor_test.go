@@ -19,10 +19,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
 	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	ct "github.com/google/certificate-transparency-go"
 	"github.com/google/certificate-transparency-go/client"
 	"github.com/google/certificate-transparency-go/ctpolicy"
 	"github.com/google/certificate-transparency-go/loglist3"
@@ -44,7 +51,7 @@ func ExampleDistributor() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	d, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), newLocalStubLogClient, monitoring.InertMetricFactory{})
+	d, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), newLocalStubLogClient, monitoring.InertMetricFactory{}, DistributorOptions{})
 	if err != nil {
 		panic(err)
 	}
@@ -74,7 +81,7 @@ func ExampleDistributor() {
 		fmt.Printf("%s\n", *sct)
 	}
 	// Output:
-	// {https://ct.googleapis.com/rocketeer/ {Version:0 LogId:aHR0cHM6Ly9jdC5nb29nbGVhcGlzLmNvbS9yb2NrZXQ= Timestamp:1234 Extensions:'' Signature:{{SHA256 ECDSA} []}}}
+	// {https://ct.googleapis.com/rocketeer/ {Version:0 LogId:aHR0cHM6Ly9jdC5nb29nbGVhcGlzLmNvbS9yb2NrZXQ= Timestamp:1234 Extensions:'' Signature:{{SHA256 ECDSA} []}} Google UsableLogStatus}
 }
 
 var (
@@ -160,7 +167,7 @@ func TestNewDistributorLogClients(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := NewDistributor(tc.ll, ctpolicy.ChromeCTPolicy{}, tc.lcBuilder, monitoring.InertMetricFactory{})
+			_, err := NewDistributor(tc.ll, ctpolicy.ChromeCTPolicy{}, tc.lcBuilder, monitoring.InertMetricFactory{}, DistributorOptions{})
 			if gotErr, wantErr := err != nil, tc.errRegexp != nil; gotErr != wantErr {
 				var unwantedErr string
 				if gotErr {
@@ -174,6 +181,32 @@ func TestNewDistributorLogClients(t *testing.T) {
 	}
 }
 
+func TestNewDistributorLogClientsByState(t *testing.T) {
+	dist, err := NewDistributor(sampleLogList(), ctpolicy.ChromeCTPolicy{}, newEmptyStubLogClient, monitoring.InertMetricFactory{}, DistributorOptions{})
+	if err != nil {
+		t.Fatalf("NewDistributor() = _, %v", err)
+	}
+	var got []string
+	for logURL := range dist.logClients {
+		got = append(got, logURL)
+	}
+	// Usable, Qualified and Pending Logs only: read-only, retired and
+	// state-less Logs are skipped.
+	want := []string{
+		"https://ct.googleapis.com/icarus/",
+		"https://ct.googleapis.com/logs/argon2020/",
+		"https://ct.googleapis.com/rocketeer/",
+	}
+	if diff := cmp.Diff(want, got, cmpopts.SortSlices(func(x, y string) bool { return x < y })); diff != "" {
+		t.Errorf("NewDistributor() built clients for unexpected Logs: diff -want +got\n%s", diff)
+	}
+
+	// Temporal intervals are kept for temporally sharded Logs.
+	if l := dist.pendingQualifiedLl.FindLogByURL("https://ct.googleapis.com/logs/argon2020/"); l == nil || l.TemporalInterval == nil {
+		t.Errorf("Qualified Log argon2020 = %+v, want it present with a temporal interval", l)
+	}
+}
+
 func TestNewDistributorRootPools(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -200,7 +233,7 @@ func TestNewDistributorRootPools(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
-			dist, _ := NewDistributor(tc.ll, ctpolicy.ChromeCTPolicy{}, newLocalStubLogClient, monitoring.InertMetricFactory{})
+			dist, _ := NewDistributor(tc.ll, ctpolicy.ChromeCTPolicy{}, newLocalStubLogClient, monitoring.InertMetricFactory{}, DistributorOptions{})
 
 			if errs := dist.RefreshRoots(ctx); len(errs) != tc.wantErrs {
 				t.Errorf("dist.RefreshRoots() = %v, want %d errors", errs, tc.wantErrs)
@@ -219,6 +252,486 @@ func TestNewDistributorRootPools(t *testing.T) {
 	}
 }
 
+func TestDistributorRootsForLog(t *testing.T) {
+	metrics := newFakeSubmissionMetrics()
+	dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), newLocalStubLogClient, monitoring.InertMetricFactory{}, DistributorOptions{Metrics: metrics})
+	if err != nil {
+		t.Fatalf("NewDistributor() = _, %v", err)
+	}
+	const logURL = "https://ct.googleapis.com/rocketeer/"
+	if _, ok := dist.RootsForLog(logURL); ok {
+		t.Errorf("dist.RootsForLog(%q) before refresh = _, true, want _, false", logURL)
+	}
+	dist.RefreshRoots(context.Background())
+
+	for _, logURL := range []string{"https://ct.googleapis.com/rocketeer/", "https://ct.googleapis.com/icarus/"} {
+		pool, ok := dist.RootsForLog(logURL)
+		if !ok {
+			t.Fatalf("dist.RootsForLog(%q) = _, false, want _, true", logURL)
+		}
+		// The pool holds the roots served by the stub which parse.
+		lc, _ := newLocalStubLogClient(&loglist3.Log{URL: logURL})
+		served, err := lc.GetAcceptedRoots(context.Background())
+		if err != nil {
+			t.Fatalf("GetAcceptedRoots() = _, %v", err)
+		}
+		var want [][]byte
+		for _, root := range served {
+			if cert, err := x509.ParseCertificate(root.Data); !x509.IsFatal(err) {
+				want = append(want, cert.RawSubject)
+			}
+		}
+		if diff := cmp.Diff(want, pool.Subjects()); diff != "" {
+			t.Errorf("dist.RootsForLog(%q) subjects: diff -want +got\n%s", logURL, diff)
+		}
+		metrics.mu.Lock()
+		if metrics.roots[logURL].IsZero() {
+			t.Errorf("last get-roots success of %q not recorded", logURL)
+		}
+		metrics.mu.Unlock()
+	}
+
+	// The pool is a copy.
+	pool, _ := dist.RootsForLog(logURL)
+	pool.AddCert(&x509.Certificate{Raw: []byte("extra"), RawSubject: []byte("extra")})
+	if again, _ := dist.RootsForLog(logURL); len(again.Subjects()) != 4 {
+		t.Errorf("dist.RootsForLog(%q) = %d roots after modifying a previous result, want 4", logURL, len(again.Subjects()))
+	}
+	if _, ok := dist.RootsForLog("https://unknown.example.com/"); ok {
+		t.Error("dist.RootsForLog(unknown) = _, true, want _, false")
+	}
+}
+
+func TestRootPoolFor(t *testing.T) {
+	testCases := []struct {
+		name      string
+		logURL    string
+		lcBuilder LogClientBuilder
+		wantRoots int
+		wantErr   bool
+	}{
+		{
+			name:      "ValidRoots",
+			logURL:    "https://ct.googleapis.com/rocketeer/",
+			lcBuilder: newLocalStubLogClient,
+			wantRoots: 4,
+		},
+		{
+			name:      "InvalidRoot",
+			logURL:    "https://ct.googleapis.com/icarus/",
+			lcBuilder: newLocalStubLogClient,
+			wantRoots: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "NoRoots",
+			logURL:    "https://ct.googleapis.com/pilot/",
+			lcBuilder: newLocalStubLogClient,
+		},
+		{
+			name:      "RootsUnavailable",
+			logURL:    "https://ct.googleapis.com/rocketeer/",
+			lcBuilder: buildStubNoRootsLogClient,
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			log := &loglist3.Log{URL: tc.logURL}
+			lc, err := tc.lcBuilder(log)
+			if err != nil {
+				t.Fatalf("lcBuilder() = _, %v", err)
+			}
+			pool, err := RootPoolFor(context.Background(), log, lc)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("RootPoolFor() = _, %v, want err? %t", err, tc.wantErr)
+			}
+			if tc.wantErr && tc.wantRoots == 0 {
+				if pool != nil {
+					t.Errorf("RootPoolFor() = %v, _, want nil pool", pool)
+				}
+				return
+			}
+			if pool == nil {
+				t.Fatal("RootPoolFor() = nil, _, want pool")
+			}
+			if got := len(pool.Subjects()); got != tc.wantRoots {
+				t.Errorf("RootPoolFor() = pool of %d roots, want %d", got, tc.wantRoots)
+			}
+		})
+	}
+
+	// The pool can be used to pre-validate chains.
+	log := &loglist3.Log{URL: "https://ct.googleapis.com/rocketeer/"}
+	lc, _ := newLocalStubLogClient(log)
+	pool, err := RootPoolFor(context.Background(), log, lc)
+	if err != nil {
+		t.Fatalf("RootPoolFor() = _, %v", err)
+	}
+	if err := ValidateChainOrder(pemFileToDERChain("../trillian/testdata/subleaf.chain"), pool); err != nil {
+		t.Errorf("ValidateChainOrder() with RootPoolFor() roots = %v, want nil", err)
+	}
+}
+
+func TestDistributorAddPreChainVerbose(t *testing.T) {
+	root, err := x509.ParseCertificate(readCertFile("../trillian/testdata/fake-ca.cert"))
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() = _, %v", err)
+	}
+	testCases := []struct {
+		name            string
+		getRoots        bool
+		wantRootSubject string
+	}{
+		{name: "WithRoots", getRoots: true, wantRootSubject: root.Subject.String()},
+		{name: "WithoutRoots", getRoots: false, wantRootSubject: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), newLocalStubLogClient, monitoring.InertMetricFactory{}, DistributorOptions{})
+			if err != nil {
+				t.Fatalf("NewDistributor() = _, %v", err)
+			}
+			if tc.getRoots {
+				dist.RefreshRoots(context.Background())
+			}
+			res, err := dist.AddPreChainVerbose(context.Background(), pemFileToDERChain("../trillian/testdata/subleaf-pre.chain"), false /* loadPendingLogs */)
+			if err != nil {
+				t.Fatalf("dist.AddPreChainVerbose() = _, %v", err)
+			}
+			if res.RootSubject != tc.wantRootSubject {
+				t.Errorf("dist.AddPreChainVerbose().RootSubject = %q, want %q", res.RootSubject, tc.wantRootSubject)
+			}
+			if len(res.SCTs) == 0 {
+				t.Error("dist.AddPreChainVerbose() returned no SCTs")
+			}
+		})
+	}
+
+	dist, _ := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), newLocalStubLogClient, monitoring.InertMetricFactory{}, DistributorOptions{})
+	if res, err := dist.AddPreChainVerbose(context.Background(), nil, false); err == nil || res != nil {
+		t.Errorf("dist.AddPreChainVerbose(empty chain) = %v, %v, want nil, error", res, err)
+	}
+}
+
+// flakyRootsStubLogClient serves roots on its first get-roots request only,
+// and fails all the following ones.
+type flakyRootsStubLogClient struct {
+	stubLogClient
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (m *flakyRootsStubLogClient) GetAcceptedRoots(ctx context.Context) ([]ct.ASN1Cert, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	if m.calls > 1 {
+		return nil, errors.New("get-roots unavailable")
+	}
+	return m.stubLogClient.GetAcceptedRoots(ctx)
+}
+
+// countingRootsStubLogClient records the number of get-roots requests in
+// flight across all the Log clients sharing its counter.
+type countingRootsStubLogClient struct {
+	stubLogClient
+	counter *inFlightCounter
+}
+
+type inFlightCounter struct {
+	mu       sync.Mutex
+	inFlight int
+	max      int
+}
+
+func (m *countingRootsStubLogClient) GetAcceptedRoots(ctx context.Context) ([]ct.ASN1Cert, error) {
+	m.counter.mu.Lock()
+	m.counter.inFlight++
+	if m.counter.inFlight > m.counter.max {
+		m.counter.max = m.counter.inFlight
+	}
+	m.counter.mu.Unlock()
+	defer func() {
+		m.counter.mu.Lock()
+		m.counter.inFlight--
+		m.counter.mu.Unlock()
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	return m.stubLogClient.GetAcceptedRoots(ctx)
+}
+
+func TestDistributorRefreshRootsConcurrency(t *testing.T) {
+	testCases := []struct {
+		name    string
+		limit   int
+		wantMax int
+	}{
+		{name: "Limited", limit: 2, wantMax: 2},
+		{name: "Serial", limit: 1, wantMax: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			counter := &inFlightCounter{}
+			lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+				return &countingRootsStubLogClient{stubLogClient: stubLogClient{logURL: log.URL, rootsCerts: RootsCerts}, counter: counter}, nil
+			}
+			dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), lcBuilder, monitoring.InertMetricFactory{}, DistributorOptions{MaxConcurrentRefresh: tc.limit})
+			if err != nil {
+				t.Fatalf("NewDistributor() = _, %v", err)
+			}
+			if len(dist.logClients) <= tc.limit {
+				t.Fatalf("Got %d Log clients, want more than %d", len(dist.logClients), tc.limit)
+			}
+
+			// Errors are irrelevant here, the stub roots of some Logs don't parse.
+			dist.RefreshRoots(context.Background())
+			if counter.max > tc.limit {
+				t.Errorf("dist.RefreshRoots() made %d concurrent get-roots requests, want at most %d", counter.max, tc.limit)
+			}
+			if counter.max != tc.wantMax {
+				t.Errorf("dist.RefreshRoots() made %d concurrent get-roots requests, want %d", counter.max, tc.wantMax)
+			}
+		})
+	}
+}
+
+// fakeClock is a Clock whose time only moves when advanced. The duration of
+// each After call is reported on the afters channel.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+	afters  chan time.Duration
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, afters: make(chan time.Duration, 100)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+	} else {
+		c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	}
+	c.mu.Unlock()
+	c.afters <- d
+	return ch
+}
+
+// Advance moves the clock forward by d, and returns the number of After
+// channels which fired as a result.
+func (c *fakeClock) Advance(d time.Duration) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	var pending []fakeWaiter
+	fired := 0
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+		fired++
+	}
+	c.waiters = pending
+	return fired
+}
+
+func TestDistributorRunJitter(t *testing.T) {
+	const refresh = time.Hour
+	testCases := []struct {
+		name       string
+		opts       DistributorOptions
+		wantMin    time.Duration
+		wantMax    time.Duration
+		wantVaried bool
+	}{
+		{name: "Fixed", wantMin: refresh, wantMax: refresh},
+		{
+			name:       "Jitter",
+			opts:       DistributorOptions{RefreshJitter: 10 * time.Minute},
+			wantMin:    50 * time.Minute,
+			wantMax:    70 * time.Minute,
+			wantVaried: true,
+		},
+		{
+			name:       "JitterWithFloor",
+			opts:       DistributorOptions{RefreshJitter: 10 * time.Minute, MinRefreshInterval: 55 * time.Minute},
+			wantMin:    55 * time.Minute,
+			wantMax:    70 * time.Minute,
+			wantVaried: true,
+		},
+		{
+			name:    "FloorAboveRefresh",
+			opts:    DistributorOptions{RefreshJitter: 10 * time.Minute, MinRefreshInterval: 2 * time.Hour},
+			wantMin: 2 * time.Hour,
+			wantMax: 2 * time.Hour,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := newFakeClock(time.Unix(1640000000, 0))
+			opts := tc.opts
+			opts.Clock = clock
+			dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), newLocalStubLogClient, monitoring.InertMetricFactory{}, opts)
+			if err != nil {
+				t.Fatalf("NewDistributor() = _, %v", err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				dist.Run(ctx, refresh)
+				close(done)
+			}()
+
+			intervals := make(map[time.Duration]bool)
+			for i := 0; i < 20; i++ {
+				interval := <-clock.afters
+				if interval < tc.wantMin || interval > tc.wantMax {
+					t.Errorf("refresh %d scheduled %v after the previous one, want within [%v, %v]", i+1, interval, tc.wantMin, tc.wantMax)
+				}
+				intervals[interval] = true
+				clock.Advance(interval)
+			}
+			cancel()
+			<-done
+			if varied := len(intervals) > 1; varied != tc.wantVaried {
+				t.Errorf("refresh intervals %v varied: %t, want %t", intervals, varied, tc.wantVaried)
+			}
+		})
+	}
+}
+
+// countingStubLogClient counts the get-roots requests it receives.
+type countingStubLogClient struct {
+	stubLogClient
+	rootsCalls *int32
+}
+
+func (m countingStubLogClient) GetAcceptedRoots(ctx context.Context) ([]ct.ASN1Cert, error) {
+	atomic.AddInt32(m.rootsCalls, 1)
+	return m.stubLogClient.GetAcceptedRoots(ctx)
+}
+
+func TestDistributorRunFakeClock(t *testing.T) {
+	const logURL = "https://ct.googleapis.com/rocketeer/"
+	var rootsCalls int32
+	lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+		if log.URL == logURL {
+			return countingStubLogClient{stubLogClient: stubLogClient{logURL: log.URL, rootsCerts: RootsCerts}, rootsCalls: &rootsCalls}, nil
+		}
+		return newLocalStubLogClient(log)
+	}
+	clock := newFakeClock(time.Unix(1640000000, 0))
+	dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), lcBuilder, monitoring.InertMetricFactory{}, DistributorOptions{Clock: clock})
+	if err != nil {
+		t.Fatalf("NewDistributor() = _, %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dist.Run(ctx, time.Hour)
+		close(done)
+	}()
+
+	// Run refreshes roots straight away, then waits for the refresh interval.
+	if got, want := <-clock.afters, time.Hour; got != want {
+		t.Fatalf("dist.Run() waits %v between refreshes, want %v", got, want)
+	}
+	if got := atomic.LoadInt32(&rootsCalls); got != 1 {
+		t.Errorf("dist.Run() made %d get-roots requests before the first wait, want 1", got)
+	}
+	if _, ok := dist.RootsForLog(logURL); !ok {
+		t.Errorf("dist.RootsForLog(%q) = _, false after the first refresh, want _, true", logURL)
+	}
+	if fired := clock.Advance(59 * time.Minute); fired != 0 {
+		t.Errorf("dist.Run() woke up %d times before the refresh interval elapsed, want 0", fired)
+	}
+	if fired := clock.Advance(time.Minute); fired != 1 {
+		t.Errorf("dist.Run() woke up %d times once the refresh interval elapsed, want 1", fired)
+	}
+	<-clock.afters
+	if got := atomic.LoadInt32(&rootsCalls); got != 2 {
+		t.Errorf("dist.Run() made %d get-roots requests after one interval, want 2", got)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestDistributorRefreshRootsRetainsRoots(t *testing.T) {
+	const flakyLogURL = "https://ct.googleapis.com/rocketeer/"
+	testCases := []struct {
+		name      string
+		ttl       time.Duration
+		wait      time.Duration
+		wantRoots int
+	}{
+		{name: "NoTTL", wantRoots: 4},
+		{name: "WithinTTL", ttl: time.Hour, wantRoots: 4},
+		{name: "Expired", ttl: time.Hour, wait: 2 * time.Hour, wantRoots: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+				if log.URL == flakyLogURL {
+					return &flakyRootsStubLogClient{stubLogClient: stubLogClient{logURL: log.URL, rootsCerts: RootsCerts}}, nil
+				}
+				return newLocalStubLogClient(log)
+			}
+			clock := newFakeClock(time.Unix(1640000000, 0))
+			dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), lcBuilder, monitoring.InertMetricFactory{}, DistributorOptions{RootsTTL: tc.ttl, Clock: clock})
+			if err != nil {
+				t.Fatalf("NewDistributor() = _, %v", err)
+			}
+			ctx := context.Background()
+
+			if errs := dist.RefreshRoots(ctx); errs[flakyLogURL] != nil {
+				t.Fatalf("dist.RefreshRoots() = %v, want no error for %s", errs, flakyLogURL)
+			}
+			clock.Advance(tc.wait)
+			if errs := dist.RefreshRoots(ctx); errs[flakyLogURL] == nil {
+				t.Fatalf("dist.RefreshRoots() = %v, want error for %s", errs, flakyLogURL)
+			}
+
+			gotRoots := 0
+			if roots, ok := dist.logRoots[flakyLogURL]; ok {
+				gotRoots = len(roots.RawCertificates())
+			}
+			if gotRoots != tc.wantRoots {
+				t.Errorf("After failed refresh got %d root(s) for Log %s, want %d", gotRoots, flakyLogURL, tc.wantRoots)
+			}
+			// fake-ca is accepted by the flaky Log only.
+			fakeCA, err := x509.ParseCertificate(readCertFile("../trillian/testdata/fake-ca.cert"))
+			if err != nil {
+				t.Fatalf("Failed to parse fake-ca.cert: %v", err)
+			}
+			if got, want := dist.rootPool.Included(fakeCA), tc.wantRoots > 0; got != want {
+				t.Errorf("After failed refresh root pool includes fake-ca: %t, want %t", got, want)
+			}
+		})
+	}
+}
+
 func pemFileToDERChain(filename string) [][]byte {
 	if len(filename) == 0 {
 		return nil
@@ -303,8 +816,10 @@ func TestDistributorAddChain(t *testing.T) {
 			getRoots:     true,
 			scts: []*AssignedSCT{
 				{
-					LogURL: "https://ct.googleapis.com/rocketeer/",
-					SCT:    testSCT("https://ct.googleapis.com/rocketeer/"),
+					LogURL:   "https://ct.googleapis.com/rocketeer/",
+					SCT:      testSCT("https://ct.googleapis.com/rocketeer/"),
+					Operator: "Google",
+					LogState: loglist3.UsableLogStatus,
 				},
 			},
 			wantErr: false,
@@ -314,7 +829,7 @@ func TestDistributorAddChain(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			dist, _ := NewDistributor(tc.ll, tc.plc, newLocalStubLogClient, monitoring.InertMetricFactory{})
+			dist, _ := NewDistributor(tc.ll, tc.plc, newLocalStubLogClient, monitoring.InertMetricFactory{}, DistributorOptions{})
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 			defer cancel()
 
@@ -329,14 +844,13 @@ func TestDistributorAddChain(t *testing.T) {
 
 			if gotErr := err != nil; gotErr != tc.wantErr {
 				t.Fatalf("dist.AddChain(from %q) = (_, error: %v), want err? %t", tc.pemChainFile, err, tc.wantErr)
-			} else if gotErr {
-				return
 			}
 
+			// SCTs collected are emitted even if the policy is not satisfied.
 			if got, want := len(scts), len(tc.scts); got != want {
 				t.Errorf("dist.AddChain(from %q) = %d SCTs, want %d SCTs", tc.pemChainFile, got, want)
 			}
-			if diff := cmp.Diff(scts, tc.scts, cmpopts.SortSlices(func(x, y *AssignedSCT) bool {
+			if diff := cmp.Diff(scts, tc.scts, cmpopts.EquateEmpty(), cmpopts.SortSlices(func(x, y *AssignedSCT) bool {
 				return x.LogURL < y.LogURL
 			})); diff != "" {
 				t.Errorf("dist.AddChain(from %q): diff -want +got\n%s", tc.pemChainFile, diff)
@@ -399,8 +913,10 @@ func TestDistributorAddPreChain(t *testing.T) {
 			getRoots:     true,
 			scts: []*AssignedSCT{
 				{
-					LogURL: "https://ct.googleapis.com/rocketeer/",
-					SCT:    testSCT("https://ct.googleapis.com/rocketeer/"),
+					LogURL:   "https://ct.googleapis.com/rocketeer/",
+					SCT:      testSCT("https://ct.googleapis.com/rocketeer/"),
+					Operator: "Google",
+					LogState: loglist3.UsableLogStatus,
 				},
 			},
 			wantErr: false,
@@ -410,7 +926,7 @@ func TestDistributorAddPreChain(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			dist, _ := NewDistributor(tc.ll, tc.plc, newLocalStubLogClient, monitoring.InertMetricFactory{})
+			dist, _ := NewDistributor(tc.ll, tc.plc, newLocalStubLogClient, monitoring.InertMetricFactory{}, DistributorOptions{})
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 			defer cancel()
 
@@ -425,14 +941,13 @@ func TestDistributorAddPreChain(t *testing.T) {
 
 			if gotErr := err != nil; gotErr != tc.wantErr {
 				t.Fatalf("dist.AddPreChain(from %q) = (_, error: %v), want err? %t", tc.pemChainFile, err, tc.wantErr)
-			} else if gotErr {
-				return
 			}
 
+			// SCTs collected are emitted even if the policy is not satisfied.
 			if got, want := len(scts), len(tc.scts); got != want {
 				t.Errorf("dist.AddPreChain(from %q) = %d SCTs, want %d SCTs", tc.pemChainFile, got, want)
 			}
-			if diff := cmp.Diff(scts, tc.scts, cmpopts.SortSlices(func(x, y *AssignedSCT) bool {
+			if diff := cmp.Diff(scts, tc.scts, cmpopts.EquateEmpty(), cmpopts.SortSlices(func(x, y *AssignedSCT) bool {
 				return x.LogURL < y.LogURL
 			})); diff != "" {
 				t.Errorf("dist.AddPreChain(from %q): diff -want +got\n%s", tc.pemChainFile, diff)
@@ -441,66 +956,1000 @@ func TestDistributorAddPreChain(t *testing.T) {
 	}
 }
 
-func TestDistributorAddTypeMismatch(t *testing.T) {
+func TestDistributorAddPEMChain(t *testing.T) {
+	readPEM := func(filename string) string {
+		t.Helper()
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("failed to read %q: %v", filename, err)
+		}
+		return string(data)
+	}
+	rocketeerSCT := []*AssignedSCT{
+		{
+			LogURL:   "https://ct.googleapis.com/rocketeer/",
+			SCT:      testSCT("https://ct.googleapis.com/rocketeer/"),
+			Operator: "Google",
+			LogState: loglist3.UsableLogStatus,
+		},
+	}
+
 	testCases := []struct {
-		name         string
-		asPreChain   bool
-		pemChainFile string
-		scts         []*AssignedSCT
-		wantErr      bool
+		name       string
+		pemChain   string
+		asPreChain bool
+		scts       []*AssignedSCT
+		wantErr    bool
 	}{
 		{
-			name:         "FullChain1PolicyCertToPreAdd",
-			asPreChain:   true,
-			pemChainFile: "../trillian/testdata/subleaf.chain",
-			scts:         nil,
-			wantErr:      true, // Sending valid cert via AddPreChain call
+			name:     "Chain",
+			pemChain: readPEM("../trillian/testdata/subleaf.chain"),
+			scts:     rocketeerSCT,
 		},
 		{
-			name:         "FullChain1PolicyPreCertToAdd",
-			asPreChain:   false,
-			pemChainFile: "../trillian/testdata/subleaf-pre.chain",
-			scts:         nil,
-			wantErr:      true, // Sending pre-cert via AddChain call
+			name:       "PreChain",
+			pemChain:   readPEM("../trillian/testdata/subleaf-pre.chain"),
+			asPreChain: true,
+			scts:       rocketeerSCT,
+		},
+		{
+			name:       "TypeMismatch",
+			pemChain:   readPEM("../trillian/testdata/subleaf.chain"),
+			asPreChain: true,
+			wantErr:    true,
+		},
+		{
+			name:     "Garbage",
+			pemChain: "garbage",
+			wantErr:  true,
 		},
 	}
-
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			dist, _ := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), newLocalStubLogClient, monitoring.InertMetricFactory{})
+			dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), newLocalStubLogClient, monitoring.InertMetricFactory{}, DistributorOptions{})
+			if err != nil {
+				t.Fatalf("NewDistributor() = _, %v", err)
+			}
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 			defer cancel()
+			dist.RefreshRoots(ctx)
 
-			if errs := dist.RefreshRoots(ctx); len(errs) != 1 || errs["https://ct.googleapis.com/icarus/"] == nil {
-				// 1 error is expected, because the Icarus log has an invalid root (see RootCerts).
-				t.Fatalf("dist.RefreshRoots() = %v, want 1 error for 'https://ct.googleapis.com/icarus/'", errs)
-			}
-
-			var scts []*AssignedSCT
-			var err error
-			if tc.asPreChain {
-				scts, err = dist.AddPreChain(context.Background(), pemFileToDERChain(tc.pemChainFile), false /* loadPendingLogs */)
-			} else {
-				scts, err = dist.AddChain(context.Background(), pemFileToDERChain(tc.pemChainFile), false /* loadPendingLogs */)
-			}
-
-			pre := ""
-			if tc.asPreChain {
-				pre = "Pre"
-			}
+			scts, err := dist.AddPEMChain(ctx, tc.pemChain, tc.asPreChain, false /* loadPendingLogs */)
 			if gotErr := err != nil; gotErr != tc.wantErr {
-				t.Fatalf("dist.Add%sChain(from %q) = (_, error: %v), want err? %t", pre, tc.pemChainFile, err, tc.wantErr)
-			} else if gotErr {
-				return
+				t.Fatalf("dist.AddPEMChain() = (_, error: %v), want err? %t", err, tc.wantErr)
 			}
-
-			if got, want := len(scts), len(tc.scts); got != want {
-				t.Errorf("dist.Add%sChain(from %q) = %d SCTs, want %d SCTs", pre, tc.pemChainFile, got, want)
+			if diff := cmp.Diff(scts, tc.scts, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("dist.AddPEMChain(): diff -want +got\n%s", diff)
 			}
-			if diff := cmp.Diff(scts, tc.scts, cmpopts.SortSlices(func(x, y *AssignedSCT) bool {
-				return x.LogURL < y.LogURL
-			})); diff != "" {
-				t.Errorf("dist.Add%sChain(from %q): diff -want +got\n%s", pre, tc.pemChainFile, diff)
+		})
+	}
+}
+
+func TestDistributorAddPreChainToLogs(t *testing.T) {
+	const (
+		rocketeer = "https://ct.googleapis.com/rocketeer/"
+		icarus    = "https://ct.googleapis.com/icarus/"
+		unknown   = "https://ct.example.com/unknown/"
+	)
+	sct := func(logURL string) *AssignedSCT {
+		return &AssignedSCT{LogURL: logURL, SCT: testSCT(logURL), Operator: "Google", LogState: loglist3.UsableLogStatus}
+	}
+	tests := []struct {
+		name         string
+		pemChainFile string
+		logURLs      []string
+		getRoots     bool
+		want         []*AssignedSCT
+		wantLogErrs  map[string]string
+		wantErr      bool
+	}{
+		{
+			name:         "Subset",
+			pemChainFile: "../trillian/testdata/subleaf-pre.chain",
+			logURLs:      []string{rocketeer},
+			getRoots:     true,
+			want:         []*AssignedSCT{sct(rocketeer)},
+		},
+		{
+			name:         "Duplicates",
+			pemChainFile: "../trillian/testdata/subleaf-pre.chain",
+			logURLs:      []string{rocketeer, rocketeer},
+			getRoots:     true,
+			want:         []*AssignedSCT{sct(rocketeer)},
+		},
+		{
+			name:         "UnknownLog",
+			pemChainFile: "../trillian/testdata/subleaf-pre.chain",
+			logURLs:      []string{rocketeer, unknown},
+			getRoots:     true,
+			want:         []*AssignedSCT{sct(rocketeer)},
+			wantLogErrs:  map[string]string{unknown: "no client registered"},
+		},
+		{
+			name:         "RootNotAccepted",
+			pemChainFile: "../trillian/testdata/subleaf-pre.chain",
+			logURLs:      []string{icarus, rocketeer},
+			getRoots:     true,
+			want:         []*AssignedSCT{sct(rocketeer)},
+			wantLogErrs:  map[string]string{icarus: "root of the chain not accepted"},
+		},
+		{
+			name:         "RootsNotFetched",
+			pemChainFile: "../trillian/testdata/subleaf-pre.chain",
+			logURLs:      []string{icarus},
+			want:         []*AssignedSCT{sct(icarus)},
+		},
+		{
+			name:         "NoLogs",
+			pemChainFile: "../trillian/testdata/subleaf-pre.chain",
+			getRoots:     true,
+			wantErr:      true,
+		},
+		{
+			name:         "MalformedChain",
+			pemChainFile: "../trillian/testdata/subleaf-pre.misordered.chain",
+			logURLs:      []string{rocketeer},
+			getRoots:     true,
+			wantErr:      true,
+		},
+		{
+			name:         "NotPrecert",
+			pemChainFile: "../trillian/testdata/subleaf.chain",
+			logURLs:      []string{rocketeer},
+			getRoots:     true,
+			wantErr:      true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), newLocalStubLogClient, monitoring.InertMetricFactory{}, DistributorOptions{})
+			if err != nil {
+				t.Fatalf("NewDistributor() = _, %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if tc.getRoots {
+				dist.RefreshRoots(ctx)
+			}
+
+			scts, logErrs, err := dist.AddPreChainToLogs(ctx, pemFileToDERChain(tc.pemChainFile), tc.logURLs)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("dist.AddPreChainToLogs() = _, _, %v, want err? %t", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, scts, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("dist.AddPreChainToLogs(): diff -want +got\n%s", diff)
+			}
+			if len(logErrs) != len(tc.wantLogErrs) {
+				t.Errorf("dist.AddPreChainToLogs() = _, %v, _, want errors for %v", logErrs, tc.wantLogErrs)
+			}
+			for logURL, want := range tc.wantLogErrs {
+				if got := logErrs[logURL]; got == nil || !strings.Contains(got.Error(), want) {
+					t.Errorf("dist.AddPreChainToLogs() error for %s = %v, want %q", logURL, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDistributorAddTypeMismatch(t *testing.T) {
+	testCases := []struct {
+		name         string
+		asPreChain   bool
+		pemChainFile string
+		scts         []*AssignedSCT
+		wantErr      bool
+	}{
+		{
+			name:         "FullChain1PolicyCertToPreAdd",
+			asPreChain:   true,
+			pemChainFile: "../trillian/testdata/subleaf.chain",
+			scts:         nil,
+			wantErr:      true, // Sending valid cert via AddPreChain call
+		},
+		{
+			name:         "FullChain1PolicyPreCertToAdd",
+			asPreChain:   false,
+			pemChainFile: "../trillian/testdata/subleaf-pre.chain",
+			scts:         nil,
+			wantErr:      true, // Sending pre-cert via AddChain call
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dist, _ := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), newLocalStubLogClient, monitoring.InertMetricFactory{}, DistributorOptions{})
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			if errs := dist.RefreshRoots(ctx); len(errs) != 1 || errs["https://ct.googleapis.com/icarus/"] == nil {
+				// 1 error is expected, because the Icarus log has an invalid root (see RootCerts).
+				t.Fatalf("dist.RefreshRoots() = %v, want 1 error for 'https://ct.googleapis.com/icarus/'", errs)
+			}
+
+			var scts []*AssignedSCT
+			var err error
+			if tc.asPreChain {
+				scts, err = dist.AddPreChain(context.Background(), pemFileToDERChain(tc.pemChainFile), false /* loadPendingLogs */)
+			} else {
+				scts, err = dist.AddChain(context.Background(), pemFileToDERChain(tc.pemChainFile), false /* loadPendingLogs */)
+			}
+
+			pre := ""
+			if tc.asPreChain {
+				pre = "Pre"
+			}
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("dist.Add%sChain(from %q) = (_, error: %v), want err? %t", pre, tc.pemChainFile, err, tc.wantErr)
+			} else if gotErr {
+				return
+			}
+
+			if got, want := len(scts), len(tc.scts); got != want {
+				t.Errorf("dist.Add%sChain(from %q) = %d SCTs, want %d SCTs", pre, tc.pemChainFile, got, want)
+			}
+			if diff := cmp.Diff(scts, tc.scts, cmpopts.SortSlices(func(x, y *AssignedSCT) bool {
+				return x.LogURL < y.LogURL
+			})); diff != "" {
+				t.Errorf("dist.Add%sChain(from %q): diff -want +got\n%s", pre, tc.pemChainFile, diff)
+			}
+		})
+	}
+}
+
+func TestDistributorPartialSCTs(t *testing.T) {
+	const failingLogURL = "https://ct.googleapis.com/icarus/"
+	const okLogURL = "https://ct.googleapis.com/rocketeer/"
+	lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+		if log.URL == failingLogURL {
+			return newEmptyStubLogClient(log)
+		}
+		return newLocalStubLogClient(log)
+	}
+	// No roots are fetched, so both usable Logs are treated as compatible.
+	dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(2), lcBuilder, monitoring.InertMetricFactory{}, DistributorOptions{})
+	if err != nil {
+		t.Fatalf("NewDistributor() = _, %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	scts, err := dist.AddPreChain(ctx, pemFileToDERChain("../trillian/testdata/subleaf-pre.chain"), false /* loadPendingLogs */)
+	var policyErr *PolicyNotSatisfiedError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("dist.AddPreChain() = _, %v, want PolicyNotSatisfiedError", err)
+	}
+	if got, want := policyErr.Obtained, 1; got != want {
+		t.Errorf("PolicyNotSatisfiedError.Obtained = %d, want %d", got, want)
+	}
+	if got, want := policyErr.Required, 2; got != want {
+		t.Errorf("PolicyNotSatisfiedError.Required = %d, want %d", got, want)
+	}
+	want := []*AssignedSCT{{LogURL: okLogURL, SCT: testSCT(okLogURL), Operator: "Google", LogState: loglist3.UsableLogStatus}}
+	if diff := cmp.Diff(scts, want); diff != "" {
+		t.Errorf("dist.AddPreChain(): diff -want +got\n%s", diff)
+	}
+}
+
+func TestDistributorSubmitToLogMetrics(t *testing.T) {
+	const failingLogURL = "https://ct.googleapis.com/icarus/"
+	const okLogURL = "https://ct.googleapis.com/rocketeer/"
+	lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+		if log.URL == failingLogURL {
+			return newEmptyStubLogClient(log)
+		}
+		return newLocalStubLogClient(log)
+	}
+	metrics := newFakeSubmissionMetrics()
+	dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), lcBuilder, monitoring.InertMetricFactory{}, DistributorOptions{Metrics: metrics})
+	if err != nil {
+		t.Fatalf("NewDistributor() = _, %v", err)
+	}
+	chain := []ct.ASN1Cert{{Data: []byte{0}}}
+	const ep = "AddPreChain"
+
+	for _, tc := range []struct {
+		logURL   string
+		wantErr  bool
+		status   string
+		errLabel string
+	}{
+		{logURL: okLogURL, status: "200"},
+		{logURL: failingLogURL, wantErr: true, status: "400", errLabel: "unknown_error"},
+	} {
+		t.Run(tc.logURL, func(t *testing.T) {
+			_, err := dist.SubmitToLog(context.Background(), tc.logURL, chain, true)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("SubmitToLog(%q) = _, %v, want err? %t", tc.logURL, err, tc.wantErr)
+			}
+
+			metrics.mu.Lock()
+			defer metrics.mu.Unlock()
+			if got, want := metrics.requests[metricKey{tc.logURL, ep, ""}], 1; got != want {
+				t.Errorf("requests = %d, want %d", got, want)
+			}
+			if got, want := metrics.responses[metricKey{tc.logURL, ep, tc.status}], 1; got != want {
+				t.Errorf("responses{status=%s} = %d, want %d", tc.status, got, want)
+			}
+			if got, want := len(metrics.latencies[metricKey{tc.logURL, ep, ""}]), 1; got != want {
+				t.Errorf("latency observations = %d, want %d", got, want)
+			}
+			wantErrs := make(map[metricKey]int)
+			if tc.errLabel != "" {
+				wantErrs[metricKey{tc.logURL, ep, tc.errLabel}] = 1
+			}
+			if diff := cmp.Diff(wantErrs, metrics.errors, cmp.AllowUnexported(metricKey{})); diff != "" {
+				t.Errorf("errors: diff -want +got\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDistributorLogPriority(t *testing.T) {
+	const preferredLogURL = "https://ct.googleapis.com/icarus/"
+	dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), newLocalStubLogClient, monitoring.InertMetricFactory{}, DistributorOptions{
+		LogPriority: map[string]int{preferredLogURL: 10},
+	})
+	if err != nil {
+		t.Fatalf("NewDistributor() = _, %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	want := []*AssignedSCT{{LogURL: preferredLogURL, SCT: testSCT(preferredLogURL), Operator: "Google", LogState: loglist3.UsableLogStatus}}
+	for i := 0; i < 20; i++ {
+		// Without roots info every usable Log is compatible with the chain.
+		scts, err := dist.AddChain(ctx, pemFileToDERChain("../trillian/testdata/subleaf.chain"), false /* loadPendingLogs */)
+		if err != nil {
+			t.Fatalf("dist.AddChain() = _, %v", err)
+		}
+		if diff := cmp.Diff(scts, want); diff != "" {
+			t.Fatalf("dist.AddChain(): diff -want +got\n%s", diff)
+		}
+	}
+}
+
+// flakyStubLogClient fails its first few add-(pre-)chain requests with the
+// given error, or by hanging until their context is done, and counts all
+// requests.
+type flakyStubLogClient struct {
+	stubLogClient
+	failures int
+	err      error
+	hang     bool
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (m *flakyStubLogClient) call(ctx context.Context, chain []ct.ASN1Cert) (*ct.SignedCertificateTimestamp, error) {
+	m.mu.Lock()
+	m.calls++
+	calls := m.calls
+	m.mu.Unlock()
+	if calls <= m.failures {
+		if m.hang {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return nil, m.err
+	}
+	return m.stubLogClient.AddChain(ctx, chain)
+}
+
+func (m *flakyStubLogClient) AddChain(ctx context.Context, chain []ct.ASN1Cert) (*ct.SignedCertificateTimestamp, error) {
+	return m.call(ctx, chain)
+}
+
+func (m *flakyStubLogClient) AddPreChain(ctx context.Context, chain []ct.ASN1Cert) (*ct.SignedCertificateTimestamp, error) {
+	return m.call(ctx, chain)
+}
+
+func TestDistributorSCTCache(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts DistributorOptions
+		// secondPending is the loadPendingLogs of the second submission.
+		secondPending bool
+		wantCalls     int
+	}{
+		{name: "Disabled", wantCalls: 2},
+		{name: "Hit", opts: DistributorOptions{SCTCacheSize: 10, SCTCacheTTL: time.Hour}, wantCalls: 1},
+		{name: "Expired", opts: DistributorOptions{SCTCacheSize: 10, SCTCacheTTL: time.Nanosecond}, wantCalls: 2},
+		{name: "OtherLoadPendingLogs", opts: DistributorOptions{SCTCacheSize: 10, SCTCacheTTL: time.Hour}, secondPending: true, wantCalls: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var lc *flakyStubLogClient
+			var mu sync.Mutex
+			lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				// Only the first Log is usable, so every submission reaches it.
+				if lc != nil {
+					return newEmptyStubLogClient(log)
+				}
+				lc = &flakyStubLogClient{stubLogClient: stubLogClient{logURL: log.URL, rootsCerts: RootsCerts}}
+				return lc, nil
+			}
+			dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), lcBuilder, monitoring.InertMetricFactory{}, tc.opts)
+			if err != nil {
+				t.Fatalf("NewDistributor() = _, %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			chain := pemFileToDERChain("../trillian/testdata/subleaf.chain")
+			first, err := dist.AddChain(ctx, chain, false /* loadPendingLogs */)
+			if err != nil {
+				t.Fatalf("dist.AddChain() = _, %v", err)
+			}
+			time.Sleep(time.Millisecond)
+			second, err := dist.AddChain(ctx, chain, tc.secondPending)
+			if err != nil {
+				t.Fatalf("dist.AddChain() = _, %v", err)
+			}
+			if diff := cmp.Diff(second, first); diff != "" {
+				t.Errorf("dist.AddChain(): diff -want +got\n%s", diff)
+			}
+			lc.mu.Lock()
+			defer lc.mu.Unlock()
+			if lc.calls != tc.wantCalls {
+				t.Errorf("Log got %d add-chain request(s), want %d", lc.calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDistributorRetry(t *testing.T) {
+	const logURL = "https://ct.googleapis.com/rocketeer/"
+	retry := RetryConfig{MaxAttempts: 5, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond, Jitter: true}
+	testCases := []struct {
+		name    string
+		retry   RetryConfig
+		err     error
+		hang    bool
+		timeout time.Duration
+		// The LogClient retries transport errors and HTTP 408, 429 and 503
+		// responses itself until its context is done, so the Distributor
+		// gets other HTTP 5xx responses and per-Log timeouts to retry.
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "ServerErrorRetried",
+			retry:     retry,
+			err:       client.RspError{StatusCode: http.StatusInternalServerError, Err: &client.ServerError{StatusCode: http.StatusInternalServerError}},
+			wantCalls: 3,
+		},
+		{
+			name:      "PerLogTimeoutRetried",
+			retry:     retry,
+			hang:      true,
+			timeout:   10 * time.Millisecond,
+			wantCalls: 3,
+		},
+		{
+			name:      "PerLogTimeoutNotRetried",
+			hang:      true,
+			timeout:   10 * time.Millisecond,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "ClientErrorNotRetried",
+			retry:     retry,
+			err:       client.RspError{StatusCode: http.StatusBadRequest, Err: errors.New("bad chain")},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "TypedServerErrorRetried",
+			retry:     retry,
+			err:       client.RspError{StatusCode: http.StatusBadGateway, Err: &client.ServerError{StatusCode: http.StatusBadGateway}},
+			wantCalls: 3,
+		},
+		{
+			name:      "TooManyRequestsRetried",
+			retry:     retry,
+			err:       client.RspError{StatusCode: http.StatusTooManyRequests, Err: &client.ClientError{StatusCode: http.StatusTooManyRequests}},
+			wantCalls: 3,
+		},
+		{
+			name:      "TypedClientErrorNotRetried",
+			retry:     retry,
+			err:       client.RspError{StatusCode: http.StatusBadRequest, Err: &client.ClientError{StatusCode: http.StatusBadRequest}},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "RetriesDisabled",
+			err:       client.RspError{StatusCode: http.StatusInternalServerError, Err: errors.New("internal")},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "AttemptsExhausted",
+			retry:     RetryConfig{MaxAttempts: 2},
+			err:       client.RspError{StatusCode: http.StatusInternalServerError, Err: errors.New("internal")},
+			wantCalls: 2,
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lc := &flakyStubLogClient{
+				stubLogClient: stubLogClient{logURL: logURL, rootsCerts: RootsCerts},
+				failures:      2,
+				err:           tc.err,
+				hang:          tc.hang,
+			}
+			lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+				if log.URL == logURL {
+					return lc, nil
+				}
+				return newLocalStubLogClient(log)
+			}
+			metrics := newFakeSubmissionMetrics()
+			dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), lcBuilder, monitoring.InertMetricFactory{}, DistributorOptions{Retry: tc.retry, PerLogTimeout: tc.timeout, Metrics: metrics})
+			if err != nil {
+				t.Fatalf("NewDistributor() = _, %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			sct, err := dist.SubmitToLog(ctx, logURL, []ct.ASN1Cert{{Data: []byte{0}}}, true)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("SubmitToLog() = _, %v, want err? %t", err, tc.wantErr)
+			}
+			if !tc.wantErr && sct == nil {
+				t.Error("SubmitToLog() returned no SCT")
+			}
+			if lc.calls != tc.wantCalls {
+				t.Errorf("SubmitToLog() made %d requests, want %d", lc.calls, tc.wantCalls)
+			}
+			// Latency is observed per request, not per retry loop.
+			if got, _ := metrics.totalLatency(logURL, "AddPreChain"); got != tc.wantCalls {
+				t.Errorf("latency observations = %d, want %d", got, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDistributorPerLogQPS(t *testing.T) {
+	const qps = 10
+	const limitedURL = "https://ct.googleapis.com/rocketeer/"
+	const otherURL = "https://ct.googleapis.com/icarus/"
+	lcs := make(map[string]*flakyStubLogClient)
+	lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+		lc := &flakyStubLogClient{stubLogClient: stubLogClient{logURL: log.URL, rootsCerts: RootsCerts}}
+		lcs[log.URL] = lc
+		return lc, nil
+	}
+	metrics := newFakeSubmissionMetrics()
+	dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), lcBuilder, monitoring.InertMetricFactory{}, DistributorOptions{PerLogQPS: qps, Metrics: metrics})
+	if err != nil {
+		t.Fatalf("NewDistributor() = _, %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	chain := []ct.ASN1Cert{{Data: []byte{0}}}
+
+	// The first request goes through at once, then the following ones are
+	// spaced out by 1/qps seconds.
+	const n = 4
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		if _, err := dist.SubmitToLog(ctx, limitedURL, chain, false); err != nil {
+			t.Fatalf("SubmitToLog(%q) #%d = _, %v", limitedURL, i, err)
+		}
+	}
+	if elapsed, want := time.Since(start), (n-1)*time.Second/qps; elapsed < want*8/10 {
+		t.Errorf("%d SubmitToLog(%q) took %v, want at least ~%v", n, limitedURL, elapsed, want)
+	}
+	if got := lcs[limitedURL].calls; got != n {
+		t.Errorf("SubmitToLog(%q) made %d requests, want %d", limitedURL, got, n)
+	}
+	// Waiting for the limit is not part of the Log's latency.
+	if _, latency := metrics.totalLatency(limitedURL, "AddChain"); latency > 50*time.Millisecond {
+		t.Errorf("latency observed = %v, want the rate limit waits left out", latency)
+	}
+
+	// Other Logs have limiters of their own.
+	start = time.Now()
+	if _, err := dist.SubmitToLog(ctx, otherURL, chain, true); err != nil {
+		t.Fatalf("SubmitToLog(%q) = _, %v", otherURL, err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("SubmitToLog(%q) took %v, want it unaffected by the limit of %q", otherURL, elapsed, limitedURL)
+	}
+
+	// A request which can't get through in time fails without reaching the Log.
+	shortCtx, shortCancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer shortCancel()
+	if _, err := dist.SubmitToLog(shortCtx, limitedURL, chain, false); err == nil {
+		t.Errorf("SubmitToLog(%q) past deadline = _, nil, want error", limitedURL)
+	}
+	if got := lcs[limitedURL].calls; got != n {
+		t.Errorf("SubmitToLog(%q) past deadline made %d requests, want %d", limitedURL, got, n)
+	}
+}
+
+// closableStubLogClient blocks get-roots requests until their context is done,
+// and counts the calls to CloseIdleConnections.
+type closableStubLogClient struct {
+	stubLogClient
+	started  chan<- struct{}
+	inFlight *int32
+	closed   int32
+}
+
+func (m *closableStubLogClient) GetAcceptedRoots(ctx context.Context) ([]ct.ASN1Cert, error) {
+	atomic.AddInt32(m.inFlight, 1)
+	defer atomic.AddInt32(m.inFlight, -1)
+	m.started <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (m *closableStubLogClient) CloseIdleConnections() {
+	atomic.AddInt32(&m.closed, 1)
+}
+
+func TestDistributorClose(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	var inFlight int32
+	started := make(chan struct{}, 10)
+	var lcs []*closableStubLogClient
+	lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+		lc := &closableStubLogClient{stubLogClient: stubLogClient{logURL: log.URL}, started: started, inFlight: &inFlight}
+		lcs = append(lcs, lc)
+		return lc, nil
+	}
+	dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), lcBuilder, monitoring.InertMetricFactory{}, DistributorOptions{})
+	if err != nil {
+		t.Fatalf("NewDistributor() = _, %v", err)
+	}
+
+	runDone := make(chan struct{})
+	go func() {
+		defer close(runDone)
+		dist.Run(context.Background(), time.Hour)
+	}()
+	// Wait for the roots refresh to be in flight for every Log.
+	for range lcs {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run() didn't start refreshing roots")
+		}
+	}
+
+	dist.Close()
+	if n := atomic.LoadInt32(&inFlight); n != 0 {
+		t.Errorf("Close() returned with %d get-roots request(s) in flight, want none", n)
+	}
+	select {
+	case <-runDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() still running after Close()")
+	}
+	for _, lc := range lcs {
+		if got := atomic.LoadInt32(&lc.closed); got != 1 {
+			t.Errorf("Close() closed idle connections of %s %d time(s), want 1", lc.logURL, got)
+		}
+	}
+
+	// Closing again is a no-op, and Run returns at once.
+	dist.Close()
+	dist.Run(context.Background(), time.Hour)
+	for _, lc := range lcs {
+		if got := atomic.LoadInt32(&lc.closed); got != 1 {
+			t.Errorf("second Close() closed idle connections of %s, want no-op", lc.logURL)
+		}
+	}
+
+	// All the goroutines of the Distributor are gone.
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutine(s) leaked after Close():\n%s", runtime.NumGoroutine()-baseline, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// slowStubLogClient delays add-(pre-)chain responses until either the delay
+// passes or the request context is done.
+type slowStubLogClient struct {
+	stubLogClient
+	delay time.Duration
+}
+
+func (m slowStubLogClient) AddChain(ctx context.Context, chain []ct.ASN1Cert) (*ct.SignedCertificateTimestamp, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(m.delay):
+	}
+	return m.stubLogClient.AddChain(ctx, chain)
+}
+
+func (m slowStubLogClient) AddPreChain(ctx context.Context, chain []ct.ASN1Cert) (*ct.SignedCertificateTimestamp, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(m.delay):
+	}
+	return m.stubLogClient.AddPreChain(ctx, chain)
+}
+
+func TestDistributorPerLogTimeout(t *testing.T) {
+	const slowLogURL = "https://ct.googleapis.com/icarus/"
+	const fastLogURL = "https://ct.googleapis.com/rocketeer/"
+	lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+		lc := stubLogClient{logURL: log.URL, rootsCerts: RootsCerts}
+		if log.URL == slowLogURL {
+			return slowStubLogClient{stubLogClient: lc, delay: time.Minute}, nil
+		}
+		return lc, nil
+	}
+	dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), lcBuilder, monitoring.InertMetricFactory{}, DistributorOptions{PerLogTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewDistributor() = _, %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dist.RefreshRoots(ctx)
+
+	chain := pemFileToDERChain("../trillian/testdata/subleaf-pre.chain")
+	asn1Chain := make([]ct.ASN1Cert, len(chain))
+	for i, c := range chain {
+		asn1Chain[i] = ct.ASN1Cert{Data: c}
+	}
+	start := time.Now()
+	if _, err := dist.SubmitToLog(ctx, slowLogURL, asn1Chain, true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SubmitToLog(%q) = _, %v, want %v", slowLogURL, err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("SubmitToLog(%q) took %v, want it bounded by the per-Log timeout", slowLogURL, elapsed)
+	}
+
+	scts, err := dist.AddPreChain(ctx, chain, false /* loadPendingLogs */)
+	if err != nil {
+		t.Fatalf("dist.AddPreChain() = _, %v", err)
+	}
+	want := []*AssignedSCT{{LogURL: fastLogURL, SCT: testSCT(fastLogURL), Operator: "Google", LogState: loglist3.UsableLogStatus}}
+	if diff := cmp.Diff(scts, want); diff != "" {
+		t.Errorf("dist.AddPreChain(): diff -want +got\n%s", diff)
+	}
+}
+
+func TestDistributorPerLogTimeoutFakeClock(t *testing.T) {
+	const slowLogURL = "https://ct.googleapis.com/icarus/"
+	const timeout = 30 * time.Second
+	lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+		lc := stubLogClient{logURL: log.URL, rootsCerts: RootsCerts}
+		if log.URL == slowLogURL {
+			return slowStubLogClient{stubLogClient: lc, delay: time.Hour}, nil
+		}
+		return lc, nil
+	}
+	clock := newFakeClock(time.Unix(1640000000, 0))
+	dist, err := NewDistributor(sampleValidLogList(), buildStubCTPolicy(1), lcBuilder, monitoring.InertMetricFactory{}, DistributorOptions{PerLogTimeout: timeout, Clock: clock})
+	if err != nil {
+		t.Fatalf("NewDistributor() = _, %v", err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := dist.SubmitToLog(context.Background(), slowLogURL, []ct.ASN1Cert{{Data: []byte{0}}}, true)
+		errc <- err
+	}()
+	if got := <-clock.afters; got != timeout {
+		t.Fatalf("SubmitToLog() set up a %v timeout, want %v", got, timeout)
+	}
+	clock.Advance(timeout)
+	if err := <-errc; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SubmitToLog(%q) = _, %v, want %v", slowLogURL, err, context.DeadlineExceeded)
+	}
+}
+
+func TestDistributorValidateChain(t *testing.T) {
+	var mu sync.Mutex
+	var lcs []*flakyStubLogClient
+	lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		lc := &flakyStubLogClient{stubLogClient: stubLogClient{logURL: log.URL, rootsCerts: RootsCerts}}
+		lcs = append(lcs, lc)
+		return lc, nil
+	}
+
+	testCases := []struct {
+		name         string
+		plc          ctpolicy.CTPolicy
+		pemChainFile string
+		asPreChain   bool
+		wantLogs     []string
+		wantErr      bool
+	}{
+		{
+			name:         "Valid",
+			plc:          buildStubCTPolicy(1),
+			pemChainFile: "../trillian/testdata/subleaf.chain",
+			wantLogs:     []string{"https://ct.googleapis.com/rocketeer/"},
+		},
+		{
+			name:         "PolicyUnsatisfiable",
+			plc:          buildStubCTPolicy(2),
+			pemChainFile: "../trillian/testdata/subleaf.chain",
+			wantErr:      true,
+		},
+		{
+			name:         "Malformed",
+			plc:          buildStubCTPolicy(1),
+			pemChainFile: "../trillian/testdata/subleaf.misordered.chain",
+			wantErr:      true,
+		},
+		{
+			name:         "TypeMismatch",
+			plc:          buildStubCTPolicy(1),
+			pemChainFile: "../trillian/testdata/subleaf.chain",
+			asPreChain:   true,
+			wantErr:      true,
+		},
+		{
+			name:    "Empty",
+			plc:     buildStubCTPolicy(1),
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dist, err := NewDistributor(sampleValidLogList(), tc.plc, lcBuilder, monitoring.InertMetricFactory{}, DistributorOptions{})
+			if err != nil {
+				t.Fatalf("NewDistributor() = _, %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			dist.RefreshRoots(ctx)
+
+			groups, err := dist.ValidateChain(pemFileToDERChain(tc.pemChainFile), tc.asPreChain)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("dist.ValidateChain(from %q) = (_, error: %v), want err? %t", tc.pemChainFile, err, tc.wantErr)
+			}
+			var gotLogs []string
+			for _, g := range groups {
+				for logURL := range g.LogURLs {
+					gotLogs = append(gotLogs, logURL)
+				}
+			}
+			if diff := cmp.Diff(tc.wantLogs, gotLogs, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("dist.ValidateChain(from %q) Logs: diff -want +got\n%s", tc.pemChainFile, diff)
+			}
+		})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, lc := range lcs {
+		lc.mu.Lock()
+		if lc.calls != 0 {
+			t.Errorf("Log %q got %d submission(s) during validation, want none", lc.logURL, lc.calls)
+		}
+		lc.mu.Unlock()
+	}
+}
+
+func TestDistributorSCTAttribution(t *testing.T) {
+	const logURL = "https://ct.googleapis.com/rocketeer/"
+	// Move the only Log accepting the chain to an operator of its own.
+	ll := sampleValidLogList()
+	var moved *loglist3.Log
+	for _, op := range ll.Operators {
+		for i, log := range op.Logs {
+			if log.URL == logURL {
+				moved = log
+				op.Logs = append(op.Logs[:i], op.Logs[i+1:]...)
+				break
+			}
+		}
+	}
+	if moved == nil {
+		t.Fatalf("Log %q not found in sample log list", logURL)
+	}
+	ll.Operators = append(ll.Operators, &loglist3.Operator{Name: "Test Operator", Logs: []*loglist3.Log{moved}})
+
+	dist, err := NewDistributor(ll, buildStubCTPolicy(1), newLocalStubLogClient, monitoring.InertMetricFactory{}, DistributorOptions{})
+	if err != nil {
+		t.Fatalf("NewDistributor() = _, %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	dist.RefreshRoots(ctx)
+
+	scts, err := dist.AddChain(ctx, pemFileToDERChain("../trillian/testdata/subleaf.chain"), false /* loadPendingLogs */)
+	if err != nil {
+		t.Fatalf("dist.AddChain() = _, %v", err)
+	}
+	want := []*AssignedSCT{{LogURL: logURL, SCT: testSCT(logURL), Operator: "Test Operator", LogState: loglist3.UsableLogStatus}}
+	if diff := cmp.Diff(want, scts); diff != "" {
+		t.Errorf("dist.AddChain(): diff -want +got\n%s", diff)
+	}
+
+	// SCTs from Logs missing from the log list are left unattributed.
+	unknown := []*AssignedSCT{{LogURL: "https://unknown.example.com/", SCT: testSCT(logURL)}}
+	dist.attributeSCTs(unknown)
+	if got := unknown[0]; got.Operator != "" || got.LogState != loglist3.UndefinedLogStatus {
+		t.Errorf("attributeSCTs() set Operator %q, LogState %v for unknown Log; want none", got.Operator, got.LogState)
+	}
+}
+
+func TestDistributorRequireDistinctOperators(t *testing.T) {
+	const rocketeer, icarus = "https://ct.googleapis.com/rocketeer/", "https://ct.googleapis.com/icarus/"
+	// Only two Logs accept the chain, so the policy requires SCTs from both.
+	rootsCerts := map[string][]rootInfo{
+		rocketeer: {{filename: "../trillian/testdata/fake-ca.cert"}},
+		icarus:    {{filename: "../trillian/testdata/fake-ca.cert"}},
+	}
+	lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+		return newRootedStubLogClient(log, rootsCerts)
+	}
+	// spread moves the icarus Log to an operator of its own.
+	spread := func(ll *loglist3.LogList) {
+		for _, op := range ll.Operators {
+			for i, log := range op.Logs {
+				if log.URL == icarus {
+					op.Logs = append(op.Logs[:i], op.Logs[i+1:]...)
+					ll.Operators = append(ll.Operators, &loglist3.Operator{Name: "Test Operator", Logs: []*loglist3.Log{log}})
+					return
+				}
+			}
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		modify    func(ll *loglist3.LogList)
+		required  int
+		wantError bool
+	}{
+		{name: "SameOperatorNotRequired"},
+		{name: "SameOperatorRequiredOne", required: 1},
+		{name: "SameOperator", required: 2, wantError: true},
+		{name: "DistinctOperators", modify: spread, required: 2},
+		{name: "TooFewDistinctOperators", modify: spread, required: 3, wantError: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ll := sampleValidLogList()
+			if tc.modify != nil {
+				tc.modify(ll)
+			}
+			dist, err := NewDistributor(ll, buildStubCTPolicy(2), lcBuilder, monitoring.InertMetricFactory{}, DistributorOptions{RequireDistinctOperators: tc.required})
+			if err != nil {
+				t.Fatalf("NewDistributor() = _, %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			dist.RefreshRoots(ctx)
+
+			scts, err := dist.AddChain(ctx, pemFileToDERChain("../trillian/testdata/subleaf.chain"), false /* loadPendingLogs */)
+			if len(scts) != 2 {
+				t.Errorf("dist.AddChain() = %d SCTs, want 2", len(scts))
+			}
+			var opErr *NotEnoughOperatorsError
+			if gotErr := errors.As(err, &opErr); gotErr != tc.wantError {
+				t.Fatalf("dist.AddChain() = _, %v, want NotEnoughOperatorsError? %t", err, tc.wantError)
+			}
+			if !tc.wantError && err != nil {
+				t.Errorf("dist.AddChain() = _, %v, want nil error", err)
+			}
+			if opErr != nil && opErr.Required != tc.required {
+				t.Errorf("dist.AddChain() = _, %v, want %d required operators", opErr, tc.required)
 			}
 		})
 	}
@@ -0,0 +1,107 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package submission
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/trillian/monitoring"
+)
+
+// SubmissionMetrics records the requests a Distributor sends to Logs. The
+// endpoint is "AddChain" or "AddPreChain". Implementations must be safe for
+// concurrent use.
+type SubmissionMetrics interface {
+	// IncRequests counts a request sent to a Log.
+	IncRequests(logURL, endpoint string)
+	// IncResponses counts a response from a Log, by HTTP status code.
+	IncResponses(logURL, endpoint string, status int)
+	// IncErrors counts a failed request to a Log, by error type:
+	// "unknown_error", "invalid_sct" or "connection_error".
+	IncErrors(logURL, endpoint, errType string)
+	// ObserveLatency records how long a request to a Log took.
+	ObserveLatency(logURL, endpoint string, latency time.Duration)
+	// SetLastGetRootsSuccess records when the roots of a Log were last
+	// fetched successfully.
+	SetLastGetRootsSuccess(logURL string, when time.Time)
+}
+
+// NoopSubmissionMetrics is a SubmissionMetrics which records nothing.
+type NoopSubmissionMetrics struct{}
+
+// IncRequests does nothing.
+func (NoopSubmissionMetrics) IncRequests(logURL, endpoint string) {}
+
+// IncResponses does nothing.
+func (NoopSubmissionMetrics) IncResponses(logURL, endpoint string, status int) {}
+
+// IncErrors does nothing.
+func (NoopSubmissionMetrics) IncErrors(logURL, endpoint, errType string) {}
+
+// ObserveLatency does nothing.
+func (NoopSubmissionMetrics) ObserveLatency(logURL, endpoint string, latency time.Duration) {}
+
+// SetLastGetRootsSuccess does nothing.
+func (NoopSubmissionMetrics) SetLastGetRootsSuccess(logURL string, when time.Time) {}
+
+// monitoringMetrics is a SubmissionMetrics exporting the metrics through a
+// monitoring.MetricFactory.
+type monitoringMetrics struct {
+	// Metrics are per-log, per-endpoint and some per-response-status code.
+	reqsCounter   monitoring.Counter   // logurl, ep => value
+	rspsCounter   monitoring.Counter   // logurl, ep, sc => value
+	errCounter    monitoring.Counter   // logurl, ep, status => value
+	logRspLatency monitoring.Histogram // logurl, ep => value
+	// Per-log
+	lastGetRootsSuccess monitoring.Gauge // Unix time
+}
+
+// NewSubmissionMetrics creates a SubmissionMetrics exporting the metrics
+// through mf, or a NoopSubmissionMetrics if mf is nil. The metrics are
+// registered with mf, so the result should be shared by all the Distributors
+// using mf rather than created for each of them.
+func NewSubmissionMetrics(mf monitoring.MetricFactory) SubmissionMetrics {
+	if mf == nil {
+		return NoopSubmissionMetrics{}
+	}
+	return &monitoringMetrics{
+		reqsCounter:         mf.NewCounter("http_reqs", "Number of requests", "logurl", "ep"),
+		rspsCounter:         mf.NewCounter("http_rsps", "Number of responses", "logurl", "ep", "httpstatus"),
+		errCounter:          mf.NewCounter("err_count", "Number of errors", "logurl", "ep", "errtype"),
+		logRspLatency:       mf.NewHistogram("http_log_latency", "Latency of responses in seconds", "logurl", "ep"),
+		lastGetRootsSuccess: mf.NewGauge("last_get_roots_success", "Unix timestamp for last successful get-roots request", "logurl"),
+	}
+}
+
+func (m *monitoringMetrics) IncRequests(logURL, endpoint string) {
+	m.reqsCounter.Inc(logURL, endpoint)
+}
+
+func (m *monitoringMetrics) IncResponses(logURL, endpoint string, status int) {
+	m.rspsCounter.Inc(logURL, endpoint, strconv.Itoa(status))
+}
+
+func (m *monitoringMetrics) IncErrors(logURL, endpoint, errType string) {
+	m.errCounter.Inc(logURL, endpoint, errType)
+}
+
+func (m *monitoringMetrics) ObserveLatency(logURL, endpoint string, latency time.Duration) {
+	m.logRspLatency.Observe(latency.Seconds(), logURL, endpoint)
+}
+
+func (m *monitoringMetrics) SetLastGetRootsSuccess(logURL string, when time.Time) {
+	m.lastGetRootsSuccess.Set(float64(when.Unix()), logURL)
+}
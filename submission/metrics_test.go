@@ -0,0 +1,127 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package submission
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/trillian/monitoring"
+)
+
+// metricKey identifies a metric of fakeSubmissionMetrics. label is the HTTP
+// status or the error type, if any.
+type metricKey struct {
+	logURL, endpoint, label string
+}
+
+// fakeSubmissionMetrics is a SubmissionMetrics recording everything in memory.
+type fakeSubmissionMetrics struct {
+	mu        sync.Mutex
+	requests  map[metricKey]int
+	responses map[metricKey]int
+	errors    map[metricKey]int
+	latencies map[metricKey][]time.Duration
+	roots     map[string]time.Time
+}
+
+func newFakeSubmissionMetrics() *fakeSubmissionMetrics {
+	return &fakeSubmissionMetrics{
+		requests:  make(map[metricKey]int),
+		responses: make(map[metricKey]int),
+		errors:    make(map[metricKey]int),
+		latencies: make(map[metricKey][]time.Duration),
+		roots:     make(map[string]time.Time),
+	}
+}
+
+func (m *fakeSubmissionMetrics) IncRequests(logURL, endpoint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[metricKey{logURL, endpoint, ""}]++
+}
+
+func (m *fakeSubmissionMetrics) IncResponses(logURL, endpoint string, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses[metricKey{logURL, endpoint, strconv.Itoa(status)}]++
+}
+
+func (m *fakeSubmissionMetrics) IncErrors(logURL, endpoint, errType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[metricKey{logURL, endpoint, errType}]++
+}
+
+func (m *fakeSubmissionMetrics) ObserveLatency(logURL, endpoint string, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := metricKey{logURL, endpoint, ""}
+	m.latencies[k] = append(m.latencies[k], latency)
+}
+
+func (m *fakeSubmissionMetrics) SetLastGetRootsSuccess(logURL string, when time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.roots[logURL] = when
+}
+
+// totalLatency returns the number and sum of the latencies observed for
+// requests to the endpoint of a Log.
+func (m *fakeSubmissionMetrics) totalLatency(logURL, endpoint string) (int, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sum time.Duration
+	lats := m.latencies[metricKey{logURL, endpoint, ""}]
+	for _, l := range lats {
+		sum += l
+	}
+	return len(lats), sum
+}
+
+func TestNewSubmissionMetrics(t *testing.T) {
+	if _, ok := NewSubmissionMetrics(nil).(NoopSubmissionMetrics); !ok {
+		t.Error("NewSubmissionMetrics(nil) is not a NoopSubmissionMetrics")
+	}
+
+	// Each SubmissionMetrics has the metrics of its own factory.
+	for i := 0; i < 2; i++ {
+		mf := monitoring.InertMetricFactory{}
+		m := NewSubmissionMetrics(mf).(*monitoringMetrics)
+		m.IncRequests("log", "AddChain")
+		m.IncResponses("log", "AddChain", 200)
+		m.IncErrors("log", "AddChain", "unknown_error")
+		m.ObserveLatency("log", "AddChain", 2*time.Second)
+		m.SetLastGetRootsSuccess("log", time.Unix(1000, 0))
+
+		if got := m.reqsCounter.Value("log", "AddChain"); got != 1 {
+			t.Errorf("http_reqs = %v, want 1", got)
+		}
+		if got := m.rspsCounter.Value("log", "AddChain", "200"); got != 1 {
+			t.Errorf("http_rsps = %v, want 1", got)
+		}
+		if got := m.errCounter.Value("log", "AddChain", "unknown_error"); got != 1 {
+			t.Errorf("err_count = %v, want 1", got)
+		}
+		if count, sum := m.logRspLatency.Info("log", "AddChain"); count != 1 || sum != 2 {
+			t.Errorf("http_log_latency = %d observations summing to %v, want 1 of 2", count, sum)
+		}
+		if got := m.lastGetRootsSuccess.Value("log"); got != 1000 {
+			t.Errorf("last_get_roots_success = %v, want 1000", got)
+		}
+	}
+}
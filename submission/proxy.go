@@ -25,7 +25,6 @@ import (
 	"github.com/google/certificate-transparency-go/asn1"
 	"github.com/google/certificate-transparency-go/ctpolicy"
 	"github.com/google/certificate-transparency-go/loglist3"
-	"github.com/google/certificate-transparency-go/schedule"
 	"github.com/google/certificate-transparency-go/tls"
 	"github.com/google/certificate-transparency-go/x509util"
 	"github.com/google/trillian/monitoring"
@@ -58,31 +57,48 @@ type DistributorBuilder func(*loglist3.LogList) (*Distributor, error)
 
 // GetDistributorBuilder given CT-policy type and Log-client builder produces
 // Distributor c-tor.
-func GetDistributorBuilder(plc CTPolicyType, lcBuilder LogClientBuilder, mf monitoring.MetricFactory) DistributorBuilder {
+func GetDistributorBuilder(plc CTPolicyType, lcBuilder LogClientBuilder, mf monitoring.MetricFactory, opts DistributorOptions) DistributorBuilder {
+	if opts.Metrics == nil {
+		// Share the metrics between the Distributors built.
+		opts.Metrics = NewSubmissionMetrics(mf)
+	}
 	if plc == AppleCTPolicy {
 		return func(ll *loglist3.LogList) (*Distributor, error) {
-			return NewDistributor(ll, ctpolicy.AppleCTPolicy{}, lcBuilder, mf)
+			return NewDistributor(ll, ctpolicy.AppleCTPolicy{}, lcBuilder, mf, opts)
 		}
 	}
 	return func(ll *loglist3.LogList) (*Distributor, error) {
-		return NewDistributor(ll, ctpolicy.ChromeCTPolicy{}, lcBuilder, mf)
+		return NewDistributor(ll, ctpolicy.ChromeCTPolicy{}, lcBuilder, mf, opts)
 	}
 }
 
-// ASN1MarshalSCTs serializes list of AssignedSCTs according to RFC6962 3.3
-func ASN1MarshalSCTs(scts []*AssignedSCT) ([]byte, error) {
+// BuildSCTList serializes scts into a TLS-encoded
+// SignedCertificateTimestampList (RFC6962 3.3), keeping their order, as
+// stapled in the TLS and OCSP extensions for SCTs.
+func BuildSCTList(scts []*AssignedSCT) ([]byte, error) {
 	if len(scts) == 0 {
-		return nil, fmt.Errorf("ASN1MarshalSCTs requires positive number of SCTs, 0 provided")
+		return nil, fmt.Errorf("BuildSCTList requires positive number of SCTs, 0 provided")
 	}
 	unassignedSCTs := make([]*ct.SignedCertificateTimestamp, 0, len(scts))
-	for _, sct := range scts {
+	for i, sct := range scts {
+		if sct == nil {
+			return nil, fmt.Errorf("AssignedSCT number %d is nil", i)
+		}
 		unassignedSCTs = append(unassignedSCTs, sct.SCT)
 	}
 	sctList, err := x509util.MarshalSCTsIntoSCTList(unassignedSCTs)
 	if err != nil {
 		return nil, err
 	}
-	encdSCTList, err := tls.Marshal(*sctList)
+	return tls.Marshal(*sctList)
+}
+
+// ASN1MarshalSCTs serializes list of AssignedSCTs according to RFC6962 3.3
+func ASN1MarshalSCTs(scts []*AssignedSCT) ([]byte, error) {
+	if len(scts) == 0 {
+		return nil, fmt.Errorf("ASN1MarshalSCTs requires positive number of SCTs, 0 provided")
+	}
+	encdSCTList, err := BuildSCTList(scts)
 	if err != nil {
 		return nil, err
 	}
@@ -165,24 +181,29 @@ func (p *Proxy) restartDistributor(ctx context.Context, ll *loglist3.LogList) er
 		// losing ll info. No good.
 		return err
 	}
+	// Keep the SCTs cached so far, unless the Logs changed.
+	p.distMu.RLock()
+	d.reuseSCTCache(p.dist)
+	p.distMu.RUnlock()
 
 	// Start refreshing roots periodically so they stay up-to-date.
 	refreshCtx, refreshCancel := context.WithCancel(ctx)
-	go schedule.Every(refreshCtx, p.rootsRefreshInterval, func(ectx context.Context) {
-		if errs := d.RefreshRoots(ectx); len(errs) > 0 {
-			for _, err := range errs {
-				klog.Warning(err)
-			}
-		}
-	})
+	go d.Run(refreshCtx, p.rootsRefreshInterval)
 
 	p.distMu.Lock()
-	defer p.distMu.Unlock()
-	if p.distCancel != nil {
-		p.distCancel()
-	}
+	prev, prevCancel := p.dist, p.distCancel
 	p.dist = d
 	p.distCancel = refreshCancel
+	p.distMu.Unlock()
+
+	// Stop the previous Distributor once swapped out, so that waiting for its
+	// roots refresh to return doesn't hold up submissions.
+	if prevCancel != nil {
+		prevCancel()
+	}
+	if prev != nil {
+		prev.Close()
+	}
 	return nil
 }
 
@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -29,6 +31,7 @@ import (
 	"github.com/google/certificate-transparency-go/loglist3"
 	"github.com/google/certificate-transparency-go/testdata"
 	"github.com/google/certificate-transparency-go/tls"
+	"github.com/google/go-cmp/cmp"
 	"github.com/google/trillian/monitoring"
 )
 
@@ -53,7 +56,7 @@ func stubLogListManager() *LogListManager {
 var imf monitoring.InertMetricFactory
 
 func TestProxyRefreshLLErr(t *testing.T) {
-	p := NewProxy(stubLogListManager(), GetDistributorBuilder(ChromeCTPolicy, NewStubLogClient, imf), imf)
+	p := NewProxy(stubLogListManager(), GetDistributorBuilder(ChromeCTPolicy, NewStubLogClient, imf, DistributorOptions{}), imf)
 
 	_, err := p.llWatcher.RefreshLogList(context.Background())
 	if err == nil {
@@ -62,7 +65,7 @@ func TestProxyRefreshLLErr(t *testing.T) {
 }
 
 func TestProxyBrokenDistributor(t *testing.T) {
-	p := NewProxy(stubLogListManager(), GetDistributorBuilder(ChromeCTPolicy, newNoLogClient, imf), imf)
+	p := NewProxy(stubLogListManager(), GetDistributorBuilder(ChromeCTPolicy, newNoLogClient, imf, DistributorOptions{}), imf)
 
 	_, err := p.llWatcher.RefreshLogList(context.Background())
 	if err == nil {
@@ -99,7 +102,7 @@ func TestProxyInitState(t *testing.T) {
 	defer os.Remove(f)
 
 	llr := NewLogListRefresher(f)
-	p := NewProxy(NewLogListManager(llr, nil), GetDistributorBuilder(ChromeCTPolicy, buildStubNoRootsLogClient, imf), imf)
+	p := NewProxy(NewLogListManager(llr, nil), GetDistributorBuilder(ChromeCTPolicy, buildStubNoRootsLogClient, imf, DistributorOptions{}), imf)
 	p.Run(context.Background(), 100*time.Millisecond, time.Hour)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -139,6 +142,141 @@ Init:
 	}
 }
 
+func TestProxySCTCacheLogListChange(t *testing.T) {
+	var mu sync.Mutex
+	var lcs []*flakyStubLogClient
+	lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		// Every Log accepts the chain's root, so that submissions don't depend
+		// on whether the roots refresh of the Distributor is done.
+		roots := map[string][]rootInfo{log.URL: {{filename: "../trillian/testdata/fake-ca.cert"}}}
+		lc := &flakyStubLogClient{stubLogClient: stubLogClient{logURL: log.URL, rootsCerts: roots}}
+		lcs = append(lcs, lc)
+		return lc, nil
+	}
+	calls := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := 0
+		for _, lc := range lcs {
+			lc.mu.Lock()
+			n += lc.calls
+			lc.mu.Unlock()
+		}
+		return n
+	}
+	opts := DistributorOptions{SCTCacheSize: 10}
+	p := NewProxy(stubLogListManager(), func(ll *loglist3.LogList) (*Distributor, error) {
+		return NewDistributor(ll, buildStubCTPolicy(1), lcBuilder, imf, opts)
+	}, imf)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	chain := pemFileToDERChain("../trillian/testdata/subleaf.chain")
+
+	if err := p.restartDistributor(ctx, sampleValidLogList()); err != nil {
+		t.Fatalf("restartDistributor() = %v", err)
+	}
+	first, err := p.AddChain(ctx, chain, false /* loadPendingLogs */)
+	if err != nil || len(first) == 0 {
+		t.Fatalf("p.AddChain() = %v, %v, want SCTs", first, err)
+	}
+	firstCalls := calls()
+
+	// A new version of the log list with the same Logs keeps the cache.
+	ll := sampleValidLogList()
+	ll.Version = "updated"
+	if err := p.restartDistributor(ctx, ll); err != nil {
+		t.Fatalf("restartDistributor() = %v", err)
+	}
+	scts, err := p.AddChain(ctx, chain, false /* loadPendingLogs */)
+	if err != nil {
+		t.Fatalf("p.AddChain() = _, %v", err)
+	}
+	if diff := cmp.Diff(scts, first); diff != "" {
+		t.Errorf("p.AddChain() after unchanged Logs: diff -want +got\n%s", diff)
+	}
+	if got := calls(); got != firstCalls {
+		t.Errorf("p.AddChain() after unchanged Logs made %d request(s), want none", got-firstCalls)
+	}
+
+	// Retiring the Log which issued the cached SCT makes it stale.
+	retired := first[0].LogURL
+	ll = sampleValidLogList()
+	ll.FindLogByURL(retired).State = &loglist3.LogStates{Retired: &loglist3.LogState{}}
+	if err := p.restartDistributor(ctx, ll); err != nil {
+		t.Fatalf("restartDistributor() = %v", err)
+	}
+	scts, err = p.AddChain(ctx, chain, false /* loadPendingLogs */)
+	if err != nil {
+		t.Fatalf("p.AddChain() = _, %v", err)
+	}
+	if got := calls(); got == firstCalls {
+		t.Error("p.AddChain() after a Log retired made no requests, want SCTs from the Logs")
+	}
+	for _, sct := range scts {
+		if sct.LogURL == retired {
+			t.Errorf("p.AddChain() after %q retired returned an SCT from it", retired)
+		}
+	}
+}
+
+func TestProxyRestartClosesDistributor(t *testing.T) {
+	// dist holds the Log clients and roots requests of a Distributor.
+	type dist struct {
+		started  chan struct{}
+		inFlight int32
+		lcs      []*closableStubLogClient
+	}
+	var mu sync.Mutex
+	var dists []*dist
+	p := NewProxy(stubLogListManager(), func(ll *loglist3.LogList) (*Distributor, error) {
+		dd := &dist{started: make(chan struct{}, 10)}
+		mu.Lock()
+		dists = append(dists, dd)
+		mu.Unlock()
+		lcBuilder := func(log *loglist3.Log) (client.AddLogClient, error) {
+			lc := &closableStubLogClient{stubLogClient: stubLogClient{logURL: log.URL}, started: dd.started, inFlight: &dd.inFlight}
+			dd.lcs = append(dd.lcs, lc)
+			return lc, nil
+		}
+		return NewDistributor(ll, buildStubCTPolicy(1), lcBuilder, imf, DistributorOptions{})
+	}, imf)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := p.restartDistributor(ctx, sampleValidLogList()); err != nil {
+		t.Fatalf("restartDistributor() = %v", err)
+	}
+	first := dists[0]
+	// Wait for the roots refresh of the first Distributor to be under way.
+	for range first.lcs {
+		select {
+		case <-first.started:
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for the roots refresh to start")
+		}
+	}
+	if err := p.restartDistributor(ctx, sampleValidLogList()); err != nil {
+		t.Fatalf("restartDistributor() = %v", err)
+	}
+
+	// The first Distributor is closed by the time restartDistributor returns.
+	if got := atomic.LoadInt32(&first.inFlight); got != 0 {
+		t.Errorf("%d roots request(s) of the replaced Distributor still in flight, want 0", got)
+	}
+	for _, lc := range first.lcs {
+		if got := atomic.LoadInt32(&lc.closed); got != 1 {
+			t.Errorf("CloseIdleConnections() of replaced %q called %d times, want 1", lc.logURL, got)
+		}
+	}
+	for _, lc := range dists[1].lcs {
+		if got := atomic.LoadInt32(&lc.closed); got != 0 {
+			t.Errorf("CloseIdleConnections() of active %q called %d times, want 0", lc.logURL, got)
+		}
+	}
+}
+
 // Helper func building slice of N AssignedSCTs.
 func buildAssignedSCTs(t *testing.T, n int) []*AssignedSCT {
 	rawSCT := testdata.TestCertProof
@@ -221,3 +359,49 @@ func TestASN1MarshalSCT(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildSCTList(t *testing.T) {
+	var scts []*AssignedSCT
+	for _, rawSCT := range [][]byte{testdata.TestCertProof, testdata.TestPreCertProof} {
+		var sct ct.SignedCertificateTimestamp
+		if _, err := tls.Unmarshal(rawSCT, &sct); err != nil {
+			t.Fatalf("failed to tls-unmarshal test SCT: %s", err)
+		}
+		scts = append(scts, &AssignedSCT{LogURL: "ct.googleapis.com/racketeer/", SCT: &sct})
+	}
+
+	tests := []struct {
+		name    string
+		scts    []*AssignedSCT
+		wantErr bool
+	}{
+		{name: "OneSCT", scts: scts[:1]},
+		{name: "TwoSCTs", scts: scts},
+		{name: "Reversed", scts: []*AssignedSCT{scts[1], scts[0]}},
+		{name: "NoSCT", wantErr: true},
+		{name: "NilSCT", scts: buildNilAssignedSCT(), wantErr: true},
+		{name: "NilAssignedSCT", scts: []*AssignedSCT{scts[0], nil}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := BuildSCTList(test.scts)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("BuildSCTList()=_, %v; want err? %t", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			parsed, err := ct.ParseSCTList(got)
+			if err != nil {
+				t.Fatalf("ParseSCTList(BuildSCTList())=_, %v", err)
+			}
+			var want []*ct.SignedCertificateTimestamp
+			for _, sct := range test.scts {
+				want = append(want, sct.SCT)
+			}
+			if diff := cmp.Diff(want, parsed); diff != "" {
+				t.Errorf("ParseSCTList(BuildSCTList()): diff -want +got\n%s", diff)
+			}
+		})
+	}
+}
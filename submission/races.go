@@ -17,12 +17,14 @@ package submission
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	ct "github.com/google/certificate-transparency-go"
 	"github.com/google/certificate-transparency-go/ctpolicy"
+	"github.com/google/certificate-transparency-go/loglist3"
 )
 
 const (
@@ -55,6 +57,10 @@ type safeSubmissionState struct {
 	mu          sync.Mutex
 	logToGroups map[string]ctpolicy.GroupSet
 	groupNeeds  map[string]int
+	// groupOperators holds the Log operators of the groups counting distinct
+	// operators, and groupCounted the operators already providing an SCT.
+	groupOperators map[string]map[string]string
+	groupCounted   map[string]map[string]bool
 
 	results map[string]*submissionResult
 	cancels map[string]context.CancelFunc
@@ -64,14 +70,33 @@ func newSafeSubmissionState(groups ctpolicy.LogPolicyData) *safeSubmissionState
 	var s safeSubmissionState
 	s.logToGroups = ctpolicy.GroupByLogs(groups)
 	s.groupNeeds = make(map[string]int)
+	s.groupOperators = make(map[string]map[string]string)
+	s.groupCounted = make(map[string]map[string]bool)
 	for _, g := range groups {
 		s.groupNeeds[g.Name] = g.MinInclusions
+		if g.LogOperators != nil {
+			s.groupOperators[g.Name] = g.LogOperators
+			s.groupCounted[g.Name] = make(map[string]bool)
+		}
 	}
 	s.results = make(map[string]*submissionResult)
 	s.cancels = make(map[string]context.CancelFunc)
 	return &s
 }
 
+// awaits returns whether the group still needs an SCT from the Log, i.e. it
+// needs more SCTs and, if it counts distinct operators, none came from the
+// Log's operator yet.
+func (sub *safeSubmissionState) awaits(groupName, logURL string) bool {
+	if sub.groupNeeds[groupName] <= 0 {
+		return false
+	}
+	if ops := sub.groupOperators[groupName]; ops != nil {
+		return !sub.groupCounted[groupName][ops[logURL]]
+	}
+	return true
+}
+
 // request includes empty submissionResult in the set, returns whether
 // the entry is requested for the first time.
 func (sub *safeSubmissionState) request(logURL string, cancel context.CancelFunc) bool {
@@ -84,7 +109,7 @@ func (sub *safeSubmissionState) request(logURL string, cancel context.CancelFunc
 	sub.results[logURL] = &submissionResult{}
 	isAwaited := false
 	for g := range sub.logToGroups[logURL] {
-		if sub.groupNeeds[g] > 0 {
+		if sub.awaits(g, logURL) {
 			isAwaited = true
 			break
 		}
@@ -113,9 +138,16 @@ func (sub *safeSubmissionState) setResult(logURL string, sct *ct.SignedCertifica
 		if groupName == ctpolicy.BaseName {
 			continue
 		}
-		if sub.groupNeeds[groupName] > 0 {
+		if sub.awaits(groupName, logURL) {
 			sub.results[logURL] = &submissionResult{sct: sct, err: err}
 		}
+		if ops := sub.groupOperators[groupName]; ops != nil {
+			if sub.groupCounted[groupName][ops[logURL]] {
+				// Another SCT from the operator doesn't count.
+				continue
+			}
+			sub.groupCounted[groupName][ops[logURL]] = true
+		}
 		sub.groupNeeds[groupName]--
 	}
 
@@ -145,7 +177,7 @@ func (sub *safeSubmissionState) setResult(logURL string, sct *ct.SignedCertifica
 	for logURL, groupSet := range sub.logToGroups {
 		isAwaited := false
 		for g := range groupSet {
-			if sub.groupNeeds[g] > 0 {
+			if sub.awaits(g, logURL) {
 				isAwaited = true
 				break
 			}
@@ -190,8 +222,27 @@ func postInterval(idx int, parallelStart int, dur time.Duration) time.Duration {
 	return time.Duration(idx+1-parallelStart) * dur
 }
 
+// priorityTiers splits the session, ordered by descending Log-priority, into
+// runs of Logs sharing a priority.
+func priorityTiers(session []string, priorities map[string]int) [][]string {
+	if len(priorities) == 0 || len(session) == 0 {
+		return [][]string{session}
+	}
+	var tiers [][]string
+	start := 0
+	for i := 1; i <= len(session); i++ {
+		if i == len(session) || priorities[session[i]] != priorities[session[start]] {
+			tiers = append(tiers, session[start:i])
+			start = i
+		}
+	}
+	return tiers
+}
+
 // groupRace shuffles logs within the group, submits avoiding
-// duplicate-requests and collects responses.
+// duplicate-requests and collects responses. Logs are submitted to in tiers
+// of descending Log-priority: a tier is only started once all requests of the
+// previous one are done and the group still needs SCTs.
 func groupRace(ctx context.Context, chain []ct.ASN1Cert, asPreChain bool,
 	group *ctpolicy.LogGroupInfo, parallelStart int,
 	state *safeSubmissionState, submitter Submitter) groupState {
@@ -205,38 +256,40 @@ func groupRace(ctx context.Context, chain []ct.ASN1Cert, asPreChain bool,
 		counter <- count{}
 	}
 
-	for i, logURL := range session {
-		subCtx, cancel := context.WithCancel(ctx)
-		go func(i int, logURL string) {
-			defer countCall()
-			timeoutchan := time.After(postInterval(i, parallelStart, PostBatchInterval))
+	for _, tier := range priorityTiers(session, group.LogPriorities) {
+		for i, logURL := range tier {
+			subCtx, cancel := context.WithCancel(ctx)
+			go func(i int, logURL string) {
+				defer countCall()
+				timeoutchan := time.After(postInterval(i, parallelStart, PostBatchInterval))
 
+				select {
+				case <-subCtx.Done():
+					return
+				case <-timeoutchan:
+				}
+				if state.groupComplete(group.Name) {
+					cancel()
+					return
+				}
+				if firstRequested := state.request(logURL, cancel); !firstRequested {
+					return
+				}
+				sct, err := submitter.SubmitToLog(subCtx, logURL, chain, asPreChain)
+				// TODO(Mercurrent): verify SCT
+				state.setResult(logURL, sct, err)
+			}(i, logURL)
+		}
+		// Wait until either all logs within the tier are processed or context
+		// is cancelled.
+		for range tier {
 			select {
-			case <-subCtx.Done():
-				return
-			case <-timeoutchan:
-			}
-			if state.groupComplete(group.Name) {
-				cancel()
-				return
-			}
-			if firstRequested := state.request(logURL, cancel); !firstRequested {
-				return
-			}
-			sct, err := submitter.SubmitToLog(subCtx, logURL, chain, asPreChain)
-			// TODO(Mercurrent): verify SCT
-			state.setResult(logURL, sct, err)
-		}(i, logURL)
-	}
-	// Wait until either all logs within session are processed or context is
-	// cancelled.
-	for range session {
-		select {
-		case <-ctx.Done():
-			return groupState{Name: group.Name, Success: state.groupComplete(group.Name)}
-		case <-counter:
-			if state.groupComplete(group.Name) {
-				return groupState{Name: group.Name, Success: true}
+			case <-ctx.Done():
+				return groupState{Name: group.Name, Success: state.groupComplete(group.Name)}
+			case <-counter:
+				if state.groupComplete(group.Name) {
+					return groupState{Name: group.Name, Success: true}
+				}
 			}
 		}
 	}
@@ -266,9 +319,47 @@ func parallelNums(groups ctpolicy.LogPolicyData) map[string]int {
 type AssignedSCT struct {
 	LogURL string
 	SCT    *ct.SignedCertificateTimestamp
+	// Operator is the name of the Log's operator, and LogState the state of
+	// the Log, according to the log list of the Distributor which obtained
+	// the SCT. They are left empty by GetSCTs itself.
+	Operator string
+	LogState loglist3.LogStatus
+}
+
+// PolicyNotSatisfiedError is returned when the SCTs collected for a chain do
+// not satisfy all of the policy's Log-groups. The SCTs that were obtained are
+// still returned alongside it.
+type PolicyNotSatisfiedError struct {
+	// FailedGroups lists names of the Log-groups that didn't receive enough
+	// SCTs.
+	FailedGroups []string
+	// Obtained is the number of SCTs collected.
+	Obtained int
+	// Required is the minimal number of SCTs needed to satisfy the policy.
+	Required int
+}
+
+func (e *PolicyNotSatisfiedError) Error() string {
+	return fmt.Sprintf("log-group(s) %s didn't receive enough SCTs: got %d, want at least %d", strings.Join(e.FailedGroups, ", "), e.Obtained, e.Required)
+}
+
+// requiredSCTs returns the minimal number of SCTs satisfying all the groups.
+func requiredSCTs(groups ctpolicy.LogPolicyData) int {
+	var base, subsetSum int
+	for _, g := range groups {
+		if g.IsBase {
+			base = g.MinInclusions
+		} else {
+			subsetSum += g.MinInclusions
+		}
+	}
+	if base > subsetSum {
+		return base
+	}
+	return subsetSum
 }
 
-func completenessError(groupComplete map[string]bool) error {
+func completenessError(groups ctpolicy.LogPolicyData, groupComplete map[string]bool, scts []*AssignedSCT) error {
 	failedGroups := []string{}
 	for name, success := range groupComplete {
 		if !success {
@@ -276,14 +367,16 @@ func completenessError(groupComplete map[string]bool) error {
 		}
 	}
 	if len(failedGroups) > 0 {
-		return fmt.Errorf("log-group(s) %s didn't receive enough SCTs", strings.Join(failedGroups, ", "))
+		sort.Strings(failedGroups)
+		return &PolicyNotSatisfiedError{FailedGroups: failedGroups, Obtained: len(scts), Required: requiredSCTs(groups)}
 	}
 	return nil
 }
 
 // GetSCTs picks required number of Logs according to policy-group logic and
 // collects SCTs from them.
-// Emits all collected SCTs even when any error produced.
+// Emits all collected SCTs even when any error produced. If the policy is not
+// satisfied the error is a *PolicyNotSatisfiedError.
 func GetSCTs(ctx context.Context, submitter Submitter, chain []ct.ASN1Cert, asPreChain bool, groups ctpolicy.LogPolicyData) ([]*AssignedSCT, error) {
 	groupComplete := make(map[string]bool)
 	for _, g := range groups {
@@ -305,10 +398,12 @@ func GetSCTs(ctx context.Context, submitter Submitter, chain []ct.ASN1Cert, asPr
 	for i := 0; i < len(groups); i++ {
 		select {
 		case <-ctx.Done():
-			return submissions.collectSCTs(), completenessError(groupComplete)
+			scts := submissions.collectSCTs()
+			return scts, completenessError(groups, groupComplete, scts)
 		case g := <-groupEvents:
 			groupComplete[g.Name] = g.Success
 		}
 	}
-	return submissions.collectSCTs(), completenessError(groupComplete)
+	scts := submissions.collectSCTs()
+	return scts, completenessError(groups, groupComplete, scts)
 }
@@ -16,6 +16,7 @@ package submission
 
 import (
 	"context"
+	"errors"
 	"regexp"
 	"sync"
 	"testing"
@@ -23,8 +24,11 @@ import (
 
 	ct "github.com/google/certificate-transparency-go"
 	"github.com/google/certificate-transparency-go/ctpolicy"
+	"github.com/google/certificate-transparency-go/loglist3"
 	"github.com/google/certificate-transparency-go/testdata"
 	"github.com/google/certificate-transparency-go/tls"
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/go-cmp/cmp"
 )
 
 func testdataSCT() *ct.SignedCertificateTimestamp {
@@ -169,3 +173,155 @@ func TestGetSCTs(t *testing.T) {
 		})
 	}
 }
+
+// tieredSubmitter answers each Log after its delay, failing the Logs listed
+// in fail, and records the requests and responses in order.
+type tieredSubmitter struct {
+	delay map[string]time.Duration
+	fail  map[string]bool
+
+	mu     sync.Mutex
+	events []string
+}
+
+func (ts *tieredSubmitter) record(event string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.events = append(ts.events, event)
+}
+
+func (ts *tieredSubmitter) SubmitToLog(ctx context.Context, logURL string, _ []ct.ASN1Cert, _ bool) (*ct.SignedCertificateTimestamp, error) {
+	ts.record("request " + logURL)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(ts.delay[logURL]):
+	}
+	ts.record("response " + logURL)
+	if ts.fail[logURL] {
+		return nil, errors.New("log failed")
+	}
+	return testdataSCT(), nil
+}
+
+func TestGetSCTsLogPriorityFallback(t *testing.T) {
+	// The preferred Log answers after PostBatchInterval, when the other Log
+	// would have been requested if they shared a priority.
+	slow := PostBatchInterval + PostBatchInterval/2
+	testCases := []struct {
+		name       string
+		fail       map[string]bool
+		wantEvents []string
+		wantLogs   []string
+	}{
+		{
+			name:       "PreferredSucceeds",
+			wantEvents: []string{"request a1.com", "response a1.com"},
+			wantLogs:   []string{"a1.com"},
+		},
+		{
+			name:       "PreferredFails",
+			fail:       map[string]bool{"a1.com": true},
+			wantEvents: []string{"request a1.com", "response a1.com", "request a2.com", "response a2.com"},
+			wantLogs:   []string{"a2.com"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := &tieredSubmitter{delay: map[string]time.Duration{"a1.com": slow}, fail: tc.fail}
+			groups := ctpolicy.LogPolicyData{
+				"a": {
+					Name:          "a",
+					LogURLs:       map[string]bool{"a1.com": true, "a2.com": true},
+					MinInclusions: 1,
+					LogWeights:    map[string]float32{"a1.com": 1.0, "a2.com": 1.0},
+					LogPriorities: map[string]int{"a1.com": 10},
+				},
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			scts, err := GetSCTs(ctx, ts, []ct.ASN1Cert{{Data: []byte{0}}}, false, groups)
+			if err != nil {
+				t.Fatalf("GetSCTs() = _, %v", err)
+			}
+			var gotLogs []string
+			for _, sct := range scts {
+				gotLogs = append(gotLogs, sct.LogURL)
+			}
+			if diff := cmp.Diff(gotLogs, tc.wantLogs); diff != "" {
+				t.Errorf("GetSCTs() SCT Logs: diff -want +got\n%s", diff)
+			}
+			ts.mu.Lock()
+			defer ts.mu.Unlock()
+			if diff := cmp.Diff(ts.events, tc.wantEvents); diff != "" {
+				t.Errorf("GetSCTs() requests: diff -want +got\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPriorityTiers(t *testing.T) {
+	session := []string{"a", "b", "c", "d"}
+	testCases := []struct {
+		name       string
+		priorities map[string]int
+		want       [][]string
+	}{
+		{name: "NoPriorities", want: [][]string{{"a", "b", "c", "d"}}},
+		{name: "OneTier", priorities: map[string]int{"e": 1}, want: [][]string{{"a", "b", "c", "d"}}},
+		{name: "Tiers", priorities: map[string]int{"a": 2, "b": 1, "c": 1}, want: [][]string{{"a"}, {"b", "c"}, {"d"}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(priorityTiers(session, tc.priorities), tc.want); diff != "" {
+				t.Errorf("priorityTiers(): diff -want +got\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetSCTsDistinctOperators(t *testing.T) {
+	ll := &loglist3.LogList{
+		Operators: []*loglist3.Operator{
+			{Name: "A", Logs: []*loglist3.Log{{URL: "a1.com"}, {URL: "a2.com"}}},
+			{Name: "B", Logs: []*loglist3.Log{{URL: "b1.com"}}},
+			{Name: "C", Logs: []*loglist3.Log{{URL: "c1.com"}}},
+		},
+	}
+	operators := map[string]string{"a1.com": "A", "a2.com": "A", "b1.com": "B", "c1.com": "C"}
+	testCases := []struct {
+		name    string
+		fail    map[string]bool
+		wantErr bool
+	}{
+		{name: "AllUp"},
+		{name: "OnlyLogOfOperatorDown", fail: map[string]bool{"b1.com": true}},
+		{name: "OperatorDown", fail: map[string]bool{"a1.com": true, "a2.com": true}},
+		{name: "TwoOperatorsDown", fail: map[string]bool{"b1.com": true, "c1.com": true}, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			groups, err := ctpolicy.DiversityCTPolicy{MinSCTs: 2, MinOperators: 2}.LogsByGroup(&x509.Certificate{}, ll)
+			if err != nil {
+				t.Fatalf("LogsByGroup() = _, %v", err)
+			}
+			ts := &tieredSubmitter{fail: tc.fail}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			scts, err := GetSCTs(ctx, ts, []ct.ASN1Cert{{Data: []byte{0}}}, false, groups)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("GetSCTs() = _, %v; want error: %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			ops := make(map[string]bool)
+			for _, sct := range scts {
+				ops[operators[sct.LogURL]] = true
+			}
+			if len(ops) < 2 {
+				t.Errorf("GetSCTs() returned SCTs from operators %v; want at least 2 distinct", ops)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package submission
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// sctCacheKey identifies a submission whose SCTs are cached: the SHA-256 of
+// the leaf certificate, and the way it was submitted.
+type sctCacheKey struct {
+	leaf            [sha256.Size]byte
+	asPreChain      bool
+	loadPendingLogs bool
+}
+
+// newSCTCacheKey returns the key of a submission of leaf.
+func newSCTCacheKey(leaf []byte, asPreChain, loadPendingLogs bool) sctCacheKey {
+	return sctCacheKey{leaf: sha256.Sum256(leaf), asPreChain: asPreChain, loadPendingLogs: loadPendingLogs}
+}
+
+// sctCacheEntry holds the SCTs issued for a single submission.
+type sctCacheEntry struct {
+	key  sctCacheKey
+	scts []*AssignedSCT
+	// llVersion identifies the Logs of the log list the SCTs were collected
+	// under.
+	llVersion string
+	expires   time.Time
+}
+
+// sctCache is an LRU cache of SCTs keyed by the submission they were issued
+// for. It holds its own copies of the SCTs, so callers may modify them.
+type sctCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // of *sctCacheEntry, most recently used first
+	entries map[sctCacheKey]*list.Element
+}
+
+// newSCTCache creates a cache holding SCTs of up to size certificates for ttl
+// each. Zero ttl means entries are evicted only to make room for new ones.
+func newSCTCache(size int, ttl time.Duration) *sctCache {
+	return &sctCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[sctCacheKey]*list.Element),
+	}
+}
+
+// get returns a copy of the SCTs cached for the submission, if any. Entries
+// cached under another log list version are stale, and evicted.
+func (c *sctCache) get(key sctCacheKey, llVersion string) ([]*AssignedSCT, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*sctCacheEntry)
+	if entry.llVersion != llVersion || (c.ttl > 0 && !c.now().Before(entry.expires)) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return copySCTs(entry.scts), true
+}
+
+// put stores a copy of SCTs collected under the given log list version for
+// the submission, evicting the least recently used entry if the cache is full.
+func (c *sctCache) put(key sctCacheKey, llVersion string, scts []*AssignedSCT) {
+	scts = copySCTs(scts)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*sctCacheEntry)
+		entry.scts, entry.llVersion, entry.expires = scts, llVersion, expires
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&sctCacheEntry{key: key, scts: scts, llVersion: llVersion, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*sctCacheEntry).key)
+	}
+}
+
+// copySCTs returns a copy of scts sharing no pointers with it.
+func copySCTs(scts []*AssignedSCT) []*AssignedSCT {
+	if scts == nil {
+		return nil
+	}
+	cp := make([]*AssignedSCT, len(scts))
+	for i, sct := range scts {
+		if sct == nil {
+			continue
+		}
+		asct := *sct
+		if sct.SCT != nil {
+			ctSCT := *sct.SCT
+			ctSCT.Extensions = cloneBytes(sct.SCT.Extensions)
+			ctSCT.Signature.Signature = cloneBytes(sct.SCT.Signature.Signature)
+			asct.SCT = &ctSCT
+		}
+		cp[i] = &asct
+	}
+	return cp
+}
+
+// cloneBytes returns a copy of b, keeping nil and empty slices apart.
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append(make([]byte, 0, len(b)), b...)
+}
@@ -0,0 +1,166 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package submission
+
+import (
+	"testing"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/go-cmp/cmp"
+)
+
+// testSCTCacheKey returns the cache key of a plain submission of leaf.
+func testSCTCacheKey(leaf []byte) sctCacheKey {
+	return newSCTCacheKey(leaf, false, false)
+}
+
+func TestSCTCache(t *testing.T) {
+	const logURL = "https://ct.googleapis.com/rocketeer/"
+	scts := []*AssignedSCT{{LogURL: logURL, SCT: testSCT(logURL)}}
+	leaf, other := []byte("leaf"), []byte("other")
+	start := time.Unix(1600000000, 0)
+
+	testCases := []struct {
+		name    string
+		size    int
+		ttl     time.Duration
+		put     [][]byte
+		elapsed time.Duration
+		get     []byte
+		wantHit bool
+	}{
+		{name: "Hit", size: 2, ttl: time.Minute, put: [][]byte{leaf}, get: leaf, wantHit: true},
+		{name: "Miss", size: 2, ttl: time.Minute, put: [][]byte{leaf}, get: other},
+		{name: "Empty", size: 2, ttl: time.Minute, get: leaf},
+		{name: "WithinTTL", size: 2, ttl: time.Minute, put: [][]byte{leaf}, elapsed: 59 * time.Second, get: leaf, wantHit: true},
+		{name: "Expired", size: 2, ttl: time.Minute, put: [][]byte{leaf}, elapsed: time.Minute, get: leaf},
+		{name: "NoTTL", size: 2, put: [][]byte{leaf}, elapsed: 24 * time.Hour, get: leaf, wantHit: true},
+		{name: "Evicted", size: 1, ttl: time.Minute, put: [][]byte{leaf, other}, get: leaf},
+		{name: "NotEvicted", size: 1, ttl: time.Minute, put: [][]byte{leaf, other}, get: other, wantHit: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := start
+			c := newSCTCache(tc.size, tc.ttl)
+			c.now = func() time.Time { return now }
+			for _, p := range tc.put {
+				c.put(testSCTCacheKey(p), "v1", scts)
+			}
+			now = now.Add(tc.elapsed)
+
+			got, ok := c.get(testSCTCacheKey(tc.get), "v1")
+			if ok != tc.wantHit {
+				t.Fatalf("get(%q) = _, %t, want %t", tc.get, ok, tc.wantHit)
+			}
+			if !ok {
+				return
+			}
+			if diff := cmp.Diff(got, scts); diff != "" {
+				t.Errorf("get(%q): diff -want +got\n%s", tc.get, diff)
+			}
+		})
+	}
+}
+
+func TestSCTCacheLRU(t *testing.T) {
+	c := newSCTCache(2, 0)
+	a, b, d := []byte("a"), []byte("b"), []byte("d")
+	c.put(testSCTCacheKey(a), "", nil)
+	c.put(testSCTCacheKey(b), "", nil)
+	// Using a makes b the least recently used entry.
+	if _, ok := c.get(testSCTCacheKey(a), ""); !ok {
+		t.Fatalf("get(%q) missed, want hit", a)
+	}
+	c.put(testSCTCacheKey(d), "", nil)
+	if _, ok := c.get(testSCTCacheKey(b), ""); ok {
+		t.Errorf("get(%q) hit, want miss", b)
+	}
+	for _, k := range [][]byte{a, d} {
+		if _, ok := c.get(testSCTCacheKey(k), ""); !ok {
+			t.Errorf("get(%q) missed, want hit", k)
+		}
+	}
+}
+
+func TestSCTCacheLogListVersion(t *testing.T) {
+	c := newSCTCache(2, 0)
+	leaf := []byte("leaf")
+	c.put(testSCTCacheKey(leaf), "v1", nil)
+	if _, ok := c.get(testSCTCacheKey(leaf), "v2"); ok {
+		t.Errorf("get(%q, v2) hit, want miss for an entry cached under v1", leaf)
+	}
+	// The stale entry is gone, even for its own version.
+	if _, ok := c.get(testSCTCacheKey(leaf), "v1"); ok {
+		t.Errorf("get(%q, v1) hit, want miss after eviction", leaf)
+	}
+	c.put(testSCTCacheKey(leaf), "v2", nil)
+	if _, ok := c.get(testSCTCacheKey(leaf), "v2"); !ok {
+		t.Errorf("get(%q, v2) missed, want hit", leaf)
+	}
+}
+
+func TestSCTCacheKey(t *testing.T) {
+	const logURL = "https://ct.googleapis.com/rocketeer/"
+	leaf := []byte("leaf")
+	c := newSCTCache(4, 0)
+	c.put(newSCTCacheKey(leaf, false, false), "", []*AssignedSCT{{LogURL: logURL, SCT: testSCT(logURL)}})
+	for _, key := range []sctCacheKey{
+		newSCTCacheKey(leaf, true, false),
+		newSCTCacheKey(leaf, false, true),
+		newSCTCacheKey(leaf, true, true),
+	} {
+		if _, ok := c.get(key, ""); ok {
+			t.Errorf("get(%+v) hit, want miss for a different submission", key)
+		}
+	}
+}
+
+func TestSCTCacheCopies(t *testing.T) {
+	const logURL = "https://ct.googleapis.com/rocketeer/"
+	key := testSCTCacheKey([]byte("leaf"))
+	c := newSCTCache(2, 0)
+	signedSCT := func() *ct.SignedCertificateTimestamp {
+		sct := testSCT(logURL)
+		sct.Extensions = ct.CTExtensions{0x01, 0x02}
+		sct.Signature.Signature = []byte{0x03, 0x04}
+		return sct
+	}
+	scts := []*AssignedSCT{{LogURL: logURL, SCT: signedSCT()}}
+	c.put(key, "", scts)
+	// Modifying the SCTs after caching them doesn't change the cache.
+	scts[0].Operator = "put"
+	scts[0].SCT.Timestamp++
+	scts[0].SCT.Extensions[0] ^= 0xff
+	scts[0].SCT.Signature.Signature[0] ^= 0xff
+
+	got, ok := c.get(key, "")
+	if !ok {
+		t.Fatalf("get() missed, want hit")
+	}
+	want := []*AssignedSCT{{LogURL: logURL, SCT: signedSCT()}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("get(): diff -want +got\n%s", diff)
+	}
+	// Neither does modifying the SCTs it returned.
+	got[0].Operator = "get"
+	got[0].SCT.Timestamp++
+	got[0].SCT.Extensions[0] ^= 0xff
+	got[0].SCT.Signature.Signature[0] ^= 0xff
+	got[0] = nil
+	if got, _ := c.get(key, ""); !cmp.Equal(got, want) {
+		t.Errorf("get() = %v after modifying a previous result, want %v", got, want)
+	}
+}
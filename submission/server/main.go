@@ -35,9 +35,12 @@ var (
 	logListPath              = flag.String("loglist_path", "https://www.gstatic.com/ct/log_list/v3/log_list.json", "Path for list of CT Logs in JSON format")
 	logListRefreshInterval   = flag.Duration("loglist_refresh_interval", 24*time.Hour, "Interval between consecutive reads of Log-list")
 	rootsRefreshInterval     = flag.Duration("roots_refresh_interval", 24*time.Hour, "Interval between consecutive get-roots calls")
+	rootsRefreshJitter       = flag.Duration("roots_refresh_jitter", 0, "Maximum random shift applied to each interval between get-roots calls")
+	minRootsRefreshInterval  = flag.Duration("min_roots_refresh_interval", 0, "Minimum interval between consecutive get-roots calls, whatever the jitter")
 	policyType               = flag.String("policy_type", "chrome", "CT-policy <chrome|apple>")
 	dryRun                   = flag.Bool("dry_run", false, "No real submissions done")
 	addPreChainTimeout       = flag.Duration("add_prechain_timeout", 10*time.Second, "Timeout for each add-prechain call")
+	perLogTimeout            = flag.Duration("per_log_timeout", 0, "Timeout for each individual Log request within an add-(pre-)chain call; 0 means no per-Log limit")
 	loadPendingQualifiedLogs = flag.Bool("load_pending_qualified_logs", true, "Whether to submit cert to one of Pending+Qualified Logs along main submission")
 )
 
@@ -63,7 +66,12 @@ func main() {
 	}
 	mf := prometheus.MetricFactory{}
 
-	s := submission.NewProxyServer(*logListPath, submission.GetDistributorBuilder(plc, lcb, mf), *addPreChainTimeout, mf)
+	opts := submission.DistributorOptions{
+		PerLogTimeout:      *perLogTimeout,
+		RefreshJitter:      *rootsRefreshJitter,
+		MinRefreshInterval: *minRootsRefreshInterval,
+	}
+	s := submission.NewProxyServer(*logListPath, submission.GetDistributorBuilder(plc, lcb, mf, opts), *addPreChainTimeout, mf)
 	s.Run(context.Background(), *logListRefreshInterval, *rootsRefreshInterval, *loadPendingQualifiedLogs)
 	http.HandleFunc("/ct/v1/proxy/add-pre-chain/", s.HandleAddPreChain)
 	http.HandleFunc("/ct/v1/proxy/add-chain/", s.HandleAddChain)
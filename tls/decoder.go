@@ -0,0 +1,90 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls
+
+import (
+	"errors"
+	"io"
+)
+
+// minRead is the minimum free space in a Decoder's buffer before a read.
+const minRead = 512
+
+// A Decoder reads and decodes a sequence of TLS-encoded values from an input
+// stream, without buffering the whole stream.
+type Decoder struct {
+	r   io.Reader
+	buf []byte // buf[off:] holds the data read but not yet decoded
+	off int
+	err error // error from the last read, reported once buf is drained
+}
+
+// NewDecoder returns a Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the next TLS-encoded value from the input and stores it in the
+// value pointed to by val, as Unmarshal does.
+//
+// Decode returns io.EOF if the input ends before the value starts, and
+// io.ErrUnexpectedEOF if it ends part-way through the value.
+func (d *Decoder) Decode(val interface{}) error {
+	for {
+		data := d.buf[d.off:]
+		if len(data) > 0 {
+			rest, err := Unmarshal(data, val)
+			if err == nil {
+				d.off += len(data) - len(rest)
+				return nil
+			}
+			if _, ok := err.(truncatedError); !ok {
+				return err
+			}
+		}
+		if d.err != nil {
+			if d.err == io.EOF && len(data) > 0 {
+				return io.ErrUnexpectedEOF
+			}
+			return d.err
+		}
+		d.fill()
+	}
+}
+
+// fill reads more data into the buffer. Unless the input ends first, it waits
+// until the amount of buffered data has doubled, so that a large value only
+// has its parsing retried a few times.
+func (d *Decoder) fill() {
+	n := copy(d.buf, d.buf[d.off:])
+	d.buf, d.off = d.buf[:n], 0
+
+	want := n
+	if want < 1 {
+		want = 1
+	}
+	if free := cap(d.buf) - n; free < want || free < minRead {
+		buf := make([]byte, n, 2*n+minRead)
+		copy(buf, d.buf)
+		d.buf = buf
+	}
+
+	read, err := io.ReadAtLeast(d.r, d.buf[n:cap(d.buf)], want)
+	d.buf = d.buf[:n+read]
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	d.err = err
+}
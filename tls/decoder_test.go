@@ -0,0 +1,112 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoder(t *testing.T) {
+	var want []testSliceOfStructs
+	var data []byte
+	for i := 0; i < 50; i++ {
+		val := testSliceOfStructs{Vals: []testVariant{
+			{Which: 0, Val16: newUint16(uint16(i))},
+			{Which: 1, Val32: newUint32(uint32(i) << 16)},
+		}}
+		enc, err := Marshal(val)
+		if err != nil {
+			t.Fatalf("Marshal()=nil,%v", err)
+		}
+		want = append(want, val)
+		data = append(data, enc...)
+	}
+
+	for _, test := range []struct {
+		name string
+		r    func() io.Reader
+	}{
+		{name: "Whole", r: func() io.Reader { return bytes.NewReader(data) }},
+		{name: "OneByte", r: func() io.Reader { return iotest.OneByteReader(bytes.NewReader(data)) }},
+		{name: "DataErr", r: func() io.Reader { return iotest.DataErrReader(bytes.NewReader(data)) }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewDecoder(test.r())
+			var got []testSliceOfStructs
+			for {
+				var val testSliceOfStructs
+				err := d.Decode(&val)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Decode() after %d values: %v", len(got), err)
+				}
+				got = append(got, val)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Decode() got %d values %+v; want %d values %+v", len(got), got, len(want), want)
+			}
+		})
+	}
+}
+
+func dh(h string) []byte {
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestDecoderErrors(t *testing.T) {
+	readErr := errors.New("read failed")
+	for _, test := range []struct {
+		name    string
+		r       io.Reader
+		wantErr error
+		errstr  string
+	}{
+		{name: "Empty", r: bytes.NewReader(nil), wantErr: io.EOF},
+		{name: "Truncated", r: bytes.NewReader(dh("0400010002" + "0400")), wantErr: io.ErrUnexpectedEOF},
+		{name: "TruncatedOneByte", r: iotest.OneByteReader(bytes.NewReader(dh("0400010002" + "0400"))), wantErr: io.ErrUnexpectedEOF},
+		{name: "ReadError", r: io.MultiReader(bytes.NewReader(dh("0400010002")), iotest.ErrReader(readErr)), wantErr: readErr},
+		// The second vector holds 3 bytes, which don't make whole uint16s; this
+		// is reported without reading on to the error.
+		{name: "BadVector", r: io.MultiReader(bytes.NewReader(dh("0400010002"+"03010203")), iotest.ErrReader(readErr)), errstr: "truncated"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewDecoder(test.r)
+			var err error
+			for i := 0; i < 3 && err == nil; i++ {
+				var val testNonByteSlice
+				err = d.Decode(&val)
+			}
+			if test.wantErr != nil && err != test.wantErr {
+				t.Errorf("Decode()=%v; want %v", err, test.wantErr)
+			}
+			if test.errstr != "" && (err == nil || !strings.Contains(err.Error(), test.errstr)) {
+				t.Errorf("Decode()=%v; want error containing %q", err, test.errstr)
+			}
+		})
+	}
+}
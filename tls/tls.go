@@ -59,17 +59,39 @@ func (e syntaxError) Error() string {
 	return "tls: syntax error: " + prefix + e.msg
 }
 
+// A truncatedError is a syntaxError reporting that the TLS data ended before
+// the value being parsed.
+type truncatedError struct {
+	syntaxError
+}
+
 // Uint24 is an unsigned 3-byte integer.
 type Uint24 uint32
 
+// Uint48 is an unsigned 6-byte integer.
+type Uint48 uint64
+
 // Enum is an unsigned integer.
 type Enum uint64
 
+// FastUnmarshaler is implemented by structure types which provide a
+// hand-written decoder for their TLS encoding, to avoid the overhead of
+// reflection for frequently decoded types. UnmarshalTLS decodes the value from
+// the start of data and returns the remainder of data following it, or false
+// if the data could not be decoded. In the latter case the value is decoded by
+// reflection instead, so that errors are reported consistently; UnmarshalTLS
+// therefore only needs to handle valid encodings, but must accept exactly the
+// encodings which the reflective decoder accepts, with the same results.
+type FastUnmarshaler interface {
+	UnmarshalTLS(data []byte) (rest []byte, ok bool)
+}
+
 var (
 	uint8Type  = reflect.TypeOf(uint8(0))
 	uint16Type = reflect.TypeOf(uint16(0))
 	uint24Type = reflect.TypeOf(Uint24(0))
 	uint32Type = reflect.TypeOf(uint32(0))
+	uint48Type = reflect.TypeOf(Uint48(0))
 	uint64Type = reflect.TypeOf(uint64(0))
 	enumType   = reflect.TypeOf(Enum(0))
 )
@@ -88,6 +110,7 @@ var (
 //	uint16		uint16
 //	uint24		tls.Uint24
 //	uint32		uint32
+//	uint48		tls.Uint48
 //	uint64		uint64
 //	enum		tls.Enum	size:S or maxval:N
 //	Type<N,M>	[]Type		minlen:N,maxlen:M
@@ -97,6 +120,7 @@ var (
 //	select(T) {
 //	 case e1: Type	*T		selector:Field,val:e1
 //	}
+//	optional<T>	*T		optional
 //
 // TLS variants (RFC 5246 s4.6.1) are only supported when the value of the
 // associated enumeration type is available earlier in the same enclosing
@@ -123,6 +147,14 @@ var (
 //	   Data32 *uint32   `tls:"selector:Sel,val:2"`
 //	 }
 //
+// An optional value is encoded as a presence byte, 0 if the value is absent or
+// 1 if it is present, followed by the value itself when present. The Go field
+// must be a pointer, which is nil when the value is absent. Other tags of an
+// optional field apply to the pointed-to value, so for example an optional
+// vector tagged with "optional,minlen:1,maxlen:255" is encoded as a presence
+// byte, then (if present) a 1-byte length and 1 to 255 bytes of data. Optional
+// fields cannot also be variants.
+//
 // TLS fixed-length vectors of types other than opaque or uint8 are not supported.
 //
 // For TLS variable-length vectors that are themselves used in other vectors,
@@ -142,6 +174,9 @@ var (
 //	   Inners []InnerType  `tls:"minlen:1,maxlen:65535"`
 //	}
 //
+// Structures implementing FastUnmarshaler are decoded by their UnmarshalTLS
+// method rather than by reflection where possible.
+//
 // If the encoded value does not fit in the Go type, Unmarshal returns a parse error.
 func Unmarshal(b []byte, val interface{}) ([]byte, error) {
 	return UnmarshalWithParams(b, val, "")
@@ -194,6 +229,7 @@ type fieldInfo struct {
 	maxlen   uint64 // Only relevant for slices
 	selector string // Only relevant for select sub-values
 	val      uint64 // Only relevant for select sub-values
+	optional bool   // Whether the value is preceded by a presence byte
 	name     string // Used for better error messages
 }
 
@@ -207,9 +243,12 @@ func (i *fieldInfo) fieldName() string {
 // Given a tag string, return a fieldInfo describing the field.
 func fieldTagToFieldInfo(str string, name string) (*fieldInfo, error) {
 	var info *fieldInfo
+	optional := false
 	// Iterate over clauses in the tag, ignoring any that don't parse properly.
 	for _, part := range strings.Split(str, ",") {
 		switch {
+		case part == "optional":
+			optional = true
 		case strings.HasPrefix(part, "maxval:"):
 			if v, err := strconv.ParseUint(part[7:], 10, 64); err == nil {
 				info = &fieldInfo{count: byteCount(v), countSet: true}
@@ -267,9 +306,15 @@ func fieldTagToFieldInfo(str string, name string) (*fieldInfo, error) {
 				return nil, structuralError{name, "specified selector value but not field in " + str}
 			}
 		}
-	} else if name != "" {
+	} else if name != "" || optional {
 		info = &fieldInfo{name: name}
 	}
+	if optional {
+		if info.selector != "" {
+			return nil, structuralError{name, "optional field cannot have a selector in " + str}
+		}
+		info.optional = true
+	}
 	return info, nil
 }
 
@@ -296,7 +341,7 @@ func readVarUint(data []byte, info *fieldInfo) (uint64, error) {
 		return 0, structuralError{info.fieldName(), "no field size information available"}
 	}
 	if len(data) < int(info.count) {
-		return 0, syntaxError{info.fieldName(), "truncated variable-length integer"}
+		return 0, truncatedError{syntaxError{info.fieldName(), "truncated variable-length integer"}}
 	}
 	var result uint64
 	for i := uint(0); i < info.count; i++ {
@@ -316,39 +361,75 @@ func parseField(v reflect.Value, data []byte, initOffset int, info *fieldInfo) (
 	rest := data[offset:]
 
 	fieldType := v.Type()
+	if info != nil && info.optional {
+		if fieldType.Kind() != reflect.Ptr {
+			return offset, structuralError{info.fieldName(), "optional field not a pointer type"}
+		}
+		if len(rest) < 1 {
+			return offset, truncatedError{syntaxError{info.fieldName(), "truncated presence byte"}}
+		}
+		switch rest[0] {
+		case 0:
+			v.Set(reflect.Zero(fieldType))
+			return offset + 1, nil
+		case 1:
+		default:
+			return offset, syntaxError{info.fieldName(), fmt.Sprintf("invalid presence byte %d", rest[0])}
+		}
+		v.Set(reflect.New(fieldType.Elem()))
+		inner := *info
+		inner.optional = false
+		return parseField(v.Elem(), data, offset+1, &inner)
+	}
+
+	if v.Kind() == reflect.Struct && v.CanAddr() {
+		if u, ok := v.Addr().Interface().(FastUnmarshaler); ok {
+			if after, ok := u.UnmarshalTLS(rest); ok {
+				return len(data) - len(after), nil
+			}
+		}
+	}
+
 	// First look for known fixed types.
 	switch fieldType {
 	case uint8Type:
 		if len(rest) < 1 {
-			return offset, syntaxError{info.fieldName(), "truncated uint8"}
+			return offset, truncatedError{syntaxError{info.fieldName(), "truncated uint8"}}
 		}
 		v.SetUint(uint64(rest[0]))
 		offset++
 		return offset, nil
 	case uint16Type:
 		if len(rest) < 2 {
-			return offset, syntaxError{info.fieldName(), "truncated uint16"}
+			return offset, truncatedError{syntaxError{info.fieldName(), "truncated uint16"}}
 		}
 		v.SetUint(uint64(binary.BigEndian.Uint16(rest)))
 		offset += 2
 		return offset, nil
 	case uint24Type:
 		if len(rest) < 3 {
-			return offset, syntaxError{info.fieldName(), "truncated uint24"}
+			return offset, truncatedError{syntaxError{info.fieldName(), "truncated uint24"}}
 		}
-		v.SetUint(uint64(data[0])<<16 | uint64(data[1])<<8 | uint64(data[2]))
+		v.SetUint(uint64(rest[0])<<16 | uint64(rest[1])<<8 | uint64(rest[2]))
 		offset += 3
 		return offset, nil
 	case uint32Type:
 		if len(rest) < 4 {
-			return offset, syntaxError{info.fieldName(), "truncated uint32"}
+			return offset, truncatedError{syntaxError{info.fieldName(), "truncated uint32"}}
 		}
 		v.SetUint(uint64(binary.BigEndian.Uint32(rest)))
 		offset += 4
 		return offset, nil
+	case uint48Type:
+		if len(rest) < 6 {
+			return offset, truncatedError{syntaxError{info.fieldName(), "truncated uint48"}}
+		}
+		v.SetUint(uint64(binary.BigEndian.Uint16(rest))<<32 | uint64(binary.BigEndian.Uint32(rest[2:])))
+		offset += 6
+		return offset, nil
 	case uint64Type:
 		if len(rest) < 8 {
-			return offset, syntaxError{info.fieldName(), "truncated uint64"}
+			return offset, truncatedError{syntaxError{info.fieldName(), "truncated uint64"}}
 		}
 		v.SetUint(uint64(binary.BigEndian.Uint64(rest)))
 		offset += 8
@@ -449,7 +530,7 @@ func parseField(v reflect.Value, data []byte, initOffset int, info *fieldInfo) (
 		datalen := v.Len()
 
 		if datalen > len(rest) {
-			return offset, syntaxError{info.fieldName(), "truncated array"}
+			return offset, truncatedError{syntaxError{info.fieldName(), "truncated array"}}
 		}
 		inner := rest[:datalen]
 		offset += datalen
@@ -473,7 +554,7 @@ func parseField(v reflect.Value, data []byte, initOffset int, info *fieldInfo) (
 		rest = rest[info.count:]
 
 		if datalen > len(rest) {
-			return offset, syntaxError{info.fieldName(), "truncated slice"}
+			return offset, truncatedError{syntaxError{info.fieldName(), "truncated slice"}}
 		}
 		inner := rest[:datalen]
 		offset += datalen
@@ -490,6 +571,11 @@ func parseField(v reflect.Value, data []byte, initOffset int, info *fieldInfo) (
 			var err error
 			innerOffset, err = parseField(single.Elem(), inner, innerOffset, nil)
 			if err != nil {
+				// The length of the vector is known, so running out of its data
+				// is not a sign that more data is to come.
+				if te, ok := err.(truncatedError); ok {
+					err = te.syntaxError
+				}
 				return offset, err
 			}
 			v.Set(reflect.Append(v, single.Elem()))
@@ -528,6 +614,19 @@ func marshalField(out *bytes.Buffer, v reflect.Value, info *fieldInfo) error {
 		prefix = info.name + ": "
 	}
 	fieldType := v.Type()
+	if info != nil && info.optional {
+		if fieldType.Kind() != reflect.Ptr {
+			return structuralError{info.fieldName(), "optional field not a pointer type"}
+		}
+		if v.IsNil() {
+			out.WriteByte(0)
+			return nil
+		}
+		out.WriteByte(1)
+		inner := *info
+		inner.optional = false
+		return marshalField(out, v.Elem(), &inner)
+	}
 	// First look for known fixed types.
 	switch fieldType {
 	case uint8Type:
@@ -552,6 +651,15 @@ func marshalField(out *bytes.Buffer, v reflect.Value, info *fieldInfo) error {
 		binary.BigEndian.PutUint32(scratch, uint32(v.Uint()))
 		out.Write(scratch)
 		return nil
+	case uint48Type:
+		i := v.Uint()
+		if i > 0xffffffffffff {
+			return structuralError{info.fieldName(), fmt.Sprintf("uint48 overflow %d", i)}
+		}
+		scratch := make([]byte, 8)
+		binary.BigEndian.PutUint64(scratch, i)
+		out.Write(scratch[2:])
+		return nil
 	case uint64Type:
 		scratch := make([]byte, 8)
 		binary.BigEndian.PutUint64(scratch, uint64(v.Uint()))
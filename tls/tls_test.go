@@ -64,6 +64,19 @@ type testInnerType struct {
 	Val []byte `tls:"minlen:0,maxlen:65535"`
 }
 
+type testOptional struct {
+	Before uint8
+	Inner  *testStruct `tls:"optional"`
+	Data   *[]byte     `tls:"optional,minlen:1,maxlen:4"`
+	After  uint8
+}
+
+type testWideInts struct {
+	Before uint8
+	Val24  Uint24
+	Val48  Uint48
+}
+
 type testSliceOfSlices struct {
 	Inners []testInnerType `tls:"minlen:0,maxlen:65535"`
 }
@@ -137,6 +150,10 @@ func TestFieldTagToFieldInfo(t *testing.T) {
 		{"selector:Bob,val:x9", &fieldInfo{selector: "Bob"}, ""},
 		{"selector:Fred,val:1", &fieldInfo{selector: "Fred", val: 1}, ""},
 		{"val:9,selector:Fred,val:1", &fieldInfo{selector: "Fred", val: 1}, ""},
+		{"optional", &fieldInfo{optional: true}, ""},
+		{"optional,minlen:1,maxlen:255", &fieldInfo{count: 1, countSet: true, minlen: 1, maxlen: 255, optional: true}, ""},
+		{"optional,minlen:1", nil, "unknown size"},
+		{"optional,selector:Fred,val:1", nil, "cannot have a selector"},
 	}
 	for _, test := range tests {
 		got, err := fieldTagToFieldInfo(test.tag, "")
@@ -162,6 +179,7 @@ func newUint8(n uint8) *uint8    { return &n }
 func newUint16(n uint16) *uint16 { return &n }
 func newUint24(n Uint24) *Uint24 { return &n }
 func newUint32(n uint32) *uint32 { return &n }
+func newUint48(n Uint48) *Uint48 { return &n }
 func newUint64(n uint64) *uint64 { return &n }
 func newInt16(n int16) *int16    { return &n }
 func newEnum(n Enum) *Enum       { return &n }
@@ -177,7 +195,11 @@ func TestUnmarshalMarshalWithParamsRoundTrip(t *testing.T) {
 		{"0101", "", newUint16(0x0101)},
 		{"010203", "", newUint24(0x010203)},
 		{"000000", "", newUint24(0x00)},
+		{"ffffff", "", newUint24(0xffffff)},
 		{"00000009", "", newUint32(0x09)},
+		{"010203040506", "", newUint48(0x010203040506)},
+		{"000000000000", "", newUint48(0x00)},
+		{"ffffffffffff", "", newUint48(0xffffffffffff)},
 		{"0000000901020304", "", newUint64(0x0901020304)},
 		{"030405", "", &[3]byte{3, 4, 5}},
 		{"03", "", &[1]byte{3}},
@@ -201,6 +223,7 @@ func TestUnmarshalMarshalWithParamsRoundTrip(t *testing.T) {
 				},
 			},
 		},
+		{"01ffffff000000000001", "", &testWideInts{Before: 1, Val24: 0xffffff, Val48: 1}},
 		{"000a00030102030003040506", "",
 			&testSliceOfSlices{
 				Inners: []testInnerType{
@@ -210,6 +233,25 @@ func TestUnmarshalMarshalWithParamsRoundTrip(t *testing.T) {
 			},
 		},
 		{"011011", "", &testAliasEnum{Val: 1, Val16: newUint16(0x1011)}},
+		{"00", "optional", new(*uint16)},
+		{"010102", "optional", func() **uint16 { v := newUint16(0x0102); return &v }()},
+		{"01000002", "", &testOptional{Before: 1, After: 2}},
+		{"0101020a0b01010102030400110002", "",
+			&testOptional{
+				Before: 1,
+				Inner:  &testStruct{Data: []byte{0xa, 0xb}, IntVal: 0x101, Other: [4]byte{1, 2, 3, 4}, Enum: 17},
+				After:  2,
+			},
+		},
+		{"0100010301020302", "", &testOptional{Before: 1, Data: &[]byte{1, 2, 3}, After: 2}},
+		{"0101020a0b010101020304001101020a0b02", "",
+			&testOptional{
+				Before: 1,
+				Inner:  &testStruct{Data: []byte{0xa, 0xb}, IntVal: 0x101, Other: [4]byte{1, 2, 3, 4}, Enum: 17},
+				Data:   &[]byte{0xa, 0xb},
+				After:  2,
+			},
+		},
 		{"0403", "", &SignatureAndHashAlgorithm{Hash: SHA256, Signature: ECDSA}},
 		{"04030003010203", "",
 			&DigitallySigned{
@@ -272,6 +314,8 @@ func TestUnmarshalWithParamsFailures(t *testing.T) {
 		{"0103", "", newUint24(0x010203), "truncated"},
 		{"00", "", newUint24(0x00), "truncated"},
 		{"000009", "", newUint32(0x09), "truncated"},
+		{"0102030405", "", newUint48(0x0102030405), "truncated"},
+		{"01ffffff0000000000", "", &testWideInts{}, "truncated"},
 		{"00000901020304", "", newUint64(0x0901020304), "truncated"},
 		{"0102", "", newInt16(0x0102), "unsupported type"}, // TLS encoding only supports unsigned integers
 		{"0607", "", &[3]byte{6, 7, 8}, "truncated array"},
@@ -296,6 +340,11 @@ func TestUnmarshalWithParamsFailures(t *testing.T) {
 		{"0102", "", &testMissingSelector{Val: newUint16(1)}, "selector not seen"},
 		{"000007", "", &testChoiceNotPointer{Which: 0, Val: 7}, "choice field not a pointer type"},
 		{"05010102020303", "", &testNonByteSlice{Vals: []uint16{0x101, 0x202, 0x303}}, "truncated"},
+		{"", "optional", new(*uint16), "truncated"},
+		{"02", "optional", new(*uint16), "invalid presence byte"},
+		{"01", "optional", new(*uint16), "truncated"},
+		{"0102", "optional", newUint16(0x0102), "not a pointer"},
+		{"010001050102030405", "", &testOptional{}, "too large"},
 		{"0101", "size:2", newNonEnumAlias(0x0102), "unsupported type"},
 		{"0403010203", "",
 			&DigitallySigned{
@@ -321,6 +370,8 @@ func TestMarshalWithParamsFailures(t *testing.T) {
 		errstr string
 	}{
 		{Uint24(0x1000000), "", "overflow"},
+		{Uint48(0x1000000000000), "", "overflow"},
+		{testWideInts{Val24: 0x1000000}, "", "overflow"},
 		{int16(0x0102), "", "unsupported type"}, // All TLS ints are unsigned
 		{Enum(1), "", "field tag missing"},
 		{Enum(256), "size:1", "too large"},
@@ -343,6 +394,8 @@ func TestMarshalWithParamsFailures(t *testing.T) {
 		{testDuplicateSelectorVal{Which: 0, Val: newUint16(1)}, "", "duplicate selector value"},
 		{testNonByteSlice{Vals: []uint16{1, 2, 3, 4}}, "", "too large"},
 		{testSliceOfStructs{[]testVariant{{Which: 3}}}, "", "unhandled value for selector"},
+		{uint16(1), "optional", "not a pointer"},
+		{testOptional{Data: &[]byte{}}, "", "too small"},
 		{nonEnumAlias(0x0102), "", "unsupported type"},
 	}
 	for _, test := range tests {
@@ -353,3 +406,59 @@ func TestMarshalWithParamsFailures(t *testing.T) {
 		}
 	}
 }
+
+// testFast decodes its encoding itself, but leaves values above 0x7f to the
+// reflective decoder.
+type testFast struct {
+	Val uint8
+}
+
+var testFastDecodes int
+
+func (f *testFast) UnmarshalTLS(data []byte) ([]byte, bool) {
+	if len(data) < 1 || data[0] > 0x7f {
+		return nil, false
+	}
+	testFastDecodes++
+	f.Val = data[0]
+	return data[1:], true
+}
+
+type testFastOuter struct {
+	Before uint8
+	Inner  testFast
+	After  uint8
+}
+
+func TestFastUnmarshaler(t *testing.T) {
+	var tests = []struct {
+		data     string // hex encoded
+		want     testFastOuter
+		wantFast bool
+		errstr   string
+	}{
+		{data: "010203", want: testFastOuter{Before: 1, Inner: testFast{Val: 2}, After: 3}, wantFast: true},
+		{data: "018003", want: testFastOuter{Before: 1, Inner: testFast{Val: 0x80}, After: 3}},
+		{data: "01", errstr: "truncated"},
+	}
+	for _, test := range tests {
+		in, _ := hex.DecodeString(test.data)
+		testFastDecodes = 0
+		var got testFastOuter
+		_, err := Unmarshal(in, &got)
+		if test.errstr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.errstr) {
+				t.Errorf("Unmarshal(%s)=_,%v; want error %q", test.data, err, test.errstr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unmarshal(%s)=_,%v; want _,nil", test.data, err)
+		} else if got != test.want {
+			t.Errorf("Unmarshal(%s)=%+v; want %+v", test.data, got, test.want)
+		}
+		if gotFast := testFastDecodes > 0; gotFast != test.wantFast {
+			t.Errorf("Unmarshal(%s) used UnmarshalTLS: %t; want %t", test.data, gotFast, test.wantFast)
+		}
+	}
+}
@@ -224,10 +224,11 @@ func verifyChain(rawChain []ct.ASN1Cert) error {
 	rootPool := x509.NewCertPool()
 	rootPool.AddCert(chain[len(chain)-1])
 	opts := x509.VerifyOptions{
-		Roots:             rootPool,
-		Intermediates:     intermediatePool,
-		DisableTimeChecks: true,
-		KeyUsages:         []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		Roots:                 rootPool,
+		Intermediates:         intermediatePool,
+		DisableTimeChecks:     true,
+		AcceptPrecertificates: true,
+		KeyUsages:             []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
 	}
 
 	chain[0].UnhandledCriticalExtensions = nil
@@ -531,6 +531,44 @@ func (r *GetSTHResponse) ToSignedTreeHead() (*SignedTreeHead, error) {
 	return &sth, nil
 }
 
+// ToGetSTHResponse creates a GetSTHResponse from the SignedTreeHead, i.e. the
+// inverse of GetSTHResponse.ToSignedTreeHead.  The Version and LogID fields are
+// not part of the get-sth response, and so are dropped.
+func (s *SignedTreeHead) ToGetSTHResponse() (*GetSTHResponse, error) {
+	sig, err := tls.Marshal(s.TreeHeadSignature)
+	if err != nil {
+		return nil, fmt.Errorf("tls.Marshal(): %s", err)
+	}
+	return &GetSTHResponse{
+		TreeSize:          s.TreeSize,
+		Timestamp:         s.Timestamp,
+		SHA256RootHash:    s.SHA256RootHash[:],
+		TreeHeadSignature: sig,
+	}, nil
+}
+
+// MarshalSTHJSON encodes the SignedTreeHead in the JSON format of a get-sth
+// response (section 4.3), with the root hash and signature base64-encoded.
+// Unlike json.Marshal of a SignedTreeHead, the output is independent of this
+// package's Go types, so is suitable for persisting STHs.
+func MarshalSTHJSON(sth *SignedTreeHead) ([]byte, error) {
+	rsp, err := sth.ToGetSTHResponse()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(rsp)
+}
+
+// UnmarshalSTHJSON decodes a SignedTreeHead from the JSON format of a get-sth
+// response (section 4.3), as produced by MarshalSTHJSON.
+func UnmarshalSTHJSON(b []byte) (*SignedTreeHead, error) {
+	var rsp GetSTHResponse
+	if err := json.Unmarshal(b, &rsp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal get-sth response: %v", err)
+	}
+	return rsp.ToSignedTreeHead()
+}
+
 // GetSTHConsistencyResponse represents the JSON response to the get-sth-consistency
 // GET method from section 4.4.  (The corresponding GET request has parameters 'first' and
 // 'second'.)
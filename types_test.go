@@ -17,11 +17,13 @@ package ct
 import (
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/google/certificate-transparency-go/tls"
+	"github.com/google/go-cmp/cmp"
 )
 
 const (
@@ -246,3 +248,115 @@ func TestSTHString(t *testing.T) {
 		})
 	}
 }
+
+func TestSTHJSONRoundTrip(t *testing.T) {
+	rsp := &GetSTHResponse{
+		TreeSize:          278437663,
+		Timestamp:         1527076172068,
+		SHA256RootHash:    mustHexDecode(validRootHash),
+		TreeHeadSignature: mustHexDecode(validSignature),
+	}
+	sth, err := rsp.ToSignedTreeHead()
+	if err != nil {
+		t.Fatalf("ToSignedTreeHead(): %v", err)
+	}
+	for _, test := range []struct {
+		desc string
+		sth  SignedTreeHead
+	}{
+		{desc: "valid", sth: *sth},
+		{desc: "empty-tree", sth: SignedTreeHead{TreeHeadSignature: sth.TreeHeadSignature}},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			data, err := MarshalSTHJSON(&test.sth)
+			if err != nil {
+				t.Fatalf("MarshalSTHJSON(): %v", err)
+			}
+			got, err := UnmarshalSTHJSON(data)
+			if err != nil {
+				t.Fatalf("UnmarshalSTHJSON(%s): %v", data, err)
+			}
+			if diff := cmp.Diff(got, &test.sth); diff != "" {
+				t.Errorf("UnmarshalSTHJSON(MarshalSTHJSON()) diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSTHJSONDropsLogID(t *testing.T) {
+	sth := SignedTreeHead{
+		Version:           V1,
+		TreeSize:          10,
+		TreeHeadSignature: DigitallySigned{Signature: []byte("sig")},
+	}
+	if err := sth.LogID.FromBase64String("aPaY+B9kgr46jO65KB1M/HFRXWeT1ETRCmesu09P+8Q="); err != nil {
+		t.Fatalf("FromBase64String(): %v", err)
+	}
+	data, err := MarshalSTHJSON(&sth)
+	if err != nil {
+		t.Fatalf("MarshalSTHJSON(): %v", err)
+	}
+	if strings.Contains(string(data), "log_id") || strings.Contains(string(data), "sth_version") {
+		t.Errorf("MarshalSTHJSON()=%s, want no log_id or sth_version fields", data)
+	}
+}
+
+func TestUnmarshalSTHJSON(t *testing.T) {
+	// Response body from a real get-sth request to the Pilot log.
+	const pilotSTH = `{"tree_size":3721782,"timestamp":1396609800587,` +
+		`"sha256_root_hash":"SxKOxksguvHPyUaKYKXoZHzXl91Q257+JQ0AUMlFfeo=",` +
+		`"tree_head_signature":"BAMARjBEAiBUYO2tODlUUw4oWGiVPUHqZadRRyXs9T2rSXchA79VsQIgLASkQv3cu4XdPFCZbgFkIUefniNPCpO3LzzHX53l+wg="}`
+
+	for _, test := range []struct {
+		desc    string
+		body    string
+		wantErr string
+	}{
+		{desc: "pilot", body: pilotSTH},
+		{desc: "not-json", body: "tree_size=1", wantErr: "failed to unmarshal"},
+		{desc: "bad-base64", body: `{"tree_size":1,"sha256_root_hash":"!!!","tree_head_signature":""}`, wantErr: "failed to unmarshal"},
+		{desc: "short-root-hash", body: `{"tree_size":1,"sha256_root_hash":"AAAA","tree_head_signature":"BAMAAA=="}`, wantErr: "sha256_root_hash is invalid length"},
+		{desc: "no-signature", body: `{"tree_size":1,"sha256_root_hash":"SxKOxksguvHPyUaKYKXoZHzXl91Q257+JQ0AUMlFfeo="}`, wantErr: "tls.Unmarshal"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			sth, err := UnmarshalSTHJSON([]byte(test.body))
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("UnmarshalSTHJSON()=%v, %v, want err containing %q", sth, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnmarshalSTHJSON(): %v", err)
+			}
+			if got, want := sth.TreeSize, uint64(3721782); got != want {
+				t.Errorf("TreeSize=%d, want %d", got, want)
+			}
+			if got, want := sth.Timestamp, uint64(1396609800587); got != want {
+				t.Errorf("Timestamp=%d, want %d", got, want)
+			}
+			if got, want := sth.SHA256RootHash.Base64String(), "SxKOxksguvHPyUaKYKXoZHzXl91Q257+JQ0AUMlFfeo="; got != want {
+				t.Errorf("SHA256RootHash=%s, want %s", got, want)
+			}
+			if got, want := sth.TreeHeadSignature.Algorithm.Hash, tls.SHA256; got != want {
+				t.Errorf("TreeHeadSignature.Algorithm.Hash=%v, want %v", got, want)
+			}
+
+			// Re-encoding must give back the same JSON, modulo field order and spacing.
+			data, err := MarshalSTHJSON(sth)
+			if err != nil {
+				t.Fatalf("MarshalSTHJSON(): %v", err)
+			}
+			var got, want map[string]interface{}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("json.Unmarshal(%s): %v", data, err)
+			}
+			if err := json.Unmarshal([]byte(test.body), &want); err != nil {
+				t.Fatalf("json.Unmarshal(%s): %v", test.body, err)
+			}
+			if diff := cmp.Diff(got, want); diff != "" {
+				t.Errorf("MarshalSTHJSON() diff (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -199,6 +199,11 @@ type VerifyOptions struct {
 	DisableEKUChecks               bool
 	DisablePathLenChecks           bool
 	DisableNameConstraintChecks    bool
+	// AcceptPrecertificates allows the leaf to carry the CT poison extension.
+	// The poison is recognized by the parser rather than being recorded in
+	// UnhandledCriticalExtensions, but precertificates are still rejected by
+	// default as they are not usable as ordinary certificates.
+	AcceptPrecertificates bool
 	// KeyUsage specifies which Extended Key Usage values are acceptable. A leaf
 	// certificate is accepted if it contains any of the listed values. An empty
 	// list means ExtKeyUsageServerAuth. To accept any key usage, include
@@ -571,6 +576,9 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 	if !opts.DisableCriticalExtensionChecks && len(c.UnhandledCriticalExtensions) > 0 {
 		return UnhandledCriticalExtension{ID: c.UnhandledCriticalExtensions[0]}
 	}
+	if !opts.DisableCriticalExtensionChecks && !opts.AcceptPrecertificates && c.IsPrecertificate() {
+		return UnhandledCriticalExtension{ID: OIDExtensionCTPoison}
+	}
 
 	if !opts.DisableNameChecks && len(currentChain) > 0 {
 		child := currentChain[len(currentChain)-1]
@@ -1164,6 +1164,12 @@ func (h UnhandledCriticalExtension) Error() string {
 // result still as a DER-encoded TBSCertificate.  This function will fail if
 // there is not exactly 1 extension of the type specified by the oid present.
 func removeExtension(tbsData []byte, oid asn1.ObjectIdentifier) ([]byte, error) {
+	return replaceExtension(tbsData, oid, nil)
+}
+
+// replaceExtension is like removeExtension, but if replacement is not nil it
+// takes the place of the removed extension.
+func replaceExtension(tbsData []byte, oid asn1.ObjectIdentifier, replacement *pkix.Extension) ([]byte, error) {
 	var tbs tbsCertificate
 	rest, err := asn1.Unmarshal(tbsData, &tbs)
 	if err != nil {
@@ -1183,7 +1189,11 @@ func removeExtension(tbsData []byte, oid asn1.ObjectIdentifier) ([]byte, error)
 	if extAt == -1 {
 		return nil, errors.New("no extension of specified type present")
 	}
-	tbs.Extensions = append(tbs.Extensions[:extAt], tbs.Extensions[extAt+1:]...)
+	if replacement != nil {
+		tbs.Extensions[extAt] = *replacement
+	} else {
+		tbs.Extensions = append(tbs.Extensions[:extAt], tbs.Extensions[extAt+1:]...)
+	}
 	// Clear out the asn1.RawContent so the re-marshal operation sees the
 	// updated structure (rather than just copying the out-of-date DER data).
 	tbs.Raw = nil
@@ -1203,6 +1213,16 @@ func RemoveSCTList(tbsData []byte) ([]byte, error) {
 	return removeExtension(tbsData, OIDExtensionCTSCT)
 }
 
+// ReplaceSCTListWithPoison takes a DER-encoded TBSCertificate and replaces the
+// CT SCT extension that contains the SCT list with the CT poison extension, at
+// the same position, and returns the result still as a DER-encoded
+// TBSCertificate.  This function will fail if there is not exactly 1 CT SCT
+// extension present.
+func ReplaceSCTListWithPoison(tbsData []byte) ([]byte, error) {
+	poison := pkix.Extension{Id: OIDExtensionCTPoison, Critical: true, Value: asn1.NullBytes}
+	return replaceExtension(tbsData, OIDExtensionCTSCT, &poison)
+}
+
 // RemoveCTPoison takes a DER-encoded TBSCertificate and removes the CT poison
 // extension (preserving the order of other extensions), and returns the result
 // still as a DER-encoded TBSCertificate.  This function will fail if there is
@@ -2061,6 +2081,16 @@ func parseCertificate(in *certificate) (*Certificate, error) {
 					nfe.AddError(errors.New("trailing data after TLS-encoded SCT list"))
 				}
 			}
+		} else if e.Id.Equal(OIDExtensionCTPoison) {
+			// RFC 6962 s3.1: the poison extension is critical and holds an
+			// ASN.1 NULL; precertificates are identified by its presence, so
+			// it is not reported as an unhandled critical extension.
+			if !e.Critical {
+				nfe.AddError(errors.New("x509: CT poison extension is not critical"))
+			}
+			if !bytes.Equal(e.Value, asn1.NullBytes) {
+				nfe.AddError(errors.New("x509: CT poison extension does not contain ASN.1 NULL"))
+			}
 		} else {
 			// Unknown extensions are recorded if critical.
 			unhandled = true
@@ -30,6 +30,7 @@ import (
 	"time"
 
 	"github.com/google/certificate-transparency-go/asn1"
+	"github.com/google/certificate-transparency-go/testdata"
 	"github.com/google/certificate-transparency-go/x509/pkix"
 	"golang.org/x/crypto/ed25519"
 )
@@ -1790,6 +1791,41 @@ func TestRemoveCTPoison(t *testing.T) {
 	}
 }
 
+func TestRemoveCTPoisonMatchesFinalCert(t *testing.T) {
+	parse := func(pemData string) *Certificate {
+		t.Helper()
+		block, _ := pem.Decode([]byte(pemData))
+		if block == nil {
+			t.Fatal("failed to decode PEM")
+		}
+		cert, err := ParseCertificate(block.Bytes)
+		if IsFatal(err) {
+			t.Fatalf("failed to parse certificate: %v", err)
+		}
+		return cert
+	}
+	// The final certificate was issued from the precertificate, so both
+	// reduce to the same TBSCertificate once their CT extensions are removed.
+	precert := parse(testdata.TestPreCertPEM)
+	cert := parse(testdata.TestEmbeddedCertPEM)
+
+	got, err := RemoveCTPoison(precert.RawTBSCertificate)
+	if err != nil {
+		t.Fatalf("RemoveCTPoison(precert)=nil,%v; want _,nil", err)
+	}
+	want, err := RemoveSCTList(cert.RawTBSCertificate)
+	if err != nil {
+		t.Fatalf("RemoveSCTList(cert)=nil,%v; want _,nil", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("RemoveCTPoison(precert)=%x; want %x", got, want)
+	}
+
+	if _, err := RemoveCTPoison(cert.RawTBSCertificate); err == nil {
+		t.Error("RemoveCTPoison(cert)=_,nil; want error")
+	}
+}
+
 func makeCert(t *testing.T, template, issuer *Certificate) *Certificate {
 	t.Helper()
 	certData, err := CreateCertificate(rand.Reader, template, issuer, &testPrivateKey.PublicKey, testPrivateKey)
@@ -1803,6 +1839,115 @@ func makeCert(t *testing.T, template, issuer *Certificate) *Certificate {
 	return cert
 }
 
+func TestParseCTPoison(t *testing.T) {
+	tests := []struct {
+		name       string
+		pemData    string
+		wantPoison bool
+	}{
+		{name: "precert", pemData: testdata.TestPreCertPEM, wantPoison: true},
+		{name: "cert", pemData: testdata.TestEmbeddedCertPEM, wantPoison: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			block, _ := pem.Decode([]byte(test.pemData))
+			if block == nil {
+				t.Fatal("failed to decode PEM")
+			}
+			cert, err := ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatalf("ParseCertificate()=nil,%v; want _,nil", err)
+			}
+			if got := cert.IsPrecertificate(); got != test.wantPoison {
+				t.Errorf("IsPrecertificate()=%v; want %v", got, test.wantPoison)
+			}
+			for _, oid := range cert.UnhandledCriticalExtensions {
+				if oid.Equal(OIDExtensionCTPoison) {
+					t.Errorf("UnhandledCriticalExtensions contains CT poison")
+				}
+			}
+		})
+	}
+}
+
+func TestParseCTPoisonInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		ext     pkix.Extension
+		wantErr string
+	}{
+		{
+			name:    "non-critical",
+			ext:     pkix.Extension{Id: OIDExtensionCTPoison, Critical: false, Value: asn1.NullBytes},
+			wantErr: "not critical",
+		},
+		{
+			name:    "non-null",
+			ext:     pkix.Extension{Id: OIDExtensionCTPoison, Critical: true, Value: []byte{0x02, 0x01, 0x01}},
+			wantErr: "does not contain ASN.1 NULL",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			template := Certificate{
+				SerialNumber:    big.NewInt(1),
+				Subject:         pkix.Name{CommonName: "precert"},
+				NotBefore:       time.Now(),
+				NotAfter:        time.Now().Add(time.Hour),
+				ExtraExtensions: []pkix.Extension{test.ext},
+			}
+			der, err := CreateCertificate(rand.Reader, &template, &template, &testPrivateKey.PublicKey, testPrivateKey)
+			if err != nil {
+				t.Fatalf("CreateCertificate()=nil,%v; want _,nil", err)
+			}
+			cert, err := ParseCertificate(der)
+			if err == nil || IsFatal(err) {
+				t.Fatalf("ParseCertificate()=_,%v; want non-fatal error", err)
+			}
+			if !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("ParseCertificate()=_,%v; want error containing %q", err, test.wantErr)
+			}
+			if !cert.IsPrecertificate() {
+				t.Error("IsPrecertificate()=false; want true")
+			}
+		})
+	}
+}
+
+func TestVerifyPrecertificate(t *testing.T) {
+	poisonExt := pkix.Extension{Id: OIDExtensionCTPoison, Critical: true, Value: asn1.NullBytes}
+	template := Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "precert"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		ExtraExtensions:       []pkix.Extension{poisonExt},
+	}
+	der, err := CreateCertificate(rand.Reader, &template, &template, &testPrivateKey.PublicKey, testPrivateKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate()=nil,%v; want _,nil", err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate()=nil,%v; want _,nil", err)
+	}
+	roots := NewCertPool()
+	roots.AddCert(cert)
+
+	opts := VerifyOptions{Roots: roots, KeyUsages: []ExtKeyUsage{ExtKeyUsageAny}}
+	_, err = cert.Verify(opts)
+	if ext, ok := err.(UnhandledCriticalExtension); !ok || !ext.ID.Equal(OIDExtensionCTPoison) {
+		t.Errorf("Verify()=_,%v; want UnhandledCriticalExtension for CT poison", err)
+	}
+
+	opts.AcceptPrecertificates = true
+	if _, err := cert.Verify(opts); err != nil {
+		t.Errorf("Verify(AcceptPrecertificates)=_,%v; want _,nil", err)
+	}
+}
+
 func TestBuildPrecertTBS(t *testing.T) {
 	poisonExt := pkix.Extension{Id: OIDExtensionCTPoison, Critical: true, Value: asn1.NullBytes}
 	preIssuerKeyID := []byte{0x19, 0x09, 0x19, 0x70}
@@ -0,0 +1,107 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package x509util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+// ReorderChain sorts certs into a chain running from the leaf to the root, by
+// matching the issuer of each certificate to the subject of another one and
+// checking its signature. Every certificate must be part of the chain. If
+// roots is non-nil, the last certificate of the chain must be a member of
+// roots or be issued by one. As for Log submissions, validity periods and
+// extensions are not checked. Returns an error if no such chain exists.
+func ReorderChain(certs []*x509.Certificate, roots *x509.CertPool) ([]*x509.Certificate, error) {
+	if len(certs) == 0 {
+		return nil, errors.New("empty chain")
+	}
+
+	// The leaf is the only certificate which issues none of the others.
+	leaf := -1
+	for i, cert := range certs {
+		issuer := false
+		for j, other := range certs {
+			if i != j && issuedBy(other, cert) {
+				issuer = true
+				break
+			}
+		}
+		if issuer {
+			continue
+		}
+		if leaf >= 0 {
+			return nil, fmt.Errorf("certificates at index %d and %d are both leaves", leaf, i)
+		}
+		leaf = i
+	}
+	if leaf < 0 {
+		return nil, errors.New("no leaf certificate found")
+	}
+
+	chain := []*x509.Certificate{certs[leaf]}
+	used := make([]bool, len(certs))
+	used[leaf] = true
+	for len(chain) < len(certs) {
+		cur := chain[len(chain)-1]
+		if issuedBy(cur, cur) {
+			// Self-signed, so the chain can't go any further.
+			break
+		}
+		next := -1
+		for i, cert := range certs {
+			if !used[i] && issuedBy(cur, cert) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			break
+		}
+		chain = append(chain, certs[next])
+		used[next] = true
+	}
+	for i, u := range used {
+		if !u {
+			return nil, fmt.Errorf("certificate at index %d is not part of the chain", i)
+		}
+	}
+
+	if roots != nil {
+		opts := x509.VerifyOptions{
+			Roots:                          roots,
+			DisableTimeChecks:              true,
+			DisableCriticalExtensionChecks: true,
+			DisableNameChecks:              true,
+			DisableEKUChecks:               true,
+			DisablePathLenChecks:           true,
+			DisableNameConstraintChecks:    true,
+			KeyUsages:                      []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}
+		if _, err := chain[len(chain)-1].Verify(opts); err != nil {
+			return nil, fmt.Errorf("chain does not lead to a root: %v", err)
+		}
+	}
+	return chain, nil
+}
+
+// issuedBy reports whether cert names issuer as its issuer and is signed by it.
+func issuedBy(cert, issuer *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, issuer.RawSubject) && cert.CheckSignatureFrom(issuer) == nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package x509util_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/certificate-transparency-go/testdata"
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/certificate-transparency-go/x509util"
+)
+
+func readChain(t *testing.T, filename string) []*x509.Certificate {
+	t.Helper()
+	rawChain, err := x509util.ReadPossiblePEMFile(filename, "CERTIFICATE")
+	if err != nil {
+		t.Fatalf("ReadPossiblePEMFile(%q)=_,%v", filename, err)
+	}
+	var chain []*x509.Certificate
+	for _, der := range rawChain {
+		cert, err := x509.ParseCertificate(der)
+		if x509.IsFatal(err) {
+			t.Fatalf("ParseCertificate(%q)=_,%v", filename, err)
+		}
+		chain = append(chain, cert)
+	}
+	return chain
+}
+
+func TestReorderChain(t *testing.T) {
+	const dir = "../trillian/testdata/"
+	ordered := readChain(t, dir+"subleaf.chain")
+	misordered := readChain(t, dir+"subleaf.misordered.chain")
+	misorderedPre := readChain(t, dir+"subleaf-pre.misordered.chain")
+	otherLeaf := readChain(t, dir+"subleaf-pre.chain")[0]
+	fakeCA := readChain(t, dir+"fake-ca.cert")
+	roots := x509.NewCertPool()
+	roots.AddCert(fakeCA[0])
+	otherCA, err := x509util.CertificateFromPEM([]byte(testdata.CACertPEM))
+	if x509.IsFatal(err) {
+		t.Fatalf("CertificateFromPEM()=_,%v", err)
+	}
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(otherCA)
+
+	var tests = []struct {
+		name    string
+		certs   []*x509.Certificate
+		roots   *x509.CertPool
+		want    []*x509.Certificate
+		wantErr string
+	}{
+		// The intermediates of the misordered fixtures are swapped.
+		{name: "Misordered", certs: misordered, roots: roots, want: []*x509.Certificate{misordered[0], misordered[2], misordered[1], misordered[3]}},
+		{name: "MisorderedPrecert", certs: misorderedPre, roots: roots, want: []*x509.Certificate{misorderedPre[0], misorderedPre[2], misorderedPre[1], misorderedPre[3]}},
+		{name: "Ordered", certs: ordered, roots: roots, want: ordered},
+		{name: "Reversed", certs: []*x509.Certificate{ordered[3], ordered[2], ordered[1], ordered[0]}, roots: roots, want: ordered},
+		{name: "RootOmitted", certs: []*x509.Certificate{ordered[1], ordered[0], ordered[2]}, roots: roots, want: ordered[:3]},
+		{name: "NilRoots", certs: []*x509.Certificate{ordered[1], ordered[0]}, want: ordered[:2]},
+		{name: "LeafOnly", certs: ordered[:1], roots: roots, wantErr: "does not lead to a root"},
+		{name: "UnknownRoot", certs: ordered, roots: otherRoots, wantErr: "does not lead to a root"},
+		{name: "Gap", certs: []*x509.Certificate{ordered[0], ordered[2], ordered[3]}, roots: roots, wantErr: "both leaves"},
+		{name: "Duplicate", certs: append([]*x509.Certificate{ordered[3]}, ordered...), roots: roots, wantErr: "not part of the chain"},
+		{name: "TwoLeaves", certs: []*x509.Certificate{ordered[0], otherLeaf, ordered[1]}, roots: roots, wantErr: "both leaves"},
+		{name: "Empty", roots: roots, wantErr: "empty chain"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := x509util.ReorderChain(test.certs, test.roots)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("ReorderChain()=_,%v, want error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReorderChain()=_,%v, want nil", err)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("ReorderChain() returned %d certs, want %d", len(got), len(test.want))
+			}
+			for i := range got {
+				if !got[i].Equal(test.want[i]) {
+					t.Errorf("ReorderChain()[%d]=%q, want %q", i, got[i].Subject.CommonName, test.want[i].Subject.CommonName)
+				}
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"time"
 
 	ct "github.com/google/certificate-transparency-go"
 	"github.com/google/certificate-transparency-go/asn1"
@@ -861,6 +862,33 @@ func ExtractSCT(sctData *x509.SerializedSCT) (*ct.SignedCertificateTimestamp, er
 	return &sct, nil
 }
 
+// SCTToString generates a string describing the given SCT, in the style of
+// CertificateToString.
+func SCTToString(sct *ct.SignedCertificateTimestamp) string {
+	var result bytes.Buffer
+	result.WriteString("Signed Certificate Timestamp:\n")
+	if sct == nil {
+		result.WriteString("    <nil>\n")
+		return result.String()
+	}
+	result.WriteString(fmt.Sprintf("    Version: %v (%#x)\n", sct.SCTVersion, uint64(sct.SCTVersion)))
+	result.WriteString("    Log ID:\n")
+	result.WriteString(fmt.Sprintf("        Base64: %s\n", base64.StdEncoding.EncodeToString(sct.LogID.KeyID[:])))
+	result.WriteString("        Hex:\n")
+	appendHexData(&result, sct.LogID.KeyID[:], 16, "            ")
+	result.WriteString("\n")
+	result.WriteString(fmt.Sprintf("    Timestamp: %s (%d)\n", ct.TimestampToTime(sct.Timestamp).UTC().Format(time.RFC3339Nano), sct.Timestamp))
+	result.WriteString(fmt.Sprintf("    Extensions: %d bytes\n", len(sct.Extensions)))
+	if len(sct.Extensions) > 0 {
+		appendHexData(&result, sct.Extensions, 16, "        ")
+		result.WriteString("\n")
+	}
+	result.WriteString(fmt.Sprintf("    Signature Algorithm: %v with %v\n", sct.Signature.Algorithm.Hash, sct.Signature.Algorithm.Signature))
+	appendHexData(&result, sct.Signature.Signature, 16, "        ")
+	result.WriteString("\n")
+	return result.String()
+}
+
 // MarshalSCTsIntoSCTList serializes SCTs into SCT list.
 func MarshalSCTsIntoSCTList(scts []*ct.SignedCertificateTimestamp) (*x509.SignedCertificateTimestampList, error) {
 	var sctList x509.SignedCertificateTimestampList
@@ -879,6 +907,27 @@ func MarshalSCTsIntoSCTList(scts []*ct.SignedCertificateTimestamp) (*x509.Signed
 	return &sctList, nil
 }
 
+// BuildPrecertTBS returns the DER-encoded TBSCertificate of the
+// pre-certificate which was submitted to Logs to obtain the SCTs embedded in
+// leaf, by replacing the SCT list extension of leaf with the CT poison
+// extension. issuer must be the issuer of leaf, and is assumed to have signed
+// the pre-certificate directly rather than through a Precertificate Signing
+// Certificate. The TBSCertificate which the embedded SCTs were issued over is
+// x509.BuildPrecertTBS of the result.
+func BuildPrecertTBS(leaf, issuer *x509.Certificate) ([]byte, error) {
+	if leaf == nil || issuer == nil {
+		return nil, errors.New("leaf and issuer certificates are required")
+	}
+	if !bytes.Equal(leaf.RawIssuer, issuer.RawSubject) {
+		return nil, errors.New("leaf certificate was not issued by issuer")
+	}
+	tbs, err := x509.ReplaceSCTListWithPoison(leaf.RawTBSCertificate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace SCT list with poison: %v", err)
+	}
+	return tbs, nil
+}
+
 var pemCertificatePrefix = []byte("-----BEGIN CERTIFICATE")
 
 // ParseSCTsFromCertificate parses any SCTs that are embedded in the
@@ -898,3 +947,38 @@ func ParseSCTsFromCertificate(certBytes []byte) ([]*ct.SignedCertificateTimestam
 	}
 	return ParseSCTsFromSCTList(&cert.SCTList)
 }
+
+// SCTsFromCertificate parses the SCTs embedded in the given certificate's SCT
+// list extension. It returns no SCTs and no error if the certificate has no
+// such extension, and an error if the extension is malformed.
+func SCTsFromCertificate(cert *x509.Certificate) ([]*ct.SignedCertificateTimestamp, error) {
+	if cert == nil {
+		return nil, errors.New("certificate is nil")
+	}
+	var ext *pkix.Extension
+	for i := range cert.Extensions {
+		if cert.Extensions[i].Id.Equal(x509.OIDExtensionCTSCT) {
+			ext = &cert.Extensions[i]
+			break
+		}
+	}
+	if ext == nil {
+		return nil, nil
+	}
+
+	// Decode the extension afresh, as x509.ParseCertificate only reports a
+	// malformed SCT list as a non-fatal error.
+	var rawSCTList []byte
+	if rest, err := asn1.Unmarshal(ext.Value, &rawSCTList); err != nil {
+		return nil, fmt.Errorf("failed to asn1.Unmarshal SCT list extension: %v", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after ASN1-encoded SCT list")
+	}
+	var sctList x509.SignedCertificateTimestampList
+	if rest, err := tls.Unmarshal(rawSCTList, &sctList); err != nil {
+		return nil, fmt.Errorf("failed to tls.Unmarshal SCT list: %v", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after TLS-encoded SCT list")
+	}
+	return ParseSCTsFromSCTList(&sctList)
+}
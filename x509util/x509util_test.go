@@ -0,0 +1,219 @@
+// Copyright 2022 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package x509util_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/asn1"
+	"github.com/google/certificate-transparency-go/testdata"
+	"github.com/google/certificate-transparency-go/tls"
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/certificate-transparency-go/x509/pkix"
+	"github.com/google/certificate-transparency-go/x509util"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of the tests")
+
+// makeCert returns a self-signed certificate with the given SCT list and extra
+// extensions.
+func makeCert(t *testing.T, sctList x509.SignedCertificateTimestampList, extra []pkix.Extension) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey()=nil,%v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: "sct.example.com"},
+		NotBefore:       time.Unix(1600000000, 0),
+		NotAfter:        time.Unix(1700000000, 0),
+		SCTList:         sctList,
+		ExtraExtensions: extra,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate()=nil,%v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if x509.IsFatal(err) {
+		t.Fatalf("x509.ParseCertificate()=nil,%v", err)
+	}
+	return cert
+}
+
+func TestSCTsFromCertificate(t *testing.T) {
+	var want []*ct.SignedCertificateTimestamp
+	var sctList x509.SignedCertificateTimestampList
+	for _, data := range [][]byte{testdata.TestCertProof, testdata.TestPreCertProof, testdata.TestInvalidProof} {
+		var sct ct.SignedCertificateTimestamp
+		if _, err := tls.Unmarshal(data, &sct); err != nil {
+			t.Fatalf("tls.Unmarshal()=_,%v", err)
+		}
+		want = append(want, &sct)
+		sctList.SCTList = append(sctList.SCTList, x509.SerializedSCT{Val: data})
+	}
+
+	malformed := func(sctListData []byte) []pkix.Extension {
+		value, err := asn1.Marshal(sctListData)
+		if err != nil {
+			t.Fatalf("asn1.Marshal()=nil,%v", err)
+		}
+		return []pkix.Extension{{Id: x509.OIDExtensionCTSCT, Value: value}}
+	}
+
+	tests := []struct {
+		name    string
+		cert    *x509.Certificate
+		want    []*ct.SignedCertificateTimestamp
+		wantErr bool
+	}{
+		{name: "ThreeSCTs", cert: makeCert(t, sctList, nil), want: want},
+		{name: "NoExtension", cert: makeCert(t, x509.SignedCertificateTimestampList{}, nil)},
+		{
+			name:    "NotOctetString",
+			cert:    makeCert(t, x509.SignedCertificateTimestampList{}, []pkix.Extension{{Id: x509.OIDExtensionCTSCT, Value: []byte{0x01, 0x02}}}),
+			wantErr: true,
+		},
+		{
+			name:    "TruncatedList",
+			cert:    makeCert(t, x509.SignedCertificateTimestampList{}, malformed([]byte{0x00, 0x10, 0x00})),
+			wantErr: true,
+		},
+		{
+			name:    "BadSCT",
+			cert:    makeCert(t, x509.SignedCertificateTimestampList{}, malformed([]byte{0x00, 0x03, 0x00, 0x01, 0x07})),
+			wantErr: true,
+		},
+		{name: "NilCert", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := x509util.SCTsFromCertificate(test.cert)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("SCTsFromCertificate()=_,%v; want error? %t", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("SCTsFromCertificate()=%v; want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSCTToString(t *testing.T) {
+	parse := func(data []byte) *ct.SignedCertificateTimestamp {
+		var sct ct.SignedCertificateTimestamp
+		if _, err := tls.Unmarshal(data, &sct); err != nil {
+			t.Fatalf("tls.Unmarshal()=_,%v", err)
+		}
+		return &sct
+	}
+	withExts := parse(testdata.TestPreCertProof)
+	withExts.Extensions = ct.CTExtensions{0x00, 0x01, 0x02, 0x03}
+
+	tests := []struct {
+		name string
+		sct  *ct.SignedCertificateTimestamp
+	}{
+		{name: "cert", sct: parse(testdata.TestCertProof)},
+		{name: "extensions", sct: withExts},
+		{name: "nil"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := x509util.SCTToString(test.sct)
+			golden := filepath.Join("testdata", "sct-"+test.name+".golden")
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatalf("os.WriteFile()=%v", err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("os.ReadFile()=_,%v", err)
+			}
+			if got != string(want) {
+				t.Errorf("SCTToString()=\n%s\nwant (from %s):\n%s", got, golden, want)
+			}
+		})
+	}
+}
+
+func TestBuildPrecertTBS(t *testing.T) {
+	parse := func(pemData string) *x509.Certificate {
+		t.Helper()
+		cert, err := x509util.CertificateFromPEM([]byte(pemData))
+		if x509.IsFatal(err) {
+			t.Fatalf("failed to parse certificate: %v", err)
+		}
+		return cert
+	}
+	ca := parse(testdata.CACertPEM)
+	embedded := parse(testdata.TestEmbeddedCertPEM)
+	precert := parse(testdata.TestPreCertPEM)
+
+	for _, test := range []struct {
+		desc    string
+		leaf    *x509.Certificate
+		issuer  *x509.Certificate
+		want    []byte
+		wantErr string
+	}{
+		{desc: "embedded", leaf: embedded, issuer: ca, want: precert.RawTBSCertificate},
+		{desc: "no-sct-list", leaf: parse(testdata.TestCertPEM), issuer: ca, wantErr: "failed to replace SCT list"},
+		{desc: "wrong-issuer", leaf: embedded, issuer: embedded, wantErr: "not issued by issuer"},
+		{desc: "no-issuer", leaf: embedded, wantErr: "are required"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := x509util.BuildPrecertTBS(test.leaf, test.issuer)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("BuildPrecertTBS()=_,%v; want err containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildPrecertTBS()=nil,%v; want tbs,nil", err)
+			}
+			if !bytes.Equal(got, test.want) {
+				t.Errorf("BuildPrecertTBS()=%x; want %x", got, test.want)
+			}
+			// The logged TBSCertificate is that of the leaf without SCT list.
+			logged, err := x509.BuildPrecertTBS(got, nil)
+			if err != nil {
+				t.Fatalf("x509.BuildPrecertTBS()=nil,%v; want tbs,nil", err)
+			}
+			want, err := x509.RemoveSCTList(test.leaf.RawTBSCertificate)
+			if err != nil {
+				t.Fatalf("x509.RemoveSCTList()=nil,%v; want tbs,nil", err)
+			}
+			if !bytes.Equal(logged, want) {
+				t.Errorf("x509.BuildPrecertTBS(BuildPrecertTBS())=%x; want %x", logged, want)
+			}
+		})
+	}
+}